@@ -27,7 +27,9 @@ CREATE TABLE hubs (
                        email TEXT NOT NULL,
                        name TEXT NOT NULL,
                        sensor TEXT NOT NULL,
-                       UNIQUE (email, sensor)
+                       secret_hash TEXT,
+                       -- идентификатор хаба — логин устройства в брокере, один на всю систему
+                       UNIQUE (sensor)
 );
 
 CREATE TABLE queens (
@@ -38,6 +40,19 @@ CREATE TABLE queens (
                         UNIQUE (email, name)
 );
 
+-- Пасека: координаты и часовой пояс нужны анализаторам (зима по широте, границы суток)
+CREATE TABLE apiaries (
+                          id SERIAL PRIMARY KEY,
+                          user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+                          name TEXT NOT NULL,
+                          latitude FLOAT,
+                          longitude FLOAT,
+                          altitude FLOAT,
+                          timezone TEXT NOT NULL DEFAULT 'UTC',
+                          notes TEXT NOT NULL DEFAULT '',
+                          UNIQUE (user_id, name)
+);
+
 CREATE TABLE hives (
                        id SERIAL PRIMARY KEY,
                        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
@@ -47,9 +62,44 @@ CREATE TABLE hives (
                        sensor_id INTEGER REFERENCES sensors(id),
                        hub_id INTEGER REFERENCES hubs(id),
                        queen_id INTEGER REFERENCES queens(id),
-                       status BOOLEAN DEFAULT TRUE
+                       status BOOLEAN DEFAULT TRUE,
+                       -- auto — сезон по календарю пасеки, summer/winter — выставлен вручную
+                       season_mode TEXT NOT NULL DEFAULT 'auto',
+                       apiary_id INTEGER REFERENCES apiaries(id) ON DELETE SET NULL
 );
 CREATE INDEX ON hives (user_id);
+CREATE INDEX ON hives (apiary_id);
+
+-- Участники пасеки или улья с ролями (viewer, editor, owner); владелец —
+-- тот, кто завёл улей или пасеку, в таблице не хранится.
+CREATE TABLE memberships (
+                             id SERIAL PRIMARY KEY,
+                             user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                             apiary_id INTEGER REFERENCES apiaries(id) ON DELETE CASCADE,
+                             hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+                             role TEXT NOT NULL,
+                             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                             CHECK ((apiary_id IS NULL) <> (hive_id IS NULL))
+);
+CREATE UNIQUE INDEX ON memberships (user_id, apiary_id) WHERE apiary_id IS NOT NULL;
+CREATE UNIQUE INDEX ON memberships (user_id, hive_id) WHERE hive_id IS NOT NULL;
+CREATE INDEX ON memberships (apiary_id);
+CREATE INDEX ON memberships (hive_id);
+
+-- Приглашения по почте: хранится только хеш токена из письма
+CREATE TABLE invitations (
+                             id BIGSERIAL PRIMARY KEY,
+                             token_hash TEXT NOT NULL UNIQUE,
+                             email TEXT NOT NULL,
+                             apiary_id INTEGER REFERENCES apiaries(id) ON DELETE CASCADE,
+                             hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+                             role TEXT NOT NULL,
+                             invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                             expires_at TIMESTAMP NOT NULL,
+                             CHECK ((apiary_id IS NULL) <> (hive_id IS NULL))
+);
+CREATE INDEX ON invitations (lower(email));
 
 CREATE TABLE tasks (
                        id TEXT PRIMARY KEY,
@@ -62,28 +112,100 @@ CREATE TABLE tasks (
 CREATE INDEX ON tasks (email);
 CREATE INDEX ON tasks (email, hive_name);
 
+-- Осмотры ульев; незаполненные показатели — NULL (не оценивались)
+CREATE TABLE inspections (
+                             id BIGSERIAL PRIMARY KEY,
+                             hive_id INTEGER NOT NULL REFERENCES hives(id) ON DELETE CASCADE,
+                             inspected_on DATE NOT NULL,
+                             brood_frames FLOAT,
+                             honey_frames FLOAT,
+                             pollen_frames FLOAT,
+                             queen_seen BOOLEAN,
+                             eggs_seen BOOLEAN,
+                             queen_cells INTEGER,
+                             temperament TEXT NOT NULL DEFAULT '',
+                             varroa_count INTEGER,
+                             varroa_method TEXT NOT NULL DEFAULT '',
+                             notes TEXT NOT NULL DEFAULT '',
+                             author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+                             created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX ON inspections (hive_id, inspected_on DESC);
+
+-- Сырые замеры секционированы по месяцам. Месячные секции создаёт заранее и удаляет
+-- по сроку хранения фоновое задание (internal/analyzer/partition); в секцию default
+-- попадают замеры, для месяца которых секции ещё нет.
+-- quality — флаг достоверности замера (ok, out_of_range, spike, stuck), выставляется
+-- при приёме (internal/domain/quality); анализаторы и агрегаты берут только ok.
 CREATE TABLE temperature (
-                             id SERIAL PRIMARY KEY,
+                             id SERIAL,
                              hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
                              level FLOAT NOT NULL,
-                             recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                             recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                             quality TEXT NOT NULL DEFAULT 'ok',
+                             PRIMARY KEY (id, recorded_at),
                              UNIQUE (hub_id, recorded_at)
-);
+) PARTITION BY RANGE (recorded_at);
+CREATE TABLE temperature_default PARTITION OF temperature DEFAULT;
 
 CREATE TABLE weight (
-                        id SERIAL PRIMARY KEY,
+                        id SERIAL,
                         hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
                         level FLOAT NOT NULL,
-                        recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                        quality TEXT NOT NULL DEFAULT 'ok',
+                        PRIMARY KEY (id, recorded_at),
                         UNIQUE (hub_id, recorded_at)
-);
+) PARTITION BY RANGE (recorded_at);
+CREATE TABLE weight_default PARTITION OF weight DEFAULT;
 
 CREATE TABLE noise (
-                       id SERIAL PRIMARY KEY,
+                       id SERIAL,
                        hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
                        level FLOAT NOT NULL,
-                       recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                       quality TEXT NOT NULL DEFAULT 'ok',
+                       PRIMARY KEY (id, recorded_at),
                        UNIQUE (hub_id, recorded_at)
+) PARTITION BY RANGE (recorded_at);
+CREATE TABLE noise_default PARTITION OF noise DEFAULT;
+
+-- Часовые и суточные агрегаты телеметрии (min/max/avg/count). Поддерживаются
+-- фоновым заданием (internal/analyzer/rollup); metric — temperature, noise или weight.
+CREATE TABLE telemetry_hourly (
+                       hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+                       metric TEXT NOT NULL,
+                       bucket TIMESTAMP NOT NULL,
+                       min_value FLOAT NOT NULL,
+                       max_value FLOAT NOT NULL,
+                       avg_value FLOAT NOT NULL,
+                       sample_count INTEGER NOT NULL,
+                       PRIMARY KEY (hub_id, metric, bucket)
+);
+
+CREATE TABLE telemetry_daily (
+                       hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+                       metric TEXT NOT NULL,
+                       bucket TIMESTAMP NOT NULL,
+                       min_value FLOAT NOT NULL,
+                       max_value FLOAT NOT NULL,
+                       avg_value FLOAT NOT NULL,
+                       sample_count INTEGER NOT NULL,
+                       PRIMARY KEY (hub_id, metric, bucket)
+);
+
+-- Device shadow: желаемая (desired) и применённая устройством (reported) конфигурация хаба.
+-- -1 — параметр не задан / устройство о нём не сообщало.
+CREATE TABLE device_shadow (
+                       hub_id INTEGER PRIMARY KEY REFERENCES hubs(id) ON DELETE CASCADE,
+                       desired_sampling_noise INT NOT NULL DEFAULT -1,
+                       desired_sampling_temp INT NOT NULL DEFAULT -1,
+                       desired_frequency INT NOT NULL DEFAULT -1,
+                       desired_at TIMESTAMP,
+                       reported_sampling_noise INT NOT NULL DEFAULT -1,
+                       reported_sampling_temp INT NOT NULL DEFAULT -1,
+                       reported_frequency INT NOT NULL DEFAULT -1,
+                       reported_at TIMESTAMP
 );
 
 CREATE TABLE app_description (
@@ -106,4 +228,125 @@ CREATE TABLE instruction_items (
 );
 
 CREATE UNIQUE INDEX instruction_items_position_idx
-                       ON instruction_items(position);
\ No newline at end of file
+                       ON instruction_items(position);
+-- История прогонов фоновых анализаторов (internal/analyzer.Registry)
+CREATE TABLE analyzer_runs (
+                       id SERIAL PRIMARY KEY,
+                       analyzer TEXT NOT NULL,
+                       started_at TIMESTAMPTZ NOT NULL,
+                       finished_at TIMESTAMPTZ NOT NULL,
+                       hives_processed INT NOT NULL DEFAULT 0,
+                       alerts_raised INT NOT NULL DEFAULT 0,
+                       errors TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX analyzer_runs_analyzer_started_idx
+                       ON analyzer_runs(analyzer, started_at DESC);
+
+-- Пороги алертов: строка без улья (hive_id IS NULL) — значения пользователя по умолчанию,
+-- строка с ульем — переопределения для улья. NULL в поле — порог наследуется.
+CREATE TABLE alert_rules (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+                       noise_high FLOAT,
+                       noise_delta FLOAT,
+                       temperature_normal FLOAT,
+                       temperature_delta_up FLOAT,
+                       temperature_delta_down FLOAT,
+                       battery_low INT,
+                       signal_low INT,
+                       updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX alert_rules_user_default_idx
+                       ON alert_rules(user_id) WHERE hive_id IS NULL;
+CREATE UNIQUE INDEX alert_rules_hive_idx
+                       ON alert_rules(hive_id);
+
+-- Входящие уведомления: каждый отправленный алерт, даже если push не дошёл до телефона.
+-- read_at IS NULL — не прочитано. При удалении улья история остаётся без привязки.
+CREATE TABLE notifications (
+                       id BIGSERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       hive_id INTEGER REFERENCES hives(id) ON DELETE SET NULL,
+                       type TEXT NOT NULL,
+                       severity TEXT NOT NULL,
+                       title TEXT NOT NULL,
+                       body TEXT NOT NULL DEFAULT '',
+                       created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                       read_at TIMESTAMPTZ
+);
+
+CREATE INDEX notifications_user_created_idx
+                       ON notifications(user_id, created_at DESC);
+CREATE INDEX notifications_user_unread_idx
+                       ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Настройки доставки уведомлений. Нет строки — только push (значения по умолчанию).
+-- webhook_secret подписывает тело вебхука (HMAC-SHA256, заголовок X-BeeIOT-Signature).
+CREATE TABLE notification_preferences (
+                       user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                       channels TEXT[] NOT NULL DEFAULT '{push}',
+                       webhook_url TEXT NOT NULL DEFAULT '',
+                       webhook_secret TEXT NOT NULL DEFAULT '',
+                       alert_types JSONB NOT NULL DEFAULT '{}',
+                       min_severity TEXT NOT NULL DEFAULT 'info',
+                       timezone TEXT NOT NULL DEFAULT 'UTC',
+                       quiet_hours JSONB NOT NULL DEFAULT '[]',
+                       digest TEXT NOT NULL DEFAULT 'off',
+                       digest_sent_at TIMESTAMPTZ,
+                       updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Уведомления, придержанные на время тихих часов; уходят одной сводкой после их окончания.
+CREATE TABLE held_notifications (
+                       id BIGSERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                       message JSONB NOT NULL,
+                       created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_held_notifications_user ON held_notifications(user_id, created_at);
+
+-- Хронология улья: события, найденные анализаторами (source = 'analyzer') или записанные пасечником.
+CREATE TABLE hive_events (
+                       id BIGSERIAL PRIMARY KEY,
+                       hive_id INTEGER NOT NULL REFERENCES hives(id) ON DELETE CASCADE,
+                       type TEXT NOT NULL,
+                       source TEXT NOT NULL DEFAULT 'analyzer',
+                       title TEXT NOT NULL,
+                       details JSONB NOT NULL DEFAULT '{}',
+                       occurred_at TIMESTAMPTZ NOT NULL,
+                       created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_hive_events_hive ON hive_events(hive_id, occurred_at DESC);
+
+-- Спектр шума: уровень levels[i] (дБ) в полосе [edges[i], edges[i+1]) Гц.
+-- Набор полос задаёт прошивка, поэтому границы хранятся вместе с уровнями.
+-- Секционирован по месяцам, как сырые замеры (см. internal/analyzer/partition).
+CREATE TABLE noise_spectrum (
+                       id BIGSERIAL,
+                       hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+                       edges FLOAT[] NOT NULL,
+                       levels FLOAT[] NOT NULL,
+                       recorded_at TIMESTAMP NOT NULL,
+                       PRIMARY KEY (id, recorded_at),
+                       UNIQUE (hub_id, recorded_at)
+) PARTITION BY RANGE (recorded_at);
+CREATE TABLE noise_spectrum_default PARTITION OF noise_spectrum DEFAULT;
+
+-- История статусов устройств (заряд, сигнал, ошибки); -1 — значение не сообщено.
+-- Секционирована по месяцам, как сырые замеры.
+CREATE TABLE device_status (
+                       id BIGSERIAL,
+                       hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+                       battery_level INT NOT NULL DEFAULT -1,
+                       signal_strength INT NOT NULL DEFAULT -1,
+                       errors TEXT[] NOT NULL DEFAULT '{}',
+                       recorded_at TIMESTAMP NOT NULL,
+                       PRIMARY KEY (id, recorded_at),
+                       UNIQUE (hub_id, recorded_at)
+) PARTITION BY RANGE (recorded_at);
+CREATE TABLE device_status_default PARTITION OF device_status DEFAULT;
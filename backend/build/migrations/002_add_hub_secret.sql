@@ -0,0 +1,4 @@
+-- Секрет устройства для аутентификации в MQTT-брокере (bcrypt-хеш).
+-- У хабов, созданных до миграции, секрета нет — его нужно выпустить
+-- через POST /api/hub/secret/rotate.
+ALTER TABLE hubs ADD COLUMN IF NOT EXISTS secret_hash TEXT;
@@ -0,0 +1,13 @@
+-- Device shadow: желаемая (desired) и применённая устройством (reported) конфигурация хаба.
+-- -1 — параметр не задан / устройство о нём не сообщало.
+CREATE TABLE IF NOT EXISTS device_shadow (
+    hub_id INTEGER PRIMARY KEY REFERENCES hubs(id) ON DELETE CASCADE,
+    desired_sampling_noise INT NOT NULL DEFAULT -1,
+    desired_sampling_temp INT NOT NULL DEFAULT -1,
+    desired_frequency INT NOT NULL DEFAULT -1,
+    desired_at TIMESTAMP,
+    reported_sampling_noise INT NOT NULL DEFAULT -1,
+    reported_sampling_temp INT NOT NULL DEFAULT -1,
+    reported_frequency INT NOT NULL DEFAULT -1,
+    reported_at TIMESTAMP
+);
@@ -0,0 +1,23 @@
+-- Часовые и суточные агрегаты телеметрии (min/max/avg/count). Поддерживаются
+-- фоновым заданием (internal/analyzer/rollup); metric — temperature, noise или weight.
+CREATE TABLE IF NOT EXISTS telemetry_hourly (
+    hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+    metric TEXT NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    min_value FLOAT NOT NULL,
+    max_value FLOAT NOT NULL,
+    avg_value FLOAT NOT NULL,
+    sample_count INTEGER NOT NULL,
+    PRIMARY KEY (hub_id, metric, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS telemetry_daily (
+    hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+    metric TEXT NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    min_value FLOAT NOT NULL,
+    max_value FLOAT NOT NULL,
+    avg_value FLOAT NOT NULL,
+    sample_count INTEGER NOT NULL,
+    PRIMARY KEY (hub_id, metric, bucket)
+);
@@ -0,0 +1,26 @@
+-- Переводит temperature, weight и noise на секционирование по месяцам.
+-- Все имеющиеся замеры переносятся в секцию default; при первом запуске фоновое
+-- задание (internal/analyzer/partition) создаёт месячные секции и перекладывает
+-- в них строки из default. Повторный запуск миграции ничего не делает.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['temperature', 'weight', 'noise'] LOOP
+        IF EXISTS (SELECT 1 FROM pg_class WHERE relname = t AND relkind = 'r') THEN
+            EXECUTE format('ALTER TABLE %I RENAME TO %I', t, t || '_old');
+            EXECUTE format('CREATE TABLE %I (
+                id SERIAL,
+                hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+                level FLOAT NOT NULL,
+                recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY (id, recorded_at),
+                UNIQUE (hub_id, recorded_at)
+            ) PARTITION BY RANGE (recorded_at)', t);
+            EXECUTE format('CREATE TABLE %I PARTITION OF %I DEFAULT', t || '_default', t);
+            EXECUTE format('INSERT INTO %I (hub_id, level, recorded_at)
+                SELECT hub_id, level, recorded_at FROM %I WHERE recorded_at IS NOT NULL', t, t || '_old');
+            EXECUTE format('DROP TABLE %I', t || '_old');
+        END IF;
+    END LOOP;
+END $$;
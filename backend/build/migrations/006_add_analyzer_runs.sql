@@ -0,0 +1,14 @@
+-- История прогонов фоновых анализаторов (internal/analyzer.Registry): когда
+-- запускался, сколько ульев обработал, сколько алертов поднял и какие были ошибки.
+CREATE TABLE IF NOT EXISTS analyzer_runs (
+    id SERIAL PRIMARY KEY,
+    analyzer TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    hives_processed INT NOT NULL DEFAULT 0,
+    alerts_raised INT NOT NULL DEFAULT 0,
+    errors TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS analyzer_runs_analyzer_started_idx
+    ON analyzer_runs(analyzer, started_at DESC);
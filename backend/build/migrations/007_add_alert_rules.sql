@@ -0,0 +1,21 @@
+-- Пороги алертов: строка без улья (hive_id IS NULL) — значения пользователя по умолчанию,
+-- строка с ульем — переопределения для улья. NULL в поле — порог наследуется
+-- (от правил пользователя, а затем от значений по умолчанию в internal/domain/alerts).
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+    noise_high FLOAT,
+    noise_delta FLOAT,
+    temperature_normal FLOAT,
+    temperature_delta_up FLOAT,
+    temperature_delta_down FLOAT,
+    battery_low INT,
+    signal_low INT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS alert_rules_user_default_idx
+    ON alert_rules(user_id) WHERE hive_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS alert_rules_hive_idx
+    ON alert_rules(hive_id);
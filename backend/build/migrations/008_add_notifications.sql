@@ -0,0 +1,18 @@
+-- Входящие уведомления: каждый отправленный алерт, даже если push не дошёл до телефона.
+-- read_at IS NULL — не прочитано. При удалении улья история остаётся без привязки.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hive_id INTEGER REFERENCES hives(id) ON DELETE SET NULL,
+    type TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx
+    ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS notifications_user_unread_idx
+    ON notifications(user_id) WHERE read_at IS NULL;
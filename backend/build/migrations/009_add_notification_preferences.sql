@@ -0,0 +1,9 @@
+-- Настройки доставки уведомлений. Нет строки — только push (значения по умолчанию).
+-- webhook_secret подписывает тело вебхука (HMAC-SHA256, заголовок X-BeeIOT-Signature).
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    channels TEXT[] NOT NULL DEFAULT '{push}',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_secret TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
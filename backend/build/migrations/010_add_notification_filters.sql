@@ -0,0 +1,15 @@
+-- Фильтры уведомлений: типы алертов, минимальная важность, тихие часы в зоне пользователя.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS alert_types JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS min_severity TEXT NOT NULL DEFAULT 'info';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours JSONB NOT NULL DEFAULT '[]';
+
+-- Уведомления, придержанные на время тихих часов; уходят одной сводкой после их окончания.
+CREATE TABLE IF NOT EXISTS held_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_held_notifications_user ON held_notifications(user_id, created_at);
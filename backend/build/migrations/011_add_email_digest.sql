@@ -0,0 +1,3 @@
+-- Сводка по пасеке на почту: off, daily или weekly; digest_sent_at — время прошлой отправки.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest TEXT NOT NULL DEFAULT 'off';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMPTZ;
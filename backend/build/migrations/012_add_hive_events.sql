@@ -0,0 +1,13 @@
+-- Хронология улья: события, найденные анализаторами (source = 'analyzer') или записанные пасечником.
+CREATE TABLE IF NOT EXISTS hive_events (
+    id BIGSERIAL PRIMARY KEY,
+    hive_id INTEGER NOT NULL REFERENCES hives(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'analyzer',
+    title TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_hive_events_hive ON hive_events(hive_id, occurred_at DESC);
@@ -0,0 +1,10 @@
+-- Спектр шума: уровень levels[i] (дБ) в полосе [edges[i], edges[i+1]) Гц.
+-- Набор полос задаёт прошивка, поэтому границы хранятся вместе с уровнями.
+CREATE TABLE IF NOT EXISTS noise_spectrum (
+    id BIGSERIAL PRIMARY KEY,
+    hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+    edges FLOAT[] NOT NULL,
+    levels FLOAT[] NOT NULL,
+    recorded_at TIMESTAMP NOT NULL,
+    UNIQUE (hub_id, recorded_at)
+);
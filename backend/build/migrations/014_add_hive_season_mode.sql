@@ -0,0 +1,3 @@
+-- Режим сезона улья для анализатора температуры: auto — по календарю пасеки,
+-- summer/winter — выставлен пасечником вручную.
+ALTER TABLE hives ADD COLUMN IF NOT EXISTS season_mode TEXT NOT NULL DEFAULT 'auto';
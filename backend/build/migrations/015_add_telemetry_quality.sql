@@ -0,0 +1,5 @@
+-- Флаг достоверности сырых замеров: ok, out_of_range, spike, stuck (internal/domain/quality).
+-- Столбец, добавленный к секционированной таблице, появляется во всех её секциях.
+ALTER TABLE temperature ADD COLUMN IF NOT EXISTS quality TEXT NOT NULL DEFAULT 'ok';
+ALTER TABLE weight ADD COLUMN IF NOT EXISTS quality TEXT NOT NULL DEFAULT 'ok';
+ALTER TABLE noise ADD COLUMN IF NOT EXISTS quality TEXT NOT NULL DEFAULT 'ok';
@@ -0,0 +1,12 @@
+-- История статусов устройств (заряд, сигнал, ошибки). Раньше хранился только
+-- последний статус в Redis; история нужна для выгрузки телеметрии.
+-- -1 — устройство значение не сообщило.
+CREATE TABLE IF NOT EXISTS device_status (
+    id BIGSERIAL PRIMARY KEY,
+    hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+    battery_level INT NOT NULL DEFAULT -1,
+    signal_strength INT NOT NULL DEFAULT -1,
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    recorded_at TIMESTAMP NOT NULL,
+    UNIQUE (hub_id, recorded_at)
+);
@@ -0,0 +1,16 @@
+-- Пасеки: место, где стоят ульи. Координаты и часовой пояс пасеки нужны
+-- анализаторам — по широте оценивается зима, по поясу считаются границы суток.
+CREATE TABLE IF NOT EXISTS apiaries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    latitude FLOAT,
+    longitude FLOAT,
+    altitude FLOAT,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    notes TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE hives ADD COLUMN IF NOT EXISTS apiary_id INTEGER REFERENCES apiaries(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS hives_apiary_id_idx ON hives (apiary_id);
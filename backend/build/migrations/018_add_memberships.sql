@@ -0,0 +1,31 @@
+-- Участники пасеки или улья с ролями (viewer, editor, owner). Тот, кто завёл
+-- улей или пасеку, — владелец и в таблице не хранится.
+CREATE TABLE IF NOT EXISTS memberships (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    apiary_id INTEGER REFERENCES apiaries(id) ON DELETE CASCADE,
+    hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((apiary_id IS NULL) <> (hive_id IS NULL))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS memberships_user_apiary_idx ON memberships (user_id, apiary_id) WHERE apiary_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS memberships_user_hive_idx ON memberships (user_id, hive_id) WHERE hive_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS memberships_apiary_id_idx ON memberships (apiary_id);
+CREATE INDEX IF NOT EXISTS memberships_hive_id_idx ON memberships (hive_id);
+
+-- Приглашения по почте. Хранится только хеш токена из письма; принять приглашение
+-- может лишь пользователь с тем же адресом почты.
+CREATE TABLE IF NOT EXISTS invitations (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+    apiary_id INTEGER REFERENCES apiaries(id) ON DELETE CASCADE,
+    hive_id INTEGER REFERENCES hives(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    CHECK ((apiary_id IS NULL) <> (hive_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS invitations_email_idx ON invitations (lower(email));
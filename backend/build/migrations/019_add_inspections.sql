@@ -0,0 +1,20 @@
+-- Осмотры ульев: структурированная запись того, что пасечник увидел в улье.
+-- Незаполненные показатели — NULL (не оценивались), а не ноль.
+CREATE TABLE IF NOT EXISTS inspections (
+    id BIGSERIAL PRIMARY KEY,
+    hive_id INTEGER NOT NULL REFERENCES hives(id) ON DELETE CASCADE,
+    inspected_on DATE NOT NULL,
+    brood_frames FLOAT,
+    honey_frames FLOAT,
+    pollen_frames FLOAT,
+    queen_seen BOOLEAN,
+    eggs_seen BOOLEAN,
+    queen_cells INTEGER,
+    temperament TEXT NOT NULL DEFAULT '',
+    varroa_count INTEGER,
+    varroa_method TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS inspections_hive_idx ON inspections (hive_id, inspected_on DESC);
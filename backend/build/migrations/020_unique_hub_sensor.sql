@@ -0,0 +1,22 @@
+-- Идентификатор хаба — логин устройства в MQTT-брокере, поэтому он должен быть
+-- единственным на всю систему, а не у одного пользователя: UNIQUE (email, sensor)
+-- заменяется на UNIQUE (sensor), как в init.sql. Из ранее заведённых дубликатов
+-- идентификатор остаётся за хабом с секретом (или за первым заведённым), остальные
+-- получают суффикс -dup-<id> и теряют секрет: они не смогут войти в брокер, пока
+-- их владельцы не заведут хаб под своим идентификатором. История замеров остаётся
+-- у переименованных хабов. Повторный запуск миграции ничего не делает.
+UPDATE hubs SET sensor = sensor || '-dup-' || id, secret_hash = NULL
+WHERE id NOT IN (
+    SELECT DISTINCT ON (sensor) id FROM hubs
+    ORDER BY sensor, secret_hash IS NULL, id
+);
+
+DROP INDEX IF EXISTS hubs_sensor_secret_idx;
+ALTER TABLE hubs DROP CONSTRAINT IF EXISTS hubs_email_sensor_key;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'hubs_sensor_key') THEN
+        ALTER TABLE hubs ADD CONSTRAINT hubs_sensor_key UNIQUE (sensor);
+    END IF;
+END $$;
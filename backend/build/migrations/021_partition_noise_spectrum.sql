@@ -0,0 +1,23 @@
+-- Переводит noise_spectrum на секционирование по месяцам, как сырые замеры в 005:
+-- месячные секции создаёт и удаляет по сроку хранения фоновое задание
+-- (internal/analyzer/partition). Имеющиеся строки переносятся в секцию default.
+-- Повторный запуск миграции ничего не делает.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'noise_spectrum' AND relkind = 'r') THEN
+        ALTER TABLE noise_spectrum RENAME TO noise_spectrum_old;
+        CREATE TABLE noise_spectrum (
+            id BIGSERIAL,
+            hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+            edges FLOAT[] NOT NULL,
+            levels FLOAT[] NOT NULL,
+            recorded_at TIMESTAMP NOT NULL,
+            PRIMARY KEY (id, recorded_at),
+            UNIQUE (hub_id, recorded_at)
+        ) PARTITION BY RANGE (recorded_at);
+        CREATE TABLE noise_spectrum_default PARTITION OF noise_spectrum DEFAULT;
+        INSERT INTO noise_spectrum (hub_id, edges, levels, recorded_at)
+            SELECT hub_id, edges, levels, recorded_at FROM noise_spectrum_old;
+        DROP TABLE noise_spectrum_old;
+    END IF;
+END $$;
@@ -0,0 +1,24 @@
+-- Переводит device_status на секционирование по месяцам, как noise_spectrum в 021:
+-- месячные секции создаёт и удаляет по сроку хранения фоновое задание
+-- (internal/analyzer/partition). Имеющиеся строки переносятся в секцию default.
+-- Повторный запуск миграции ничего не делает.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'device_status' AND relkind = 'r') THEN
+        ALTER TABLE device_status RENAME TO device_status_old;
+        CREATE TABLE device_status (
+            id BIGSERIAL,
+            hub_id INTEGER REFERENCES hubs(id) ON DELETE CASCADE,
+            battery_level INT NOT NULL DEFAULT -1,
+            signal_strength INT NOT NULL DEFAULT -1,
+            errors TEXT[] NOT NULL DEFAULT '{}',
+            recorded_at TIMESTAMP NOT NULL,
+            PRIMARY KEY (id, recorded_at),
+            UNIQUE (hub_id, recorded_at)
+        ) PARTITION BY RANGE (recorded_at);
+        CREATE TABLE device_status_default PARTITION OF device_status DEFAULT;
+        INSERT INTO device_status (hub_id, battery_level, signal_strength, errors, recorded_at)
+            SELECT hub_id, battery_level, signal_strength, errors, recorded_at FROM device_status_old;
+        DROP TABLE device_status_old;
+    END IF;
+END $$;
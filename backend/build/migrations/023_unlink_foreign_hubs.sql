@@ -0,0 +1,7 @@
+-- Хаб можно привязать только к улью его владельца: телеметрия и алерты хаба ведутся
+-- от имени владельца хаба. Раньше редактор мог привязать к чужому улью свой хаб —
+-- такие привязки снимаются, хабы остаются у своих владельцев.
+-- Повторный запуск миграции ничего не делает.
+UPDATE hives h SET hub_id = NULL
+FROM hubs hu, users o
+WHERE hu.id = h.hub_id AND o.id = h.user_id AND hu.email <> o.email;
@@ -0,0 +1,87 @@
+// Команда import загружает телеметрию хаба из CSV напрямую в БД — тот же разбор
+// и та же запись, что у POST /api/telemetry/import, но без ограничения на размер
+// загрузки. Подключение к БД — из переменных DB_USER, DB_PASSWORD, DB_HOST, DB_PORT, DB_NAME.
+//
+//	go run ./cmd/import -email foo@bar.com -hub hub-001 -file scale.csv \
+//	    -columns "weight=Вес кг" -tz Europe/Moscow -dry-run
+package main
+
+import (
+	"BeeIOT/internal/domain/importer"
+	"BeeIOT/internal/infrastructure/postgres"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	email := flag.String("email", "", "владелец хаба")
+	hub := flag.String("hub", "", "идентификатор (sensor) хаба")
+	path := flag.String("file", "", "CSV-файл; - — stdin")
+	columns := flag.String("columns", "", "столбцы метрик: temperature=...,noise=...,weight=...")
+	units := flag.String("units", "", "единицы в файле: temperature=c|f|k, weight=kg|g|lb, noise=db")
+	timeColumn := flag.String("time-column", "time", "столбец времени")
+	timeFormat := flag.String("time-format", "", "раскладка Go, unix или unix_ms; пусто — распространённые форматы")
+	tz := flag.String("tz", "UTC", "IANA-зона для времени без зоны")
+	delimiter := flag.String("delimiter", "", "разделитель столбцов; пусто — по заголовку")
+	dryRun := flag.Bool("dry-run", false, "только проверить файл и вывести отчёт")
+	skipInvalid := flag.Bool("skip-invalid", false, "записать файл, пропустив строки с ошибками")
+	flag.Parse()
+
+	if *email == "" || *hub == "" || *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts, err := importer.ParseOptions(*columns, *units, *timeColumn, *timeFormat, *tz, *delimiter)
+	if err != nil {
+		log.Fatalf("options: %v", err)
+	}
+
+	in := os.Stdin
+	if *path != "-" {
+		if in, err = os.Open(*path); err != nil {
+			log.Fatalf("open: %v", err)
+		}
+		defer in.Close()
+	}
+	result, err := importer.Parse(in, opts, time.Now())
+	if err != nil {
+		log.Fatalf("parse: %v", err)
+	}
+	if *dryRun {
+		printReport(result.Report(nil))
+		return
+	}
+	if result.ErrorCount > 0 && !*skipInvalid {
+		printReport(result.Report(nil))
+		log.Fatalf("%d invalid rows, fix them or pass -skip-invalid", result.ErrorCount)
+	}
+
+	db, err := postgres.NewDB()
+	if err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	if _, err := db.GetHubBySensor(ctx, *email, *hub); err != nil {
+		log.Fatalf("hub %q of %s not found: %v", *hub, *email, err)
+	}
+	imported, err := importer.Write(ctx, db, *email, *hub, result)
+	if err != nil {
+		log.Fatalf("import: %v (imported so far: %v)", err, imported)
+	}
+	printReport(result.Report(imported))
+}
+
+func printReport(report any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -1,10 +1,23 @@
 package main
 
 import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/analyzer/digest"
 	"BeeIOT/internal/analyzer/noise"
+	"BeeIOT/internal/analyzer/partition"
+	"BeeIOT/internal/analyzer/queenless"
+	"BeeIOT/internal/analyzer/quiet"
+	"BeeIOT/internal/analyzer/rollup"
+	"BeeIOT/internal/analyzer/swarm"
 	"BeeIOT/internal/analyzer/temperature"
+	"BeeIOT/internal/analyzer/watchdog"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/notifyTypes"
 	"BeeIOT/internal/domain/mqtt"
 	"BeeIOT/internal/domain/notification"
+	"BeeIOT/internal/domain/season"
+	"BeeIOT/internal/domain/stream"
 	"BeeIOT/internal/http"
 	"BeeIOT/internal/infrastructure/postgres"
 	redis2 "BeeIOT/internal/infrastructure/redis"
@@ -12,6 +25,8 @@ import (
 	"context"
 	"os"
 	"time"
+	// тихие часы считаются в зоне пользователя, а в alpine-образе нет базы зон
+	_ "time/tzdata"
 
 	"github.com/rs/zerolog"
 )
@@ -49,22 +64,45 @@ func main() {
 	analyzersCtx, cancel := context.WithCancel(context.WithValue(context.Background(), "logger", logger))
 	defer cancel()
 
-	notifi, err := notification.NewNotification(analyzersCtx, logger)
-	if err != nil {
-		logger.Error().Err(err).Msg("Failed to initialize notification")
-		return
+	// Без FIREBASE_DATA (dev, тесты) push только пишется в лог
+	var push interfaces.Notifier
+	if fcm, err := notification.NewNotification(analyzersCtx, logger); err != nil {
+		logger.Warn().Err(err).Msg("Firebase is not configured, push notifications will be logged only")
+		push = notification.NewLog(notifyTypes.ChannelPush, logger)
+	} else {
+		push = notification.NewFCM(fcm, db)
 	}
-	temperature.NewAnalyzer(analyzersCtx, 24*time.Hour, db, notifi).Start()
-	noise.NewAnalyzer(analyzersCtx, 24*time.Hour, db, notifi).Start()
+	dispatcher := notification.NewDispatcher(db, logger, push, notification.NewEmail(smtp), notification.NewWebhook())
+	// Доставка идёт в фоне, чтобы медленный вебхук или SMTP не держал обработчики MQTT
+	queue := notification.NewQueue(dispatcher, logger)
+	queue.Start(analyzersCtx, 4)
+	// Все алерты идут через один Notifier: он ведёт состояние инцидентов в Redis
+	// и гасит повторы (см. alerts.Policy).
+	notifier := alerts.NewNotifier(db, redis, queue, logger)
+	registry := analyzer.NewRegistry(analyzersCtx, db)
+	registry.Register(temperature.NewAnalyzer(analyzersCtx, db, notifier, season.CalendarFromEnv()), temperature.Period)
+	registry.Register(noise.NewAnalyzer(analyzersCtx, db, notifier), 24*time.Hour)
+	registry.Register(swarm.NewAnalyzer(analyzersCtx, db, notifier), 30*time.Minute)
+	registry.Register(queenless.NewAnalyzer(analyzersCtx, db, notifier), time.Hour)
+	registry.Register(watchdog.NewAnalyzer(analyzersCtx, db, redis, notifier), time.Minute)
+	registry.Register(rollup.NewAnalyzer(analyzersCtx, db), 15*time.Minute)
+	registry.Register(partition.NewAnalyzer(analyzersCtx, db, partition.RetentionFromEnv()), 24*time.Hour)
+	registry.Register(quiet.NewAnalyzer(analyzersCtx, dispatcher), 5*time.Minute)
+	registry.Register(digest.NewAnalyzer(analyzersCtx, db, redis, smtp, notifier), time.Hour)
+	registry.Start()
 
 	logger.Info().Msg("Initializing MQTT...")
-	mqttServer, err := mqtt.NewMQTTClient(db, redis, notifi, logger)
+	mqttServer, err := mqtt.NewMQTTClient(db, redis, notifier, logger)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to connect to mqtt server")
 		return
 	}
 	defer mqttServer.Disconnect()
 
+	logger.Info().Msg("Starting telemetry stream broker...")
+	broker := stream.NewBroker(redis, logger)
+	broker.Start(analyzersCtx)
+
 	logger.Info().Msg("Starting HTTP server...")
-	http.StartServer(db, smtp, redis, mqttServer, redis, logger)
+	http.StartServer(db, smtp, redis, mqttServer, broker, redis, logger)
 }
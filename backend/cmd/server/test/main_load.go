@@ -15,10 +15,14 @@
 package main
 
 import (
+	"BeeIOT/internal/analyzer"
 	"BeeIOT/internal/analyzer/noise"
 	"BeeIOT/internal/analyzer/temperature"
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/mqtt"
+	"BeeIOT/internal/domain/season"
+	"BeeIOT/internal/domain/stream"
 	"BeeIOT/internal/http"
 	"BeeIOT/internal/infrastructure/postgres"
 	redis2 "BeeIOT/internal/infrastructure/redis"
@@ -38,6 +42,10 @@ func (m *MockSMTP) SendConfirmationCode(_, _ string) error {
 	return nil
 }
 
+func (m *MockSMTP) SendEmail(_, _, _, _ string) error {
+	return nil
+}
+
 // MockPasswordKeeper - мок для хранения кодов подтверждения
 // Всегда сохраняет код "123456" вместо реального сгенерированного
 type MockPasswordKeeper struct {
@@ -79,10 +87,14 @@ func main() {
 	logger.Info().Msg("Starting analyzers...")
 	analyzersCtx, cancel := context.WithCancel(context.WithValue(context.Background(), "logger", logger))
 	defer cancel()
-	temperature.NewAnalyzer(analyzersCtx, 24*60*time.Hour, db, nil).Start()
-	noise.NewAnalyzer(analyzersCtx, 24*60*time.Hour, db, nil).Start()
+	registry := analyzer.NewRegistry(analyzersCtx, db)
+	// Push выключен, но состояние инцидентов ведём — как в проде
+	notifier := alerts.NewNotifier(db, redis, nil, logger)
+	registry.Register(temperature.NewAnalyzer(analyzersCtx, db, notifier, season.CalendarFromEnv()), 24*60*time.Hour)
+	registry.Register(noise.NewAnalyzer(analyzersCtx, db, notifier), 24*60*time.Hour)
+	registry.Start()
 	logger.Info().Msg("Initializing MQTT...")
-	mqttServer, err := mqtt.NewMQTTClient(db, redis, nil, logger)
+	mqttServer, err := mqtt.NewMQTTClient(db, redis, notifier, logger)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to connect to mqtt server")
 		return
@@ -90,7 +102,10 @@ func main() {
 	defer func() {
 		_ = mqttServer.Close()
 	}()
+	logger.Info().Msg("Starting telemetry stream broker...")
+	broker := stream.NewBroker(redis, logger)
+	broker.Start(analyzersCtx)
 	logger.Info().Msg("Starting HTTP server...")
 	// Передаем мок SMTP и мок PasswordKeeper
-	http.StartServer(db, smtp, redis, mqttServer, mockPasswordKeeper, logger)
+	http.StartServer(db, smtp, redis, mqttServer, broker, mockPasswordKeeper, logger)
 }
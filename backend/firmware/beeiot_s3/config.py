@@ -2,7 +2,9 @@
 """
 config.py — Конфигурация прошивки BeeIoT (ESP32-S3 + SIM7020C + DS18B20 + INMP441).
 
-ИНСТРУКЦИЯ: перед прошивкой нового устройства поменять DEVICE_ID.
+ИНСТРУКЦИЯ: перед прошивкой нового устройства поменять DEVICE_ID и
+MQTT_PASSWORD — секрет выдаётся сервером при создании хаба
+(POST /api/hub/create) или при перевыпуске (POST /api/hub/secret/rotate).
 """
 
 # === Идентификатор датчика (используется в MQTT-топиках) ===
@@ -11,13 +13,20 @@ DEVICE_ID = "sensor_001"
 # === MQTT брокер ===
 MQTT_BROKER  = "62.109.16.63"
 MQTT_PORT    = 1883
-MQTT_USER    = ""
+# Логин — идентификатор хаба, пароль — его секрет. Брокер пускает устройство
+# только в топики /device/{DEVICE_ID}/*.
+MQTT_USER    = DEVICE_ID
 MQTT_PASSWORD = ""
 MQTT_KEEPALIVE = 60
 
 TOPIC_DATA   = "/device/{}/data"
 TOPIC_STATUS = "/device/{}/status"
 TOPIC_CONFIG = "/device/{}/config"
+# Подтверждение применённого конфига (device shadow)
+TOPIC_CONFIG_ACK = "/device/{}/config/ack"
+# Last Will: брокер сам опубликует сюда сообщение, если устройство пропадёт
+# без штатного disconnect (села батарея, оборвалась связь посреди цикла).
+TOPIC_LWT    = "/device/{}/lwt"
 
 # === SIM7020C (NB-IoT) ===
 # Если симки нет — выруби чтобы не ждать таймаут каждый цикл.
@@ -125,6 +134,20 @@ NOISE_JUNK_THRESHOLD = 1 << 22
 # первые несколько минут после питания.
 NOISE_WARMUP_MS = 2000
 
+# Спектр шума по полосам (поле spectrum в /device/{id}/data). Считается FFT
+# по тому же окну, что и уровень шума: окно режется на куски по
+# SPECTRUM_FFT_SIZE сэмплов (Hann), мощности кусков усредняются (Welch).
+# При 32 кГц и 1024 точках бин ≈ 31 Гц — в каждую полосу ниже попадает
+# минимум 3 бина. Сервер ищет безматочность по доле мощности в 200–500 Гц,
+# поэтому эти границы должны остаться среди SPECTRUM_EDGES_HZ. Полос не
+# больше 64 (mqttTypes.MaxSpectrumBands), иначе сервер выбросит спектр.
+# SPECTRUM_SEGMENTS ограничивает число FFT на цикл: чистый Python на S3
+# тратит ~0.2 с на одно преобразование 1024 точек.
+SPECTRUM_ENABLED   = True
+SPECTRUM_FFT_SIZE  = 1024          # степень двойки
+SPECTRUM_SEGMENTS  = 4
+SPECTRUM_EDGES_HZ  = (100, 200, 300, 400, 500, 600, 800, 1000, 1500, 2000)
+
 # === Питание / батарея ===
 # Делителя на ADC нет → шлём -1 (сервер интерпретирует как "нет данных").
 BATTERY_LEVEL_DEFAULT = -1
@@ -156,6 +179,9 @@ BUFFER_MAX_SIZE_BYTES  = 4_194_304   # 4 МБ — для SD; для flash сов
 # чем раз в N мс или при накоплении N записей. Снижает износ FAT.
 BUFFER_FLUSH_MIN_MS    = 30_000
 BUFFER_FLUSH_MAX_RECS  = 16
+# Бэклог выгружается на сервер пачками (DeviceDataBatch) по N записей в
+# одном MQTT-сообщении — вместо отдельной публикации на каждую запись.
+BUFFER_UPLOAD_BATCH    = 48
 
 # === SD-карта (модуль на SPI) — нужна только при BUFFER_BACKEND="sd"/"auto" ===
 SD_SPI_ID    = 2          # HSPI на ESP32-S3
@@ -71,6 +71,15 @@ def _apply_config(cfg):
         machine.reset()
 
 
+def _applied_config():
+    """Интервалы, которые устройство использует сейчас (для device shadow)."""
+    return protocol.make_applied_config(
+        config.DEFAULT_SAMPLING_NOISE,
+        config.DEFAULT_SAMPLING_TEMP,
+        config.DEFAULT_STATUS_FREQUENCY,
+    )
+
+
 def _bring_up_modem():
     """Пытается поднять SIM7020 + MQTT. Возвращает (transport, ok)."""
     sim = SIM7020(
@@ -108,6 +117,8 @@ def _bring_up_wifi():
         keepalive=config.MQTT_KEEPALIVE,
         user=config.MQTT_USER,
         password=config.MQTT_PASSWORD,
+        lwt_topic=config.TOPIC_LWT.format(config.DEVICE_ID),
+        lwt_msg=protocol.dumps(protocol.make_lwt_payload()),
     ):
         return wifi, False, "wifi_mqtt_connect_failed"
     return wifi, True, None
@@ -158,12 +169,15 @@ def _run_cycle():
             errors.append("temperature_init_error")
 
         noise = -1.0
+        spectrum = None
         try:
             noise_sensor = NoiseSensor()
             try:
                 noise = noise_sensor.read()
                 if noise == -1.0:
                     errors.append("noise_read_error")
+                else:
+                    spectrum = noise_sensor.last_spectrum
             finally:
                 noise_sensor.deinit()
                 del noise_sensor
@@ -173,7 +187,7 @@ def _run_cycle():
         gc.collect()
 
         _log("T={} °C, N={} dB".format(temperature, noise))
-        data_payload = protocol.make_data_payload(temperature, noise, ts)
+        data_payload = protocol.make_data_payload(temperature, noise, ts, spectrum)
 
         # === CONNECT_NETWORK (модем → WiFi fallback) ===
         if getattr(config, 'MODEM_ENABLED', True):
@@ -229,6 +243,7 @@ def _run_cycle():
             signal=signal,
             ts=ts,
             errors=errors,
+            applied_config=_applied_config(),
         )
         transport.mqtt_publish(topic_status, protocol.dumps(status_payload))
 
@@ -238,6 +253,13 @@ def _run_cycle():
         if msg:
             _topic, payload = msg
             _apply_config(protocol.parse_config(payload))
+            # Сообщаем серверу, что реально применили. При
+            # APPLY_SERVER_INTERVALS=False интервалы не меняются, и сервер
+            # честно покажет, что конфиг хаба не синхронизирован.
+            transport.mqtt_publish(
+                config.TOPIC_CONFIG_ACK.format(config.DEVICE_ID),
+                protocol.dumps(_applied_config()),
+            )
         else:
             _log("No config received (timeout)")
 
@@ -246,9 +268,11 @@ def _run_cycle():
         if not buf.is_empty():
             backlog = buf.pop_all()
             _log("flushing {} buffered records".format(len(backlog)))
-            for record in backlog:
-                transport.mqtt_publish(topic_data, protocol.dumps(record))
-            gc.collect()
+            step = config.BUFFER_UPLOAD_BATCH
+            for i in range(0, len(backlog), step):
+                batch = protocol.make_batch_payload(backlog[i:i + step])
+                transport.mqtt_publish(topic_data, protocol.dumps(batch))
+                gc.collect()
 
         transport.mqtt_publish(topic_data, protocol.dumps(data_payload))
 
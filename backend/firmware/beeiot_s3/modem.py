@@ -234,6 +234,8 @@ class SIM7020:
 
         # AT+CMQCON=<id>,<version>,<client_id>,<keepalive>,<cleansession>,<willflag>
         # version=3 (MQTT 3.1), cleansession=1, willflag=0
+        # Last Will через SIM7020 не выставляем — для NB-IoT-сборки отключение
+        # ловит только серверный watchdog по пропущенным интервалам.
         if user:
             cmd = 'AT+CMQCON={},3,"{}",{},1,0,"{}","{}"'.format(
                 self._mqtt_id, client_id, keepalive, user, password)
@@ -4,6 +4,8 @@ noise.py — Чтение уровня шума с INMP441 (I2S MEMS-микро
 
 INMP441 кладёт 24-битный знаковый сэмпл в верхние биты 32-битного слова I2S.
 Алгоритм: окно ~NOISE_WINDOW_MS → RMS → пересчёт в dB SPL по калибровке.
+По тому же окну FFT считается спектр по полосам SPECTRUM_EDGES_HZ
+(NoiseSensor.last_spectrum) — по нему сервер ищет признаки безматочности.
 
 Калибровка по даташиту: чувствительность -26 dBFS при 94 dB SPL (1 кГц).
 Соответствует смещению ~120 для пересчёта dBFS → dB SPL. Точная калибровка
@@ -60,6 +62,104 @@ def _parse_format(name):
     return I2S.MONO, 0, sample_size
 
 
+def _fft(re, im, cos_t, sin_t):
+    """
+    Радикс-2 FFT на месте. len(re) == len(im) — степень двойки,
+    cos_t/sin_t — таблицы cos/sin(2πk/n) для k < n/2.
+    """
+    n = len(re)
+    # Бит-реверсная перестановка
+    j = 0
+    for i in range(1, n):
+        bit = n >> 1
+        while j & bit:
+            j ^= bit
+            bit >>= 1
+        j |= bit
+        if i < j:
+            re[i], re[j] = re[j], re[i]
+            im[i], im[j] = im[j], im[i]
+    size = 2
+    while size <= n:
+        half = size >> 1
+        step = n // size
+        for start in range(0, n, size):
+            k = 0
+            for a in range(start, start + half):
+                b = a + half
+                wr = cos_t[k]
+                wi = -sin_t[k]
+                tr = re[b] * wr - im[b] * wi
+                ti = re[b] * wi + im[b] * wr
+                re[b] = re[a] - tr
+                im[b] = im[a] - ti
+                re[a] += tr
+                im[a] += ti
+                k += step
+        size <<= 1
+
+
+def _band_levels(samples, mean, junk_threshold):
+    """
+    Спектр окна по полосам config.SPECTRUM_EDGES_HZ: (edges, levels),
+    levels[i] — уровень в полосе [edges[i], edges[i+1]) в dB SPL, 1 знак.
+    None, если окно короче одного куска FFT.
+
+    Мощность полосы — сумма бинов одностороннего спектра, нормированная
+    на мощность окна Hann, так что сумма по всем полосам совпадает с
+    flat-RMS из read(). Уровни без A-weighting: сервер сравнивает доли
+    мощности полос, а A-фильтр занизил бы низкие полосы.
+    """
+    n = config.SPECTRUM_FFT_SIZE
+    segments = min(len(samples) // n, config.SPECTRUM_SEGMENTS)
+    if segments == 0:
+        return None
+    edges = config.SPECTRUM_EDGES_HZ
+    bin_hz = config.I2S_SAMPLE_RATE_HZ / n
+    # Диапазоны бинов полос: k·bin_hz ∈ [edges[i], edges[i+1])
+    bins = []
+    for i in range(len(edges) - 1):
+        lo = int(math.ceil(edges[i] / bin_hz))
+        hi = min(int(math.ceil(edges[i + 1] / bin_hz)), n // 2)
+        bins.append((lo, hi))
+
+    two_pi_n = 2.0 * math.pi / n
+    window = [0.5 - 0.5 * math.cos(two_pi_n * i) for i in range(n)]
+    window_power = sum(w * w for w in window)
+    cos_t = [math.cos(two_pi_n * k) for k in range(n // 2)]
+    sin_t = [math.sin(two_pi_n * k) for k in range(n // 2)]
+
+    power = [0.0] * len(bins)
+    re = [0.0] * n
+    im = [0.0] * n
+    for seg in range(segments):
+        base = seg * n
+        for i in range(n):
+            d = samples[base + i] - mean
+            # мусор подаём как тишину — так же, как в A-weighting ветке read()
+            re[i] = d * window[i] if abs(d) < junk_threshold else 0.0
+            im[i] = 0.0
+        _fft(re, im, cos_t, sin_t)
+        for idx in range(len(bins)):
+            lo, hi = bins[idx]
+            acc = 0.0
+            for k in range(lo, hi):
+                acc += re[k] * re[k] + im[k] * im[k]
+            power[idx] += acc
+
+    full_scale_sq = float(1 << 23) ** 2
+    norm = 2.0 / (n * window_power * segments)
+    levels = []
+    for p in power:
+        mean_sq = p * norm
+        if mean_sq < 1:
+            levels.append(0.0)
+            continue
+        level = 10.0 * math.log10(mean_sq / full_scale_sq) + config.NOISE_DB_OFFSET
+        levels.append(round(level if level > 0 else 0.0, 1))
+    return list(edges), levels
+
+
 class NoiseSensor:
     def __init__(self):
         i2s_format, offset, stride = _parse_format(
@@ -86,6 +186,9 @@ class NoiseSensor:
         # "холодный старт" фильтра в начале каждого окна (200 мс), что
         # лучше, чем тянуть стейт между раздельными вызовами с deinit/init.
         self._aw_state = [0.0] * 12
+        # Спектр последнего окна read() — пара (edges, levels) для
+        # protocol.make_data_payload, или None.
+        self.last_spectrum = None
         # Прогрев. После старта BCLK INMP441 выдаёт ВАЛИДНЫЕ байты сразу же,
         # но внутренний high-pass на 3 Гц устаканивается ~250–500 мс, а после
         # cold-boot переходный процесс ADC занимает ещё дольше. Без полного
@@ -116,7 +219,10 @@ class NoiseSensor:
           2. Вычитаем DC-смещение (среднее) — INMP441 даёт постоянную составляющую,
              без её удаления RMS улетает в стратосферу и даёт фиктивные ~110+ dB.
           3. RMS → dBFS → dB SPL по калибровке.
+          4. При SPECTRUM_ENABLED — спектр по полосам того же окна
+             в self.last_spectrum.
         """
+        self.last_spectrum = None
         try:
             samples_needed = (config.I2S_SAMPLE_RATE_HZ * config.NOISE_WINDOW_MS) // 1000
             sample_size = self._sample_size
@@ -216,6 +322,13 @@ class NoiseSensor:
                 return -1.0
             mean_sq = sum_sq / clean_count
 
+            if getattr(config, "SPECTRUM_ENABLED", False):
+                # Спектр необязателен: сбой FFT не должен терять сам уровень шума.
+                try:
+                    self.last_spectrum = _band_levels(samples, mean, junk_threshold)
+                except Exception as e:
+                    _log("spectrum error: {}".format(e))
+
             if config.DEBUG:
                 peak_all = 0
                 peak_clean = 0
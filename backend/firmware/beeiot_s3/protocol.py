@@ -9,14 +9,16 @@ protocol.py — Формирование и парсинг сообщений п
 import ujson
 
 
-def make_data_payload(temperature, noise, ts):
+def make_data_payload(temperature, noise, ts, spectrum=None):
     """
     /device/{id}/data — DeviceData
 
     -1 = "нет данных" (сервер пропустит запись соответствующего поля).
     Вес шлём -1/0 — у этого устройства нет тензодатчика.
+    spectrum — необязательная пара (edges, levels): границы полос в Гц
+    и уровни в дБ, len(levels) == len(edges) - 1.
     """
-    return {
+    payload = {
         "temperature":      temperature if temperature is not None else -1,
         "temperature_time": ts,
         "noise":            noise if noise is not None else -1,
@@ -24,18 +26,72 @@ def make_data_payload(temperature, noise, ts):
         "weight":           -1,
         "weight_time":      0,
     }
+    if spectrum is not None:
+        edges, levels = spectrum
+        payload["spectrum"] = {"edges": list(edges), "levels": list(levels)}
+    return payload
 
 
-def make_status_payload(battery, signal, ts, errors):
+def make_batch_payload(records):
     """
-    /device/{id}/status — DeviceStatus
+    /device/{id}/data — DeviceDataBatch
+
+    Пачка записей из оффлайн-буфера: каждая метрика — массив
+    {"value", "time"}. Пустые значения (-1) не передаём.
+    Спектр снят в окне шума, поэтому его метка времени — noise_time:
+    элемент массива spectrum — {"edges", "levels", "time"}.
+    """
+    batch = {"temperature": [], "noise": [], "weight": [], "spectrum": []}
+    for rec in records:
+        for key in ("temperature", "noise", "weight"):
+            value = rec.get(key, -1)
+            ts = rec.get(key + "_time", 0)
+            if value != -1 and ts:
+                batch[key].append({"value": value, "time": ts})
+        spectrum = rec.get("spectrum")
+        ts = rec.get("noise_time", 0)
+        if spectrum and ts:
+            batch["spectrum"].append({
+                "edges":  spectrum["edges"],
+                "levels": spectrum["levels"],
+                "time":   ts,
+            })
+    return batch
+
+
+def make_applied_config(sampling_noise, sampling_temp, frequency):
+    """
+    /device/{id}/config/ack и поле config в статусе — AppliedConfig.
+    Интервалы, которые устройство реально использует (device shadow на сервере).
     """
     return {
+        "sampling_rate_noise":       sampling_noise,
+        "sampling_rate_temperature": sampling_temp,
+        "frequency_status":          frequency,
+    }
+
+
+def make_status_payload(battery, signal, ts, errors, applied_config=None):
+    """
+    /device/{id}/status — DeviceStatus
+    """
+    payload = {
         "battery_level":   battery if battery is not None else -1,
         "signal_strength": signal if signal is not None else -1,
         "timestamp":       ts,
         "errors":          errors or [],
     }
+    if applied_config is not None:
+        payload["config"] = applied_config
+    return payload
+
+
+def make_lwt_payload():
+    """
+    /device/{id}/lwt — Last Will. Публикуется брокером, а не устройством,
+    поэтому без метки времени: момент отключения сервер знает сам.
+    """
+    return {"online": False}
 
 
 def parse_config(raw):
@@ -184,7 +184,8 @@ class WiFiMQTT:
     # MQTT
     # ===================================================================
 
-    def mqtt_connect(self, broker, port, client_id, keepalive=60, user="", password=""):
+    def mqtt_connect(self, broker, port, client_id, keepalive=60, user="", password="",
+                     lwt_topic=None, lwt_msg=None):
         try:
             from umqtt.simple import MQTTClient
         except ImportError:
@@ -202,6 +203,8 @@ class WiFiMQTT:
                     keepalive=keepalive,
                 )
                 self._client.set_callback(self._on_message)
+                if lwt_topic:
+                    self._client.set_last_will(lwt_topic, lwt_msg or "", qos=1)
                 self._client.connect()
                 _log("MQTT connected to {}:{}".format(broker, port))
                 return True
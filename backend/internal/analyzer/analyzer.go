@@ -0,0 +1,166 @@
+// Package analyzer — общий каркас фоновых анализаторов: интерфейс, реестр с расписаниями,
+// учёт прогонов и отправка уведомлений. Конкретные детекторы живут в подпакетах.
+package analyzer
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Analyzer — детектор, который реестр запускает по расписанию.
+// Run вызывается последовательно (прогоны одного анализатора не пересекаются)
+// и отмечает в run обработанные ульи, поднятые алерты и ошибки.
+type Analyzer interface {
+	Name() string
+	Run(run *Run) error
+}
+
+// Run — учёт одного прогона анализатора. Сохраняется в таблицу analyzer_runs.
+type Run struct {
+	Analyzer       string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	HivesProcessed int
+	AlertsRaised   int
+	Errors         []string
+}
+
+// HiveProcessed отмечает обработанный улей (для watchdog-а — датчик, для сводки
+// тихих часов — получателя).
+func (r *Run) HiveProcessed() {
+	r.HivesProcessed++
+}
+
+// AlertRaised отмечает обнаруженную проблему, о которой пользователь должен узнать.
+func (r *Run) AlertRaised() {
+	r.AlertsRaised++
+}
+
+// Error отмечает ошибку, не прервавшую прогон целиком.
+func (r *Run) Error(err error) {
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+// runHistory — сколько хранить историю прогонов. Watchdog запускается раз в минуту,
+// так что без чистки таблица растёт на полторы тысячи строк в сутки.
+const runHistory = 30 * 24 * time.Hour
+
+type entry struct {
+	analyzer Analyzer
+	period   time.Duration
+}
+
+// Registry запускает зарегистрированные анализаторы, каждый со своим периодом,
+// и сохраняет историю прогонов.
+type Registry struct {
+	ctx     context.Context
+	db      interfaces.DB
+	logger  zerolog.Logger
+	mu      sync.Mutex
+	entries []entry
+}
+
+func NewRegistry(ctx context.Context, db interfaces.DB) *Registry {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Registry{ctx: ctx, db: db, logger: logger}
+}
+
+// Register добавляет анализатор с периодом запуска по умолчанию. Период можно
+// переопределить переменной окружения ANALYZER_<NAME>_PERIOD (например,
+// ANALYZER_TEMPERATURE_PERIOD=6h) без пересборки.
+func (r *Registry) Register(a Analyzer, period time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{analyzer: a, period: schedule(a.Name(), period)})
+}
+
+func schedule(name string, period time.Duration) time.Duration {
+	key := "ANALYZER_" + strings.ToUpper(name) + "_PERIOD"
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return period
+}
+
+// Start запускает все зарегистрированные анализаторы: первый прогон сразу,
+// дальше — по периоду, до отмены контекста реестра.
+func (r *Registry) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		r.logger.Info().Str("analyzer", e.analyzer.Name()).Dur("period", e.period).Msg("starting analyzer")
+		go r.loop(e)
+	}
+	go r.pruneHistory()
+}
+
+// pruneHistory раз в сутки удаляет прогоны старше runHistory.
+func (r *Registry) pruneHistory() {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		deleted, err := r.db.DeleteAnalyzerRunsBefore(r.ctx, time.Now().Add(-runHistory))
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("failed to prune analyzer runs")
+		} else if deleted > 0 {
+			r.logger.Info().Int64("deleted", deleted).Msg("analyzer runs pruned")
+		}
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Registry) loop(e entry) {
+	r.runOnce(e.analyzer)
+	ticker := time.NewTicker(e.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.runOnce(e.analyzer)
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// runOnce выполняет один прогон и сохраняет его в историю.
+func (r *Registry) runOnce(a Analyzer) Run {
+	run := Run{Analyzer: a.Name(), StartedAt: time.Now()}
+	run.Error(a.Run(&run))
+	run.FinishedAt = time.Now()
+
+	r.logger.Info().Str("analyzer", run.Analyzer).
+		Dur("took", run.FinishedAt.Sub(run.StartedAt)).
+		Int("hives", run.HivesProcessed).
+		Int("alerts", run.AlertsRaised).
+		Int("errors", len(run.Errors)).
+		Msg("analyzer run finished")
+
+	err := r.db.NewAnalyzerRun(r.ctx, dbTypes.AnalyzerRun{
+		Analyzer:       run.Analyzer,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+		HivesProcessed: run.HivesProcessed,
+		AlertsRaised:   run.AlertsRaised,
+		Errors:         run.Errors,
+	})
+	if err != nil {
+		r.logger.Warn().Err(err).Str("analyzer", run.Analyzer).Msg("failed to save analyzer run")
+	}
+	return run
+}
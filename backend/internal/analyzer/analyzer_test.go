@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockDB struct {
+	interfaces.DB
+	Runs []dbTypes.AnalyzerRun
+}
+
+func (m *MockDB) NewAnalyzerRun(_ context.Context, run dbTypes.AnalyzerRun) error {
+	m.Runs = append(m.Runs, run)
+	return nil
+}
+
+type fakeAnalyzer struct {
+	err error
+}
+
+func (f *fakeAnalyzer) Name() string {
+	return "fake"
+}
+
+func (f *fakeAnalyzer) Run(run *Run) error {
+	run.HiveProcessed()
+	run.HiveProcessed()
+	run.AlertRaised()
+	run.Error(errors.New("hive 2: no data"))
+	return f.err
+}
+
+func newTestRegistry(db *MockDB) *Registry {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	return NewRegistry(ctx, db)
+}
+
+func TestRunOnce_SavesHistory(t *testing.T) {
+	db := &MockDB{}
+	r := newTestRegistry(db)
+
+	run := r.runOnce(&fakeAnalyzer{err: errors.New("failed to get hives")})
+
+	if len(db.Runs) != 1 {
+		t.Fatalf("expected one saved run, got %d", len(db.Runs))
+	}
+	saved := db.Runs[0]
+	if saved.Analyzer != "fake" || saved.HivesProcessed != 2 || saved.AlertsRaised != 1 {
+		t.Errorf("unexpected saved run: %+v", saved)
+	}
+	if len(saved.Errors) != 2 || saved.Errors[1] != "failed to get hives" {
+		t.Errorf("expected per-hive and fatal errors, got %v", saved.Errors)
+	}
+	if run.FinishedAt.Before(run.StartedAt) {
+		t.Errorf("finished before start: %v < %v", run.FinishedAt, run.StartedAt)
+	}
+}
+
+func TestSchedule_EnvOverride(t *testing.T) {
+	t.Setenv("ANALYZER_FAKE_PERIOD", "")
+	if got := schedule("fake", time.Hour); got != time.Hour {
+		t.Errorf("expected default period, got %v", got)
+	}
+	t.Setenv("ANALYZER_FAKE_PERIOD", "90s")
+	if got := schedule("fake", time.Hour); got != 90*time.Second {
+		t.Errorf("expected 90s from env, got %v", got)
+	}
+	t.Setenv("ANALYZER_FAKE_PERIOD", "soon")
+	if got := schedule("fake", time.Hour); got != time.Hour {
+		t.Errorf("expected default for invalid value, got %v", got)
+	}
+}
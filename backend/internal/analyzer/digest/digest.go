@@ -0,0 +1,111 @@
+// Package digest — фоновое задание, рассылающее по почте ежедневную или еженедельную
+// сводку по пасеке тем, кто на неё подписался (см. notification_preferences.digest).
+package digest
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Совпадают с notification.DigestDaily и notification.DigestWeekly.
+const (
+	periodDaily  = "daily"
+	periodWeekly = "weekly"
+)
+
+// sendHour — час по местному времени пользователя, после которого уходит сводка.
+// Задание запускается раз в час, так что письмо приходит в начале восьмого.
+const sendHour = 7
+
+type Analyzer struct {
+	db       interfaces.DB
+	inMemDb  interfaces.InMemoryDB
+	sender   interfaces.EmailSender
+	notifier *alerts.Notifier
+	ctx      context.Context
+	logger   zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB, inMemDb interfaces.InMemoryDB,
+	sender interfaces.EmailSender, notifier *alerts.Notifier) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{db: db, inMemDb: inMemDb, sender: sender, notifier: notifier, ctx: ctx, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "digest"
+}
+
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.sendDue(run, time.Now())
+}
+
+// sendDue отправляет сводки, срок которых наступил. Каждая отправленная сводка
+// учитывается в run как алерт.
+func (a *Analyzer) sendDue(run *analyzer.Run, now time.Time) error {
+	subs, err := a.db.GetDigestSubscribers(a.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get digest subscribers: %w", err)
+	}
+	for _, sub := range subs {
+		loc, err := time.LoadLocation(sub.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		slot, ok := dueSlot(sub.Period, sub.SentAt, now, loc)
+		if !ok {
+			continue
+		}
+		run.HiveProcessed()
+		if err := a.send(sub, slot, loc); err != nil {
+			a.logger.Warn().Err(err).Str("email", sub.Email).Msg("digest: failed to send")
+			run.Error(fmt.Errorf("%s: %w", sub.Email, err))
+			continue
+		}
+		run.AlertRaised()
+	}
+	return nil
+}
+
+func (a *Analyzer) send(sub dbTypes.DigestSubscriber, slot time.Time, loc *time.Location) error {
+	from := slot.AddDate(0, 0, -1)
+	if sub.Period == periodWeekly {
+		from = slot.AddDate(0, 0, -7)
+	}
+	report, err := a.build(a.ctx, sub, from, slot)
+	if err != nil {
+		return err
+	}
+	subject, text, html, err := render(report, loc)
+	if err != nil {
+		return err
+	}
+	if err := a.sender.SendEmail(sub.Email, subject, text, html); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	a.logger.Info().Str("email", sub.Email).Str("period", sub.Period).Int("hives", len(report.Hives)).Msg("digest: sent")
+	// не записали — в следующий час письмо уйдёт повторно; это лучше, чем потерять сводку
+	return a.db.SetDigestSent(a.ctx, sub.Email, slot)
+}
+
+// dueSlot возвращает момент, за который пора отправить сводку: сегодня в sendHour
+// по местному времени, если с тех пор сводка ещё не уходила. Недельная уходит только
+// по понедельникам: пропущенную из-за простоя сервера не досылаем посреди недели.
+func dueSlot(period string, sentAt, now time.Time, loc *time.Location) (time.Time, bool) {
+	local := now.In(loc)
+	if period == periodWeekly && local.Weekday() != time.Monday {
+		return time.Time{}, false
+	}
+	slot := time.Date(local.Year(), local.Month(), local.Day(), sendHour, 0, 0, 0, loc)
+	if local.Before(slot) {
+		return time.Time{}, false
+	}
+	return slot, sentAt.Before(slot)
+}
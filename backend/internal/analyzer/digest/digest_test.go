@@ -0,0 +1,204 @@
+package digest
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+type MockDB struct {
+	interfaces.DB
+	Subscribers []dbTypes.DigestSubscriber
+	Sent        map[string]time.Time
+}
+
+func (m *MockDB) GetDigestSubscribers(_ context.Context) ([]dbTypes.DigestSubscriber, error) {
+	return m.Subscribers, nil
+}
+
+func (m *MockDB) SetDigestSent(_ context.Context, email string, at time.Time) error {
+	m.Sent[email] = at
+	return nil
+}
+
+// GetHives — свой Hive1 и Hive2 друга, где пользователь участник.
+func (m *MockDB) GetHives(_ context.Context, email string, _ *bool) ([]dbTypes.Hive, error) {
+	return []dbTypes.Hive{{NameHive: "Hive1", Email: email, QueenName: "Мария"}, {NameHive: "Hive2", Email: "f@b.c"}}, nil
+}
+
+func (m *MockDB) GetHubSensorByHive(_ context.Context, email, hive string) (string, error) {
+	if hive == "Hive2" || email == "f@b.c" {
+		return "", fmt.Errorf("failed to get hub sensor by hive: %w", pgx.ErrNoRows)
+	}
+	return "hub-1", nil
+}
+
+func (m *MockDB) GetTelemetryRollup(_ context.Context, _, _, metric, _ string, since time.Time) ([]dbTypes.TelemetryRollup, error) {
+	switch metric {
+	case "temperature":
+		return []dbTypes.TelemetryRollup{
+			{Date: since, Min: 33, Max: 35, Avg: 34, Count: 1},
+			{Date: since.Add(time.Hour), Min: 32, Max: 36.5, Avg: 35, Count: 3},
+		}, nil
+	case "weight":
+		return []dbTypes.TelemetryRollup{
+			{Date: since, Avg: 40, Count: 1},
+			{Date: since.Add(time.Hour), Avg: 41.2, Count: 1},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (m *MockDB) GetQueens(_ context.Context, _ string) ([]dbTypes.Queen, error) {
+	// выход матки — через 14 дней после старта
+	return []dbTypes.Queen{{Name: "Мария", StartDate: time.Date(2026, 5, 29, 0, 0, 0, 0, time.UTC)}}, nil
+}
+
+type MockInMemoryDB struct {
+	interfaces.InMemoryDB
+	States map[string]string
+}
+
+func (m *MockInMemoryDB) GetLastDeviceStatus(_ context.Context, _ string) (string, error) {
+	return `{"battery_level": 64, "signal_strength": -1, "timestamp": 1780000000, "errors": []}`, nil
+}
+
+func (m *MockInMemoryDB) GetAlertStates(_ context.Context, prefix string) (map[string]string, error) {
+	result := map[string]string{}
+	for k, v := range m.States {
+		if strings.HasPrefix(k, prefix) {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+type MockSender struct {
+	To, Subject, Text, HTML string
+	Err                     error
+}
+
+func (m *MockSender) SendEmail(to, subject, text, html string) error {
+	m.To, m.Subject, m.Text, m.HTML = to, subject, text, html
+	return m.Err
+}
+
+func TestDueSlot(t *testing.T) {
+	msk, _ := time.LoadLocation("Europe/Moscow")
+	// понедельник, 8 июня 2026, 07:30 по Москве
+	now := time.Date(2026, 6, 8, 4, 30, 0, 0, time.UTC)
+	slot := time.Date(2026, 6, 8, 7, 0, 0, 0, msk)
+
+	tests := []struct {
+		name   string
+		period string
+		sentAt time.Time
+		now    time.Time
+		want   bool
+	}{
+		{"daily never sent", periodDaily, time.Time{}, now, true},
+		{"daily already sent", periodDaily, slot, now, false},
+		{"daily sent yesterday", periodDaily, slot.AddDate(0, 0, -1), now, true},
+		{"daily before send hour", periodDaily, time.Time{}, now.Add(-time.Hour), false},
+		{"weekly on monday", periodWeekly, slot.AddDate(0, 0, -7), now, true},
+		{"weekly on tuesday", periodWeekly, time.Time{}, now.AddDate(0, 0, 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := dueSlot(tt.period, tt.sentAt, tt.now, msk)
+			if ok != tt.want {
+				t.Fatalf("dueSlot() = %v, want %v", ok, tt.want)
+			}
+			if ok && !got.Equal(slot) {
+				t.Errorf("expected slot %v, got %v", slot, got)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	if temperatureStats(nil) != nil || noiseTrend([]dbTypes.TelemetryRollup{{Avg: 1, Count: 1}}) != nil {
+		t.Fatal("expected no stats without data")
+	}
+	trend := noiseTrend([]dbTypes.TelemetryRollup{
+		{Avg: 40, Count: 2}, {Avg: 40, Count: 2}, {Avg: 50, Count: 1}, {Avg: 46, Count: 3},
+	})
+	if trend.First != 40 || trend.Last != 47 || trend.Direction != "растёт" {
+		t.Errorf("unexpected trend %+v", trend)
+	}
+	if w := weightChange([]dbTypes.TelemetryRollup{{Avg: 40}, {Avg: 38.5}}); w.Delta != -1.5 {
+		t.Errorf("unexpected weight change %+v", w)
+	}
+}
+
+func TestSendDue(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	db := &MockDB{
+		Subscribers: []dbTypes.DigestSubscriber{
+			{Email: "a@b.c", Name: "Пчеловод", Period: periodDaily, Timezone: "Europe/Moscow"},
+			{Email: "w@b.c", Period: periodWeekly, Timezone: "UTC"},
+		},
+		Sent: map[string]time.Time{},
+	}
+	states := &MockInMemoryDB{States: map[string]string{
+		"a@b.c:Hive1:battery_low": `{"status":"escalated","opened_at":"2026-06-10T10:00:00Z"}`,
+		"a@b.c:Hive1:temperature": `{"status":"resolved"}`,
+		"f@b.c:Hive2:signal_low":  `{"status":"open","opened_at":"2026-06-10T11:00:00Z"}`,
+		"f@b.c:Hive3:signal_low":  `{"status":"open","opened_at":"2026-06-10T11:00:00Z"}`,
+	}}
+	sender := &MockSender{}
+	a := NewAnalyzer(ctx, db, states, sender, alerts.NewNotifier(db, states, nil, zerolog.Nop()))
+
+	// четверг, 11 июня 2026, 08:10 по Москве — суточная сводка пора, недельная нет
+	var run analyzer.Run
+	if err := a.sendDue(&run, time.Date(2026, 6, 11, 5, 10, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.AlertsRaised != 1 || len(db.Sent) != 1 || sender.To != "a@b.c" {
+		t.Fatalf("expected one daily digest, got %+v, sent %v", run, db.Sent)
+	}
+	if sender.Subject != "Ежедневная сводка по пасеке за 11.06.2026" {
+		t.Errorf("unexpected subject %q", sender.Subject)
+	}
+	for _, want := range []string{
+		"Здравствуйте, Пчеловод!",
+		"== Hive1 ==",
+		"Температура: мин 32.0 °C, макс 36.5 °C, средняя 34.8 °C",
+		"Вес: 41.2 кг (+1.2 кг)",
+		"Батарея: 64%",
+		"== Hive2 ==\nТемпература: нет данных",
+		"- Hive1: Низкий заряд батареи (с 10.06 13:00, не устранено)",
+		"- Hive2: Слабый сигнал (с 10.06 14:00",
+		"- 12.06 — выход матки: Мария (Hive1)",
+	} {
+		if !strings.Contains(sender.Text, want) {
+			t.Errorf("text digest misses %q:\n%s", want, sender.Text)
+		}
+	}
+	if strings.Contains(sender.Text, "Сигнал") || strings.Contains(sender.Text, "Отклонение температуры") {
+		t.Errorf("expected no signal and no resolved alerts in digest:\n%s", sender.Text)
+	}
+	if strings.Contains(sender.Text, "Hive3") {
+		t.Errorf("expected no alerts of hives not shared with the user:\n%s", sender.Text)
+	}
+	if !strings.Contains(sender.HTML, "<b>Hive1</b>") || !strings.Contains(sender.HTML, "36.5") {
+		t.Errorf("unexpected html digest:\n%s", sender.HTML)
+	}
+
+	// повторный прогон в тот же час ничего не шлёт
+	db.Subscribers[0].SentAt = db.Sent["a@b.c"]
+	run = analyzer.Run{}
+	_ = a.sendDue(&run, time.Date(2026, 6, 11, 6, 10, 0, 0, time.UTC))
+	if run.AlertsRaised != 0 {
+		t.Errorf("expected digest not to be resent, got %d", run.AlertsRaised)
+	}
+}
@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// view — данные для шаблонов: отчёт и зона пользователя для вывода дат.
+type view struct {
+	Report
+	Title string
+}
+
+func funcs(loc *time.Location) map[string]any {
+	return map[string]any{
+		"num":  func(v float64) string { return fmt.Sprintf("%.1f", v) },
+		"sign": func(v float64) string { return fmt.Sprintf("%+.1f", v) },
+		"date": func(t time.Time) string { return t.In(loc).Format("02.01.2006") },
+		"day":  func(t time.Time) string { return t.Format("02.01") },
+		"time": func(t time.Time) string { return t.In(loc).Format("02.01 15:04") },
+	}
+}
+
+var (
+	textTmpl = textTemplate.Must(textTemplate.New("digest.txt.tmpl").
+			Funcs(funcs(time.UTC)).ParseFS(templatesFS, "templates/digest.txt.tmpl"))
+	htmlTmpl = htmlTemplate.Must(htmlTemplate.New("digest.html.tmpl").
+			Funcs(funcs(time.UTC)).ParseFS(templatesFS, "templates/digest.html.tmpl"))
+)
+
+// render возвращает тему, текстовую и HTML-версии письма. Даты выводятся в зоне loc.
+func render(r Report, loc *time.Location) (subject, text, html string, err error) {
+	title := "Ежедневная сводка по пасеке"
+	if r.Period == periodWeekly {
+		title = "Еженедельная сводка по пасеке"
+	}
+	subject = title + " за " + r.To.In(loc).Format("02.01.2006")
+	v := view{Report: r, Title: title}
+
+	tt, err := textTmpl.Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	var buf bytes.Buffer
+	if err := tt.Funcs(funcs(loc)).Execute(&buf, v); err != nil {
+		return "", "", "", fmt.Errorf("failed to render text digest: %w", err)
+	}
+	text = buf.String()
+
+	ht, err := htmlTmpl.Clone()
+	if err != nil {
+		return "", "", "", err
+	}
+	buf.Reset()
+	if err := ht.Funcs(funcs(loc)).Execute(&buf, v); err != nil {
+		return "", "", "", fmt.Errorf("failed to render html digest: %w", err)
+	}
+	return subject, text, buf.String(), nil
+}
@@ -0,0 +1,287 @@
+package digest
+
+import (
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/calcQueen"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/mqttTypes"
+	"BeeIOT/internal/domain/rollup"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// trendThreshold — изменение шума (%) между половинами периода, ниже которого тренд «стабилен».
+const trendThreshold = 10.0
+
+// Report — содержимое одной сводки.
+type Report struct {
+	Name   string
+	Period string
+	From   time.Time
+	To     time.Time
+	Hives  []HiveReport
+	Alerts []AlertLine
+	Queens []QueenEvent
+}
+
+// HiveReport — показатели одного улья за период. nil — данных за период нет.
+type HiveReport struct {
+	Name        string
+	Temperature *Stats
+	Noise       *Trend
+	Weight      *Change
+	// Battery и Signal — из последнего статуса хаба; -1 — нет данных
+	Battery  int
+	Signal   int
+	StatusAt time.Time
+}
+
+type Stats struct {
+	Min, Max, Avg float64
+}
+
+// Trend — средний шум в первой и второй половине периода.
+type Trend struct {
+	First, Last float64
+	ChangePct   float64
+	Direction   string
+}
+
+// Change — изменение веса между первым и последним интервалом периода.
+type Change struct {
+	First, Last, Delta float64
+}
+
+// AlertLine — незакрытый инцидент.
+type AlertLine struct {
+	Hive   string
+	Title  string
+	Since  time.Time
+	Status string
+}
+
+// QueenEvent — предстоящий этап маточного календаря.
+type QueenEvent struct {
+	Queen string
+	Hive  string
+	Stage string
+	Date  time.Time
+}
+
+// build собирает сводку пользователя за период (from, to].
+func (a *Analyzer) build(ctx context.Context, sub dbTypes.DigestSubscriber, from, to time.Time) (Report, error) {
+	report := Report{Name: sub.Name, Period: sub.Period, From: from, To: to}
+
+	hives, err := a.db.GetHives(ctx, sub.Email, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to get hives: %w", err)
+	}
+	// суточная сводка — по часовым агрегатам, недельная — по суточным
+	resolution := rollup.ResolutionHour
+	if sub.Period == periodWeekly {
+		resolution = rollup.ResolutionDay
+	}
+	queenHive := make(map[string]string)
+	for _, hive := range hives {
+		if hive.QueenName != "" {
+			queenHive[hive.QueenName] = hive.NameHive
+		}
+		hr := HiveReport{Name: hive.NameHive, Battery: -1, Signal: -1}
+		// улей участника принадлежит другому пользователю: хаб ищется по почте владельца
+		sensor, err := a.db.GetHubSensorByHive(ctx, hive.Email, hive.NameHive)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// улей без хаба — показываем только название
+			report.Hives = append(report.Hives, hr)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		if err := a.fillTelemetry(ctx, &hr, sub.Email, sensor, resolution, from); err != nil {
+			return report, err
+		}
+		a.fillStatus(ctx, &hr, sensor)
+		report.Hives = append(report.Hives, hr)
+	}
+
+	incidents, err := a.notifier.HiveIncidents(ctx, hives)
+	if err != nil {
+		// сводка полезна и без алертов
+		a.logger.Warn().Err(err).Str("email", sub.Email).Msg("digest: failed to get open incidents")
+	}
+	for _, inc := range incidents {
+		title, ok := alertTitles[inc.Type]
+		if !ok {
+			title = inc.Type
+		}
+		report.Alerts = append(report.Alerts, AlertLine{
+			Hive: inc.Hive, Title: title, Since: inc.State.OpenedAt, Status: inc.State.Status,
+		})
+	}
+
+	queens, err := a.db.GetQueens(ctx, sub.Email)
+	if err != nil {
+		return report, fmt.Errorf("failed to get queens: %w", err)
+	}
+	report.Queens = queenEvents(queens, queenHive, to, queenHorizon(sub.Period))
+	return report, nil
+}
+
+func (a *Analyzer) fillTelemetry(ctx context.Context, hr *HiveReport, email, sensor, resolution string, from time.Time) error {
+	temps, err := a.db.GetTelemetryRollup(ctx, email, sensor, rollup.MetricTemperature, resolution, from)
+	if err != nil {
+		return err
+	}
+	noise, err := a.db.GetTelemetryRollup(ctx, email, sensor, rollup.MetricNoise, resolution, from)
+	if err != nil {
+		return err
+	}
+	weight, err := a.db.GetTelemetryRollup(ctx, email, sensor, rollup.MetricWeight, resolution, from)
+	if err != nil {
+		return err
+	}
+	hr.Temperature = temperatureStats(temps)
+	hr.Noise = noiseTrend(noise)
+	hr.Weight = weightChange(weight)
+	return nil
+}
+
+// fillStatus берёт заряд и сигнал из последнего статуса хаба (кэш MQTT в Redis).
+func (a *Analyzer) fillStatus(ctx context.Context, hr *HiveReport, sensor string) {
+	raw, err := a.inMemDb.GetLastDeviceStatus(ctx, sensor)
+	if err != nil || raw == "" {
+		return
+	}
+	var status mqttTypes.DeviceStatus
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return
+	}
+	hr.Battery = status.BatteryLevel
+	hr.Signal = status.SignalStrength
+	if status.Timestamp > 0 {
+		hr.StatusAt = time.Unix(status.Timestamp, 0)
+	}
+}
+
+// temperatureStats — минимум, максимум и среднее, взвешенное по числу замеров.
+func temperatureStats(buckets []dbTypes.TelemetryRollup) *Stats {
+	if len(buckets) == 0 {
+		return nil
+	}
+	s := &Stats{Min: buckets[0].Min, Max: buckets[0].Max}
+	sum, count := 0.0, 0
+	for _, b := range buckets {
+		s.Min = min(s.Min, b.Min)
+		s.Max = max(s.Max, b.Max)
+		sum += b.Avg * float64(b.Count)
+		count += b.Count
+	}
+	if count > 0 {
+		s.Avg = sum / float64(count)
+	}
+	return s
+}
+
+// noiseTrend сравнивает средний шум первой и второй половины периода.
+func noiseTrend(buckets []dbTypes.TelemetryRollup) *Trend {
+	if len(buckets) < 2 {
+		return nil
+	}
+	half := len(buckets) / 2
+	t := &Trend{First: average(buckets[:half]), Last: average(buckets[half:])}
+	if t.First != 0 {
+		t.ChangePct = (t.Last - t.First) / t.First * 100
+	}
+	switch {
+	case t.ChangePct >= trendThreshold:
+		t.Direction = "растёт"
+	case t.ChangePct <= -trendThreshold:
+		t.Direction = "снижается"
+	default:
+		t.Direction = "стабилен"
+	}
+	return t
+}
+
+func weightChange(buckets []dbTypes.TelemetryRollup) *Change {
+	if len(buckets) < 2 {
+		return nil
+	}
+	c := &Change{First: buckets[0].Avg, Last: buckets[len(buckets)-1].Avg}
+	c.Delta = c.Last - c.First
+	return c
+}
+
+func average(buckets []dbTypes.TelemetryRollup) float64 {
+	sum, count := 0.0, 0
+	for _, b := range buckets {
+		sum += b.Avg * float64(b.Count)
+		count += b.Count
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// queenHorizon — на сколько дней вперёд показывать этапы маточного календаря.
+func queenHorizon(period string) int {
+	if period == periodWeekly {
+		return 7
+	}
+	return 3
+}
+
+// queenEvents возвращает этапы маточного календаря, попадающие в ближайшие days дней.
+func queenEvents(queens []dbTypes.Queen, queenHive map[string]string, now time.Time, days int) []QueenEvent {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	until := today.AddDate(0, 0, days)
+	var result []QueenEvent
+	for _, q := range queens {
+		var cal calcQueen.QueenPhaseCalendar
+		cal.CalculatePreciseCalendar(q.StartDate)
+		for _, st := range queenStages(cal) {
+			date, err := calcQueen.ParseDate(st.date)
+			if err != nil || date.Before(today) || !date.Before(until) {
+				continue
+			}
+			result = append(result, QueenEvent{Queen: q.Name, Hive: queenHive[q.Name], Stage: st.name, Date: date})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result
+}
+
+type stage struct {
+	name, date string
+}
+
+// queenStages — этапы календаря, о которых стоит напомнить пасечнику.
+func queenStages(c calcQueen.QueenPhaseCalendar) []stage {
+	return []stage{
+		{"запечатка маточников", c.LarvaPhase.Sealed},
+		{"отбор маточников", c.PupaPhase.Selection},
+		{"выход матки", c.QueenPhase.EmergenceStart},
+		{"облёт матки", c.QueenPhase.MatingFlightStart},
+		{"проверка засева", c.QueenPhase.EggLayingCheckStart},
+	}
+}
+
+// alertTitles — названия типов алертов для сводки.
+var alertTitles = map[string]string{
+	alerts.TypeBatteryLow:    "Низкий заряд батареи",
+	alerts.TypeSignalLow:     "Слабый сигнал",
+	alerts.TypeDeviceErrors:  "Ошибки датчика",
+	alerts.TypeNoiseHigh:     "Высокий уровень шума",
+	alerts.TypeNoiseChange:   "Резкое изменение шума",
+	alerts.TypeTemperature:   "Отклонение температуры",
+	alerts.TypeSensorOffline: "Датчик не на связи",
+	alerts.TypeSwarm:         "Риск роения",
+	alerts.TypeQueenless:     "Возможна безматочность",
+}
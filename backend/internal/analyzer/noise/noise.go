@@ -1,9 +1,12 @@
 package noise
 
 import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/apiary"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/models/dbTypes"
-	"BeeIOT/internal/domain/notification"
+	"BeeIOT/internal/domain/models/notifyTypes"
 	"context"
 	"errors"
 	"fmt"
@@ -14,42 +17,30 @@ import (
 )
 
 type Analyzer struct {
-	period       time.Duration
-	db           interfaces.DB
-	ctx          context.Context
-	notification *notification.Notification
-	logger       zerolog.Logger
+	db       interfaces.DB
+	ctx      context.Context
+	notifier *alerts.Notifier
+	logger   zerolog.Logger
 }
 
-func NewAnalyzer(ctx context.Context, period time.Duration, db interfaces.DB, notification *notification.Notification) *Analyzer {
+func NewAnalyzer(ctx context.Context, db interfaces.DB, notifier *alerts.Notifier) *Analyzer {
 	logger := ctx.Value("logger").(zerolog.Logger)
-	return &Analyzer{period: period, db: db, ctx: ctx, notification: notification, logger: logger}
+	return &Analyzer{db: db, ctx: ctx, notifier: notifier, logger: logger}
 }
 
-func (a *Analyzer) Start() {
-	go func() {
-		a.analyzeNoise()
-		ticker := time.NewTicker(a.period)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				a.analyzeNoise()
-			case <-a.ctx.Done():
-				return
-			}
-		}
-	}()
+func (a *Analyzer) Name() string {
+	return "noise"
 }
 
-func (a *Analyzer) analyzeNoise() {
-	a.logger.Info().Msg("noise analyzer: starting run")
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.analyzeNoise(run)
+}
+
+func (a *Analyzer) analyzeNoise(run *analyzer.Run) error {
 	ct := time.Now()
-	computingStartTime := a.createStartDayTime(ct.Year(), ct.Month(), ct.Day())
 	hives, err := a.db.GetHives(a.ctx, "", nil)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("failed to get hives")
-		return
+		return fmt.Errorf("failed to get hives: %w", err)
 	}
 	a.logger.Info().Int("total_hives", len(hives)).Msg("noise analyzer: hives loaded")
 	for _, hive := range hives {
@@ -57,92 +48,84 @@ func (a *Analyzer) analyzeNoise() {
 			a.logger.Debug().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Msg("skip hive without hub")
 			continue
 		}
+		// сутки считаются по часовому поясу пасеки: ночь пасечника не делится на два дня
+		computingStartTime := a.createStartDayTime(ct, apiary.Location(hive.Timezone))
 		SchumeikoDataMap, err := a.db.GetNoiseSinceDay(a.ctx, *hive.HubID, computingStartTime)
 		if err != nil {
 			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to get noise since time map")
+			run.Error(err)
 			continue
 		}
+		run.HiveProcessed()
+		rules, err := alerts.ForHive(a.ctx, a.db, hive.Email, hive.NameHive)
+		if err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to get alert rules, using defaults")
+		}
 		a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("days", len(SchumeikoDataMap)).Msg("analyzing noise")
-		a.analyzeDay(SchumeikoDataMap, hive, computingStartTime)
-		if err := a.db.UpdateHiveNoiseCheck(a.ctx, hive.Id, computingStartTime); err != nil {
+		a.analyzeDay(run, SchumeikoDataMap, hive, computingStartTime, rules)
+		if err := a.db.UpdateHiveNoiseCheck(a.ctx, hive.Id, computingStartTime.UTC()); err != nil {
 			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to update hive noise check")
+			run.Error(err)
 		}
 	}
-	a.logger.Info().Msg("noise analyzer: run finished")
+	return nil
 }
 
-func (a *Analyzer) createStartDayTime(year int, month time.Month, day int) time.Time {
-	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+// createStartDayTime — полночь суток в поясе loc, в которые попадает t.
+func (a *Analyzer) createStartDayTime(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
 }
 
-// criticalNoiseDelta — порог изменения среднего уровня шума (в dB SPL) между
-// двумя соседними днями, при котором поднимается уведомление.
-//
-// Прошивка в firmware/beeiot_s3/noise.py пишет уровень шума в dB SPL после
-// калибровки INMP441 (RMS → dBFS → dB SPL с offset из NOISE_DB_OFFSET).
-// Типичный диапазон шума пчелиной семьи — 40…80 dB SPL. Изменение на 8 dB
-// соответствует субъективному удвоению громкости и хорошо коррелирует с
-// событиями, которые мы реально хотим ловить: начало роения, появление
-// нескольких маток, резкое ослабление семьи.
-//
-// Хотим чувствительнее — снижаем до 5.0; хотим консервативнее — поднимаем
-// до 10.0. Старое значение 200.0 было нонсенсом (200 дБ — уровень ударной
-// волны, в улье физически недостижим), из-за чего анализатор никогда не
-// срабатывал.
-const criticalNoiseDelta = 8.0
-
-func (a *Analyzer) analyzeDay(
-	data map[time.Time][]dbTypes.HivesNoiseData, hive dbTypes.Hive, curTime time.Time) {
+// analyzeDay сравнивает среднесуточный шум соседних дней. Порог изменения —
+// rules.NoiseDelta (см. alerts.Default). Если ни один день не выбился, открытый
+// инцидент по шуму закрывается.
+func (a *Analyzer) analyzeDay(run *analyzer.Run,
+	data map[time.Time][]dbTypes.HivesNoiseData, hive dbTypes.Hive, curTime time.Time, rules alerts.Rules) {
 
+	abnormal := false
 	for date, noises := range data {
 		if date.Equal(curTime) {
 			continue
 		}
-		prevTime := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-		if prevData, ok := data[prevTime.Add(-24*time.Hour)]; ok {
+		// AddDate, а не -24h: при переходе на летнее время сутки короче или длиннее
+		prevTime := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+		if prevData, ok := data[prevTime.AddDate(0, 0, -1)]; ok {
 			prev := a.averageNoise(prevData)
 			cur := a.averageNoise(noises)
-			if math.Abs(prev-cur) < criticalNoiseDelta {
+			if math.Abs(prev-cur) < rules.NoiseDelta {
 				continue
 			}
+			abnormal = true
 			a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Float64("prev", prev).Float64("cur", cur).Msg("abnormal noise detected")
-			if a.notification == nil {
-				a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
-				continue
-			}
-			tokens, err := a.db.GetFirebaseToken(a.ctx, hive.Email)
-			if err != nil {
-				a.logger.Warn().Int("hiveId", hive.Id).
-					Str("email", hive.Email).Err(err).Msg("failed to get firebase token")
-				continue
-			}
-			a.logger.Info().Int("hiveId", hive.Id).Str("email", hive.Email).Int("tokens", len(tokens)).Msg("sending noise notification")
-			if len(tokens) == 0 {
-				continue
-			}
-			badToken, err := a.notification.SendNotification(a.ctx, notification.Data{
-				Title: "Критический уровень шума",
-				Body: fmt.Sprintf(`Уровень шума изменился с %.2f до %.2f.
+			run.AlertRaised()
+			_, err := a.notifier.Raise(a.ctx, alerts.Alert{Type: alerts.TypeNoiseChange, Email: hive.Email, Hive: hive.NameHive,
+				Data: notifyTypes.Message{
+					Title: "Критический уровень шума",
+					Body: fmt.Sprintf(`Уровень шума изменился с %.2f до %.2f.
 Необходимо проверить его состояние`, prev, cur),
-				Data: map[string]string{
-					"hive": hive.NameHive,
-				},
-				Tokens:    tokens,
-				Important: false,
-			})
+					Data: map[string]string{
+						"hive": hive.NameHive,
+					},
+					Important: false,
+				}})
 			switch {
-			case errors.Is(err, notification.ErrInvalidTokens):
-				err = a.db.DeleteFirebaseToken(a.ctx, hive.Email, badToken)
-				if err != nil {
-					a.logger.Warn().Int("hiveId", hive.Id).
-						Str("email", hive.Email).Err(err).Msg("failed to delete invalid firebase token")
-				}
+			case errors.Is(err, alerts.ErrNotificationDisabled):
+				a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
 			case err != nil:
 				a.logger.Warn().Int("hiveId", hive.Id).
 					Str("email", hive.Email).Err(err).Msg("failed to send notification")
+				run.Error(err)
 			}
 		}
 	}
+	if abnormal {
+		return
+	}
+	alert := alerts.Alert{Type: alerts.TypeNoiseChange, Email: hive.Email, Hive: hive.NameHive}
+	if _, err := a.notifier.Clear(a.ctx, alert); err != nil {
+		a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to resolve noise alert")
+	}
 }
 
 func (a *Analyzer) averageNoise(data []dbTypes.HivesNoiseData) float64 {
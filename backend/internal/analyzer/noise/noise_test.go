@@ -1,6 +1,8 @@
 package noise
 
 import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"context"
@@ -14,16 +16,22 @@ type MockDB struct {
 	interfaces.DB
 	Hives     []dbTypes.Hive
 	NoiseData map[time.Time][]dbTypes.HivesNoiseData
+	Since     []time.Time
 }
 
 func (m *MockDB) GetHives(_ context.Context, _ string, _ *bool) ([]dbTypes.Hive, error) {
 	return m.Hives, nil
 }
 
-func (m *MockDB) GetNoiseSinceDay(_ context.Context, _ int, _ time.Time) (map[time.Time][]dbTypes.HivesNoiseData, error) {
+func (m *MockDB) GetNoiseSinceDay(_ context.Context, _ int, since time.Time) (map[time.Time][]dbTypes.HivesNoiseData, error) {
+	m.Since = append(m.Since, since)
 	return m.NoiseData, nil
 }
 
+func (m *MockDB) GetEffectiveAlertRules(_ context.Context, _, _, _ string) (dbTypes.AlertRules, error) {
+	return dbTypes.AlertRules{}, nil
+}
+
 func (m *MockDB) UpdateHiveNoiseCheck(_ context.Context, _ int, _ time.Time) error {
 	return nil
 }
@@ -95,10 +103,12 @@ func TestAnalyzeNoise(t *testing.T) {
 	logger := zerolog.Nop()
 	ctxLogger := context.WithValue(ctx, "logger", logger)
 
-	analyzer := NewAnalyzer(ctxLogger, 1*time.Hour, mockDB, nil)
+	a := NewAnalyzer(ctxLogger, mockDB, nil)
 
 	// Run analysis — with nil notification, analyzeDay skips notification sending
-	analyzer.analyzeNoise()
+	if err := a.analyzeNoise(&analyzer.Run{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 }
 
 func TestAverageNoise(t *testing.T) {
@@ -121,3 +131,73 @@ func TestAverageNoise(t *testing.T) {
 
 	}
 }
+
+func TestAnalyzeDay_UsesHiveRules(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	a := NewAnalyzer(ctx, &MockDB{}, nil)
+
+	today := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	data := map[time.Time][]dbTypes.HivesNoiseData{
+		today.Add(-48 * time.Hour): {{Level: 60}},
+		today.Add(-24 * time.Hour): {{Level: 66}},
+	}
+
+	// скачок на 6 дБ ниже порога по умолчанию (8 дБ)
+	run := &analyzer.Run{}
+	a.analyzeDay(run, data, dbTypes.Hive{Id: 1}, today, alerts.Default())
+	if run.AlertsRaised != 0 {
+		t.Fatalf("expected no alert with default rules, got %d", run.AlertsRaised)
+	}
+
+	// для нуклеуса порог снижен до 5 дБ
+	delta := 5.0
+	run = &analyzer.Run{}
+	a.analyzeDay(run, data, dbTypes.Hive{Id: 1}, today, alerts.Resolve(dbTypes.AlertRules{NoiseDelta: &delta}))
+	if run.AlertsRaised != 1 {
+		t.Fatalf("expected alert with hive rules, got %d", run.AlertsRaised)
+	}
+}
+
+func TestAnalyzeNoise_ApiaryTimezone(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID := 1
+	mockDB := &MockDB{Hives: []dbTypes.Hive{
+		{Id: 1, NameHive: "Hive1", HubID: &hubID, Timezone: "Asia/Vladivostok"},
+		{Id: 2, NameHive: "Hive2", HubID: &hubID},
+	}}
+	a := NewAnalyzer(ctx, mockDB, nil)
+	if err := a.analyzeNoise(&analyzer.Run{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mockDB.Since) != 2 {
+		t.Fatalf("expected noise requested for both hives, got %v", mockDB.Since)
+	}
+	// сутки улья на пасеке начинаются в полночь по Владивостоку, без пасеки — по UTC
+	vladivostok := mockDB.Since[0]
+	if vladivostok.Location().String() != "Asia/Vladivostok" || vladivostok.Hour() != 0 || vladivostok.Minute() != 0 {
+		t.Errorf("expected Vladivostok midnight, got %s", vladivostok)
+	}
+	if utc := mockDB.Since[1]; utc.Location() != time.UTC || utc.Hour() != 0 {
+		t.Errorf("expected UTC midnight, got %s", utc)
+	}
+}
+
+func TestAnalyzeDay_DaylightSaving(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	a := NewAnalyzer(ctx, &MockDB{}, nil)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	// 29 марта 2026 в Берлине переводят часы — эти сутки длятся 23 часа
+	data := map[time.Time][]dbTypes.HivesNoiseData{
+		time.Date(2026, 3, 29, 0, 0, 0, 0, berlin): {{Level: 60}},
+		time.Date(2026, 3, 30, 0, 0, 0, 0, berlin): {{Level: 75}},
+	}
+	run := &analyzer.Run{}
+	a.analyzeDay(run, data, dbTypes.Hive{Id: 1}, time.Date(2026, 3, 31, 0, 0, 0, 0, berlin), alerts.Default())
+	if run.AlertsRaised != 1 {
+		t.Fatalf("expected days around DST switch to be compared, got %d alerts", run.AlertsRaised)
+	}
+}
@@ -0,0 +1,170 @@
+package partition
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/export"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/rollup"
+	"BeeIOT/internal/domain/spectrum"
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// aheadMonths — на сколько месяцев вперёд держим готовые секции. Запас нужен,
+// чтобы замеры не попадали в default, даже если задание какое-то время не запускалось.
+const aheadMonths = 2
+
+// defaultRetentionMonths — сколько месяцев хранить сырые замеры, если
+// TELEMETRY_RETENTION_MONTHS не задан. Старше — только часовые и суточные агрегаты.
+const defaultRetentionMonths = 12
+
+// RetentionFromEnv читает срок хранения сырых замеров в месяцах из TELEMETRY_RETENTION_MONTHS.
+// 0 — хранить всё.
+func RetentionFromEnv() int {
+	v, err := strconv.Atoi(os.Getenv("TELEMETRY_RETENTION_MONTHS"))
+	if err != nil || v < 0 {
+		return defaultRetentionMonths
+	}
+	return v
+}
+
+// details — подробности замеров: спектр шума и история статусов устройств. Секции для
+// них создаются так же, а по сроку хранения удаляются без проверки агрегатов —
+// графики за старые месяцы строятся только по агрегатам основных метрик.
+var details = []string{spectrum.Metric, export.MetricStatus}
+
+// Analyzer — фоновое задание, которое заранее создаёт месячные секции сырых замеров
+// и удаляет секции старше срока хранения.
+type Analyzer struct {
+	retentionMonths int
+	db              interfaces.DB
+	ctx             context.Context
+	logger          zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB, retentionMonths int) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{retentionMonths: retentionMonths, db: db, ctx: ctx, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "partition"
+}
+
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	a.maintain(run, time.Now())
+	return nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// upcomingMonths — текущий месяц и aheadMonths следующих.
+func upcomingMonths(now time.Time) []time.Time {
+	start := monthStart(now)
+	months := make([]time.Time, 0, aheadMonths+1)
+	for i := 0; i <= aheadMonths; i++ {
+		months = append(months, start.AddDate(0, i, 0))
+	}
+	return months
+}
+
+// retentionCutoff — секции, целиком лежащие раньше этой даты, подлежат удалению.
+// Текущий месяц в срок не засчитывается: при сроке в 12 месяцев храним 12 полных месяцев.
+func retentionCutoff(now time.Time, retentionMonths int) time.Time {
+	return monthStart(now).AddDate(0, -retentionMonths, 0)
+}
+
+func (a *Analyzer) maintain(run *analyzer.Run, now time.Time) {
+	created, dropped, kept := 0, 0, 0
+	for _, metric := range rollup.Metrics {
+		created += a.ensurePartitions(run, metric, now)
+		if a.retentionMonths > 0 {
+			d, k := a.applyRetention(run, metric, now, true)
+			dropped += d
+			kept += k
+		}
+	}
+	for _, metric := range details {
+		created += a.ensurePartitions(run, metric, now)
+		if a.retentionMonths > 0 {
+			d, _ := a.applyRetention(run, metric, now, false)
+			dropped += d
+		}
+	}
+	a.logger.Info().Int("created", created).Int("dropped", dropped).Int("kept_not_rolled_up", kept).
+		Int("retention_months", a.retentionMonths).Msg("partition: maintenance finished")
+}
+
+// ensurePartitions создаёт секции на ближайшие месяцы и для месяцев, замеры за которые
+// лежат в default. Возвращает число созданных секций.
+func (a *Analyzer) ensurePartitions(run *analyzer.Run, metric string, now time.Time) int {
+	months := upcomingMonths(now)
+	pending, err := a.db.GetUnpartitionedMonths(a.ctx, metric)
+	if err != nil {
+		a.logger.Error().Err(err).Str("metric", metric).Msg("partition: failed to get unpartitioned months")
+		run.Error(err)
+	}
+	months = append(months, pending...)
+
+	created := 0
+	for _, month := range months {
+		ok, err := a.db.EnsureTelemetryPartition(a.ctx, metric, month)
+		if err != nil {
+			a.logger.Error().Err(err).Str("metric", metric).Time("month", month).Msg("partition: failed to create partition")
+			run.Error(err)
+			continue
+		}
+		if ok {
+			created++
+			a.logger.Info().Str("metric", metric).Time("month", month).Msg("partition: partition created")
+		}
+	}
+	return created
+}
+
+// applyRetention удаляет секции старше срока хранения. Если rolledUp, секция удаляется,
+// только когда её замеры уже учтены в часовых агрегатах. Возвращает число удалённых
+// и оставленных из-за этого секций.
+func (a *Analyzer) applyRetention(run *analyzer.Run, metric string, now time.Time, rolledUp bool) (dropped, kept int) {
+	partitions, err := a.db.GetTelemetryPartitions(a.ctx, metric)
+	if err != nil {
+		a.logger.Error().Err(err).Str("metric", metric).Msg("partition: failed to list partitions")
+		run.Error(err)
+		return 0, 0
+	}
+	cutoff := retentionCutoff(now, a.retentionMonths)
+	for _, p := range partitions {
+		if p.To.After(cutoff) {
+			continue
+		}
+		covered := true
+		if rolledUp {
+			covered, err = a.db.IsTelemetryPartitionRolledUp(a.ctx, metric, p)
+			if err != nil {
+				a.logger.Error().Err(err).Str("partition", p.Name).Msg("partition: failed to check rollup coverage")
+				run.Error(err)
+				continue
+			}
+		}
+		if !covered {
+			kept++
+			a.logger.Warn().Str("partition", p.Name).Msg("partition: partition is past retention but not rolled up yet, keeping")
+			continue
+		}
+		if err := a.db.DropTelemetryPartition(a.ctx, metric, p); err != nil {
+			a.logger.Error().Err(err).Str("partition", p.Name).Msg("partition: failed to drop partition")
+			run.Error(err)
+			continue
+		}
+		dropped++
+		a.logger.Info().Str("partition", p.Name).Msg("partition: partition dropped")
+	}
+	return dropped, kept
+}
@@ -0,0 +1,137 @@
+package partition
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockDB struct {
+	interfaces.DB
+	Partitions    []dbTypes.TelemetryPartition
+	Unpartitioned []time.Time
+	NotRolledUp   map[string]bool
+	Ensured       map[string][]time.Time
+	Dropped       []string
+}
+
+func (m *MockDB) EnsureTelemetryPartition(_ context.Context, metric string, month time.Time) (bool, error) {
+	if m.Ensured == nil {
+		m.Ensured = map[string][]time.Time{}
+	}
+	m.Ensured[metric] = append(m.Ensured[metric], month)
+	return true, nil
+}
+
+func (m *MockDB) GetTelemetryPartitions(_ context.Context, _ string) ([]dbTypes.TelemetryPartition, error) {
+	return m.Partitions, nil
+}
+
+func (m *MockDB) GetUnpartitionedMonths(_ context.Context, _ string) ([]time.Time, error) {
+	return m.Unpartitioned, nil
+}
+
+func (m *MockDB) IsTelemetryPartitionRolledUp(_ context.Context, _ string, p dbTypes.TelemetryPartition) (bool, error) {
+	return !m.NotRolledUp[p.Name], nil
+}
+
+func (m *MockDB) DropTelemetryPartition(_ context.Context, _ string, p dbTypes.TelemetryPartition) error {
+	m.Dropped = append(m.Dropped, p.Name)
+	return nil
+}
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestAnalyzer(db *MockDB, retention int) *Analyzer {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	return NewAnalyzer(ctx, db, retention)
+}
+
+func TestEnsurePartitions_AheadAndDefault(t *testing.T) {
+	db := &MockDB{Unpartitioned: []time.Time{month(2025, time.November)}}
+	a := newTestAnalyzer(db, 0)
+	now := time.Date(2026, time.December, 15, 10, 0, 0, 0, time.UTC)
+
+	if created := a.ensurePartitions(&analyzer.Run{}, "temperature", now); created != 4 {
+		t.Fatalf("expected 4 partitions, got %d", created)
+	}
+	want := []time.Time{month(2026, time.December), month(2027, time.January), month(2027, time.February), month(2025, time.November)}
+	got := db.Ensured["temperature"]
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("partition %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestApplyRetention_DropsOnlyRolledUp(t *testing.T) {
+	db := &MockDB{
+		Partitions: []dbTypes.TelemetryPartition{
+			{Name: "noise_p202503", From: month(2025, time.March), To: month(2025, time.April)},
+			{Name: "noise_p202504", From: month(2025, time.April), To: month(2025, time.May)},
+			{Name: "noise_p202505", From: month(2025, time.May), To: month(2025, time.June)},
+			{Name: "noise_p202506", From: month(2025, time.June), To: month(2025, time.July)},
+		},
+		NotRolledUp: map[string]bool{"noise_p202504": true},
+	}
+	a := newTestAnalyzer(db, 12)
+	now := time.Date(2026, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	dropped, kept := a.applyRetention(&analyzer.Run{}, "noise", now, true)
+	if dropped != 2 || kept != 1 {
+		t.Fatalf("expected 2 dropped and 1 kept, got %d and %d", dropped, kept)
+	}
+	if len(db.Dropped) != 2 || db.Dropped[0] != "noise_p202503" || db.Dropped[1] != "noise_p202505" {
+		t.Errorf("unexpected dropped partitions: %v", db.Dropped)
+	}
+}
+
+func TestApplyRetention_DetailsWithoutRollups(t *testing.T) {
+	// у спектра агрегатов нет: секция старше срока удаляется без проверки
+	db := &MockDB{
+		Partitions:  []dbTypes.TelemetryPartition{{Name: "noise_spectrum_p202503", From: month(2025, time.March), To: month(2025, time.April)}},
+		NotRolledUp: map[string]bool{"noise_spectrum_p202503": true},
+	}
+	now := time.Date(2026, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	dropped, kept := newTestAnalyzer(db, 12).applyRetention(&analyzer.Run{}, "spectrum", now, false)
+	if dropped != 1 || kept != 0 {
+		t.Fatalf("expected detail partition dropped, got %d dropped and %d kept", dropped, kept)
+	}
+}
+
+func TestMaintain_RetentionDisabled(t *testing.T) {
+	db := &MockDB{Partitions: []dbTypes.TelemetryPartition{
+		{Name: "weight_p200001", From: month(2000, time.January), To: month(2000, time.February)},
+	}}
+	newTestAnalyzer(db, 0).maintain(&analyzer.Run{}, time.Now())
+
+	if len(db.Dropped) != 0 {
+		t.Errorf("expected nothing dropped with retention disabled, got %v", db.Dropped)
+	}
+	if len(db.Ensured) != 5 {
+		t.Errorf("expected partitions ensured for all metrics and details, got %v", db.Ensured)
+	}
+}
+
+func TestRetentionFromEnv(t *testing.T) {
+	t.Setenv("TELEMETRY_RETENTION_MONTHS", "")
+	if got := RetentionFromEnv(); got != defaultRetentionMonths {
+		t.Errorf("expected default %d, got %d", defaultRetentionMonths, got)
+	}
+	t.Setenv("TELEMETRY_RETENTION_MONTHS", "6")
+	if got := RetentionFromEnv(); got != 6 {
+		t.Errorf("expected 6, got %d", got)
+	}
+	t.Setenv("TELEMETRY_RETENTION_MONTHS", "0")
+	if got := RetentionFromEnv(); got != 0 {
+		t.Errorf("expected 0, got %d", got)
+	}
+}
@@ -0,0 +1,203 @@
+// Package queenless — анализатор безматочности по спектру шума. Потеряв матку,
+// семья начинает «реветь»: энергия гула смещается в полосу 200–500 Гц. Анализатор
+// сравнивает долю этой полосы за последние сутки с собственной нормой семьи за
+// предыдущую неделю, поэтому громкая от природы семья тревоги не вызывает.
+package queenless
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"BeeIOT/internal/domain/spectrum"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// window — за какой период оценивается текущее состояние семьи
+	window = 24 * time.Hour
+	// baselineDays — сколько суток до window берётся как норма семьи
+	baselineDays = 7
+	// minSamples — меньше спектров за период — выводов не делаем
+	minSamples = 12
+)
+
+// Пороги доли полосы рёва. С нормой семьи сравниваем прирост; без нормы
+// (спектр только начали слать) требуем заметно больший абсолютный уровень.
+const (
+	roarShare       = 0.5
+	roarRise        = 0.15
+	roarShareNoBase = 0.65
+)
+
+// EventQueenlessSuspected — тип события в хронологии улья.
+const EventQueenlessSuspected = "queenless_suspected"
+
+// Assessment — итог оценки: медианная доля полосы рёва за сутки и норма семьи.
+type Assessment struct {
+	Suspected bool
+	Enough    bool
+	Ratio     float64
+	Baseline  float64
+	// HasBaseline — хватило ли данных за прошлую неделю на норму
+	HasBaseline bool
+	Samples     int
+}
+
+type Analyzer struct {
+	db       interfaces.DB
+	ctx      context.Context
+	notifier *alerts.Notifier
+	logger   zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB, notifier *alerts.Notifier) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{db: db, ctx: ctx, notifier: notifier, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "queenless"
+}
+
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.analyzeQueenless(run, time.Now())
+}
+
+func (a *Analyzer) analyzeQueenless(run *analyzer.Run, now time.Time) error {
+	hives, err := a.db.GetHives(a.ctx, "", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get hives: %w", err)
+	}
+	for _, hive := range hives {
+		if hive.HubID == nil || !hive.Status {
+			continue
+		}
+		spectra, err := a.db.GetNoiseSpectrumSinceTimeById(a.ctx, *hive.HubID, now.Add(-window-baselineDays*24*time.Hour).UTC())
+		if err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("queenless: failed to get noise spectrum")
+			run.Error(err)
+			continue
+		}
+		if len(spectra) == 0 {
+			// прошивка без спектра
+			continue
+		}
+		run.HiveProcessed()
+		result := assess(spectra, now)
+		if !result.Enough {
+			continue
+		}
+		a.report(run, hive, result, now)
+	}
+	return nil
+}
+
+// assess делит спектры на текущие сутки и норму за неделю до них
+// и сравнивает медианы доли полосы рёва.
+func assess(spectra []dbTypes.HivesNoiseSpectrum, now time.Time) Assessment {
+	cutoff := now.Add(-window)
+	var recent, baseline []float64
+	for _, s := range spectra {
+		ratio := spectrum.RoarRatio(s.Edges, s.Levels)
+		if s.Date.Before(cutoff) {
+			baseline = append(baseline, ratio)
+		} else {
+			recent = append(recent, ratio)
+		}
+	}
+
+	result := Assessment{Samples: len(recent)}
+	if len(recent) < minSamples {
+		return result
+	}
+	result.Enough = true
+	result.Ratio = median(recent)
+	if len(baseline) >= minSamples {
+		result.HasBaseline = true
+		result.Baseline = median(baseline)
+		result.Suspected = result.Ratio >= roarShare && result.Ratio-result.Baseline >= roarRise
+	} else {
+		result.Suspected = result.Ratio >= roarShareNoBase
+	}
+	return result
+}
+
+func (a *Analyzer) report(run *analyzer.Run, hive dbTypes.Hive, result Assessment, now time.Time) {
+	alert := alerts.Alert{Type: alerts.TypeQueenless, Email: hive.Email, Hive: hive.NameHive}
+	if !result.Suspected {
+		if _, err := a.notifier.Clear(a.ctx, alert); err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("queenless: failed to resolve alert")
+		}
+		return
+	}
+
+	a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).
+		Float64("ratio", result.Ratio).Float64("baseline", result.Baseline).Int("samples", result.Samples).
+		Msg("queenless colony suspected")
+	run.AlertRaised()
+
+	title := "Возможно, семья осталась без матки"
+	alert.Data = notifyTypes.Message{
+		Title: title,
+		Body:  describe(result),
+		Data:  map[string]string{"hive": hive.NameHive, "roar_ratio": fmt.Sprintf("%.2f", result.Ratio)},
+	}
+	action, err := a.notifier.Raise(a.ctx, alert)
+	switch {
+	case errors.Is(err, alerts.ErrNotificationDisabled):
+		a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
+	case err != nil:
+		a.logger.Warn().Int("hiveId", hive.Id).Str("email", hive.Email).Err(err).Msg("failed to send notification")
+		run.Error(err)
+	}
+	if action != alerts.ActionOpen {
+		return
+	}
+	details := map[string]any{"roar_ratio": round(result.Ratio), "samples": result.Samples}
+	if result.HasBaseline {
+		details["baseline"] = round(result.Baseline)
+	}
+	_, err = a.db.NewHiveEvent(a.ctx, hive.Id, dbTypes.HiveEvent{
+		Type:       EventQueenlessSuspected,
+		Source:     "analyzer",
+		Title:      title,
+		Details:    details,
+		OccurredAt: now,
+	})
+	if err != nil {
+		a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("queenless: failed to record hive event")
+		run.Error(err)
+	}
+}
+
+// describe — текст уведомления.
+func describe(r Assessment) string {
+	text := fmt.Sprintf("Гул семьи сместился в полосу 200–500 Гц: на неё приходится %.0f%% энергии шума", 100*r.Ratio)
+	if r.HasBaseline {
+		text += fmt.Sprintf(" при обычных %.0f%%", 100*r.Baseline)
+	}
+	return text + ".\nТак звучит семья без матки. Осмотрите улей: есть ли засев и маточники."
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
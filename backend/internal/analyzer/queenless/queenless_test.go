@@ -0,0 +1,138 @@
+package queenless
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	now   = time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	edges = []float64{100, 200, 300, 500, 1000}
+)
+
+// spectra строит n спектров с шагом в час, начиная с start; roar — уровень
+// полос 200–300 и 300–500 Гц (дБ), остальные полосы — 40 дБ.
+func spectra(start time.Time, n int, roar float64) []dbTypes.HivesNoiseSpectrum {
+	result := make([]dbTypes.HivesNoiseSpectrum, n)
+	for i := range result {
+		result[i] = dbTypes.HivesNoiseSpectrum{
+			Date:   start.Add(time.Duration(i) * time.Hour),
+			Edges:  edges,
+			Levels: []float64{40, roar, roar, 40},
+		}
+	}
+	return result
+}
+
+func TestAssess(t *testing.T) {
+	weekAgo := now.Add(-8 * 24 * time.Hour)
+	today := now.Add(-window)
+	tests := []struct {
+		name      string
+		data      []dbTypes.HivesNoiseSpectrum
+		enough    bool
+		suspected bool
+	}{
+		// норма — ровный спектр (доля 0.5), сегодня полоса рёва на 6 дБ громче (0.8)
+		{"roar above baseline", append(spectra(weekAgo, 48, 40), spectra(today, 24, 46)...), true, true},
+		// громкая от природы семья: доля высокая, но такая же, как всю неделю
+		{"loud colony", append(spectra(weekAgo, 48, 46), spectra(today, 24, 46)...), true, false},
+		{"normal colony", append(spectra(weekAgo, 48, 40), spectra(today, 24, 40)...), true, false},
+		// нормы нет — решает абсолютный порог
+		{"no baseline, strong roar", spectra(today, 24, 46), true, true},
+		{"no baseline, moderate roar", spectra(today, 24, 42), true, false},
+		{"too few samples", spectra(today, 6, 46), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := assess(tt.data, now)
+			if r.Enough != tt.enough || r.Suspected != tt.suspected {
+				t.Errorf("assess() = %+v, want enough=%v suspected=%v", r, tt.enough, tt.suspected)
+			}
+		})
+	}
+}
+
+type MockDB struct {
+	interfaces.DB
+	Hives   []dbTypes.Hive
+	Spectra []dbTypes.HivesNoiseSpectrum
+	Events  []dbTypes.HiveEvent
+}
+
+func (m *MockDB) GetHives(_ context.Context, _ string, _ *bool) ([]dbTypes.Hive, error) {
+	return m.Hives, nil
+}
+
+func (m *MockDB) GetNoiseSpectrumSinceTimeById(_ context.Context, _ int, _ time.Time) ([]dbTypes.HivesNoiseSpectrum, error) {
+	return m.Spectra, nil
+}
+
+func (m *MockDB) NewHiveEvent(_ context.Context, _ int, e dbTypes.HiveEvent) (int64, error) {
+	m.Events = append(m.Events, e)
+	return int64(len(m.Events)), nil
+}
+
+func (m *MockDB) NewNotification(_ context.Context, _ string, _ dbTypes.Notification) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return nil, nil
+}
+
+type MockStates struct {
+	interfaces.InMemoryDB
+	States map[string]string
+}
+
+func (m *MockStates) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.States[key], nil
+}
+
+func (m *MockStates) SetAlertState(_ context.Context, key, state string, _ time.Duration) error {
+	m.States[key] = state
+	return nil
+}
+
+func TestAnalyzeQueenless(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID := 1
+	db := &MockDB{
+		Hives: []dbTypes.Hive{
+			{Id: 1, NameHive: "Hive1", Email: "a@b.c", HubID: &hubID, Status: true},
+			{Id: 2, NameHive: "Hive2", Email: "a@b.c", Status: true},
+		},
+		Spectra: append(spectra(now.Add(-8*24*time.Hour), 48, 40), spectra(now.Add(-window), 24, 46)...),
+	}
+	states := &MockStates{States: map[string]string{}}
+	a := NewAnalyzer(ctx, db, alerts.NewNotifier(db, states, nil, zerolog.Nop()))
+
+	var run analyzer.Run
+	if err := a.analyzeQueenless(&run, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.HivesProcessed != 1 || run.AlertsRaised != 1 {
+		t.Fatalf("expected one queenless hive, got %+v", run)
+	}
+	if len(db.Events) != 1 || db.Events[0].Type != EventQueenlessSuspected || db.Events[0].Details["baseline"] != 0.5 {
+		t.Fatalf("expected queenless event, got %+v", db.Events)
+	}
+	if states.States["a@b.c:Hive1:queenless"] == "" {
+		t.Error("expected queenless alert state to be saved")
+	}
+
+	// повторный прогон через час: событие не дублируется
+	run = analyzer.Run{}
+	_ = a.analyzeQueenless(&run, now.Add(time.Hour))
+	if len(db.Events) != 1 {
+		t.Errorf("expected no duplicate event, got %d", len(db.Events))
+	}
+}
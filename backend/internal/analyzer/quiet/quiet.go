@@ -0,0 +1,47 @@
+// Package quiet — фоновое задание, отправляющее сводку уведомлений, отложенных
+// на тихие часы пользователя, когда эти часы заканчиваются.
+package quiet
+
+import (
+	"BeeIOT/internal/analyzer"
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// Flusher отправляет отложенные уведомления (см. notification.Dispatcher.FlushHeld).
+type Flusher interface {
+	FlushHeld(ctx context.Context) (int, error)
+}
+
+type Analyzer struct {
+	flusher Flusher
+	ctx     context.Context
+	logger  zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, flusher Flusher) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{flusher: flusher, ctx: ctx, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "quiet_digest"
+}
+
+// Run рассылает сводки. Сводка — не новая проблема, а доставка уже поднятых алертов,
+// поэтому в прогоне она учитывается как обработанный получатель, а не как алерт.
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	sent, err := a.flusher.FlushHeld(a.ctx)
+	for range sent {
+		run.HiveProcessed()
+	}
+	if sent > 0 {
+		a.logger.Info().Int("digests", sent).Msg("quiet_digest: held notifications sent")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to flush held notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package quiet
+
+import (
+	"BeeIOT/internal/analyzer"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type mockFlusher struct {
+	sent int
+	err  error
+}
+
+func (m *mockFlusher) FlushHeld(_ context.Context) (int, error) {
+	return m.sent, m.err
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	a := NewAnalyzer(ctx, &mockFlusher{sent: 2, err: errors.New("smtp down")})
+
+	var run analyzer.Run
+	if err := a.Run(&run); err == nil {
+		t.Fatal("expected flush error to be returned")
+	}
+	if run.HivesProcessed != 2 || run.AlertsRaised != 0 {
+		t.Errorf("expected 2 recipients processed and no alerts, got %d, %d", run.HivesProcessed, run.AlertsRaised)
+	}
+}
@@ -0,0 +1,65 @@
+package rollup
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/interfaces"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// lookback — за какой период назад агрегаты пересчитываются при каждом запуске.
+// Датчик без связи копит замеры в буфере и досылает их пачкой (см. mqtt.handleDeviceDataBatch),
+// так что последние часы и сутки могут ещё дополняться. Трое суток с запасом покрывают
+// типичный разрыв связи NB-IoT.
+const lookback = 72 * time.Hour
+
+// Analyzer — фоновое задание, поддерживающее таблицы telemetry_hourly и telemetry_daily.
+type Analyzer struct {
+	db     interfaces.DB
+	ctx    context.Context
+	logger zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{db: db, ctx: ctx, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "rollup"
+}
+
+func (a *Analyzer) Run(_ *analyzer.Run) error {
+	return a.refresh(time.Now())
+}
+
+// refreshSince определяет, с какого момента пересчитывать агрегаты. Если агрегатов
+// ещё нет — считаем всю историю; если сервер простаивал дольше lookback —
+// начинаем с последнего посчитанного часа, чтобы не оставить дыру.
+func refreshSince(now, latest time.Time) time.Time {
+	if latest.IsZero() {
+		return time.Time{}
+	}
+	since := now.Add(-lookback)
+	if latest.Before(since) {
+		return latest
+	}
+	return since
+}
+
+func (a *Analyzer) refresh(now time.Time) error {
+	latest, err := a.db.GetLatestRollupTime(a.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest rollup time: %w", err)
+	}
+	since := refreshSince(now, latest)
+	start := time.Now()
+	if err := a.db.RefreshRollups(a.ctx, since); err != nil {
+		return fmt.Errorf("failed to refresh rollups since %s: %w", since.Format(time.RFC3339), err)
+	}
+	a.logger.Info().Time("since", since).Dur("took", time.Since(start)).Msg("rollup: rollups refreshed")
+	return nil
+}
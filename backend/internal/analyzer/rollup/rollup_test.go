@@ -0,0 +1,80 @@
+package rollup
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockDB struct {
+	interfaces.DB
+	Latest     time.Time
+	LatestErr  error
+	Refreshed  []time.Time
+	RefreshErr error
+}
+
+func (m *MockDB) GetLatestRollupTime(_ context.Context) (time.Time, error) {
+	return m.Latest, m.LatestErr
+}
+
+func (m *MockDB) RefreshRollups(_ context.Context, since time.Time) error {
+	m.Refreshed = append(m.Refreshed, since)
+	return m.RefreshErr
+}
+
+func newTestAnalyzer(db *MockDB) *Analyzer {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	return NewAnalyzer(ctx, db)
+}
+
+func TestRefresh_FirstRunBackfillsHistory(t *testing.T) {
+	db := &MockDB{}
+	if err := newTestAnalyzer(db).refresh(time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(db.Refreshed) != 1 || !db.Refreshed[0].IsZero() {
+		t.Fatalf("expected full backfill from zero time, got %v", db.Refreshed)
+	}
+}
+
+func TestRefresh_UsesLookbackWindow(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	db := &MockDB{Latest: now.Add(-time.Hour)}
+	if err := newTestAnalyzer(db).refresh(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(db.Refreshed) != 1 || !db.Refreshed[0].Equal(now.Add(-lookback)) {
+		t.Fatalf("expected refresh since %v, got %v", now.Add(-lookback), db.Refreshed)
+	}
+}
+
+func TestRefresh_CatchesUpAfterDowntime(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	latest := now.Add(-10 * 24 * time.Hour)
+	db := &MockDB{Latest: latest}
+	if err := newTestAnalyzer(db).refresh(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(db.Refreshed) != 1 || !db.Refreshed[0].Equal(latest) {
+		t.Fatalf("expected refresh since last rollup %v, got %v", latest, db.Refreshed)
+	}
+}
+
+func TestRefresh_SkipsOnLatestError(t *testing.T) {
+	db := &MockDB{LatestErr: errors.New("db down")}
+	if err := newTestAnalyzer(db).refresh(time.Now()); err == nil {
+		t.Fatal("expected error to be reported")
+	}
+
+	if len(db.Refreshed) != 0 {
+		t.Fatalf("expected no refresh on error, got %v", db.Refreshed)
+	}
+}
@@ -0,0 +1,156 @@
+package swarm
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Пороги признаков роения. Подобраны по описаниям роения в литературе и записям
+// с наших ульев; у отдельных семей могут потребовать подстройки.
+const (
+	// noiseRiseMin и noiseRiseFull — подъём шума (дБ) от утреннего уровня: меньше
+	// noiseRiseMin не учитывается, noiseRiseFull и больше — признак в полную силу.
+	noiseRiseMin  = 4.0
+	noiseRiseFull = 10.0
+	// noiseRiseHours — сколько часов подряд шум должен расти, чтобы подъём считался устойчивым.
+	noiseRiseHours = 3
+	// noiseTolerance — допустимый провал часового среднего (дБ), не прерывающий рост.
+	noiseTolerance = 0.5
+
+	// tempSpikeMin и tempSpikeFull — рост температуры в гнезде (°C) за последний час
+	// относительно медианы предыдущих часов.
+	tempSpikeMin  = 1.0
+	tempSpikeFull = 3.0
+
+	// weightDropMin и weightDropMax — потеря веса (кг), типичная для вышедшего роя.
+	// Больше weightDropMax — скорее пасечник снял магазин или рамки.
+	weightDropMin = 1.0
+	weightDropMax = 3.0
+	// weightDropWindow — за сколько рой покидает улей.
+	weightDropWindow = 30 * time.Minute
+)
+
+// point — замер временного ряда.
+type point struct {
+	at    time.Time
+	value float64
+}
+
+// noiseRise возвращает силу признака «устойчивый рост шума» (0..1) и величину подъёма в дБ.
+// Ряд — замеры с утра; рост должен длиться не меньше noiseRiseHours часов подряд.
+func noiseRise(series []point) (float64, float64) {
+	hourly := hourlyMeans(series)
+	if len(hourly) < noiseRiseHours+1 {
+		return 0, 0
+	}
+	// считаем, сколько последних часов подряд шум не падал
+	rising := 0
+	for i := len(hourly) - 1; i > 0; i-- {
+		if hourly[i] < hourly[i-1]-noiseTolerance {
+			break
+		}
+		rising++
+	}
+	rise := hourly[len(hourly)-1] - hourly[0]
+	if rising < noiseRiseHours || rise < noiseRiseMin {
+		return 0, rise
+	}
+	return strength(rise, noiseRiseFull), rise
+}
+
+// tempSpike возвращает силу признака «всплеск температуры в гнезде» (0..1) и величину
+// всплеска: максимум за последний час минус медиана более ранних замеров.
+func tempSpike(series []point, now time.Time) (float64, float64) {
+	var before, recent []float64
+	for _, p := range series {
+		if p.at.After(now.Add(-time.Hour)) {
+			recent = append(recent, p.value)
+		} else {
+			before = append(before, p.value)
+		}
+	}
+	if len(before) == 0 || len(recent) == 0 {
+		return 0, 0
+	}
+	peak := recent[0]
+	for _, v := range recent {
+		peak = math.Max(peak, v)
+	}
+	delta := peak - median(before)
+	if delta < tempSpikeMin {
+		return 0, delta
+	}
+	return strength(delta, tempSpikeFull), delta
+}
+
+// weightDrop ищет резкую (за weightDropWindow) потерю веса, после которой вес
+// не вернулся. Возвращает 1 и величину потери, если она похожа на вышедший рой.
+func weightDrop(series []point) (float64, float64) {
+	best := 0.0
+	for i, from := range series {
+		for j := i + 1; j < len(series) && series[j].at.Sub(from.at) <= weightDropWindow; j++ {
+			drop := from.value - series[j].value
+			if drop <= best {
+				continue
+			}
+			// пасечник поднял крышку и поставил обратно — вес вернулся
+			if recovered(series[j+1:], from.value) {
+				continue
+			}
+			best = drop
+		}
+	}
+	if best < weightDropMin || best > weightDropMax {
+		return 0, best
+	}
+	return 1, best
+}
+
+// recovered сообщает, вернулся ли вес к исходному (с точностью до половины минимальной потери).
+func recovered(after []point, before float64) bool {
+	if len(after) == 0 {
+		return false
+	}
+	values := make([]float64, len(after))
+	for i, p := range after {
+		values[i] = p.value
+	}
+	return median(values) > before-weightDropMin/2
+}
+
+// hourlyMeans — средние по часам, по порядку; часы без замеров пропускаются.
+func hourlyMeans(series []point) []float64 {
+	var result []float64
+	var hour time.Time
+	sum, n := 0.0, 0
+	for _, p := range series {
+		h := p.at.Truncate(time.Hour)
+		if n > 0 && !h.Equal(hour) {
+			result = append(result, sum/float64(n))
+			sum, n = 0, 0
+		}
+		hour = h
+		sum += p.value
+		n++
+	}
+	if n > 0 {
+		result = append(result, sum/float64(n))
+	}
+	return result
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// strength — сила признака: доля от значения full, не больше 1.
+func strength(v, full float64) float64 {
+	return math.Min(1, v/full)
+}
@@ -0,0 +1,229 @@
+// Package swarm — анализатор риска роения. В отличие от noise, сравнивающего
+// среднесуточный шум соседних дней, смотрит на картину внутри дня: устойчивый рост
+// шума до полудня, всплеск температуры в гнезде и резкую потерю 1–3 кг веса.
+package swarm
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// alertScore — риск (0–100), начиная с которого пасечник получает важное уведомление.
+const alertScore = 60
+
+// Роение бывает днём: утром шум только набирает силу, вечером рой уже не выходит.
+const (
+	morningHour = 6
+	middayHour  = 12
+	firstHour   = 9
+	lastHour    = 17
+)
+
+// EventSwarmRisk — тип события в хронологии улья.
+const EventSwarmRisk = "swarm_risk"
+
+// Risk — оценка риска роения улья и признаки, из которых она сложилась.
+type Risk struct {
+	Score int
+	// Noise, Temperature, Weight — сила признаков, 0..1
+	Noise, Temperature, Weight float64
+	// NoiseRise (дБ), TempDelta (°C), WeightDrop (кг) — измеренные величины
+	NoiseRise, TempDelta, WeightDrop float64
+	HasWeight                        bool
+}
+
+type Analyzer struct {
+	db       interfaces.DB
+	ctx      context.Context
+	notifier *alerts.Notifier
+	logger   zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB, notifier *alerts.Notifier) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{db: db, ctx: ctx, notifier: notifier, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "swarm"
+}
+
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.analyzeSwarm(run, time.Now())
+}
+
+func (a *Analyzer) analyzeSwarm(run *analyzer.Run, now time.Time) error {
+	hives, err := a.db.GetHives(a.ctx, "", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get hives: %w", err)
+	}
+	zones := make(map[string]*time.Location)
+	for _, hive := range hives {
+		if hive.HubID == nil || !hive.Status {
+			continue
+		}
+		loc, ok := zones[hive.Email]
+		if !ok {
+			loc = a.location(hive.Email)
+			zones[hive.Email] = loc
+		}
+		local := now.In(loc)
+		if local.Hour() < firstHour || local.Hour() >= lastHour {
+			continue
+		}
+		risk, err := a.assess(*hive.HubID, now, loc)
+		if err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("swarm: failed to get telemetry")
+			run.Error(err)
+			continue
+		}
+		run.HiveProcessed()
+		a.report(run, hive, risk, now)
+	}
+	return nil
+}
+
+// location — часовой пояс владельца улья (из настроек уведомлений); по нему считаются утро и полдень.
+func (a *Analyzer) location(email string) *time.Location {
+	prefs, err := a.db.GetNotificationPreferences(a.ctx, email)
+	if err != nil {
+		a.logger.Warn().Err(err).Str("email", email).Msg("swarm: failed to get user timezone, using UTC")
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (a *Analyzer) assess(hubId int, now time.Time, loc *time.Location) (Risk, error) {
+	local := now.In(loc)
+	morning := time.Date(local.Year(), local.Month(), local.Day(), morningHour, 0, 0, 0, loc)
+	midday := time.Date(local.Year(), local.Month(), local.Day(), middayHour, 0, 0, 0, loc)
+
+	noise, err := a.db.GetNoiseSinceTimeById(a.ctx, hubId, morning.UTC())
+	if err != nil {
+		return Risk{}, err
+	}
+	temps, err := a.db.GetTemperaturesSinceTimeById(a.ctx, hubId, now.Add(-6*time.Hour).UTC())
+	if err != nil {
+		return Risk{}, err
+	}
+	weights, err := a.db.GetWeightSinceTimeById(a.ctx, hubId, now.Add(-2*time.Hour).UTC())
+	if err != nil {
+		return Risk{}, err
+	}
+
+	var noiseSeries, tempSeries, weightSeries []point
+	for _, n := range noise {
+		if n.Date.Before(midday) {
+			noiseSeries = append(noiseSeries, point{n.Date, n.Level})
+		}
+	}
+	for _, t := range temps {
+		tempSeries = append(tempSeries, point{t.Date, t.Temperature})
+	}
+	for _, w := range weights {
+		weightSeries = append(weightSeries, point{w.Date, w.Weight})
+	}
+	return score(noiseSeries, tempSeries, weightSeries, now), nil
+}
+
+// score объединяет признаки в риск 0–100. Ни один признак в одиночку не дотягивает
+// до alertScore: шум растёт и в жаркий день, вес падает и при осмотре. Без весов
+// шум и температура делят вес признака массы между собой.
+func score(noise, temps, weights []point, now time.Time) Risk {
+	var r Risk
+	r.Noise, r.NoiseRise = noiseRise(noise)
+	r.Temperature, r.TempDelta = tempSpike(temps, now)
+	r.HasWeight = len(weights) > 1
+	wNoise, wTemp, wWeight := 0.55, 0.45, 0.0
+	if r.HasWeight {
+		r.Weight, r.WeightDrop = weightDrop(weights)
+		wNoise, wTemp, wWeight = 0.4, 0.3, 0.3
+	}
+	r.Score = int(math.Round(100 * (wNoise*r.Noise + wTemp*r.Temperature + wWeight*r.Weight)))
+	return r
+}
+
+func (a *Analyzer) report(run *analyzer.Run, hive dbTypes.Hive, risk Risk, now time.Time) {
+	alert := alerts.Alert{Type: alerts.TypeSwarm, Email: hive.Email, Hive: hive.NameHive}
+	if risk.Score < alertScore {
+		if _, err := a.notifier.Clear(a.ctx, alert); err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("swarm: failed to resolve alert")
+		}
+		return
+	}
+
+	a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("score", risk.Score).
+		Float64("noiseRise", risk.NoiseRise).Float64("tempDelta", risk.TempDelta).Float64("weightDrop", risk.WeightDrop).
+		Msg("swarm risk detected")
+	run.AlertRaised()
+
+	title := fmt.Sprintf("Риск роения %d%%", risk.Score)
+	alert.Data = notifyTypes.Message{
+		Title:     title,
+		Body:      describe(risk),
+		Data:      map[string]string{"hive": hive.NameHive, "score": strconv.Itoa(risk.Score)},
+		Important: true,
+	}
+	action, err := a.notifier.Raise(a.ctx, alert)
+	switch {
+	case errors.Is(err, alerts.ErrNotificationDisabled):
+		a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
+	case err != nil:
+		a.logger.Warn().Int("hiveId", hive.Id).Str("email", hive.Email).Err(err).Msg("failed to send notification")
+		run.Error(err)
+	}
+	// в хронологию — только начало эпизода, а не каждый прогон
+	if action != alerts.ActionOpen {
+		return
+	}
+	_, err = a.db.NewHiveEvent(a.ctx, hive.Id, dbTypes.HiveEvent{
+		Type:   EventSwarmRisk,
+		Source: "analyzer",
+		Title:  title,
+		Details: map[string]any{
+			"score":       risk.Score,
+			"noise_rise":  round(risk.NoiseRise),
+			"temp_delta":  round(risk.TempDelta),
+			"weight_drop": round(risk.WeightDrop),
+		},
+		OccurredAt: now,
+	})
+	if err != nil {
+		a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("swarm: failed to record hive event")
+		run.Error(err)
+	}
+}
+
+// describe — текст уведомления: какие признаки сработали.
+func describe(r Risk) string {
+	text := "Семья готовится к роению или рой уже вышел."
+	if r.Noise > 0 {
+		text += fmt.Sprintf("\nШум с утра вырос на %.1f дБ.", r.NoiseRise)
+	}
+	if r.Temperature > 0 {
+		text += fmt.Sprintf("\nТемпература в гнезде подскочила на %.1f °C.", r.TempDelta)
+	}
+	if r.Weight > 0 {
+		text += fmt.Sprintf("\nВес улья резко упал на %.1f кг.", r.WeightDrop)
+	}
+	return text + "\nПроверьте улей и будьте готовы снять рой."
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,233 @@
+package swarm
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// series строит ряд с шагом step начиная с start по функции f(i).
+func series(start time.Time, step time.Duration, n int, f func(i int) float64) []point {
+	result := make([]point, n)
+	for i := range result {
+		result[i] = point{start.Add(time.Duration(i) * step), f(i)}
+	}
+	return result
+}
+
+var morning = time.Date(2026, 6, 10, 6, 0, 0, 0, time.UTC)
+
+func TestNoiseRise(t *testing.T) {
+	// 06:00–11:00, шум растёт на 2 дБ в час
+	rising := series(morning, 10*time.Minute, 30, func(i int) float64 { return 40 + float64(i/6)*2 })
+	if s, rise := noiseRise(rising); s != 0.8 || rise != 8 {
+		t.Errorf("expected strong rise, got %v, %v", s, rise)
+	}
+
+	// жаркий день: шум вырос, но к полудню уже падает
+	hot := series(morning, 10*time.Minute, 30, func(i int) float64 {
+		return []float64{40, 44, 48, 50, 46}[i/6]
+	})
+	if s, _ := noiseRise(hot); s != 0 {
+		t.Errorf("expected no sustained rise, got %v", s)
+	}
+
+	// ровный шум
+	flat := series(morning, 10*time.Minute, 30, func(i int) float64 { return 40 + float64(i%2)*0.3 })
+	if s, _ := noiseRise(flat); s != 0 {
+		t.Errorf("expected no rise on flat series, got %v", s)
+	}
+}
+
+func TestTempSpike(t *testing.T) {
+	now := morning.Add(5 * time.Hour)
+	// гнездо держит 34.5 °C, в последние полчаса — 37 °C
+	spike := series(morning, 10*time.Minute, 31, func(i int) float64 {
+		if i >= 27 {
+			return 37
+		}
+		return 34.5
+	})
+	if s, delta := tempSpike(spike, now); s < 0.8 || delta != 2.5 {
+		t.Errorf("expected spike, got %v, %v", s, delta)
+	}
+	steady := series(morning, 10*time.Minute, 31, func(i int) float64 { return 34.5 + float64(i%3)*0.2 })
+	if s, _ := tempSpike(steady, now); s != 0 {
+		t.Errorf("expected no spike, got %v", s)
+	}
+}
+
+func TestWeightDrop(t *testing.T) {
+	start := morning.Add(4 * time.Hour)
+	tests := []struct {
+		name string
+		f    func(i int) float64
+		want float64
+	}{
+		{"swarm left", func(i int) float64 {
+			if i >= 6 {
+				return 42.3
+			}
+			return 44.5
+		}, 1},
+		{"super removed", func(i int) float64 {
+			if i >= 6 {
+				return 30
+			}
+			return 44.5
+		}, 0},
+		{"lid lifted and put back", func(i int) float64 {
+			if i == 6 {
+				return 42
+			}
+			return 44.5
+		}, 0},
+		{"nectar flow", func(i int) float64 { return 44 + float64(i)*0.05 }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s, drop := weightDrop(series(start, 5*time.Minute, 24, tt.f)); s != tt.want {
+				t.Errorf("weightDrop() = %v (drop %.1f), want %v", s, drop, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	now := morning.Add(5 * time.Hour)
+	noise := series(morning, 10*time.Minute, 30, func(i int) float64 { return 40 + float64(i/6)*3 })
+	temps := series(morning, 10*time.Minute, 31, func(i int) float64 {
+		if i >= 27 {
+			return 37.5
+		}
+		return 34.5
+	})
+
+	// без весов шума и температуры достаточно для тревоги
+	if r := score(noise, temps, nil, now); r.Score != 100 || r.HasWeight {
+		t.Errorf("expected full risk without weight, got %+v", r)
+	}
+	// с весами, но без потери веса — риск ниже, но выше порога
+	weights := series(now.Add(-2*time.Hour), 10*time.Minute, 12, func(int) float64 { return 44 })
+	if r := score(noise, temps, weights, now); r.Score != 70 || !r.HasWeight {
+		t.Errorf("expected 70 with flat weight, got %+v", r)
+	}
+	// только шум — ниже порога
+	if r := score(noise, nil, nil, now); r.Score >= alertScore {
+		t.Errorf("expected noise alone to stay below alert, got %+v", r)
+	}
+}
+
+type MockDB struct {
+	interfaces.DB
+	Hives  []dbTypes.Hive
+	Events []dbTypes.HiveEvent
+}
+
+func (m *MockDB) GetHives(_ context.Context, _ string, _ *bool) ([]dbTypes.Hive, error) {
+	return m.Hives, nil
+}
+
+func (m *MockDB) GetNotificationPreferences(_ context.Context, _ string) (dbTypes.NotificationPreferences, error) {
+	return dbTypes.NotificationPreferences{Timezone: "Europe/Moscow"}, nil
+}
+
+// Ряды в UTC: утро по Москве — 03:00 UTC.
+func (m *MockDB) GetNoiseSinceTimeById(_ context.Context, _ int, since time.Time) ([]dbTypes.HivesNoiseData, error) {
+	var result []dbTypes.HivesNoiseData
+	for _, p := range series(since, 10*time.Minute, 36, func(i int) float64 { return 40 + float64(i/6)*3 }) {
+		result = append(result, dbTypes.HivesNoiseData{Date: p.at, Level: p.value})
+	}
+	return result, nil
+}
+
+func (m *MockDB) GetTemperaturesSinceTimeById(_ context.Context, _ int, since time.Time) ([]dbTypes.HivesTemperatureData, error) {
+	var result []dbTypes.HivesTemperatureData
+	for _, p := range series(since, 10*time.Minute, 36, func(i int) float64 {
+		if i >= 33 {
+			return 37.5
+		}
+		return 34.5
+	}) {
+		result = append(result, dbTypes.HivesTemperatureData{Date: p.at, Temperature: p.value})
+	}
+	return result, nil
+}
+
+func (m *MockDB) GetWeightSinceTimeById(_ context.Context, _ int, _ time.Time) ([]dbTypes.HivesWeightData, error) {
+	return nil, nil
+}
+
+func (m *MockDB) NewHiveEvent(_ context.Context, _ int, e dbTypes.HiveEvent) (int64, error) {
+	m.Events = append(m.Events, e)
+	return int64(len(m.Events)), nil
+}
+
+func (m *MockDB) NewNotification(_ context.Context, _ string, _ dbTypes.Notification) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return nil, nil
+}
+
+type MockStates struct {
+	interfaces.InMemoryDB
+	States map[string]string
+}
+
+func (m *MockStates) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.States[key], nil
+}
+
+func (m *MockStates) SetAlertState(_ context.Context, key, state string, _ time.Duration) error {
+	m.States[key] = state
+	return nil
+}
+
+func TestAnalyzeSwarm(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID := 1
+	db := &MockDB{Hives: []dbTypes.Hive{
+		{Id: 1, NameHive: "Hive1", Email: "a@b.c", HubID: &hubID, Status: true},
+		{Id: 2, NameHive: "Hive2", Email: "a@b.c", Status: true},
+	}}
+	states := &MockStates{States: map[string]string{}}
+	a := NewAnalyzer(ctx, db, alerts.NewNotifier(db, states, nil, zerolog.Nop()))
+
+	// 12:00 по Москве
+	now := time.Date(2026, 6, 10, 9, 0, 0, 0, time.UTC)
+	var run analyzer.Run
+	if err := a.analyzeSwarm(&run, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.HivesProcessed != 1 || run.AlertsRaised != 1 {
+		t.Fatalf("expected one hive with swarm risk, got %+v", run)
+	}
+	if len(db.Events) != 1 || db.Events[0].Type != EventSwarmRisk || db.Events[0].Details["score"] != 100 {
+		t.Fatalf("expected swarm event, got %+v", db.Events)
+	}
+	if states.States["a@b.c:Hive1:swarm"] == "" {
+		t.Error("expected swarm alert state to be saved")
+	}
+
+	// повторный прогон: алерт подавлен, событие не дублируется
+	run = analyzer.Run{}
+	_ = a.analyzeSwarm(&run, now.Add(30*time.Minute))
+	if len(db.Events) != 1 {
+		t.Errorf("expected no duplicate event, got %d", len(db.Events))
+	}
+
+	// ночью анализатор ульи не смотрит
+	run = analyzer.Run{}
+	_ = a.analyzeSwarm(&run, time.Date(2026, 6, 10, 20, 0, 0, 0, time.UTC))
+	if run.HivesProcessed != 0 {
+		t.Errorf("expected hives to be skipped at night, got %d", run.HivesProcessed)
+	}
+}
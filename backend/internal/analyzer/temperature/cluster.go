@@ -0,0 +1,103 @@
+package temperature
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"sort"
+	"time"
+)
+
+// Зимой расплода нет: клуб греет только себя, и датчик, мимо которого клуб
+// проходит вслед за кормом, может показывать и 30 °C, и 5 °C. Поэтому абсолютный
+// диапазон не проверяется — смотрим на тренд и внезапное остывание.
+const (
+	// clusterLookback — сколько суток истории смотрим зимой
+	clusterLookback = 7 * 24 * time.Hour
+	// dropWindow — последние часы, в которых ищем остывание; сравниваются с dropBaseline перед ними.
+	// Анализатор запускается с периодом Period = dropWindow, иначе остывание пройдёт между проверками
+	dropWindow   = 6 * time.Hour
+	dropBaseline = 24 * time.Hour
+	// dropThreshold — падение медианы (°C), после которого клуб считается остывшим
+	dropThreshold = 6.0
+	// coolingRate — устойчивое остывание (°C в сутки) по тренду суточных медиан
+	coolingRate  = 1.0
+	minTrendDays = 4
+	// minClusterSamples — меньше замеров в окне — выводов не делаем
+	minClusterSamples = 3
+)
+
+// EventClusterDrop — тип события в хронологии улья.
+const EventClusterDrop = "cluster_drop"
+
+// ClusterState — оценка зимнего клуба.
+type ClusterState struct {
+	// Dropped — клуб резко остыл: с Baseline до Current за последние часы
+	Dropped           bool
+	Baseline, Current float64
+	// Cooling — температура у датчика устойчиво падает на Rate °C в сутки уже Days суток
+	Cooling bool
+	Rate    float64
+	Days    int
+}
+
+func assessCluster(data []dbTypes.HivesTemperatureData, now time.Time) ClusterState {
+	var s ClusterState
+	var recent, before []float64
+	days := make(map[time.Time][]float64)
+	for _, d := range data {
+		switch age := now.Sub(d.Date); {
+		case age < 0 || age > clusterLookback:
+			continue
+		case age <= dropWindow:
+			recent = append(recent, d.Temperature)
+		case age <= dropWindow+dropBaseline:
+			before = append(before, d.Temperature)
+		}
+		day := d.Date.UTC().Truncate(24 * time.Hour)
+		days[day] = append(days[day], d.Temperature)
+	}
+
+	if len(recent) >= minClusterSamples && len(before) >= minClusterSamples {
+		s.Current, s.Baseline = median(recent), median(before)
+		s.Dropped = s.Baseline-s.Current >= dropThreshold
+	}
+
+	// тренд суточных медиан; сегодняшние неполные сутки тоже учитываем
+	var xs, ys []float64
+	for day, values := range days {
+		xs = append(xs, day.Sub(now.Add(-clusterLookback)).Hours()/24)
+		ys = append(ys, median(values))
+	}
+	s.Days = len(xs)
+	if s.Days >= minTrendDays {
+		s.Rate = -slope(xs, ys)
+		s.Cooling = s.Rate >= coolingRate
+	}
+	return s
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// slope — наклон прямой наименьших квадратов.
+func slope(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
@@ -1,52 +1,65 @@
 package temperature
 
 import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/models/dbTypes"
-	"BeeIOT/internal/domain/notification"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"BeeIOT/internal/domain/season"
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
+const (
+	// Period — период запуска анализатора: зимой резкое остывание клуба ищется
+	// в последних dropWindow часах, поэтому проверять реже нельзя.
+	Period = dropWindow
+	// summerPeriod — летний диапазон гнезда проверяется раз в сутки, как и раньше
+	summerPeriod = 24 * time.Hour
+)
+
 type Analyzer struct {
-	period       time.Duration
-	db           interfaces.DB
-	ctx          context.Context
-	notification *notification.Notification
-	logger       zerolog.Logger
+	db       interfaces.DB
+	ctx      context.Context
+	notifier *alerts.Notifier
+	calendar season.Calendar
+	logger   zerolog.Logger
 }
 
-func NewAnalyzer(ctx context.Context, period time.Duration, db interfaces.DB, notification *notification.Notification) *Analyzer {
+// NewAnalyzer создаёт анализатор температуры. По calendar для ульев в режиме auto
+// выбирается модель: летом — диапазон гнезда с расплодом, зимой — клуб.
+// Для ульев на пасеке с заданной широтой calendar заменяется календарём этой широты.
+func NewAnalyzer(ctx context.Context, db interfaces.DB, notifier *alerts.Notifier, calendar season.Calendar) *Analyzer {
 	logger := ctx.Value("logger").(zerolog.Logger)
-	return &Analyzer{period: period, db: db, ctx: ctx, logger: logger, notification: notification}
+	return &Analyzer{db: db, ctx: ctx, logger: logger, notifier: notifier, calendar: calendar}
 }
 
-func (a *Analyzer) Start() {
-	go func() {
-		a.analyzeTemperature()
-		ticker := time.NewTicker(a.period)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				a.analyzeTemperature()
-			case <-a.ctx.Done():
-				return
-			}
-		}
-	}()
+// calendarFor — календарь зимы для улья: по широте его пасеки, если она известна.
+func (a *Analyzer) calendarFor(hive dbTypes.Hive) season.Calendar {
+	if hive.Latitude != nil {
+		return season.ForLatitude(*hive.Latitude)
+	}
+	return a.calendar
+}
+
+func (a *Analyzer) Name() string {
+	return "temperature"
 }
 
-func (a *Analyzer) analyzeTemperature() {
-	a.logger.Info().Msg("temperature analyzer: starting run")
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.analyzeTemperature(run, time.Now())
+}
+
+func (a *Analyzer) analyzeTemperature(run *analyzer.Run, now time.Time) error {
 	hives, err := a.db.GetHives(a.ctx, "", nil)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("failed to get hives")
-		return
+		return fmt.Errorf("failed to get hives: %w", err)
 	}
 	a.logger.Info().Int("total_hives", len(hives)).Msg("temperature analyzer: hives loaded")
 	for _, hive := range hives {
@@ -54,75 +67,138 @@ func (a *Analyzer) analyzeTemperature() {
 			a.logger.Debug().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Msg("skip hive without hub")
 			continue
 		}
-		data, err := a.db.GetTemperaturesSinceTimeById(a.ctx, *hive.HubID, hive.DateTemperature)
+		winter := season.IsWinter(hive.SeasonMode, a.calendarFor(hive), now)
+		if !winter && now.Sub(hive.DateTemperature) < summerPeriod {
+			continue
+		}
+		since := hive.DateTemperature
+		if winter {
+			// тренду клуба нужна история, а не только замеры с прошлой проверки
+			since = now.Add(-clusterLookback)
+		}
+		data, err := a.db.GetTemperaturesSinceTimeById(a.ctx, *hive.HubID, since)
 		if err != nil {
 			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to get temperature")
+			run.Error(err)
 			continue
 		}
-		a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("samples", len(data)).Msg("analyzing temperature")
-		a.temperatureAnalysis(data, hive)
-		if errUpd := a.db.UpdateHiveTemperatureCheck(a.ctx, hive.Id, time.Now()); errUpd != nil {
+		run.HiveProcessed()
+		rules, err := alerts.ForHive(a.ctx, a.db, hive.Email, hive.NameHive)
+		if err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to get alert rules, using defaults")
+		}
+		a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("samples", len(data)).Bool("winter", winter).Msg("analyzing temperature")
+		if winter {
+			a.clusterAnalysis(run, data, hive, now)
+		} else {
+			a.temperatureAnalysis(run, data, hive, rules)
+		}
+		if errUpd := a.db.UpdateHiveTemperatureCheck(a.ctx, hive.Id, now); errUpd != nil {
 			a.logger.Warn().Err(errUpd).Int("hiveId", hive.Id).Msg("failed to update hive temperature check")
+			run.Error(errUpd)
 		}
 	}
-	a.logger.Info().Msg("temperature analyzer: run finished")
-}
-
-const temperatureNormal = 34.0
-const temperatureDeltaUp = 5.0
-const temperatureDeltaDown = 5.0
-
-func (a *Analyzer) isNormallyTemperature(temp float64) bool {
-	return temp >= (temperatureNormal-temperatureDeltaDown) && temp <= (temperatureNormal+temperatureDeltaUp)
+	return nil
 }
 
-func (a *Analyzer) temperatureAnalysis(data []dbTypes.HivesTemperatureData, hive dbTypes.Hive) {
+func (a *Analyzer) temperatureAnalysis(run *analyzer.Run, data []dbTypes.HivesTemperatureData, hive dbTypes.Hive,
+	rules alerts.Rules) {
 	var abnormalCount int
 	var lastAbnormal float64
 	for _, elem := range data {
-		if a.isNormallyTemperature(elem.Temperature) {
+		if rules.TemperatureOK(elem.Temperature) {
 			continue
 		}
 		abnormalCount++
 		lastAbnormal = elem.Temperature
 	}
+	alert := alerts.Alert{Type: alerts.TypeTemperature, Email: hive.Email, Hive: hive.NameHive}
 	if abnormalCount == 0 {
 		a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("samples", len(data)).Msg("temperature normal, no notification")
+		if _, err := a.notifier.Clear(a.ctx, alert); err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to resolve temperature alert")
+		}
 		return
 	}
 	a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("abnormal", abnormalCount).Float64("last", lastAbnormal).Msg("abnormal temperature detected")
-	if a.notification == nil {
-		a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
-		return
+	run.AlertRaised()
+	alert.Data = notifyTypes.Message{
+		Title: "Критический уровень температуры в улье",
+		Body: fmt.Sprintf(`Последнее значение: %.2f (аномальных замеров: %d).
+Норма: от %.2f до %.2f. Необходимо проверить состояние улья`, lastAbnormal, abnormalCount,
+			rules.TemperatureNormal-rules.TemperatureDeltaDown, rules.TemperatureNormal+rules.TemperatureDeltaUp),
+		Data: map[string]string{
+			"hive":  hive.NameHive,
+			"model": season.ModeSummer,
+		},
+		Important: false,
 	}
-	tokens, err := a.db.GetFirebaseToken(a.ctx, hive.Email)
-	if err != nil {
-		a.logger.Warn().Err(err).Int("hiveId", hive.Id).Str("email", hive.Email).Msg("failed to get firebase tokens")
+	a.raise(run, hive, alert)
+}
+
+// clusterAnalysis — зимняя модель: тревога, если клуб резко остыл (семья могла
+// погибнуть) или устойчиво остывает несколько суток.
+func (a *Analyzer) clusterAnalysis(run *analyzer.Run, data []dbTypes.HivesTemperatureData, hive dbTypes.Hive, now time.Time) {
+	state := assessCluster(data, now)
+	alert := alerts.Alert{Type: alerts.TypeTemperature, Email: hive.Email, Hive: hive.NameHive}
+	if !state.Dropped && !state.Cooling {
+		a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Int("samples", len(data)).Msg("winter cluster normal, no notification")
+		if _, err := a.notifier.Clear(a.ctx, alert); err != nil {
+			a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("failed to resolve temperature alert")
+		}
 		return
 	}
-	a.logger.Info().Int("hiveId", hive.Id).Str("email", hive.Email).Int("tokens", len(tokens)).Msg("sending temperature notification")
-	if len(tokens) == 0 {
+
+	a.logger.Info().Int("hiveId", hive.Id).Str("hive", hive.NameHive).Bool("dropped", state.Dropped).
+		Float64("baseline", state.Baseline).Float64("current", state.Current).Float64("rate", state.Rate).
+		Msg("winter cluster cooling detected")
+	run.AlertRaised()
+
+	alert.Data = notifyTypes.Message{
+		Title: "Клуб постепенно остывает",
+		Body: fmt.Sprintf(`Температура у датчика снижается на %.1f °C в сутки (%d сут.).
+Клуб уходит от датчика вслед за кормом или слабеет. Проверьте запасы корма`, state.Rate, state.Days),
+		Data: map[string]string{
+			"hive":  hive.NameHive,
+			"model": season.ModeWinter,
+		},
+	}
+	if state.Dropped {
+		alert.Data.Title = "Резкое остывание зимнего клуба"
+		alert.Data.Body = fmt.Sprintf(`За последние часы температура клуба упала с %.1f до %.1f °C.
+Так бывает, если семья погибла или клуб отошёл от датчика. Послушайте улей, постучав по стенке`, state.Baseline, state.Current)
+		alert.Data.Important = true
+	}
+	action := a.raise(run, hive, alert)
+	// в хронологию — только начало эпизода резкого остывания
+	if !state.Dropped || action != alerts.ActionOpen {
 		return
 	}
-	badToken, err := a.notification.SendNotification(a.ctx, notification.Data{
-		Title: "Критический уровень температуры в улье",
-		Body: fmt.Sprintf(`Последнее значение: %.2f (аномальных замеров: %d).
-Норма: %.2f +- %.2f. Необходимо проверить состояние улья`, lastAbnormal, abnormalCount, temperatureNormal, temperatureDeltaUp),
-		Data: map[string]string{
-			"hive": hive.NameHive,
+	_, err := a.db.NewHiveEvent(a.ctx, hive.Id, dbTypes.HiveEvent{
+		Type:   EventClusterDrop,
+		Source: "analyzer",
+		Title:  alert.Data.Title,
+		Details: map[string]any{
+			"baseline": math.Round(state.Baseline*10) / 10,
+			"current":  math.Round(state.Current*10) / 10,
 		},
-		Tokens:    tokens,
-		Important: false,
+		OccurredAt: now,
 	})
+	if err != nil {
+		a.logger.Warn().Err(err).Int("hiveId", hive.Id).Msg("temperature: failed to record hive event")
+		run.Error(err)
+	}
+}
+
+func (a *Analyzer) raise(run *analyzer.Run, hive dbTypes.Hive, alert alerts.Alert) alerts.Action {
+	action, err := a.notifier.Raise(a.ctx, alert)
 	switch {
-	case errors.Is(err, notification.ErrInvalidTokens):
-		err = a.db.DeleteFirebaseToken(a.ctx, hive.Email, badToken)
-		if err != nil {
-			a.logger.Warn().Int("hiveId", hive.Id).
-				Str("email", hive.Email).Err(err).Msg("failed to delete invalid firebase token")
-		}
+	case errors.Is(err, alerts.ErrNotificationDisabled):
+		a.logger.Warn().Int("hiveId", hive.Id).Msg("notification service is nil, skipping")
 	case err != nil:
 		a.logger.Warn().Int("hiveId", hive.Id).
 			Str("email", hive.Email).Err(err).Msg("failed to send notification")
+		run.Error(err)
 	}
+	return action
 }
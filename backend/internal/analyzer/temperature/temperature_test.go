@@ -1,8 +1,11 @@
 package temperature
 
 import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/season"
 	"context"
 	"testing"
 	"time"
@@ -14,6 +17,8 @@ type MockDB struct {
 	interfaces.DB
 	Hives    []dbTypes.Hive
 	TempData []dbTypes.HivesTemperatureData
+	Rules    dbTypes.AlertRules
+	Events   []dbTypes.HiveEvent
 }
 
 func (m *MockDB) GetHives(ctx context.Context, email string, active *bool) ([]dbTypes.Hive, error) {
@@ -24,10 +29,50 @@ func (m *MockDB) GetTemperaturesSinceTimeById(ctx context.Context, hiveId int, t
 	return m.TempData, nil
 }
 
+func (m *MockDB) GetEffectiveAlertRules(_ context.Context, _, hive, _ string) (dbTypes.AlertRules, error) {
+	return m.Rules, nil
+}
+
 func (m *MockDB) UpdateHiveTemperatureCheck(ctx context.Context, hiveId int, t time.Time) error {
 	return nil
 }
 
+func (m *MockDB) NewHiveEvent(_ context.Context, _ int, e dbTypes.HiveEvent) (int64, error) {
+	m.Events = append(m.Events, e)
+	return int64(len(m.Events)), nil
+}
+
+func (m *MockDB) NewNotification(_ context.Context, _ string, _ dbTypes.Notification) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return nil, nil
+}
+
+type MockStates struct {
+	interfaces.InMemoryDB
+	States map[string]string
+}
+
+func (m *MockStates) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.States[key], nil
+}
+
+func (m *MockStates) SetAlertState(_ context.Context, key, state string, _ time.Duration) error {
+	m.States[key] = state
+	return nil
+}
+
+// hourly — почасовые замеры за hours часов до now по функции f(часов назад).
+func hourly(now time.Time, hours int, f func(ago int) float64) []dbTypes.HivesTemperatureData {
+	data := make([]dbTypes.HivesTemperatureData, 0, hours)
+	for ago := hours - 1; ago >= 0; ago-- {
+		data = append(data, dbTypes.HivesTemperatureData{Date: now.Add(-time.Duration(ago) * time.Hour), Temperature: f(ago)})
+	}
+	return data
+}
+
 func TestAnalyzeTemperature(t *testing.T) {
 	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
 
@@ -42,33 +87,151 @@ func TestAnalyzeTemperature(t *testing.T) {
 		},
 	}
 
-	analyzer := NewAnalyzer(ctx, 1*time.Second, mockDB, nil)
+	a := NewAnalyzer(ctx, mockDB, nil, season.ForLatitude(55))
 
 	// With nil notification, temperatureAnalysis skips notification sending
-	analyzer.analyzeTemperature()
+	if err := a.analyzeTemperature(&analyzer.Run{}, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 }
 
-func TestIsNormallyTemperature(t *testing.T) {
+func TestTemperatureAnalysis_UsesHiveRules(t *testing.T) {
 	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
-	analyzer := NewAnalyzer(ctx, 1*time.Second, nil, nil)
-
-	tests := []struct {
-		temp     float64
-		expected bool
-	}{
-		{34.0, true},
-		{39.0, true},
-		{29.0, true},
-		{39.1, false},
-		{28.9, false},
-		{100.0, false},
-		{0.0, false},
-	}
-
-	for _, test := range tests {
-		result := analyzer.isNormallyTemperature(test.temp)
-		if result != test.expected {
-			t.Errorf("For temp %.2f expected %v, got %v", test.temp, test.expected, result)
+	a := NewAnalyzer(ctx, &MockDB{}, nil, season.ForLatitude(55))
+	data := []dbTypes.HivesTemperatureData{{Temperature: 18.0, Date: time.Now()}}
+
+	// по умолчанию 18 °C — аномалия
+	run := &analyzer.Run{}
+	a.temperatureAnalysis(run, data, dbTypes.Hive{Id: 1}, alerts.Default())
+	if run.AlertsRaised != 1 {
+		t.Fatalf("expected alert with default rules, got %d", run.AlertsRaised)
+	}
+
+	// для зимнего клуба с нормой 20 ± 5 — норма
+	normal := 20.0
+	run = &analyzer.Run{}
+	a.temperatureAnalysis(run, data, dbTypes.Hive{Id: 1}, alerts.Resolve(dbTypes.AlertRules{TemperatureNormal: &normal}))
+	if run.AlertsRaised != 0 {
+		t.Fatalf("expected no alert with winter rules, got %d", run.AlertsRaised)
+	}
+}
+
+var winterNow = time.Date(2027, 1, 20, 12, 0, 0, 0, time.UTC)
+
+func TestAssessCluster(t *testing.T) {
+	// клуб держит 18 °C, последние 4 часа — 6 °C
+	dropped := assessCluster(hourly(winterNow, 7*24, func(ago int) float64 {
+		if ago < 4 {
+			return 6
 		}
+		return 18
+	}), winterNow)
+	if !dropped.Dropped || dropped.Baseline != 18 || dropped.Current != 6 {
+		t.Errorf("expected sudden drop, got %+v", dropped)
+	}
+
+	// остывание на 1.5 °C в сутки
+	cooling := assessCluster(hourly(winterNow, 7*24, func(ago int) float64 { return 10 + 1.5*float64(ago)/24 }), winterNow)
+	if cooling.Dropped || !cooling.Cooling || cooling.Rate < 1.4 || cooling.Rate > 1.6 {
+		t.Errorf("expected steady cooling, got %+v", cooling)
+	}
+
+	// клуб гуляет у датчика от 12 до 22 °C — это норма
+	steady := assessCluster(hourly(winterNow, 7*24, func(ago int) float64 { return 17 + 5*float64(ago%24-12)/12 }), winterNow)
+	if steady.Dropped || steady.Cooling {
+		t.Errorf("expected normal cluster, got %+v", steady)
+	}
+}
+
+func TestAnalyzeTemperature_Winter(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID := 1
+	mockDB := &MockDB{
+		Hives: []dbTypes.Hive{{Id: 1, NameHive: "Hive1", Email: "a@b.c", HubID: &hubID}},
+		// 15 °C — для гнезда с расплодом критично, для зимнего клуба — норма
+		TempData: hourly(winterNow, 7*24, func(int) float64 { return 15 }),
+	}
+	states := &MockStates{States: map[string]string{}}
+	a := NewAnalyzer(ctx, mockDB, alerts.NewNotifier(mockDB, states, nil, zerolog.Nop()), season.ForLatitude(55))
+
+	run := &analyzer.Run{}
+	if err := a.analyzeTemperature(run, winterNow); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.AlertsRaised != 0 {
+		t.Fatalf("expected no alert for winter cluster, got %d", run.AlertsRaised)
+	}
+
+	// летний режим, выставленный вручную, проверяет диапазон гнезда
+	mockDB.Hives[0].SeasonMode = season.ModeSummer
+	run = &analyzer.Run{}
+	_ = a.analyzeTemperature(run, winterNow)
+	if run.AlertsRaised != 1 {
+		t.Fatalf("expected brood range alert in manual summer mode, got %d", run.AlertsRaised)
+	}
+	delete(states.States, "a@b.c:Hive1:temperature")
+
+	// клуб резко остыл — важное уведомление и событие в хронологии
+	mockDB.Hives[0].SeasonMode = season.ModeAuto
+	mockDB.TempData = hourly(winterNow, 7*24, func(ago int) float64 {
+		if ago < 4 {
+			return 4
+		}
+		return 15
+	})
+	run = &analyzer.Run{}
+	_ = a.analyzeTemperature(run, winterNow)
+	if run.AlertsRaised != 1 || len(mockDB.Events) != 1 || mockDB.Events[0].Type != EventClusterDrop {
+		t.Fatalf("expected cluster drop alert and event, got %d alerts, events %+v", run.AlertsRaised, mockDB.Events)
+	}
+}
+
+func TestAnalyzeTemperature_ApiaryLatitude(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID, lat := 1, -40.0
+	mockDB := &MockDB{
+		// пасека в южном полушарии: в январе там лето, хотя по общему календарю зима
+		Hives:    []dbTypes.Hive{{Id: 1, NameHive: "Hive1", Email: "a@b.c", HubID: &hubID, Latitude: &lat}},
+		TempData: hourly(winterNow, 7*24, func(int) float64 { return 15 }),
+	}
+	states := &MockStates{States: map[string]string{}}
+	a := NewAnalyzer(ctx, mockDB, alerts.NewNotifier(mockDB, states, nil, zerolog.Nop()), season.ForLatitude(55))
+
+	run := &analyzer.Run{}
+	if err := a.analyzeTemperature(run, winterNow); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.AlertsRaised != 1 {
+		t.Fatalf("expected brood range alert for southern apiary, got %d", run.AlertsRaised)
+	}
+}
+
+func TestAnalyzeTemperature_Period(t *testing.T) {
+	if Period > dropWindow {
+		t.Fatalf("analyzer period %v is longer than drop window %v", Period, dropWindow)
+	}
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	hubID := 1
+	mockDB := &MockDB{
+		// летний режим проверен 6 часов назад — до следующих суток не трогаем
+		Hives: []dbTypes.Hive{{Id: 1, NameHive: "Hive1", Email: "a@b.c", HubID: &hubID,
+			SeasonMode: season.ModeSummer, DateTemperature: winterNow.Add(-Period)}},
+		TempData: hourly(winterNow, 6, func(int) float64 { return 15 }),
+	}
+	states := &MockStates{States: map[string]string{}}
+	a := NewAnalyzer(ctx, mockDB, alerts.NewNotifier(mockDB, states, nil, zerolog.Nop()), season.ForLatitude(55))
+
+	run := &analyzer.Run{}
+	_ = a.analyzeTemperature(run, winterNow)
+	if run.HivesProcessed != 0 {
+		t.Fatalf("expected summer hive to wait for a day, got %d processed", run.HivesProcessed)
+	}
+
+	// зимний клуб проверяется на каждом запуске
+	mockDB.Hives[0].SeasonMode = season.ModeAuto
+	run = &analyzer.Run{}
+	_ = a.analyzeTemperature(run, winterNow)
+	if run.HivesProcessed != 1 {
+		t.Fatalf("expected winter hive to be analyzed every run, got %d processed", run.HivesProcessed)
 	}
 }
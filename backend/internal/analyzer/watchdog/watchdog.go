@@ -0,0 +1,148 @@
+package watchdog
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// offlineFactor — сколько пропущенных интервалов подряд считаем отключением датчика.
+// Один пропуск — обычное дело для NB-IoT (не зарегистрировался в сети, ушёл в буфер),
+// три подряд — уже повод сообщить пасечнику.
+const offlineFactor = 3
+
+// defaultInterval — интервал выхода на связь, если сервер ещё не отправлял датчику
+// конфиг. Совпадает с DEFAULT_SAMPLING_NOISE в прошивке.
+const defaultInterval = 5 * time.Second
+
+// minOfflineThreshold — нижняя граница порога. При интервалах в несколько секунд
+// offlineFactor*interval меньше периода самого watchdog-а и разового
+// переподключения к брокеру, и датчик «мигал» бы офлайн/онлайн.
+const minOfflineThreshold = 10 * time.Minute
+
+type Analyzer struct {
+	db       interfaces.DB
+	inMemDb  interfaces.InMemoryDB
+	ctx      context.Context
+	notifier *alerts.Notifier
+	logger   zerolog.Logger
+}
+
+func NewAnalyzer(ctx context.Context, db interfaces.DB, inMemDb interfaces.InMemoryDB,
+	notifier *alerts.Notifier) *Analyzer {
+	logger := ctx.Value("logger").(zerolog.Logger)
+	return &Analyzer{db: db, inMemDb: inMemDb, ctx: ctx, notifier: notifier, logger: logger}
+}
+
+func (a *Analyzer) Name() string {
+	return "watchdog"
+}
+
+func (a *Analyzer) Run(run *analyzer.Run) error {
+	return a.checkSensors(run, time.Now())
+}
+
+// offlineThreshold возвращает, сколько датчик может молчать, прежде чем считаться офлайн.
+func offlineThreshold(intervalSeconds int64) time.Duration {
+	interval := defaultInterval
+	if intervalSeconds > 0 {
+		interval = time.Duration(intervalSeconds) * time.Second
+	}
+	threshold := offlineFactor * interval
+	if threshold < minOfflineThreshold {
+		return minOfflineThreshold
+	}
+	return threshold
+}
+
+// checkSensors проходит по всем известным датчикам и помечает офлайн тех,
+// кто молчит дольше порога. Алерт поднимается один раз на отключение — при переходе
+// датчика в офлайн: пока он молчит, напоминать пасечнику нечем, новых данных нет.
+// Отметка и инцидент снимаются при первом же пакете от датчика (см. mqtt.markOnline).
+func (a *Analyzer) checkSensors(run *analyzer.Run, now time.Time) error {
+	sensors, err := a.inMemDb.GetAllSensors(a.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get sensors: %w", err)
+	}
+	intervals, err := a.inMemDb.GetSensorIntervals(a.ctx)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("watchdog: failed to get sensor intervals, using defaults")
+		intervals = map[string]int64{}
+	}
+
+	offline := 0
+	for sensorId, lastSeen := range sensors {
+		// 0 — датчик зарегистрирован, но ещё ни разу не присылал данные
+		if lastSeen == 0 {
+			continue
+		}
+		run.HiveProcessed()
+		silence := now.Sub(time.Unix(lastSeen, 0))
+		if silence < offlineThreshold(intervals[sensorId]) {
+			continue
+		}
+		offline++
+
+		isNew, err := a.inMemDb.MarkSensorOffline(a.ctx, sensorId)
+		if err != nil {
+			a.logger.Warn().Err(err).Str("sensor", sensorId).Msg("watchdog: failed to mark sensor offline")
+			run.Error(err)
+			continue
+		}
+		if !isNew {
+			continue
+		}
+		a.logger.Warn().Str("sensor", sensorId).Dur("silence", silence).Msg("watchdog: sensor went offline")
+		run.AlertRaised()
+		if err := a.notifyOffline(sensorId, silence); err != nil {
+			a.logger.Warn().Err(err).Str("sensor", sensorId).Msg("watchdog: failed to send offline notification")
+			run.Error(err)
+		}
+	}
+	a.logger.Debug().Int("sensors", len(sensors)).Int("offline", offline).Msg("watchdog: run finished")
+	return nil
+}
+
+// resolveOwner находит владельца и улей датчика: сначала через привязку датчика к улью,
+// затем через хаб. hive пустой, если датчик ни к какому улью не привязан.
+func (a *Analyzer) resolveOwner(sensorId string) (email, hive string, err error) {
+	if e, h, lookupErr := a.db.GetEmailHiveBySensorID(a.ctx, sensorId); lookupErr == nil {
+		return e, h, nil
+	}
+	if e, h, lookupErr := a.db.GetEmailHiveByHubSensor(a.ctx, sensorId); lookupErr == nil {
+		return e, h, nil
+	}
+	e, err := a.db.GetEmailByHubSensor(a.ctx, sensorId)
+	return e, "", err
+}
+
+func (a *Analyzer) notifyOffline(sensorId string, silence time.Duration) error {
+	email, hive, err := a.resolveOwner(sensorId)
+	if err != nil {
+		return fmt.Errorf("failed to resolve sensor owner: %w", err)
+	}
+	if hive == "" {
+		a.logger.Debug().Str("sensor", sensorId).Msg("watchdog: sensor is not linked to any hive, skipping notification")
+		return nil
+	}
+	_, err = a.notifier.Raise(a.ctx, alerts.Alert{Type: alerts.TypeSensorOffline, Email: email, Hive: hive,
+		Data: notifyTypes.Message{
+			Title: fmt.Sprintf("Датчик в улье %s не на связи", hive),
+			Body: fmt.Sprintf("Датчик не присылает данные уже %s. Проверьте батарею и связь датчика.",
+				silence.Round(time.Minute)),
+			Data:      map[string]string{"hive": hive, "sensor": sensorId},
+			Important: true,
+		}})
+	if errors.Is(err, alerts.ErrNotificationDisabled) {
+		a.logger.Warn().Str("sensor", sensorId).Msg("notification service is nil, skipping")
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package watchdog
+
+import (
+	"BeeIOT/internal/analyzer"
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockInMemoryDB struct {
+	interfaces.InMemoryDB
+	Sensors   map[string]int64
+	Intervals map[string]int64
+	Offline   map[string]bool
+	Marked    []string
+	States    map[string]string
+}
+
+func (m *MockInMemoryDB) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.States[key], nil
+}
+
+func (m *MockInMemoryDB) SetAlertState(_ context.Context, key, state string, _ time.Duration) error {
+	if m.States == nil {
+		m.States = map[string]string{}
+	}
+	m.States[key] = state
+	return nil
+}
+
+func (m *MockInMemoryDB) GetAllSensors(_ context.Context) (map[string]int64, error) {
+	return m.Sensors, nil
+}
+
+func (m *MockInMemoryDB) GetSensorIntervals(_ context.Context) (map[string]int64, error) {
+	return m.Intervals, nil
+}
+
+func (m *MockInMemoryDB) MarkSensorOffline(_ context.Context, sensorID string) (bool, error) {
+	if m.Offline[sensorID] {
+		return false, nil
+	}
+	m.Offline[sensorID] = true
+	m.Marked = append(m.Marked, sensorID)
+	return true, nil
+}
+
+type MockDB struct {
+	interfaces.DB
+	Resolved []string
+}
+
+func (m *MockDB) GetEmailHiveBySensorID(_ context.Context, sensorID string) (string, string, error) {
+	m.Resolved = append(m.Resolved, sensorID)
+	return "test@test.com", "Hive1", nil
+}
+
+func (m *MockDB) NewNotification(_ context.Context, _ string, _ dbTypes.Notification) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return nil, nil
+}
+
+func (m *MockDB) GetEmailHiveByHubSensor(_ context.Context, _ string) (string, string, error) {
+	return "", "", errors.New("not found")
+}
+
+func (m *MockDB) GetEmailByHubSensor(_ context.Context, _ string) (string, error) {
+	return "", errors.New("not found")
+}
+
+func newTestAnalyzer(db *MockDB, inMem *MockInMemoryDB) *Analyzer {
+	ctx := context.WithValue(context.Background(), "logger", zerolog.Nop())
+	return NewAnalyzer(ctx, db, inMem, alerts.NewNotifier(db, inMem, nil, zerolog.Nop()))
+}
+
+func TestOfflineThreshold(t *testing.T) {
+	if got := offlineThreshold(0); got != minOfflineThreshold {
+		t.Errorf("expected floor %v for default interval, got %v", minOfflineThreshold, got)
+	}
+	if got := offlineThreshold(60); got != minOfflineThreshold {
+		t.Errorf("expected floor %v for 60s interval, got %v", minOfflineThreshold, got)
+	}
+	if got := offlineThreshold(3600); got != 3*time.Hour {
+		t.Errorf("expected 3h for hourly interval, got %v", got)
+	}
+}
+
+func TestCheckSensors(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	inMem := &MockInMemoryDB{
+		Sensors: map[string]int64{
+			"fresh":     now.Add(-time.Minute).Unix(),
+			"silent":    now.Add(-time.Hour).Unix(),
+			"hourly":    now.Add(-time.Hour).Unix(), // молчит час при часовом интервале — это норма
+			"never":     0,
+			"already":   now.Add(-time.Hour).Unix(),
+			"very_slow": now.Add(-4 * time.Hour).Unix(),
+		},
+		Intervals: map[string]int64{"hourly": 3600, "very_slow": 3600},
+		Offline:   map[string]bool{"already": true},
+	}
+	db := &MockDB{}
+	a := newTestAnalyzer(db, inMem)
+
+	run := &analyzer.Run{}
+	if err := a.checkSensors(run, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	marked := map[string]bool{}
+	for _, s := range inMem.Marked {
+		marked[s] = true
+	}
+	if len(marked) != 2 || !marked["silent"] || !marked["very_slow"] {
+		t.Fatalf("expected silent and very_slow to go offline, got %v", inMem.Marked)
+	}
+	// алерт поднимается только для новых отключений, уже офлайн датчик не трогаем
+	if len(db.Resolved) != 2 {
+		t.Fatalf("expected owner lookup for newly offline sensors only, got %v", db.Resolved)
+	}
+	if run.AlertsRaised != 2 || run.HivesProcessed != 5 {
+		t.Fatalf("expected 5 sensors checked and 2 new outages, got %+v", run)
+	}
+
+	// повторные прогоны — отключение то же: ни повторных уведомлений, ни эскалации,
+	// даже когда отключение тянется дольше срока эскалации
+	inMem.Marked, db.Resolved = nil, nil
+	for _, later := range []time.Duration{time.Minute, 13 * time.Hour, 25 * time.Hour} {
+		// остальные датчики всё это время на связи
+		inMem.Sensors["fresh"] = now.Add(later).Unix()
+		inMem.Sensors["hourly"] = now.Add(later).Unix()
+		_ = a.checkSensors(&analyzer.Run{}, now.Add(later))
+	}
+	if len(inMem.Marked) != 0 || len(db.Resolved) != 0 {
+		t.Fatalf("expected no repeated offline marks or alerts, got %v, %v", inMem.Marked, db.Resolved)
+	}
+	var state alerts.State
+	if err := json.Unmarshal([]byte(inMem.States["test@test.com:Hive1:sensor_offline"]), &state); err != nil {
+		t.Fatalf("expected offline alert state, got %v", inMem.States)
+	}
+	if state.Status != alerts.StatusOpen || state.Notifications != 1 {
+		t.Fatalf("expected one open incident for the hive, got %+v", state)
+	}
+}
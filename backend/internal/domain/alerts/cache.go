@@ -0,0 +1,50 @@
+package alerts
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheTTL — сколько держим пороги улья в памяти. MQTT-обработчики проверяют пороги
+// на каждом пакете, а правила меняются редко: изменение в API вступает в силу
+// не позже чем через минуту.
+const cacheTTL = time.Minute
+
+type cachedRules struct {
+	rules   Rules
+	expires time.Time
+}
+
+// Cache — кеш действующих порогов по (email, улей) для горячего пути MQTT.
+type Cache struct {
+	db      interfaces.DB
+	mu      sync.Mutex
+	entries map[string]cachedRules
+}
+
+func NewCache(db interfaces.DB) *Cache {
+	return &Cache{db: db, entries: map[string]cachedRules{}}
+}
+
+// Get возвращает действующие пороги улья. Ошибка чтения не кешируется:
+// вернётся Default и ошибка, следующий вызов повторит запрос.
+func (c *Cache) Get(ctx context.Context, email, hive string) (Rules, error) {
+	key := email + "\x00" + hive
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.rules, nil
+	}
+	rules, err := ForHive(ctx, c.db, email, hive)
+	if err != nil {
+		return rules, err
+	}
+	c.mu.Lock()
+	c.entries[key] = cachedRules{rules: rules, expires: now.Add(cacheTTL)}
+	c.mu.Unlock()
+	return rules, nil
+}
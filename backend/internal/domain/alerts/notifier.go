@@ -0,0 +1,299 @@
+package alerts
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrNotificationDisabled — сервис уведомлений не настроен (например, в нагрузочных тестах).
+var ErrNotificationDisabled = errors.New("notification service is disabled")
+
+// Уровни важности уведомления, по возрастанию.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// severity — важность уведомления для входящих: важные алерты — critical,
+// сообщения об устранении — info, остальное — warning.
+func severity(data notifyTypes.Message, status string) string {
+	switch {
+	case status == StatusResolved:
+		return SeverityInfo
+	case data.Important:
+		return SeverityCritical
+	default:
+		return SeverityWarning
+	}
+}
+
+// Alert — срабатывание одного правила для улья.
+type Alert struct {
+	Type  string
+	Email string
+	Hive  string
+	// Data — текст уведомления. Тип, улей и важность заполняет Notifier.
+	Data notifyTypes.Message
+}
+
+func (a Alert) key() string {
+	return a.Email + ":" + a.Hive + ":" + a.Type
+}
+
+// Notifier — единая точка отправки алертов. Ведёт состояние инцидента по паре
+// (улей, тип) и решает, слать ли уведомление: первое срабатывание, напоминание
+// после Cooldown, эскалация или молчание (см. Fire). Состояние хранится в Redis,
+// поэтому переживает рестарт сервера.
+type Notifier struct {
+	db       interfaces.DB
+	states   interfaces.InMemoryDB
+	dispatch interfaces.Dispatcher
+	logger   zerolog.Logger
+	// mu сериализует чтение-изменение-запись состояния внутри процесса:
+	// MQTT-обработчики одного датчика работают параллельно.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewNotifier создаёт Notifier. dispatch == nil — уведомления выключены, но состояние
+// инцидентов и входящие всё равно ведутся.
+func NewNotifier(db interfaces.DB, states interfaces.InMemoryDB, dispatch interfaces.Dispatcher,
+	logger zerolog.Logger) *Notifier {
+	return &Notifier{db: db, states: states, dispatch: dispatch, logger: logger, now: time.Now}
+}
+
+// Raise регистрирует срабатывание и, если политика типа это допускает, отправляет уведомление.
+// Возвращает принятое решение; ErrNotificationDisabled — решение принято, но слать некуда.
+func (n *Notifier) Raise(ctx context.Context, a Alert) (Action, error) {
+	if n == nil {
+		return ActionSuppress, ErrNotificationDisabled
+	}
+	policy := PolicyFor(a.Type)
+
+	n.mu.Lock()
+	prev := n.load(ctx, a)
+	state, action := Fire(prev, policy, n.now())
+	if err := n.save(ctx, a, state, policy.StaleAfter); err != nil {
+		n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Msg("Failed to save alert state")
+	}
+	n.mu.Unlock()
+
+	if !action.Notify() {
+		n.logger.Debug().Str("alert", a.Type).Str("hive", a.Hive).Msg("Alert suppressed by cooldown")
+		return action, nil
+	}
+	data := a.Data
+	switch action {
+	case ActionRenotify:
+		data.Title = "Повторно: " + data.Title
+	case ActionEscalate:
+		data.Title = "Не устранено: " + data.Title
+		data.Important = true
+	}
+	return action, n.send(ctx, a, data, state.Status)
+}
+
+// Clear закрывает инцидент, если он открыт. Для типов с NotifyResolved отправляет
+// a.Data как уведомление об устранении. Возвращает true, если инцидент был открыт.
+func (n *Notifier) Clear(ctx context.Context, a Alert) (bool, error) {
+	if n == nil {
+		return false, nil
+	}
+	policy := PolicyFor(a.Type)
+
+	n.mu.Lock()
+	state, closed := Close(n.load(ctx, a), n.now())
+	if closed {
+		// Закрытый инцидент помним Cooldown, чтобы дребезг около порога не открывал новый.
+		if err := n.save(ctx, a, state, policy.Cooldown); err != nil {
+			n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Msg("Failed to save alert state")
+		}
+	}
+	n.mu.Unlock()
+
+	if !closed || !policy.NotifyResolved || a.Data.Title == "" {
+		return closed, nil
+	}
+	return true, n.send(ctx, a, a.Data, StatusResolved)
+}
+
+// Incident — незакрытый инцидент улья Hive владельца Owner.
+type Incident struct {
+	Owner string
+	Hive  string
+	Type  string
+	State State
+}
+
+// OpenIncidents возвращает незакрытые инциденты пользователя, от давних к свежим.
+func (n *Notifier) OpenIncidents(ctx context.Context, email string) ([]Incident, error) {
+	if n == nil || n.states == nil {
+		return nil, nil
+	}
+	raw, err := n.states.GetAlertStates(ctx, email+":")
+	if err != nil {
+		return nil, err
+	}
+	var result []Incident
+	for key, value := range raw {
+		// ключ — email:улей:тип, в имени улья тоже может быть двоеточие
+		rest := strings.TrimPrefix(key, email+":")
+		i := strings.LastIndex(rest, ":")
+		if i < 0 {
+			continue
+		}
+		var state State
+		if err := json.Unmarshal([]byte(value), &state); err != nil || state.Status == StatusResolved {
+			continue
+		}
+		result = append(result, Incident{Owner: email, Hive: rest[:i], Type: rest[i+1:], State: state})
+	}
+	sortIncidents(result)
+	return result, nil
+}
+
+// HiveIncidents возвращает незакрытые инциденты ульев hives, от давних к свежим.
+// Инциденты хранятся у владельца улья, поэтому для чужих ульев, где пользователь
+// участник, они читаются по почте их владельцев. При ошибке возвращает то, что
+// успел прочитать, вместе с ней.
+func (n *Notifier) HiveIncidents(ctx context.Context, hives []dbTypes.Hive) ([]Incident, error) {
+	owned := make(map[string]map[string]bool)
+	for _, hive := range hives {
+		if owned[hive.Email] == nil {
+			owned[hive.Email] = map[string]bool{}
+		}
+		owned[hive.Email][hive.NameHive] = true
+	}
+	var result []Incident
+	var errs []error
+	for owner, names := range owned {
+		incidents, err := n.OpenIncidents(ctx, owner)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, incident := range incidents {
+			if names[incident.Hive] {
+				result = append(result, incident)
+			}
+		}
+	}
+	sortIncidents(result)
+	return result, errors.Join(errs...)
+}
+
+func sortIncidents(incidents []Incident) {
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].State.OpenedAt.Before(incidents[j].State.OpenedAt)
+	})
+}
+
+func (n *Notifier) load(ctx context.Context, a Alert) *State {
+	if n.states == nil {
+		return nil
+	}
+	raw, err := n.states.GetAlertState(ctx, a.key())
+	if err != nil {
+		n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Msg("Failed to load alert state")
+		return nil
+	}
+	if raw == "" {
+		return nil
+	}
+	var state State
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Msg("Corrupted alert state, starting over")
+		return nil
+	}
+	return &state
+}
+
+func (n *Notifier) save(ctx context.Context, a Alert, state State, ttl time.Duration) error {
+	if n.states == nil {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return n.states.SetAlertState(ctx, a.key(), string(raw), ttl)
+}
+
+// send рассылает алерт владельцу улья и его участникам (см. recipients): каждому
+// сохраняет уведомление во входящие и отправляет по выбранным им каналам
+// (см. notification.Dispatcher). Состояние инцидента общее — по владельцу.
+func (n *Notifier) send(ctx context.Context, a Alert, msg notifyTypes.Message, status string) error {
+	msg.Type = a.Type
+	msg.Hive = a.Hive
+	msg.Severity = severity(msg, status)
+	var errs []error
+	for _, to := range n.recipients(ctx, a, msg.Severity) {
+		errs = append(errs, n.sendTo(ctx, to, a, msg, status))
+	}
+	return errors.Join(errs...)
+}
+
+// recipients — получатели алерта важности sev. Наблюдатели получают только
+// критические алерты (см. membership.ReceivesAlert). Если участников получить не
+// удалось, алерт уходит одному владельцу.
+func (n *Notifier) recipients(ctx context.Context, a Alert, sev string) []dbTypes.Member {
+	members, err := n.db.GetAlertRecipients(ctx, a.Email, a.Hive)
+	if err != nil {
+		n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Msg("Failed to get alert recipients")
+	}
+	if len(members) == 0 {
+		return []dbTypes.Member{{Email: a.Email, Role: membership.RoleOwner, Hive: a.Hive}}
+	}
+	result := make([]dbTypes.Member, 0, len(members))
+	for _, m := range members {
+		if membership.ReceivesAlert(m.Role, sev) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// sendTo сохраняет алерт во входящие получателя to (с id улья, найденным по владельцу)
+// и отправляет его по каналам получателя.
+func (n *Notifier) sendTo(ctx context.Context, to dbTypes.Member, a Alert, msg notifyTypes.Message, status string) error {
+	email := to.Email
+	id, err := n.db.NewNotification(ctx, email, dbTypes.Notification{
+		Hive:     a.Hive,
+		HiveID:   to.HiveID,
+		Type:     a.Type,
+		Severity: msg.Severity,
+		Title:    msg.Title,
+		Body:     msg.Body,
+	})
+	if err != nil {
+		// доставка важнее истории: не сохранили — всё равно отправляем
+		n.logger.Warn().Err(err).Str("alert", a.Type).Str("hive", a.Hive).Str("email", email).Msg("Failed to save notification")
+	}
+	if n.dispatch == nil {
+		return ErrNotificationDisabled
+	}
+	extra := make(map[string]string, len(msg.Data)+3)
+	maps.Copy(extra, msg.Data)
+	extra["alert_type"] = a.Type
+	extra["alert_state"] = status
+	if id != 0 {
+		// приложение по нему отмечает уведомление прочитанным при открытии пуша
+		extra["notification_id"] = strconv.FormatInt(id, 10)
+	}
+	msg.Data = extra
+	return n.dispatch.Dispatch(ctx, email, msg)
+}
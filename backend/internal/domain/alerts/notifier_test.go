@@ -0,0 +1,187 @@
+package alerts
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type mockDB struct {
+	interfaces.DB
+	inbox      []dbTypes.Notification
+	inboxOf    []string
+	recipients []dbTypes.Member
+}
+
+func (m *mockDB) NewNotification(_ context.Context, email string, n dbTypes.Notification) (int64, error) {
+	m.inbox = append(m.inbox, n)
+	m.inboxOf = append(m.inboxOf, email)
+	return int64(len(m.inbox)), nil
+}
+
+func (m *mockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return m.recipients, nil
+}
+
+type mockDispatcher struct {
+	sent map[string][]notifyTypes.Message
+}
+
+func (m *mockDispatcher) Dispatch(_ context.Context, email string, msg notifyTypes.Message) error {
+	m.sent[email] = append(m.sent[email], msg)
+	return nil
+}
+
+type mockStates struct {
+	interfaces.InMemoryDB
+	states map[string]string
+	ttls   map[string]time.Duration
+}
+
+func (m *mockStates) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.states[key], nil
+}
+
+func (m *mockStates) SetAlertState(_ context.Context, key, state string, ttl time.Duration) error {
+	m.states[key] = state
+	m.ttls[key] = ttl
+	return nil
+}
+
+func TestNotifier_RaiseAndClear(t *testing.T) {
+	states := &mockStates{states: map[string]string{}, ttls: map[string]time.Duration{}}
+	db := &mockDB{}
+	n := NewNotifier(db, states, nil, zerolog.Nop())
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	ctx := context.Background()
+	alert := Alert{Type: TypeDeviceErrors, Email: "a@b.c", Hive: "Hive1"}
+	key := "a@b.c:Hive1:device_errors"
+
+	// push выключен: решение принято и сохранено, но отправлять некуда
+	action, err := n.Raise(ctx, alert)
+	if action != ActionOpen || !errors.Is(err, ErrNotificationDisabled) {
+		t.Fatalf("expected open with disabled push, got %v, %v", action, err)
+	}
+	if states.ttls[key] != PolicyFor(TypeDeviceErrors).StaleAfter {
+		t.Fatalf("expected open state to live StaleAfter, got %v", states.ttls[key])
+	}
+
+	now = now.Add(time.Minute)
+	if action, err = n.Raise(ctx, alert); action != ActionSuppress || err != nil {
+		t.Fatalf("expected suppress within cooldown, got %v, %v", action, err)
+	}
+
+	closed, err := n.Clear(ctx, alert)
+	if !closed || err != nil {
+		t.Fatalf("expected incident to be closed, got %v, %v", closed, err)
+	}
+	if states.ttls[key] != PolicyFor(TypeDeviceErrors).Cooldown {
+		t.Fatalf("expected resolved state to live Cooldown, got %v", states.ttls[key])
+	}
+	if closed, _ = n.Clear(ctx, alert); closed {
+		t.Fatal("expected second clear to be a no-op")
+	}
+	// во входящих — открытие инцидента; сообщения об устранении нет, т.к. alert.Data пуст
+	if len(db.inbox) != 1 || db.inbox[0].Severity != SeverityWarning || db.inbox[0].Type != TypeDeviceErrors {
+		t.Fatalf("expected one warning in inbox, got %+v", db.inbox)
+	}
+}
+
+func (m *mockStates) GetAlertStates(_ context.Context, prefix string) (map[string]string, error) {
+	result := map[string]string{}
+	for k, v := range m.states {
+		if strings.HasPrefix(k, prefix) {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+func TestNotifier_OpenIncidents(t *testing.T) {
+	states := &mockStates{states: map[string]string{}, ttls: map[string]time.Duration{}}
+	n := NewNotifier(&mockDB{}, states, nil, zerolog.Nop())
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = n.Raise(ctx, Alert{Type: TypeBatteryLow, Email: "a@b.c", Hive: "Улей: 1"})
+	now = now.Add(time.Hour)
+	_, _ = n.Raise(ctx, Alert{Type: TypeSignalLow, Email: "a@b.c", Hive: "Hive2"})
+	_, _ = n.Raise(ctx, Alert{Type: TypeDeviceErrors, Email: "a@b.c", Hive: "Hive2"})
+	_, _ = n.Clear(ctx, Alert{Type: TypeDeviceErrors, Email: "a@b.c", Hive: "Hive2"})
+	_, _ = n.Raise(ctx, Alert{Type: TypeBatteryLow, Email: "x@b.c", Hive: "Hive1"})
+
+	list, err := n.OpenIncidents(ctx, "a@b.c")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 open incidents, got %+v, %v", list, err)
+	}
+	if list[0].Owner != "a@b.c" || list[0].Hive != "Улей: 1" || list[0].Type != TypeBatteryLow || list[1].Type != TypeSignalLow {
+		t.Errorf("unexpected incidents %+v", list)
+	}
+}
+
+func TestNotifier_Nil(t *testing.T) {
+	var n *Notifier
+	if _, err := n.Raise(context.Background(), Alert{Type: TypeBatteryLow}); !errors.Is(err, ErrNotificationDisabled) {
+		t.Fatalf("expected ErrNotificationDisabled, got %v", err)
+	}
+	if closed, err := n.Clear(context.Background(), Alert{Type: TypeBatteryLow}); closed || err != nil {
+		t.Fatalf("expected no-op clear, got %v, %v", closed, err)
+	}
+}
+
+func TestNotifier_FanOutToMembers(t *testing.T) {
+	states := &mockStates{states: map[string]string{}, ttls: map[string]time.Duration{}}
+	db := &mockDB{recipients: []dbTypes.Member{
+		{Email: "owner@b.c", Role: "owner", HiveID: 7},
+		{Email: "editor@b.c", Role: "editor", HiveID: 7},
+		{Email: "viewer@b.c", Role: "viewer", HiveID: 7},
+	}}
+	dispatch := &mockDispatcher{sent: map[string][]notifyTypes.Message{}}
+	n := NewNotifier(db, states, dispatch, zerolog.Nop())
+	ctx := context.Background()
+
+	// обычный алерт: наблюдатель его не получает
+	alert := Alert{Type: TypeDeviceErrors, Email: "owner@b.c", Hive: "Hive1"}
+	if _, err := n.Raise(ctx, alert); err != nil {
+		t.Fatalf("raise: %v", err)
+	}
+	if len(dispatch.sent["owner@b.c"]) != 1 || len(dispatch.sent["editor@b.c"]) != 1 {
+		t.Fatalf("expected owner and editor to be notified, got %v", dispatch.sent)
+	}
+	if len(dispatch.sent["viewer@b.c"]) != 0 {
+		t.Fatalf("viewer must not receive warning alerts, got %v", dispatch.sent["viewer@b.c"])
+	}
+	if len(db.inbox) != 2 {
+		t.Fatalf("expected 2 inbox entries, got %d", len(db.inbox))
+	}
+	// входящие участника привязаны к улью владельца, а не ищут улей по своему имени
+	if db.inboxOf[1] != "editor@b.c" || db.inbox[1].HiveID != 7 {
+		t.Fatalf("expected member inbox entry linked to owner's hive, got %s %+v", db.inboxOf[1], db.inbox[1])
+	}
+	if _, ok := states.states["owner@b.c:Hive1:device_errors"]; !ok {
+		t.Fatalf("incident state must be kept under the owner, got %v", states.states)
+	}
+
+	// важный алерт получают все
+	important := Alert{Type: TypeBatteryLow, Email: "owner@b.c", Hive: "Hive1",
+		Data: notifyTypes.Message{Title: "t", Important: true}}
+	if _, err := n.Raise(ctx, important); err != nil {
+		t.Fatalf("raise important: %v", err)
+	}
+	if len(dispatch.sent["viewer@b.c"]) != 1 {
+		t.Fatalf("viewer must receive critical alerts, got %v", dispatch.sent["viewer@b.c"])
+	}
+	if membership.ViewerAlertSeverity != SeverityCritical {
+		t.Errorf("membership.ViewerAlertSeverity = %q, want %q", membership.ViewerAlertSeverity, SeverityCritical)
+	}
+}
@@ -0,0 +1,153 @@
+// Package alerts — пороги срабатывания алертов. Пороги хранятся в таблице alert_rules:
+// строка без улья — значения пользователя по умолчанию, строка с ульем — переопределения
+// для конкретного улья. Незаданное поле наследуется от уровня выше, а в конце — от Default.
+package alerts
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+)
+
+// Rules — действующие пороги для улья.
+type Rules struct {
+	// NoiseHigh — мгновенный уровень шума (дБ SPL), выше которого шлём пуш.
+	NoiseHigh float64
+	// NoiseDelta — допустимое изменение среднесуточного шума (дБ SPL) между соседними днями.
+	NoiseDelta float64
+	// TemperatureNormal и отклонения от неё — нормальный диапазон температуры в улье (°C).
+	TemperatureNormal    float64
+	TemperatureDeltaUp   float64
+	TemperatureDeltaDown float64
+	// BatteryLow и SignalLow — заряд и уровень сигнала (%), ниже которых датчик требует внимания.
+	BatteryLow int
+	SignalLow  int
+}
+
+// Default — пороги по умолчанию, подобранные для сильной семьи летом.
+//
+// NoiseHigh: фоновый шум здоровой семьи сам по себе 60–75 дБ, поэтому пороги
+// в районе 50–60 дБ давали бы постоянный спам. Реальная аномалия (роение,
+// проникновение в улей, массовое возбуждение) — это уже 80+ дБ.
+//
+// NoiseDelta: прошивка (firmware/beeiot_s3/noise.py) пишет шум в дБ SPL после
+// калибровки INMP441 (RMS → dBFS → dB SPL с offset из NOISE_DB_OFFSET).
+// Изменение среднего на 8 дБ соответствует субъективному удвоению громкости
+// и хорошо коррелирует с событиями, которые мы хотим ловить: начало роения,
+// появление нескольких маток, резкое ослабление семьи. Чувствительнее — 5,
+// консервативнее — 10.
+//
+// TemperatureNormal: семья держит расплод при 34–35 °C; ±5 °C — граница,
+// за которой расплод страдает.
+func Default() Rules {
+	return Rules{
+		NoiseHigh:            80,
+		NoiseDelta:           8,
+		TemperatureNormal:    34,
+		TemperatureDeltaUp:   5,
+		TemperatureDeltaDown: 5,
+		BatteryLow:           20,
+		SignalLow:            10,
+	}
+}
+
+// TemperatureOK сообщает, лежит ли температура в нормальном диапазоне.
+func (r Rules) TemperatureOK(temp float64) bool {
+	return temp >= r.TemperatureNormal-r.TemperatureDeltaDown && temp <= r.TemperatureNormal+r.TemperatureDeltaUp
+}
+
+// Resolve накладывает сохранённые переопределения на Default. В stored каждое
+// поле уже выбрано из правил улья или, если там не задано, из правил пользователя.
+func Resolve(stored dbTypes.AlertRules) Rules {
+	r := Default()
+	if stored.NoiseHigh != nil {
+		r.NoiseHigh = *stored.NoiseHigh
+	}
+	if stored.NoiseDelta != nil {
+		r.NoiseDelta = *stored.NoiseDelta
+	}
+	if stored.TemperatureNormal != nil {
+		r.TemperatureNormal = *stored.TemperatureNormal
+	}
+	if stored.TemperatureDeltaUp != nil {
+		r.TemperatureDeltaUp = *stored.TemperatureDeltaUp
+	}
+	if stored.TemperatureDeltaDown != nil {
+		r.TemperatureDeltaDown = *stored.TemperatureDeltaDown
+	}
+	if stored.BatteryLow != nil {
+		r.BatteryLow = *stored.BatteryLow
+	}
+	if stored.SignalLow != nil {
+		r.SignalLow = *stored.SignalLow
+	}
+	return r
+}
+
+// ForHive возвращает действующие пороги улья hive владельца email. Если правила прочитать
+// не удалось, возвращает Default вместе с ошибкой — алерты не должны замолкать из-за сбоя БД.
+func ForHive(ctx context.Context, db interfaces.DB, email, hive string) (Rules, error) {
+	stored, err := db.GetEffectiveAlertRules(ctx, email, hive, email)
+	if err != nil {
+		return Default(), err
+	}
+	return Resolve(stored), nil
+}
+
+// ToHTTP — действующие пороги в виде ответа API.
+func (r Rules) ToHTTP() httpType.AlertThresholds {
+	return httpType.AlertThresholds{
+		NoiseHigh:            &r.NoiseHigh,
+		NoiseDelta:           &r.NoiseDelta,
+		TemperatureNormal:    &r.TemperatureNormal,
+		TemperatureDeltaUp:   &r.TemperatureDeltaUp,
+		TemperatureDeltaDown: &r.TemperatureDeltaDown,
+		BatteryLow:           &r.BatteryLow,
+		SignalLow:            &r.SignalLow,
+	}
+}
+
+// Validate проверяет, что заданные пороги физически осмысленны.
+func Validate(t httpType.AlertThresholds) error {
+	checkFloat := func(name string, v *float64, min, max float64) error {
+		if v != nil && (*v < min || *v > max) {
+			return fmt.Errorf("%s: допустимо от %g до %g", name, min, max)
+		}
+		return nil
+	}
+	checkInt := func(name string, v *int, min, max int) error {
+		if v != nil && (*v < min || *v > max) {
+			return fmt.Errorf("%s: допустимо от %d до %d", name, min, max)
+		}
+		return nil
+	}
+	for _, err := range []error{
+		checkFloat("noise_high", t.NoiseHigh, 30, 130),
+		checkFloat("noise_delta", t.NoiseDelta, 0.5, 50),
+		checkFloat("temperature_normal", t.TemperatureNormal, 0, 45),
+		checkFloat("temperature_delta_up", t.TemperatureDeltaUp, 0.5, 30),
+		checkFloat("temperature_delta_down", t.TemperatureDeltaDown, 0.5, 40),
+		checkInt("battery_low", t.BatteryLow, 0, 100),
+		checkInt("signal_low", t.SignalLow, 0, 100),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromDB переводит сохранённые переопределения в формат API.
+func FromDB(stored dbTypes.AlertRules) httpType.AlertThresholds {
+	return httpType.AlertThresholds{
+		NoiseHigh:            stored.NoiseHigh,
+		NoiseDelta:           stored.NoiseDelta,
+		TemperatureNormal:    stored.TemperatureNormal,
+		TemperatureDeltaUp:   stored.TemperatureDeltaUp,
+		TemperatureDeltaDown: stored.TemperatureDeltaDown,
+		BatteryLow:           stored.BatteryLow,
+		SignalLow:            stored.SignalLow,
+	}
+}
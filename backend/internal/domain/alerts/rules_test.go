@@ -0,0 +1,78 @@
+package alerts
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"testing"
+)
+
+func TestResolve_InheritsUnsetFields(t *testing.T) {
+	noise := 70.0
+	battery := 35
+	r := Resolve(dbTypes.AlertRules{NoiseHigh: &noise, BatteryLow: &battery})
+
+	want := Default()
+	want.NoiseHigh = 70
+	want.BatteryLow = 35
+	if r != want {
+		t.Fatalf("expected %+v, got %+v", want, r)
+	}
+}
+
+func TestDefaultTemperatureRange(t *testing.T) {
+	tests := []struct {
+		temp     float64
+		expected bool
+	}{
+		{34.0, true},
+		{39.0, true},
+		{29.0, true},
+		{39.1, false},
+		{28.9, false},
+		{100.0, false},
+		{0.0, false},
+	}
+	for _, test := range tests {
+		if result := Default().TemperatureOK(test.temp); result != test.expected {
+			t.Errorf("For temp %.2f expected %v, got %v", test.temp, test.expected, result)
+		}
+	}
+}
+
+func TestTemperatureOK(t *testing.T) {
+	// зимний клуб: норма ниже, допуск вниз шире
+	normal, down := 20.0, 12.0
+	r := Resolve(dbTypes.AlertRules{TemperatureNormal: &normal, TemperatureDeltaDown: &down})
+
+	tests := []struct {
+		temp float64
+		ok   bool
+	}{
+		{20, true},
+		{8, true},
+		{7.9, false},
+		{25, true},
+		{25.1, false},
+	}
+	for _, tt := range tests {
+		if got := r.TemperatureOK(tt.temp); got != tt.ok {
+			t.Errorf("temperature %.1f: expected %v, got %v", tt.temp, tt.ok, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ok, tooLoud, negative := 85.0, 200.0, -1
+	if err := Validate(httpType.AlertThresholds{NoiseHigh: &ok}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Validate(httpType.AlertThresholds{NoiseHigh: &tooLoud}); err == nil {
+		t.Error("expected error for 200 dB")
+	}
+	if err := Validate(httpType.AlertThresholds{SignalLow: &negative}); err == nil {
+		t.Error("expected error for negative signal threshold")
+	}
+	if err := Validate(httpType.AlertThresholds{}); err != nil {
+		t.Errorf("empty thresholds must be valid, got %v", err)
+	}
+}
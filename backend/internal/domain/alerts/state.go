@@ -0,0 +1,144 @@
+package alerts
+
+import (
+	"time"
+)
+
+// Типы алертов. Состояние ведётся отдельно для каждой пары (улей, тип).
+const (
+	TypeBatteryLow    = "battery_low"
+	TypeSignalLow     = "signal_low"
+	TypeDeviceErrors  = "device_errors"
+	TypeNoiseHigh     = "noise_high"
+	TypeNoiseChange   = "noise_change"
+	TypeTemperature   = "temperature"
+	TypeSensorOffline = "sensor_offline"
+	TypeSwarm         = "swarm"
+	TypeQueenless     = "queenless"
+)
+
+// Types — все типы алертов; по ним пользователь настраивает уведомления.
+var Types = []string{
+	TypeBatteryLow, TypeSignalLow, TypeDeviceErrors, TypeNoiseHigh,
+	TypeNoiseChange, TypeTemperature, TypeSensorOffline, TypeSwarm, TypeQueenless,
+}
+
+// Статусы инцидента.
+const (
+	StatusOpen      = "open"
+	StatusEscalated = "escalated"
+	StatusResolved  = "resolved"
+)
+
+// Action — что делать с очередным срабатыванием.
+type Action int
+
+const (
+	// ActionSuppress — инцидент уже открыт, о нём недавно сообщали.
+	ActionSuppress Action = iota
+	// ActionOpen — новый инцидент, первое уведомление.
+	ActionOpen
+	// ActionRenotify — инцидент не устранён, прошёл Cooldown с прошлого уведомления.
+	ActionRenotify
+	// ActionEscalate — инцидент открыт дольше EscalateAfter, шлём важное уведомление.
+	ActionEscalate
+)
+
+// Notify сообщает, нужно ли отправлять уведомление.
+func (a Action) Notify() bool {
+	return a != ActionSuppress
+}
+
+// Policy — как часто напоминать об инциденте и когда его эскалировать.
+type Policy struct {
+	// Cooldown — минимальный интервал между уведомлениями об одном инциденте.
+	// Инцидент, закрытый и снова открытый в пределах Cooldown (дребезг около порога),
+	// считается продолжением старого.
+	Cooldown time.Duration
+	// EscalateAfter — через сколько после открытия неустранённый инцидент эскалируется
+	// (одно уведомление с пометкой важности, дальше — напоминания раз в Cooldown).
+	EscalateAfter time.Duration
+	// StaleAfter — если срабатываний нет дольше этого срока (например, датчик замолчал
+	// и некому сообщить, что проблема ушла), инцидент считается закрытым.
+	StaleAfter time.Duration
+	// NotifyResolved — сообщать ли пользователю, что проблема ушла.
+	NotifyResolved bool
+}
+
+// policies подобраны под частоту источника: MQTT-пороги срабатывают на каждом пакете,
+// анализаторы температуры и шума — раз в сутки.
+var policies = map[string]Policy{
+	TypeBatteryLow:    {Cooldown: 24 * time.Hour, EscalateAfter: 72 * time.Hour, StaleAfter: 48 * time.Hour},
+	TypeSignalLow:     {Cooldown: 12 * time.Hour, EscalateAfter: 48 * time.Hour, StaleAfter: 24 * time.Hour},
+	TypeDeviceErrors:  {Cooldown: time.Hour, EscalateAfter: 6 * time.Hour, StaleAfter: 6 * time.Hour, NotifyResolved: true},
+	TypeNoiseHigh:     {Cooldown: time.Hour, EscalateAfter: 3 * time.Hour, StaleAfter: 2 * time.Hour},
+	TypeNoiseChange:   {Cooldown: 20 * time.Hour, EscalateAfter: 72 * time.Hour, StaleAfter: 48 * time.Hour},
+	TypeTemperature:   {Cooldown: 20 * time.Hour, EscalateAfter: 72 * time.Hour, StaleAfter: 48 * time.Hour},
+	TypeSensorOffline: {Cooldown: 12 * time.Hour, EscalateAfter: 24 * time.Hour, StaleAfter: 7 * 24 * time.Hour, NotifyResolved: true},
+	// рой выходит за полчаса: напоминать каждые полчаса бессмысленно, а к вечеру риск уходит сам
+	TypeSwarm: {Cooldown: 6 * time.Hour, EscalateAfter: 24 * time.Hour, StaleAfter: 12 * time.Hour},
+	// семья без матки не исправится за день: раз в сутки достаточно, пока пасечник не заглянет в улей
+	TypeQueenless: {Cooldown: 24 * time.Hour, EscalateAfter: 72 * time.Hour, StaleAfter: 48 * time.Hour},
+}
+
+// defaultPolicy — для типов, не перечисленных в policies.
+var defaultPolicy = Policy{Cooldown: 6 * time.Hour, EscalateAfter: 24 * time.Hour, StaleAfter: 24 * time.Hour}
+
+// PolicyFor возвращает политику уведомлений для типа алерта.
+func PolicyFor(alertType string) Policy {
+	if p, ok := policies[alertType]; ok {
+		return p
+	}
+	return defaultPolicy
+}
+
+// State — состояние инцидента по паре (улей, тип).
+type State struct {
+	Status         string    `json:"status"`
+	OpenedAt       time.Time `json:"opened_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	LastNotifiedAt time.Time `json:"last_notified_at"`
+	Notifications  int       `json:"notifications"`
+	ResolvedAt     time.Time `json:"resolved_at,omitempty"`
+}
+
+// Fire обрабатывает очередное срабатывание и возвращает новое состояние и действие.
+// prev == nil — состояния нет (инцидентов не было или они давно закрыты).
+func Fire(prev *State, p Policy, now time.Time) (State, Action) {
+	if prev == nil || (prev.Status == StatusResolved && now.Sub(prev.LastNotifiedAt) >= p.Cooldown) {
+		return State{Status: StatusOpen, OpenedAt: now, LastSeenAt: now, LastNotifiedAt: now, Notifications: 1}, ActionOpen
+	}
+
+	s := *prev
+	s.LastSeenAt = now
+	if s.Status == StatusResolved {
+		// дребезг: проблема вернулась вскоре после закрытия — продолжаем старый инцидент молча
+		s.Status = StatusOpen
+		s.ResolvedAt = time.Time{}
+		return s, ActionSuppress
+	}
+	// эскалация не ждёт Cooldown: о затянувшемся инциденте сообщаем сразу
+	if s.Status == StatusOpen && now.Sub(s.OpenedAt) >= p.EscalateAfter {
+		s.Status = StatusEscalated
+		s.LastNotifiedAt = now
+		s.Notifications++
+		return s, ActionEscalate
+	}
+	if now.Sub(s.LastNotifiedAt) < p.Cooldown {
+		return s, ActionSuppress
+	}
+	s.LastNotifiedAt = now
+	s.Notifications++
+	return s, ActionRenotify
+}
+
+// Close закрывает инцидент. Возвращает false, если закрывать нечего.
+func Close(prev *State, now time.Time) (State, bool) {
+	if prev == nil || prev.Status == StatusResolved {
+		return State{}, false
+	}
+	s := *prev
+	s.Status = StatusResolved
+	s.ResolvedAt = now
+	return s, true
+}
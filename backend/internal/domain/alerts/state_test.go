@@ -0,0 +1,62 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFire_Lifecycle(t *testing.T) {
+	p := Policy{Cooldown: time.Hour, EscalateAfter: 3 * time.Hour}
+	start := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	// пакеты каждые 5 секунд: одно уведомление при открытии, дальше тишина до Cooldown
+	s, action := Fire(nil, p, start)
+	if action != ActionOpen || s.Status != StatusOpen {
+		t.Fatalf("expected open, got %v %+v", action, s)
+	}
+	notified := 1
+	for now := start.Add(5 * time.Second); now.Before(start.Add(4 * time.Hour)); now = now.Add(5 * time.Second) {
+		var a Action
+		s, a = Fire(&s, p, now)
+		if a.Notify() {
+			notified++
+		}
+		if a == ActionEscalate && !now.Equal(start.Add(3*time.Hour)) {
+			t.Errorf("unexpected escalation time %v", now)
+		}
+	}
+	// открытие, напоминания через 1 и 2 часа, эскалация через 3 часа
+	if notified != 4 || s.Status != StatusEscalated || s.Notifications != 4 {
+		t.Fatalf("expected 4 notifications and escalation, got %d %+v", notified, s)
+	}
+
+	// после эскалации — напоминания раз в Cooldown
+	if _, a := Fire(&s, p, start.Add(4*time.Hour)); a != ActionRenotify {
+		t.Errorf("expected renotify after escalation, got %v", a)
+	}
+}
+
+func TestClose_Debounce(t *testing.T) {
+	p := Policy{Cooldown: time.Hour, EscalateAfter: 24 * time.Hour}
+	start := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	s, _ := Fire(nil, p, start)
+	s, ok := Close(&s, start.Add(time.Minute))
+	if !ok || s.Status != StatusResolved {
+		t.Fatalf("expected resolved, got %v %+v", ok, s)
+	}
+	if _, ok := Close(&s, start.Add(2*time.Minute)); ok {
+		t.Error("expected nothing to close twice")
+	}
+
+	// заряд 19/20/19 %: вернулось вскоре после закрытия — тот же инцидент, без уведомления
+	reopened, a := Fire(&s, p, start.Add(10*time.Minute))
+	if a != ActionSuppress || reopened.Status != StatusOpen || !reopened.OpenedAt.Equal(start) {
+		t.Fatalf("expected silent reopen, got %v %+v", a, reopened)
+	}
+
+	// вернулось через несколько часов — новый инцидент
+	if fresh, a := Fire(&s, p, start.Add(5*time.Hour)); a != ActionOpen || !fresh.OpenedAt.Equal(start.Add(5*time.Hour)) {
+		t.Fatalf("expected new incident, got %v %+v", a, fresh)
+	}
+}
@@ -0,0 +1,47 @@
+// Package apiary — пасека: место, где стоят ульи. Её координаты и часовой пояс
+// уточняют анализаторы: по широте оценивается зима (см. season.ForLatitude),
+// по поясу — где кончаются сутки пасечника.
+package apiary
+
+import (
+	"fmt"
+	"time"
+)
+
+// Validate проверяет координаты и часовой пояс пасеки; nil и пустой пояс — не заданы.
+func Validate(lat, lon, alt *float64, tz string) error {
+	check := func(name string, v *float64, min, max float64) error {
+		if v != nil && (*v < min || *v > max) {
+			return fmt.Errorf("%s: допустимо от %g до %g", name, min, max)
+		}
+		return nil
+	}
+	for _, err := range []error{
+		check("latitude", lat, -90, 90),
+		check("longitude", lon, -180, 180),
+		// от берегов Мёртвого моря до высокогорных пасек Гималаев
+		check("altitude", alt, -500, 6000),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	if tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("timezone: неизвестный часовой пояс %q", tz)
+		}
+	}
+	return nil
+}
+
+// Location возвращает часовой пояс пасеки; пустой или неизвестный — UTC.
+func Location(tz string) *time.Location {
+	if tz == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,38 @@
+package apiary
+
+import (
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(ptr(55.7), ptr(37.6), ptr(150), "Europe/Moscow"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Validate(nil, nil, nil, ""); err != nil {
+		t.Errorf("empty apiary location must be valid, got %v", err)
+	}
+	for name, err := range map[string]error{
+		"latitude":  Validate(ptr(90.5), nil, nil, ""),
+		"longitude": Validate(nil, ptr(-181), nil, ""),
+		"altitude":  Validate(nil, nil, ptr(9000), ""),
+		"timezone":  Validate(nil, nil, nil, "Europe/Atlantis"),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	if Location("") != time.UTC || Location("Europe/Atlantis") != time.UTC {
+		t.Error("expected UTC for empty and unknown timezone")
+	}
+	if loc := Location("Asia/Vladivostok"); loc.String() != "Asia/Vladivostok" {
+		t.Errorf("unexpected location %s", loc)
+	}
+}
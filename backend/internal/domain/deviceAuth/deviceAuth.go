@@ -0,0 +1,89 @@
+package deviceAuth
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/passwords"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrHubTaken — хаб с таким идентификатором уже заведён (возможно, другим пользователем).
+// Идентификатор — логин устройства в брокере, поэтому двух хабов с одним id быть не может.
+var ErrHubTaken = errors.New("hub id is already registered")
+
+// secretBytes — длина секрета устройства в байтах (в hex получается 48 символов)
+const secretBytes = 24
+
+// NewSecret генерирует секрет устройства и его bcrypt-хеш.
+// Открытый секрет отдаётся пользователю один раз, в БД хранится только хеш.
+func NewSecret() (secret, hash string, err error) {
+	buf := make([]byte, secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret = hex.EncodeToString(buf)
+	hash, err = passwords.HashPassword(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hash, nil
+}
+
+// Authorizer проверяет подключения к MQTT-брокеру: логин устройства — это
+// идентификатор хаба, пароль — выданный при создании хаба секрет.
+// Серверная учётка (MQTT_USERNAME/MQTT_PASSWORD) считается суперпользователем.
+type Authorizer struct {
+	db         interfaces.DB
+	serverUser string
+	serverPass string
+}
+
+func NewAuthorizer(db interfaces.DB) *Authorizer {
+	return &Authorizer{
+		db:         db,
+		serverUser: os.Getenv("MQTT_USERNAME"),
+		serverPass: os.Getenv("MQTT_PASSWORD"),
+	}
+}
+
+// Authenticate проверяет логин и пароль клиента брокера.
+func (a *Authorizer) Authenticate(ctx context.Context, username, password string) (bool, error) {
+	if username == "" || password == "" {
+		return false, nil
+	}
+	if a.IsSuperuser(username) {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(a.serverPass)) == 1, nil
+	}
+	hash, err := a.db.GetHubSecretHash(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return passwords.CheckPasswordHash(password, hash), nil
+}
+
+// IsSuperuser сообщает, является ли клиент серверной учёткой.
+func (a *Authorizer) IsSuperuser(username string) bool {
+	return a.serverUser != "" && username == a.serverUser
+}
+
+// CheckACL разрешает устройству только топики вида /device/{свой id}/...
+func (a *Authorizer) CheckACL(username, topic string) bool {
+	if a.IsSuperuser(username) {
+		return true
+	}
+	if username == "" || strings.ContainsAny(username, "/+#") {
+		return false
+	}
+	prefix := "/device/" + username + "/"
+	return strings.HasPrefix(topic, prefix) && len(topic) > len(prefix)
+}
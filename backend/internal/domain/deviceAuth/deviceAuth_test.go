@@ -0,0 +1,101 @@
+package deviceAuth
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/passwords"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type mockDB struct {
+	interfaces.DB
+	hash string
+	err  error
+}
+
+func (m *mockDB) GetHubSecretHash(_ context.Context, _ string) (string, error) {
+	if m.hash == "" && m.err == nil {
+		return "", pgx.ErrNoRows
+	}
+	return m.hash, m.err
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, hash, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret failed: %v", err)
+	}
+	if len(secret) != secretBytes*2 {
+		t.Errorf("expected %d hex chars, got %d", secretBytes*2, len(secret))
+	}
+	if !passwords.CheckPasswordHash(secret, hash) {
+		t.Error("hash does not match secret")
+	}
+	other, _, _ := NewSecret()
+	if other == secret {
+		t.Error("secrets must be random")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("MQTT_USERNAME", "server")
+	t.Setenv("MQTT_PASSWORD", "server-pass")
+
+	hash, _ := passwords.HashPassword("device-secret")
+	a := NewAuthorizer(&mockDB{hash: hash})
+	ctx := context.Background()
+
+	if ok, _ := a.Authenticate(ctx, "hub-001", "device-secret"); !ok {
+		t.Error("expected device to authenticate")
+	}
+	if ok, _ := a.Authenticate(ctx, "hub-001", "wrong"); ok {
+		t.Error("expected wrong secret to fail")
+	}
+	if ok, _ := a.Authenticate(ctx, "hub-001", ""); ok {
+		t.Error("expected empty password to fail")
+	}
+	if ok, _ := a.Authenticate(ctx, "server", "server-pass"); !ok {
+		t.Error("expected server account to authenticate")
+	}
+	if ok, _ := a.Authenticate(ctx, "server", "device-secret"); ok {
+		t.Error("expected server account with wrong password to fail")
+	}
+
+	if ok, err := NewAuthorizer(&mockDB{}).Authenticate(ctx, "hub-404", "device-secret"); ok || err != nil {
+		t.Errorf("expected unknown hub to fail without error, got %v, %v", ok, err)
+	}
+
+	a = NewAuthorizer(&mockDB{err: errors.New("db down")})
+	if _, err := a.Authenticate(ctx, "hub-001", "device-secret"); err == nil {
+		t.Error("expected db error to propagate")
+	}
+}
+
+func TestCheckACL(t *testing.T) {
+	t.Setenv("MQTT_USERNAME", "server")
+	a := NewAuthorizer(&mockDB{})
+
+	cases := []struct {
+		username, topic string
+		want            bool
+	}{
+		{"hub-001", "/device/hub-001/data", true},
+		{"hub-001", "/device/hub-001/config", true},
+		{"hub-001", "/device/hub-002/data", false},
+		{"hub-001", "/device/hub-001", false},
+		{"hub-001", "/device/hub-001/", false},
+		{"hub", "/device/hub-001/data", false},
+		{"hub-001", "/device/+/data", false},
+		{"+", "/device/+/data", false},
+		{"", "/device//data", false},
+		{"server", "/device/+/data", true},
+	}
+	for _, c := range cases {
+		if got := a.CheckACL(c.username, c.topic); got != c.want {
+			t.Errorf("CheckACL(%q, %q) = %v, want %v", c.username, c.topic, got, c.want)
+		}
+	}
+}
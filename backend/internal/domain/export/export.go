@@ -0,0 +1,181 @@
+// Package export — выгрузка телеметрии для пасечников и исследователей в CSV, NDJSON
+// и Parquet. Строки пишутся по одной по мере чтения из БД, поэтому выгрузка за годы
+// не собирается в памяти целиком; Parquet держит в памяти одну группу строк.
+package export
+
+import (
+	"BeeIOT/internal/domain/rollup"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы выгрузки.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// MetricStatus — история статусов устройства. В выгрузке раскладывается на метрики
+// MetricBattery и MetricSignal (проценты).
+const (
+	MetricStatus  = "status"
+	MetricBattery = "battery"
+	MetricSignal  = "signal"
+)
+
+// Metrics — метрики, которые можно выгрузить.
+var Metrics = append(slices.Clone(rollup.Metrics), MetricStatus)
+
+// Row — один замер в выгрузке. Quality — флаг достоверности (см. domain/quality).
+type Row struct {
+	Hub     string
+	Metric  string
+	Time    time.Time
+	Value   float64
+	Quality string
+}
+
+// Columns — столбцы выгрузки во всех форматах.
+var Columns = []string{"hub", "metric", "time", "value", "quality"}
+
+// Writer пишет строки выгрузки в выбранном формате. Close дописывает буферы
+// (и метаданные Parquet), но не закрывает нижележащий io.Writer.
+type Writer interface {
+	Write(row Row) error
+	Close() error
+}
+
+// ParseMetrics разбирает список метрик через запятую; пусто — все метрики.
+// status раскрывается в battery и signal.
+func ParseMetrics(s string) ([]string, error) {
+	requested := Metrics
+	if s != "" {
+		requested = nil
+		for _, m := range strings.Split(s, ",") {
+			m = strings.TrimSpace(m)
+			if m == "" {
+				continue
+			}
+			if !slices.Contains(Metrics, m) {
+				return nil, fmt.Errorf("invalid metric: %q", m)
+			}
+			requested = append(requested, m)
+		}
+	}
+	var metrics []string
+	for _, m := range requested {
+		expanded := []string{m}
+		if m == MetricStatus {
+			expanded = []string{MetricBattery, MetricSignal}
+		}
+		for _, e := range expanded {
+			if !slices.Contains(metrics, e) {
+				metrics = append(metrics, e)
+			}
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics in %q", s)
+	}
+	return metrics, nil
+}
+
+// NewWriter создаёт Writer формата format. В CSV и NDJSON время пишется в RFC 3339
+// по зоне loc; в Parquet — меткой времени в миллисекундах (UTC), а зона сохраняется
+// в метаданных файла.
+func NewWriter(format string, w io.Writer, loc *time.Location) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), loc: loc}, nil
+	case FormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf), loc: loc}, nil
+	case FormatParquet:
+		return newParquetWriter(w, loc), nil
+	default:
+		return nil, fmt.Errorf("invalid format: %q", format)
+	}
+}
+
+// ContentType — MIME-тип файла выгрузки.
+func ContentType(format string) string {
+	switch format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	loc           *time.Location
+	headerWritten bool
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(Columns)
+}
+
+func (c *csvWriter) Write(row Row) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{
+		row.Hub,
+		row.Metric,
+		row.Time.In(c.loc).Format(time.RFC3339),
+		strconv.FormatFloat(row.Value, 'f', -1, 64),
+		row.Quality,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonRow struct {
+	Hub     string  `json:"hub"`
+	Metric  string  `json:"metric"`
+	Time    string  `json:"time"`
+	Value   float64 `json:"value"`
+	Quality string  `json:"quality"`
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+	loc *time.Location
+}
+
+func (n *ndjsonWriter) Write(row Row) error {
+	return n.enc.Encode(ndjsonRow{
+		Hub:     row.Hub,
+		Metric:  row.Metric,
+		Time:    row.Time.In(n.loc).Format(time.RFC3339),
+		Value:   row.Value,
+		Quality: row.Quality,
+	})
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buf.Flush()
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+var testRows = []Row{
+	{Hub: "hub-1", Metric: "temperature", Time: time.Date(2026, 6, 1, 21, 30, 0, 0, time.UTC), Value: 34.5, Quality: "ok"},
+	{Hub: "hub-1", Metric: "weight", Time: time.Date(2026, 6, 1, 21, 35, 0, 0, time.UTC), Value: 250.5, Quality: "out_of_range"},
+}
+
+func TestParseMetrics(t *testing.T) {
+	got, err := ParseMetrics("")
+	if err != nil || !slices.Equal(got, []string{"temperature", "noise", "weight", "battery", "signal"}) {
+		t.Fatalf("ParseMetrics(\"\") = %v, %v", got, err)
+	}
+	got, err = ParseMetrics("status, weight,status")
+	if err != nil || !slices.Equal(got, []string{"battery", "signal", "weight"}) {
+		t.Fatalf("ParseMetrics(status) = %v, %v", got, err)
+	}
+	for _, s := range []string{"humidity", "battery", " , "} {
+		if _, err := ParseMetrics(s); err == nil {
+			t.Errorf("ParseMetrics(%q): expected error", s)
+		}
+	}
+}
+
+func writeAll(t *testing.T, format string, loc *time.Location, rows []Row) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTextFormats(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Moscow")
+
+	csv := string(writeAll(t, FormatCSV, loc, testRows))
+	want := "hub,metric,time,value,quality\n" +
+		"hub-1,temperature,2026-06-02T00:30:00+03:00,34.5,ok\n" +
+		"hub-1,weight,2026-06-02T00:35:00+03:00,250.5,out_of_range\n"
+	if csv != want {
+		t.Errorf("CSV:\n%s", csv)
+	}
+	if empty := string(writeAll(t, FormatCSV, loc, nil)); empty != "hub,metric,time,value,quality\n" {
+		t.Errorf("empty CSV must keep the header, got %q", empty)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(writeAll(t, FormatNDJSON, time.UTC, testRows))), "\n")
+	if len(lines) != 2 || lines[0] != `{"hub":"hub-1","metric":"temperature","time":"2026-06-01T21:30:00Z","value":34.5,"quality":"ok"}` {
+		t.Errorf("NDJSON: %q", lines)
+	}
+
+	if _, err := NewWriter("xlsx", &bytes.Buffer{}, time.UTC); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestParquet(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Moscow")
+	// Больше одной группы строк, чтобы проверить, что файл читается целиком
+	rows := make([]Row, 0, parquetRowGroupSize+2)
+	for len(rows) < parquetRowGroupSize {
+		rows = append(rows, testRows[0])
+	}
+	rows = append(rows, testRows...)
+	data := writeAll(t, FormatParquet, loc, rows)
+
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	if f.NumRows() != int64(len(rows)) || len(f.RowGroups()) != 2 {
+		t.Fatalf("num_rows = %d, row groups = %d", f.NumRows(), len(f.RowGroups()))
+	}
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	if !slices.Equal(names, Columns) {
+		t.Errorf("schema columns = %v", names)
+	}
+	if tz, _ := f.Lookup("timezone"); tz != "Europe/Moscow" {
+		t.Errorf("timezone metadata = %q", tz)
+	}
+
+	got, err := parquet.Read[parquetRow](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	last := got[len(got)-1]
+	want := testRows[1]
+	if last.Hub != want.Hub || last.Metric != want.Metric || !last.Time.Equal(want.Time) ||
+		last.Value != want.Value || last.Quality != want.Quality {
+		t.Errorf("last row = %+v, want %+v", last, want)
+	}
+
+	// Пустая выгрузка — корректный файл без строк
+	empty := writeAll(t, FormatParquet, time.UTC, nil)
+	if f, err := parquet.OpenFile(bytes.NewReader(empty), int64(len(empty))); err != nil || f.NumRows() != 0 {
+		t.Errorf("empty parquet must still be a valid file: %v", err)
+	}
+}
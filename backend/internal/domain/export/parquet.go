@@ -0,0 +1,72 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize — строк в группе. Группа собирается в памяти целиком,
+// поэтому размер ограничен; при ~40 байтах на строку это пара мегабайт.
+const parquetRowGroupSize = 64 * 1024
+
+// parquetBatch — сколько строк копится перед передачей писателю: построчная
+// передача заметно медленнее.
+const parquetBatch = 1024
+
+// parquetRow — строка файла Parquet. Время хранится меткой в миллисекундах (UTC);
+// у повторяющихся строковых столбцов — словарное кодирование.
+type parquetRow struct {
+	Hub     string    `parquet:"hub,dict"`
+	Metric  string    `parquet:"metric,dict"`
+	Time    time.Time `parquet:"time,timestamp(millisecond)"`
+	Value   float64   `parquet:"value"`
+	Quality string    `parquet:"quality,dict"`
+}
+
+type parquetWriter struct {
+	w    *parquet.GenericWriter[parquetRow]
+	rows []parquetRow
+}
+
+func newParquetWriter(w io.Writer, loc *time.Location) *parquetWriter {
+	return &parquetWriter{
+		w: parquet.NewGenericWriter[parquetRow](w,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.KeyValueMetadata("timezone", loc.String()),
+			parquet.CreatedBy("BeeIOT", "", ""),
+		),
+		rows: make([]parquetRow, 0, parquetBatch),
+	}
+}
+
+func (p *parquetWriter) Write(row Row) error {
+	p.rows = append(p.rows, parquetRow{
+		Hub:     row.Hub,
+		Metric:  row.Metric,
+		Time:    row.Time.UTC(),
+		Value:   row.Value,
+		Quality: row.Quality,
+	})
+	if len(p.rows) >= parquetBatch {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	_, err := p.w.Write(p.rows)
+	p.rows = p.rows[:0]
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
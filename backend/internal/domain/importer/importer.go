@@ -0,0 +1,386 @@
+// Package importer — загрузка телеметрии из CSV: журналы автономных логгеров
+// и рукописные журналы весов. Файл разбирается целиком до записи, поэтому
+// проверку (dry run) можно показать пасечнику до того, как что-то попадёт в БД.
+package importer
+
+import (
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/rollup"
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxRows — строк в одном файле. Годовой журнал логгера с опросом раз в минуту
+	// укладывается с запасом.
+	MaxRows = 1_000_000
+	// MaxErrors — сколько ошибок строк попадает в отчёт; остальные только считаются.
+	MaxErrors = 100
+	// futureSkew — насколько время замера может опережать часы сервера.
+	futureSkew = 5 * time.Minute
+)
+
+// Форматы времени в TimeFormat помимо раскладок Go.
+const (
+	TimeUnix   = "unix"
+	TimeUnixMs = "unix_ms"
+)
+
+var ErrTooManyRows = fmt.Errorf("больше %d строк", MaxRows)
+
+// timeLayouts — форматы времени, которые распознаются без явного TimeFormat.
+// Время без зоны читается в Options.Location.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+// units — перевод из единиц файла в единицы хранения: °C, кг, дБ.
+var units = map[string]map[string]func(float64) float64{
+	rollup.MetricTemperature: {
+		"c": func(v float64) float64 { return v },
+		"f": func(v float64) float64 { return (v - 32) * 5 / 9 },
+		"k": func(v float64) float64 { return v - 273.15 },
+	},
+	rollup.MetricNoise: {
+		"db": func(v float64) float64 { return v },
+	},
+	rollup.MetricWeight: {
+		"kg": func(v float64) float64 { return v },
+		"g":  func(v float64) float64 { return v / 1000 },
+		"lb": func(v float64) float64 { return v * 0.45359237 },
+	},
+}
+
+// Options — как читать файл.
+type Options struct {
+	// Columns — столбец файла для каждой метрики. Пусто — столбцы с именами метрик.
+	Columns map[string]string
+	// TimeColumn — столбец времени замера
+	TimeColumn string
+	// TimeFormat — раскладка Go, TimeUnix или TimeUnixMs; пусто — распространённые форматы (timeLayouts)
+	TimeFormat string
+	// Units — единицы метрик в файле; не указано — единицы хранения
+	Units    map[string]string
+	Location *time.Location
+	// Delimiter — разделитель столбцов; 0 — определяется по заголовку (см. sniffDelimiter)
+	Delimiter rune
+}
+
+// ParseOptions собирает Options из параметров запроса или флагов CLI. columns и units —
+// пары metric=значение через запятую, например "temperature=Temp C,weight=Вес" и "temperature=f".
+// Тексты ошибок ParseOptions и Parse показываются пасечнику.
+func ParseOptions(columns, unitList, timeColumn, timeFormat, tz, delimiter string) (Options, error) {
+	opts := Options{
+		TimeColumn: cmp.Or(strings.TrimSpace(timeColumn), "time"),
+		TimeFormat: strings.TrimSpace(timeFormat),
+		Location:   time.UTC,
+	}
+	var err error
+	if opts.Columns, err = parsePairs(columns); err != nil {
+		return Options{}, fmt.Errorf("columns: %w", err)
+	}
+	if opts.Units, err = parsePairs(unitList); err != nil {
+		return Options{}, fmt.Errorf("units: %w", err)
+	}
+	for metric, unit := range opts.Units {
+		unit = strings.ToLower(unit)
+		if _, ok := units[metric][unit]; !ok {
+			return Options{}, fmt.Errorf("неизвестная единица %q для %s", unit, metric)
+		}
+		opts.Units[metric] = unit
+	}
+	if tz != "" {
+		if opts.Location, err = time.LoadLocation(tz); err != nil {
+			return Options{}, fmt.Errorf("неизвестная зона %q", tz)
+		}
+	}
+	switch delimiter {
+	case "":
+	case "tab", "\\t", "\t":
+		opts.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\n' || r == '\r' {
+			return Options{}, fmt.Errorf("недопустимый разделитель %q", delimiter)
+		}
+		opts.Delimiter = r
+	}
+	return opts, nil
+}
+
+// parsePairs разбирает "metric=value,metric=value"; метрика должна быть известной.
+func parsePairs(s string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		metric, value, ok := strings.Cut(part, "=")
+		metric, value = strings.TrimSpace(metric), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("ожидается метрика=значение, получено %q", part)
+		}
+		if !slices.Contains(rollup.Metrics, metric) {
+			return nil, fmt.Errorf("неизвестная метрика %q", metric)
+		}
+		pairs[metric] = value
+	}
+	return pairs, nil
+}
+
+// RowError — ошибка в строке файла. Line — номер строки файла, считая заголовок.
+type RowError struct {
+	Line    int
+	Column  string
+	Message string
+}
+
+// Result — разобранный файл и отчёт о нём.
+type Result struct {
+	// Samples — принятые замеры по метрикам, по возрастанию времени, с флагами качества
+	Samples map[string][]quality.Sample
+	// Rows — строк данных в файле (без заголовка)
+	Rows int
+	// Errors — первые MaxErrors ошибок; ErrorCount — все
+	Errors     []RowError
+	ErrorCount int
+}
+
+func (r *Result) addError(line int, column, format string, args ...any) {
+	r.ErrorCount++
+	if len(r.Errors) < MaxErrors {
+		r.Errors = append(r.Errors, RowError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// Range — время первого и последнего принятого замера.
+func (r *Result) Range() (from, to time.Time) {
+	for _, samples := range r.Samples {
+		if len(samples) == 0 {
+			continue
+		}
+		if first := samples[0].Time; from.IsZero() || first.Before(from) {
+			from = first
+		}
+		if last := samples[len(samples)-1].Time; last.After(to) {
+			to = last
+		}
+	}
+	return from, to
+}
+
+// Parse читает CSV с заголовком. Ошибки в отдельных строках (время, число, дубль
+// времени) попадают в отчёт, а строка или ячейка пропускается; пустая ячейка —
+// замера нет. Ошибка возвращается, если файл нельзя разобрать целиком: нет нужных
+// столбцов, битый CSV или больше MaxRows строк.
+func Parse(r io.Reader, opts Options, now time.Time) (*Result, error) {
+	br := bufio.NewReader(r)
+	reader := csv.NewReader(br)
+	reader.Comma = opts.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = sniffDelimiter(br)
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := readHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	timeIdx, columns, err := mapColumns(header, opts)
+	if err != nil {
+		return nil, err
+	}
+	loc := cmp.Or(opts.Location, time.UTC)
+	// десятичная запятая из рукописных журналов, если она не разделитель столбцов
+	decimalComma := reader.Comma != ','
+
+	result := &Result{Samples: map[string][]quality.Sample{}}
+	seen := map[string]map[int64]bool{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("неверный CSV: %w", err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		result.Rows++
+		if result.Rows > MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		t, err := parseTime(cell(record, timeIdx), opts.TimeFormat, loc)
+		if err != nil {
+			result.addError(line, opts.TimeColumn, "неверное время %q", cell(record, timeIdx))
+			continue
+		}
+		if t.After(now.Add(futureSkew)) {
+			result.addError(line, opts.TimeColumn, "время %s в будущем", t.Format(time.RFC3339))
+			continue
+		}
+		// в БД время хранится без зоны, в UTC
+		t = t.UTC()
+		for _, c := range columns {
+			raw := cell(record, c.index)
+			if raw == "" {
+				continue
+			}
+			if decimalComma {
+				raw = strings.Replace(raw, ",", ".", 1)
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				result.addError(line, c.name, "неверное число %q", cell(record, c.index))
+				continue
+			}
+			if seen[c.metric] == nil {
+				seen[c.metric] = map[int64]bool{}
+			}
+			if seen[c.metric][t.UnixNano()] {
+				result.addError(line, c.name, "повтор замера за %s", t.Format(time.RFC3339))
+				continue
+			}
+			seen[c.metric][t.UnixNano()] = true
+			result.Samples[c.metric] = append(result.Samples[c.metric], quality.Sample{Value: c.convert(v), Time: t})
+		}
+	}
+
+	for metric, samples := range result.Samples {
+		slices.SortFunc(samples, func(a, b quality.Sample) int {
+			return a.Time.Compare(b.Time)
+		})
+		quality.CheckSeries(metric, nil, samples)
+	}
+	return result, nil
+}
+
+type column struct {
+	metric  string
+	name    string
+	index   int
+	convert func(float64) float64
+}
+
+// sniffDelimiter выбирает разделитель по первой строке: ';' (экспорт Excel в русской
+// локали) или табуляцию, если их там больше, чем запятых.
+func sniffDelimiter(r *bufio.Reader) rune {
+	head, _ := r.Peek(4096)
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	delimiter, most := ',', bytes.Count(line, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > most {
+			delimiter, most = d, n
+		}
+	}
+	return delimiter
+}
+
+func readHeader(reader *csv.Reader) ([]string, error) {
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("пустой файл")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("неверный заголовок CSV: %w", err)
+	}
+	header = slices.Clone(header)
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return header, nil
+}
+
+// mapColumns находит столбец времени и столбцы метрик. Имена сравниваются без учёта регистра.
+func mapColumns(header []string, opts Options) (int, []column, error) {
+	find := func(name string) int {
+		return slices.IndexFunc(header, func(h string) bool {
+			return strings.EqualFold(h, name)
+		})
+	}
+	timeIdx := find(opts.TimeColumn)
+	if timeIdx < 0 {
+		return 0, nil, fmt.Errorf("нет столбца времени %q", opts.TimeColumn)
+	}
+	var columns []column
+	for _, metric := range rollup.Metrics {
+		name, mapped := opts.Columns[metric]
+		if !mapped {
+			name = metric
+		}
+		idx := find(name)
+		if idx < 0 {
+			if mapped {
+				return 0, nil, fmt.Errorf("нет столбца %q для %s", name, metric)
+			}
+			continue
+		}
+		unit := opts.Units[metric]
+		convert, ok := units[metric][unit]
+		if unit == "" {
+			convert, ok = func(v float64) float64 { return v }, true
+		}
+		if !ok {
+			return 0, nil, fmt.Errorf("неизвестная единица %q для %s", unit, metric)
+		}
+		columns = append(columns, column{metric: metric, name: header[idx], index: idx, convert: convert})
+	}
+	if len(columns) == 0 {
+		return 0, nil, errors.New("нет столбцов метрик (temperature, noise, weight или заданных в columns)")
+	}
+	return timeIdx, columns, nil
+}
+
+func cell(record []string, i int) string {
+	if i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseTime(s, format string, loc *time.Location) (time.Time, error) {
+	switch format {
+	case TimeUnix, TimeUnixMs:
+		ts, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if format == TimeUnixMs {
+			return time.UnixMilli(ts), nil
+		}
+		return time.Unix(ts, 0), nil
+	case "":
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unknown time format: %q", s)
+	default:
+		return time.ParseInLocation(format, s, loc)
+	}
+}
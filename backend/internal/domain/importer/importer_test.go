@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"BeeIOT/internal/domain/quality"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("weight=Вес кг, temperature=T", "temperature=F", "", "", "Europe/Moscow", ";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Columns["weight"] != "Вес кг" || opts.Columns["temperature"] != "T" || opts.Units["temperature"] != "f" ||
+		opts.TimeColumn != "time" || opts.Location.String() != "Europe/Moscow" || opts.Delimiter != ';' {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	for _, args := range [][6]string{
+		{"humidity=H", "", "", "", "", ""},
+		{"weight", "", "", "", "", ""},
+		{"", "weight=stone", "", "", "", ""},
+		{"", "", "", "", "Mars/Olympus", ""},
+		{"", "", "", "", "", ";;"},
+	} {
+		if _, err := ParseOptions(args[0], args[1], args[2], args[3], args[4], args[5]); err == nil {
+			t.Errorf("ParseOptions%q: expected error", args)
+		}
+	}
+}
+
+func TestParse_ManualLog(t *testing.T) {
+	// Рукописный журнал весов из Excel: ';', десятичная запятая, местное время, пропуски
+	file := "\ufeffДата;Вес фунты;Темп\n" +
+		"01.05.2026 08:00;99,2;54\n" +
+		"01.05.2026 20:00;;55,4\n" +
+		"02.05.2026 08:00;abc;53\n" +
+		"32.05.2026 08:00;100;50\n" +
+		"01.05.2026 08:00;99,5;\n" +
+		"\n" +
+		"03.06.2026 08:00;101;52\n"
+	opts, err := ParseOptions("weight=Вес фунты,temperature=Темп", "weight=lb,temperature=f", "Дата", "", "Europe/Moscow", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Parse(strings.NewReader(file), opts, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 6 {
+		t.Errorf("expected 6 rows, got %d", result.Rows)
+	}
+	// abc, неверная дата, повтор веса за 01.05 08:00, будущее время
+	if result.ErrorCount != 4 || result.Errors[0].Line != 4 || result.Errors[0].Column != "Вес фунты" {
+		t.Fatalf("unexpected errors: %d %+v", result.ErrorCount, result.Errors)
+	}
+
+	weight := result.Samples["weight"]
+	if len(weight) != 1 || math.Abs(weight[0].Value-45) > 0.01 ||
+		!weight[0].Time.Equal(time.Date(2026, 5, 1, 5, 0, 0, 0, time.UTC)) || weight[0].Quality != quality.OK {
+		t.Errorf("unexpected weight: %+v", weight)
+	}
+	temperature := result.Samples["temperature"]
+	if len(temperature) != 3 || math.Abs(temperature[1].Value-13) > 0.01 {
+		t.Errorf("unexpected temperature: %+v", temperature)
+	}
+	if from, to := result.Range(); !from.Equal(weight[0].Time) || !to.Equal(temperature[2].Time) {
+		t.Errorf("unexpected range %s - %s", from, to)
+	}
+
+	report := result.Report(nil)
+	if !report.DryRun || len(report.Metrics) != 2 || report.Metrics[0].Metric != "temperature" || report.Metrics[1].Samples != 1 {
+		t.Errorf("unexpected dry-run report: %+v", report)
+	}
+	report = result.Report(map[string]int{"temperature": 2, "weight": 1})
+	if report.DryRun || report.Metrics[0].Imported != 2 || report.Metrics[0].Duplicates != 1 {
+		t.Errorf("unexpected import report: %+v", report)
+	}
+}
+
+func TestParse_LoggerFlagsImplausible(t *testing.T) {
+	file := "time,temperature,noise\n" +
+		"1780300800,34.1,40\n" +
+		"1780301400,34.2,41\n" +
+		"1780302000,34.0,40\n" +
+		"1780302600,85,40\n" +
+		"1780303200,34.1,\n"
+	opts, err := ParseOptions("", "", "", TimeUnix, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Parse(strings.NewReader(file), opts, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	temperature := result.Samples["temperature"]
+	if result.ErrorCount != 0 || len(temperature) != 5 || len(result.Samples["noise"]) != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if temperature[3].Quality != quality.OutOfRange || temperature[4].Quality != quality.OK {
+		t.Errorf("expected 85 °C flagged out of range, got %+v", temperature)
+	}
+	if report := result.Report(nil); report.Metrics[0].Flagged != 1 {
+		t.Errorf("expected one flagged sample in report, got %+v", report.Metrics)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	opts, _ := ParseOptions("", "", "", "", "", "")
+	for name, file := range map[string]string{
+		"empty":        "",
+		"no time":      "date,weight\n2026-05-01,40\n",
+		"no metrics":   "time,humidity\n2026-05-01,40\n",
+		"broken quote": "time,weight\n\"2026-05-01,40\n",
+	} {
+		if _, err := Parse(strings.NewReader(file), opts, now); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	opts.Columns = map[string]string{"weight": "kg"}
+	if _, err := Parse(strings.NewReader("time,weight\n2026-05-01,40\n"), opts, now); err == nil {
+		t.Error("expected error for missing mapped column")
+	}
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/rollup"
+	"context"
+	"fmt"
+)
+
+// Write записывает принятые замеры в телеметрию хаба и возвращает, сколько
+// записано по каждой метрике. Метрики пишутся отдельными транзакциями: при ошибке
+// уже записанные остаются, а повторный импорт того же файла их пропустит.
+func Write(ctx context.Context, db interfaces.DB, email, hub string, result *Result) (map[string]int, error) {
+	imported := map[string]int{}
+	for _, metric := range rollup.Metrics {
+		samples := result.Samples[metric]
+		if len(samples) == 0 {
+			continue
+		}
+		batch := httpType.TelemetryBatch{Email: email, Hub: hub, Samples: make([]httpType.TelemetrySample, len(samples))}
+		for i, s := range samples {
+			batch.Samples[i] = httpType.TelemetrySample{Value: s.Value, Time: s.Time, Quality: s.Quality}
+		}
+		n, err := db.ImportTelemetry(ctx, metric, batch)
+		if err != nil {
+			return imported, fmt.Errorf("failed to import %s: %w", metric, err)
+		}
+		imported[metric] = n
+	}
+	return imported, nil
+}
+
+// Report — отчёт о файле. imported == nil — проверка без записи (dry run).
+func (r *Result) Report(imported map[string]int) httpType.TelemetryImportReport {
+	report := httpType.TelemetryImportReport{
+		DryRun:     imported == nil,
+		Rows:       r.Rows,
+		Metrics:    []httpType.TelemetryImportMetric{},
+		ErrorCount: r.ErrorCount,
+		Errors:     make([]httpType.TelemetryImportError, len(r.Errors)),
+	}
+	if from, to := r.Range(); !from.IsZero() {
+		report.From, report.To = from.Unix(), to.Unix()
+	}
+	for _, metric := range rollup.Metrics {
+		samples, ok := r.Samples[metric]
+		if !ok {
+			continue
+		}
+		m := httpType.TelemetryImportMetric{Metric: metric, Samples: len(samples)}
+		for _, s := range samples {
+			if quality.Flagged(s.Quality) {
+				m.Flagged++
+			}
+		}
+		if imported != nil {
+			m.Imported = imported[metric]
+			m.Duplicates = m.Samples - m.Imported
+		}
+		report.Metrics = append(report.Metrics, m)
+	}
+	for i, e := range r.Errors {
+		report.Errors[i] = httpType.TelemetryImportError{Line: e.Line, Column: e.Column, Message: e.Message}
+	}
+	return report
+}
+
+// Accepted — сколько замеров принято из файла по всем метрикам.
+func (r *Result) Accepted() int {
+	n := 0
+	for _, samples := range r.Samples {
+		n += len(samples)
+	}
+	return n
+}
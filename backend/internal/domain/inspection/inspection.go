@@ -0,0 +1,145 @@
+// Package inspection — осмотры ульев: что пасечник увидел, открыв улей. В отличие
+// от работ (tasks) это структурированные записи, по которым можно проследить
+// развитие семьи, и вместе с событиями анализаторов они складываются в хронологию улья.
+package inspection
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout — формат дня осмотра.
+const DateLayout = "2006-01-02"
+
+// Нрав семьи при осмотре.
+const (
+	TemperamentCalm       = "calm"
+	TemperamentNervous    = "nervous"
+	TemperamentAggressive = "aggressive"
+)
+
+// Temperaments — допустимые значения нрава.
+var Temperaments = []string{TemperamentCalm, TemperamentNervous, TemperamentAggressive}
+
+// Способы учёта клеща варроа; от способа зависит, как читать число клещей.
+const (
+	// VarroaAlcoholWash — смыв спиртом с пробы пчёл
+	VarroaAlcoholWash = "alcohol_wash"
+	// VarroaSugarRoll — обкатка пробы сахарной пудрой
+	VarroaSugarRoll = "sugar_roll"
+	// VarroaStickyBoard — естественный осып на вкладыш
+	VarroaStickyBoard = "sticky_board"
+	// VarroaDroneBrood — вскрытие трутневого расплода
+	VarroaDroneBrood = "drone_brood"
+	VarroaVisual     = "visual"
+)
+
+// VarroaMethods — допустимые способы учёта клеща.
+var VarroaMethods = []string{VarroaAlcoholWash, VarroaSugarRoll, VarroaStickyBoard, VarroaDroneBrood, VarroaVisual}
+
+// Пределы показателей: с запасом на многокорпусные ульи.
+const (
+	MaxFrames     = 60
+	MaxQueenCells = 100
+)
+
+// ParseDate разбирает день осмотра; пусто — сегодня по now. Осмотр в будущем
+// (с запасом в сутки на часовые пояса) — ошибка.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	date, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date: ожидается дата в формате YYYY-MM-DD")
+	}
+	if date.After(now.Add(24 * time.Hour)) {
+		return time.Time{}, fmt.Errorf("date: дата осмотра в будущем")
+	}
+	return date, nil
+}
+
+// Validate проверяет показатели осмотра.
+func Validate(i dbTypes.Inspection) error {
+	for _, f := range []struct {
+		name string
+		v    *float64
+	}{{"brood_frames", i.BroodFrames}, {"honey_frames", i.HoneyFrames}, {"pollen_frames", i.PollenFrames}} {
+		if f.v != nil && (*f.v < 0 || *f.v > MaxFrames) {
+			return fmt.Errorf("%s: допустимо от 0 до %d рамок", f.name, MaxFrames)
+		}
+	}
+	if i.QueenCells != nil && (*i.QueenCells < 0 || *i.QueenCells > MaxQueenCells) {
+		return fmt.Errorf("queen_cells: допустимо от 0 до %d", MaxQueenCells)
+	}
+	if i.Temperament != "" && !slices.Contains(Temperaments, i.Temperament) {
+		return fmt.Errorf("temperament: ожидается одно из %s", strings.Join(Temperaments, ", "))
+	}
+	if i.VarroaMethod != "" && !slices.Contains(VarroaMethods, i.VarroaMethod) {
+		return fmt.Errorf("varroa_method: ожидается одно из %s", strings.Join(VarroaMethods, ", "))
+	}
+	if i.VarroaCount != nil {
+		if *i.VarroaCount < 0 {
+			return fmt.Errorf("varroa_count: не может быть отрицательным")
+		}
+		// без способа учёта число клещей не с чем сравнивать
+		if i.VarroaMethod == "" {
+			return fmt.Errorf("varroa_method: укажите способ учёта клеща")
+		}
+	}
+	return nil
+}
+
+// Title — заголовок осмотра в хронологии, например «Осмотр: матка видна, расплод 6 рамок».
+func Title(i dbTypes.Inspection) string {
+	var parts []string
+	switch {
+	case i.QueenSeen != nil && *i.QueenSeen:
+		parts = append(parts, "матка видна")
+	case i.EggsSeen != nil && *i.EggsSeen:
+		parts = append(parts, "яйца есть")
+	case i.QueenSeen != nil && i.EggsSeen != nil:
+		parts = append(parts, "ни матки, ни яиц")
+	}
+	if i.BroodFrames != nil {
+		parts = append(parts, "расплод "+strconv.FormatFloat(*i.BroodFrames, 'f', -1, 64)+" рам.")
+	}
+	if i.QueenCells != nil && *i.QueenCells > 0 {
+		parts = append(parts, "маточников "+strconv.Itoa(*i.QueenCells))
+	}
+	if i.VarroaCount != nil {
+		parts = append(parts, "клещей "+strconv.Itoa(*i.VarroaCount))
+	}
+	if len(parts) == 0 {
+		return "Осмотр"
+	}
+	return "Осмотр: " + strings.Join(parts, ", ")
+}
+
+// Entry — запись хронологии: осмотр или событие улья (задано ровно одно).
+type Entry struct {
+	At         time.Time
+	Inspection *dbTypes.Inspection
+	Event      *dbTypes.HiveEvent
+}
+
+// Timeline сводит осмотры и события улья в одну хронологию, от новых к старым.
+// Осмотр стоит на начале своего дня, поэтому события того же дня идут перед ним.
+func Timeline(inspections []dbTypes.Inspection, events []dbTypes.HiveEvent) []Entry {
+	entries := make([]Entry, 0, len(inspections)+len(events))
+	for i := range inspections {
+		entries = append(entries, Entry{At: inspections[i].Date, Inspection: &inspections[i]})
+	}
+	for i := range events {
+		entries = append(entries, Entry{At: events[i].OccurredAt, Event: &events[i]})
+	}
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].At.After(entries[b].At)
+	})
+	return entries
+}
@@ -0,0 +1,107 @@
+package inspection
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 6, 10, 18, 30, 0, 0, time.UTC)
+	if got, err := ParseDate("", now); err != nil || !got.Equal(time.Date(2026, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("empty date must be today, got %v, %v", got, err)
+	}
+	if got, err := ParseDate("2026-05-01", now); err != nil || got.Month() != time.May {
+		t.Errorf("unexpected %v, %v", got, err)
+	}
+	// на восток от UTC уже завтра
+	if _, err := ParseDate("2026-06-11", now); err != nil {
+		t.Errorf("tomorrow must be allowed for timezones, got %v", err)
+	}
+	for _, s := range []string{"2026-06-13", "10.06.2026", "2026-13-01"} {
+		if _, err := ParseDate(s, now); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := dbTypes.Inspection{BroodFrames: ptr(6.5), HoneyFrames: ptr(0.0), QueenCells: ptr(0),
+		Temperament: TemperamentCalm, VarroaCount: ptr(3), VarroaMethod: VarroaAlcoholWash}
+	if err := Validate(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Validate(dbTypes.Inspection{Notes: "только посмотрели летки"}); err != nil {
+		t.Errorf("inspection without measurements must be valid, got %v", err)
+	}
+	for name, i := range map[string]dbTypes.Inspection{
+		"frames":      {PollenFrames: ptr(-1.0)},
+		"too many":    {BroodFrames: ptr(61.0)},
+		"queen cells": {QueenCells: ptr(-2)},
+		"temperament": {Temperament: "angry"},
+		"method":      {VarroaMethod: "guess"},
+		"no method":   {VarroaCount: ptr(4)},
+		"negative":    {VarroaCount: ptr(-1), VarroaMethod: VarroaSugarRoll},
+	} {
+		if err := Validate(i); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	cases := []struct {
+		i    dbTypes.Inspection
+		want string
+	}{
+		{dbTypes.Inspection{}, "Осмотр"},
+		{dbTypes.Inspection{QueenSeen: ptr(true), BroodFrames: ptr(6.0)}, "Осмотр: матка видна, расплод 6 рам."},
+		{dbTypes.Inspection{QueenSeen: ptr(false), EggsSeen: ptr(true)}, "Осмотр: яйца есть"},
+		{dbTypes.Inspection{QueenSeen: ptr(false), EggsSeen: ptr(false), QueenCells: ptr(3)},
+			"Осмотр: ни матки, ни яиц, маточников 3"},
+		{dbTypes.Inspection{VarroaCount: ptr(5), VarroaMethod: VarroaStickyBoard}, "Осмотр: клещей 5"},
+	}
+	for _, c := range cases {
+		if got := Title(c.i); got != c.want {
+			t.Errorf("expected %q, got %q", c.want, got)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 6, d, 0, 0, 0, 0, time.UTC)
+	}
+	inspections := []dbTypes.Inspection{{Id: 1, Date: day(10)}, {Id: 2, Date: day(3)}}
+	events := []dbTypes.HiveEvent{
+		{ID: 10, OccurredAt: day(10).Add(14 * time.Hour)},
+		{ID: 11, OccurredAt: day(5)},
+		{ID: 12, OccurredAt: day(1)},
+	}
+
+	entries := Timeline(inspections, events)
+	want := []string{"event 10", "inspection 1", "event 11", "inspection 2", "event 12"}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, e := range entries {
+		var got string
+		switch {
+		case e.Inspection != nil && e.Event == nil:
+			got = fmt.Sprintf("inspection %d", e.Inspection.Id)
+		case e.Event != nil && e.Inspection == nil:
+			got = fmt.Sprintf("event %d", e.Event.ID)
+		}
+		if got != want[i] {
+			t.Errorf("entry %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+	if len(Timeline(nil, nil)) != 0 {
+		t.Error("expected empty timeline")
+	}
+}
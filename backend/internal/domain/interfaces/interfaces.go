@@ -3,6 +3,7 @@ package interfaces
 import (
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/models/notifyTypes"
 	"context"
 	"time"
 )
@@ -11,6 +12,28 @@ type ConfirmSender interface {
 	SendConfirmationCode(toEmail, code string) error
 }
 
+type EmailSender interface {
+	// SendEmail отправляет письмо; html может быть пустым — тогда только текст.
+	SendEmail(toEmail, subject, text, html string) error
+}
+
+// Mailer — почта, которой пользуется HTTP-сервер: коды подтверждения и приглашения.
+type Mailer interface {
+	ConfirmSender
+	EmailSender
+}
+
+// Notifier — канал доставки уведомлений (push, email, вебхук).
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error
+}
+
+// Dispatcher доставляет уведомление пользователю по всем выбранным им каналам.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, email string, msg notifyTypes.Message) error
+}
+
 type DB interface {
 	Registration(ctx context.Context, registration httpType.Registration) error
 	IsExistUser(ctx context.Context, email string) (bool, error)
@@ -24,16 +47,42 @@ type DB interface {
 
 	NewHive(ctx context.Context, email, nameHive, sensorName string) error
 	GetHives(ctx context.Context, email string, active *bool) ([]dbTypes.Hive, error)
-	GetHiveByName(ctx context.Context, email, nameHive string, active *bool) (dbTypes.Hive, error)
-	DeleteHive(ctx context.Context, email, nameHive string) error
+	GetHiveByName(ctx context.Context, email, nameHive, owner string, active *bool) (dbTypes.Hive, error)
+	GetHiveByHub(ctx context.Context, email, hubSensor string) (dbTypes.Hive, error)
+	DeleteHive(ctx context.Context, email, nameHive, owner string) error
 	UpdateHive(ctx context.Context, email string, data httpType.UpdateHive) error
 	UpdateHiveTemperatureCheck(ctx context.Context, hiveId int, t time.Time) error
 	UpdateHiveNoiseCheck(ctx context.Context, hiveId int, t time.Time) error
 	GetEmailHiveBySensorID(ctx context.Context, sensorID string) (string, string, error)
-	LinkHubToHive(ctx context.Context, email, hiveName, hubName string) error
-	LinkQueenToHive(ctx context.Context, email, hiveName, queenName string) error
-
-	NewHub(ctx context.Context, email, nameHub, sensorName string) error
+	LinkHubToHive(ctx context.Context, email, hiveName, owner, hubName string) error
+	LinkQueenToHive(ctx context.Context, email, hiveName, owner, queenName string) error
+	LinkApiaryToHive(ctx context.Context, email, hiveName, owner, apiaryName string) error
+
+	NewApiary(ctx context.Context, email string, data httpType.CreateApiary) error
+	GetApiaries(ctx context.Context, email string) ([]dbTypes.Apiary, error)
+	GetApiaryByName(ctx context.Context, email, name string) (dbTypes.Apiary, error)
+	UpdateApiary(ctx context.Context, email string, data httpType.UpdateApiary) error
+	DeleteApiary(ctx context.Context, email, name string) error
+
+	GetHiveRole(ctx context.Context, email, hive, owner string) (string, error)
+	GetHubRole(ctx context.Context, email, sensor string) (string, error)
+	GetApiaryRole(ctx context.Context, email, apiary string) (string, error)
+	CreateInvitation(ctx context.Context, inviter string, inv httpType.InviteMember, tokenHash string, expires time.Time) error
+	GetInvitations(ctx context.Context, email string) ([]dbTypes.Invitation, error)
+	AcceptInvitation(ctx context.Context, email string, id int64, tokenHash string) (dbTypes.Invitation, error)
+	DeclineInvitation(ctx context.Context, email string, id int64, tokenHash string) error
+	GetMembers(ctx context.Context, email, apiary, hive, owner string) ([]dbTypes.Member, error)
+	SetMemberRole(ctx context.Context, email string, data httpType.UpdateMember) error
+	RemoveMember(ctx context.Context, email string, data httpType.RemoveMember) error
+	GetAlertRecipients(ctx context.Context, ownerEmail, hive string) ([]dbTypes.Member, error)
+
+	NewHub(ctx context.Context, email, nameHub, sensorName, secretHash string) error
+	UpdateHubSecret(ctx context.Context, email, sensor, secretHash string) error
+	GetHubSecretHash(ctx context.Context, sensor string) (string, error)
+	SetDesiredConfig(ctx context.Context, email, sensor string, config httpType.ShadowConfig) error
+	SetReportedConfig(ctx context.Context, sensor string, config httpType.ShadowConfig, reportedAt time.Time) error
+	GetShadow(ctx context.Context, email, sensor string) (dbTypes.DeviceShadow, error)
+	GetShadowBySensor(ctx context.Context, sensor string) (dbTypes.DeviceShadow, error)
 	GetHubs(ctx context.Context, email string) ([]dbTypes.Hub, error)
 	GetHubBySensor(ctx context.Context, email, sensor string) (dbTypes.Hub, error)
 	GetHubSensorByHive(ctx context.Context, email, hiveName string) (string, error)
@@ -49,16 +98,68 @@ type DB interface {
 	UpdateQueen(ctx context.Context, email string, data httpType.UpdateQueen) error
 
 	NewTemperature(ctx context.Context, temp httpType.Temperature) error
+	NewTemperatureBatch(ctx context.Context, batch httpType.TelemetryBatch) error
 	GetTemperaturesSinceTime(ctx context.Context, email, hub string, time time.Time) ([]dbTypes.HivesTemperatureData, error)
 	GetTemperaturesSinceTimeById(ctx context.Context, hubId int, time time.Time) ([]dbTypes.HivesTemperatureData, error)
 
 	NewNoise(ctx context.Context, noise httpType.NoiseLevel) error
+	NewNoiseBatch(ctx context.Context, batch httpType.TelemetryBatch) error
 	GetNoiseSinceTime(ctx context.Context, email, hub string, time time.Time) ([]dbTypes.HivesNoiseData, error)
+	GetNoiseSinceTimeById(ctx context.Context, hubId int, time time.Time) ([]dbTypes.HivesNoiseData, error)
 	GetNoiseSinceDay(ctx context.Context, hubId int, date time.Time) (map[time.Time][]dbTypes.HivesNoiseData, error)
+	NewNoiseSpectrum(ctx context.Context, spectrum httpType.NoiseSpectrum) error
+	NewNoiseSpectrumBatch(ctx context.Context, spectra []httpType.NoiseSpectrum) error
+	GetNoiseSpectrumSinceTime(ctx context.Context, email, hub string, time time.Time) ([]dbTypes.HivesNoiseSpectrum, error)
+	GetNoiseSpectrumSinceTimeById(ctx context.Context, hubId int, time time.Time) ([]dbTypes.HivesNoiseSpectrum, error)
 
 	NewHiveWeight(ctx context.Context, weight httpType.HubWeight) error
+	NewHiveWeightBatch(ctx context.Context, batch httpType.TelemetryBatch) error
 	DeleteHiveWeight(ctx context.Context, weight httpType.HubWeight) error
 	GetWeightSinceTime(ctx context.Context, email, hub string, time time.Time) ([]dbTypes.HivesWeightData, error)
+	GetWeightSinceTimeById(ctx context.Context, hubId int, time time.Time) ([]dbTypes.HivesWeightData, error)
+
+	NewHiveEvent(ctx context.Context, hiveId int, event dbTypes.HiveEvent) (int64, error)
+	GetHiveEvents(ctx context.Context, hiveId int, since time.Time) ([]dbTypes.HiveEvent, error)
+
+	RefreshRollups(ctx context.Context, since time.Time) error
+	GetLatestRollupTime(ctx context.Context) (time.Time, error)
+	GetTelemetryRollup(ctx context.Context, email, hub, metric, resolution string, since time.Time) ([]dbTypes.TelemetryRollup, error)
+	CountTelemetrySinceTime(ctx context.Context, email, hub, metric string, since time.Time) (int, error)
+	GetTelemetryStats(ctx context.Context, email, hub string, metrics []string, bucket string, since, until time.Time, percentiles []float64) ([]dbTypes.TelemetryStats, error)
+	GetRecentTelemetry(ctx context.Context, email, hub, metric string, since, before time.Time, limit int) ([]dbTypes.TelemetrySample, error)
+	ExportTelemetry(ctx context.Context, email string, hubs, metrics []string, since, until time.Time, fn func(dbTypes.TelemetryExportRow) error) error
+	NewDeviceStatus(ctx context.Context, status httpType.DeviceStatus) error
+	ImportTelemetry(ctx context.Context, metric string, batch httpType.TelemetryBatch) (int, error)
+
+	EnsureTelemetryPartition(ctx context.Context, metric string, month time.Time) (bool, error)
+	GetTelemetryPartitions(ctx context.Context, metric string) ([]dbTypes.TelemetryPartition, error)
+	GetUnpartitionedMonths(ctx context.Context, metric string) ([]time.Time, error)
+	IsTelemetryPartitionRolledUp(ctx context.Context, metric string, partition dbTypes.TelemetryPartition) (bool, error)
+	DropTelemetryPartition(ctx context.Context, metric string, partition dbTypes.TelemetryPartition) error
+
+	SetAlertRules(ctx context.Context, email, hive, owner string, rules httpType.AlertThresholds) error
+	GetAlertRules(ctx context.Context, email, hive, owner string) (dbTypes.AlertRules, error)
+	ListAlertRules(ctx context.Context, email string) ([]dbTypes.AlertRules, error)
+	DeleteAlertRules(ctx context.Context, email, hive, owner string) error
+	GetEffectiveAlertRules(ctx context.Context, email, hive, owner string) (dbTypes.AlertRules, error)
+
+	NewAnalyzerRun(ctx context.Context, run dbTypes.AnalyzerRun) error
+	GetAnalyzerRuns(ctx context.Context, analyzer string, limit int) ([]dbTypes.AnalyzerRun, error)
+	GetLatestAnalyzerRuns(ctx context.Context) ([]dbTypes.AnalyzerRun, error)
+	DeleteAnalyzerRunsBefore(ctx context.Context, before time.Time) (int64, error)
+
+	NewNotification(ctx context.Context, email string, n dbTypes.Notification) (int64, error)
+	GetNotifications(ctx context.Context, email string, unreadOnly bool, limit, offset int) ([]dbTypes.Notification, error)
+	CountNotifications(ctx context.Context, email string) (total, unread int, err error)
+	MarkNotificationsRead(ctx context.Context, email string, ids []int64) (int64, error)
+	DeleteNotification(ctx context.Context, email string, id int64) error
+	GetNotificationPreferences(ctx context.Context, email string) (dbTypes.NotificationPreferences, error)
+	SetNotificationPreferences(ctx context.Context, email string, p dbTypes.NotificationPreferences) error
+	HoldNotification(ctx context.Context, email string, msg notifyTypes.Message) error
+	GetHeldNotificationUsers(ctx context.Context) ([]string, error)
+	ProcessHeldNotifications(ctx context.Context, email string, fn func(held []notifyTypes.Held) []int64) error
+	GetDigestSubscribers(ctx context.Context) ([]dbTypes.DigestSubscriber, error)
+	SetDigestSent(ctx context.Context, email string, at time.Time) error
 
 	SetFirebaseToken(ctx context.Context, email, device, fcm string) error
 	GetFirebaseToken(ctx context.Context, email string) ([]string, error)
@@ -70,6 +171,12 @@ type DB interface {
 	DeleteTask(ctx context.Context, email, taskID string) error
 	GetTaskByID(ctx context.Context, taskID string) (dbTypes.Task, error)
 
+	NewInspection(ctx context.Context, email, hive, owner string, inspection dbTypes.Inspection) (int64, error)
+	GetInspections(ctx context.Context, email, hive, owner string, since time.Time) ([]dbTypes.Inspection, error)
+	GetInspection(ctx context.Context, email string, id int64) (dbTypes.Inspection, error)
+	UpdateInspection(ctx context.Context, email string, inspection dbTypes.Inspection) error
+	DeleteInspection(ctx context.Context, email string, id int64) error
+
 	GetAppDescription(ctx context.Context) (dbTypes.AppDescription, error)
 	UpsertAppDescription(ctx context.Context, req httpType.UpdateAppDescriptionRequest, updatedBy string) (dbTypes.AppDescription, error)
 
@@ -90,10 +197,22 @@ type InMemoryDB interface {
 	ExistSensor(ctx context.Context, sensorID string) (bool, error)
 	GetAllSensors(ctx context.Context) (map[string]int64, error)
 	DeleteSensor(ctx context.Context, sensorID string) error
+	SetSensorInterval(ctx context.Context, sensorID string, seconds int64) error
+	GetSensorIntervals(ctx context.Context) (map[string]int64, error)
+	MarkSensorOffline(ctx context.Context, sensorID string) (bool, error)
+	MarkSensorOnline(ctx context.Context, sensorID string) (bool, error)
+	GetOfflineSensors(ctx context.Context) (map[string]bool, error)
 	SetLastSensorData(ctx context.Context, sensorID string, data string) error
 	GetLastSensorData(ctx context.Context, sensorID string) (string, error)
 	SetLastDeviceStatus(ctx context.Context, sensorID string, data string) error
 	GetLastDeviceStatus(ctx context.Context, sensorID string) (string, error)
+	PublishTelemetry(ctx context.Context, event string) error
+	SubscribeTelemetry(ctx context.Context) (<-chan string, error)
+	SetStreamTicket(ctx context.Context, ticket, email string, ttl time.Duration) error
+	TakeStreamTicket(ctx context.Context, ticket string) (string, error)
+	GetAlertState(ctx context.Context, key string) (string, error)
+	SetAlertState(ctx context.Context, key, state string, ttl time.Duration) error
+	GetAlertStates(ctx context.Context, prefix string) (map[string]string, error)
 }
 
 type PasswordData = string
@@ -0,0 +1,132 @@
+// Package membership — совместная работа на пасеке: участники пасеки или улья
+// с ролями и приглашения по почте. Владелец улья (тот, кто его завёл) всегда
+// имеет роль owner; остальные получают роль по приглашению.
+package membership
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Роли участника, по возрастанию прав.
+const (
+	// RoleViewer видит ульи, телеметрию и получает важные алерты
+	RoleViewer = "viewer"
+	// RoleEditor дополнительно меняет ульи, хабы, матки, задачи и пороги алертов
+	RoleEditor = "editor"
+	// RoleOwner дополнительно удаляет и управляет участниками
+	RoleOwner = "owner"
+)
+
+// Roles — все роли по возрастанию прав.
+var Roles = []string{RoleViewer, RoleEditor, RoleOwner}
+
+// InvitationTTL — сколько действует приглашение.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// ValidRole проверяет роль.
+func ValidRole(role string) bool {
+	return rank(role) > 0
+}
+
+func rank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Allows — достаточно ли роли role для действия, требующего min.
+func Allows(role, min string) bool {
+	return rank(role) > 0 && rank(role) >= rank(min)
+}
+
+// AtLeast — роли не ниже min.
+func AtLeast(min string) []string {
+	var result []string
+	for _, role := range Roles {
+		if Allows(role, min) {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+// ViewerAlertSeverity — важность алертов, которые получают наблюдатели. Совпадает
+// с alerts.SeverityCritical; alerts сам зависит от этого пакета, поэтому значение
+// задано здесь (соответствие проверяет тест alerts).
+const ViewerAlertSeverity = "critical"
+
+// ReceivesAlert — получает ли участник с ролью role алерт важности severity:
+// владельцы и редакторы получают все, наблюдатели — только ViewerAlertSeverity.
+func ReceivesAlert(role, severity string) bool {
+	switch role {
+	case RoleOwner, RoleEditor:
+		return true
+	case RoleViewer:
+		return severity == ViewerAlertSeverity
+	default:
+		return false
+	}
+}
+
+// NewToken создаёт токен приглашения и его хеш; в БД хранится только хеш.
+func NewToken() (token, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	token = hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken — хеш токена приглашения.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Target — на что приглашают: пасеку или отдельный улей.
+type Target struct {
+	Apiary string
+	Hive   string
+}
+
+func (t Target) String() string {
+	if t.Apiary != "" {
+		return "пасеке «" + t.Apiary + "»"
+	}
+	return "улью «" + t.Hive + "»"
+}
+
+var roleNames = map[string]string{
+	RoleViewer: "наблюдатель",
+	RoleEditor: "редактор",
+	RoleOwner:  "совладелец",
+}
+
+// InvitationEmail — тема и текст письма с приглашением. Если задан APP_URL,
+// в письмо добавляется ссылка на принятие приглашения.
+func InvitationEmail(inviter string, target Target, role, token string, expires time.Time) (subject, text string) {
+	subject = "Приглашение на пасеку"
+	text = fmt.Sprintf("%s приглашает вас к %s, роль — %s.\n\n", inviter, target, roleNames[role])
+	if base := strings.TrimRight(os.Getenv("APP_URL"), "/"); base != "" {
+		text += "Принять приглашение: " + base + "/invitations/accept?token=" + token + "\n"
+	}
+	text += "Код приглашения: " + token + "\n" +
+		"Войдите в приложение под этим адресом почты и примите приглашение до " +
+		expires.UTC().Format("02.01.2006") + ".\n" +
+		"Если вы не ждали приглашения, просто проигнорируйте это письмо."
+	return subject, text
+}
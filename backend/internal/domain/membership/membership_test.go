@@ -0,0 +1,69 @@
+package membership
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		role, min string
+		want      bool
+	}{
+		{RoleOwner, RoleOwner, true},
+		{RoleOwner, RoleViewer, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleOwner, false},
+		{RoleViewer, RoleEditor, false},
+		{"", RoleViewer, false},
+		{"admin", RoleViewer, false},
+	}
+	for _, c := range cases {
+		if got := Allows(c.role, c.min); got != c.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", c.role, c.min, got, c.want)
+		}
+	}
+	if got := AtLeast(RoleEditor); !slices.Equal(got, []string{RoleEditor, RoleOwner}) {
+		t.Errorf("AtLeast(editor) = %v", got)
+	}
+}
+
+func TestReceivesAlert(t *testing.T) {
+	if !ReceivesAlert(RoleEditor, "warning") || !ReceivesAlert(RoleOwner, "info") {
+		t.Error("owners and editors must receive every alert")
+	}
+	if ReceivesAlert(RoleViewer, "warning") || !ReceivesAlert(RoleViewer, ViewerAlertSeverity) {
+		t.Error("viewers must receive only critical alerts")
+	}
+	if ReceivesAlert("", "critical") {
+		t.Error("unknown role must not receive alerts")
+	}
+}
+
+func TestToken(t *testing.T) {
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	if hash != HashToken(token) || hash != HashToken(" "+token+"\n") {
+		t.Error("hash must match the token regardless of surrounding spaces")
+	}
+	other, _, _ := NewToken()
+	if other == token {
+		t.Error("tokens must be unique")
+	}
+}
+
+func TestInvitationEmail(t *testing.T) {
+	t.Setenv("APP_URL", "https://bee.example.com/")
+	expires := time.Date(2026, 5, 17, 12, 0, 0, 0, time.UTC)
+	_, text := InvitationEmail("a@b.c", Target{Hive: "Hive1"}, RoleEditor, "tok", expires)
+	for _, want := range []string{"a@b.c", "улью «Hive1»", "редактор", "tok", "17.05.2026",
+		"https://bee.example.com/invitations/accept?token=tok"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in invitation text:\n%s", want, text)
+		}
+	}
+}
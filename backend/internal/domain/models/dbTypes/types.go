@@ -18,6 +18,30 @@ type Hive struct {
 	QueenID         *int
 	HubName         string
 	QueenName       string
+	// SeasonMode — auto, summer или winter (см. season)
+	SeasonMode string
+	ApiaryID   *int
+	ApiaryName string
+	// Latitude и Timezone — пасеки улья; nil и пусто, если улей не на пасеке
+	// или координаты пасеки не заданы.
+	Latitude *float64
+	Timezone string
+	// Role — роль пользователя, запросившего улей (см. membership); Email — почта владельца.
+	// Пусто, если ульи читались без пользователя.
+	Role string
+}
+
+// HiveEvent — событие в хронологии улья (например, риск роения, найденный анализатором).
+type HiveEvent struct {
+	ID     int64
+	Hive   string
+	Type   string
+	Source string
+	Title  string
+	// Details — показатели, на основании которых событие записано
+	Details    map[string]any
+	OccurredAt time.Time
+	CreatedAt  time.Time
 }
 
 type Hub struct {
@@ -27,6 +51,65 @@ type Hub struct {
 	Sensor  string
 }
 
+// Apiary — пасека пользователя. Координаты необязательны; Timezone — IANA-зона.
+type Apiary struct {
+	Id        int
+	Email     string
+	Name      string
+	Latitude  *float64
+	Longitude *float64
+	Altitude  *float64
+	Timezone  string
+	Notes     string
+	// Role — роль пользователя, запросившего пасеку; Email — почта владельца.
+	Role string
+}
+
+// Member — участник пасеки или улья.
+type Member struct {
+	Email  string
+	Role   string
+	Apiary string
+	Hive   string
+	// HiveID — id улья Hive; заполняется у получателей алерта (см. GetAlertRecipients)
+	HiveID int
+	// CreatedAt — когда участник принял приглашение; nil у владельца
+	CreatedAt *time.Time
+}
+
+// Invitation — приглашение на пасеку или в улей, ещё не принятое.
+type Invitation struct {
+	Id        int64
+	Email     string
+	Apiary    string
+	Hive      string
+	Role      string
+	InvitedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Inspection — осмотр улья. Показатели, которые не оценивались, — nil;
+// Author — почта того, кто записал осмотр (пусто, если его аккаунт удалён).
+type Inspection struct {
+	Id           int64
+	Hive         string
+	Owner        string
+	Date         time.Time
+	BroodFrames  *float64
+	HoneyFrames  *float64
+	PollenFrames *float64
+	QueenSeen    *bool
+	EggsSeen     *bool
+	QueenCells   *int
+	Temperament  string
+	VarroaCount  *int
+	VarroaMethod string
+	Notes        string
+	Author       string
+	CreatedAt    time.Time
+}
+
 type Queen struct {
 	Id         int
 	Email      string
@@ -63,17 +146,173 @@ type InstructionItem struct {
 	UpdatedAt time.Time
 }
 
+// DeviceShadow — желаемая и применённая устройством конфигурация хаба.
+// -1 — параметр не задан (desired) или устройство о нём не сообщало (reported).
+type DeviceShadow struct {
+	HubId             int
+	Sensor            string
+	DesiredNoise      int
+	DesiredTemp       int
+	DesiredFrequency  int
+	DesiredAt         *time.Time
+	ReportedNoise     int
+	ReportedTemp      int
+	ReportedFrequency int
+	ReportedAt        *time.Time
+}
+
 type HivesTemperatureData struct {
 	Date        time.Time
 	Temperature float64
+	Quality     string
 }
 
 type HivesNoiseData struct {
-	Date  time.Time
-	Level float64
+	Date    time.Time
+	Level   float64
+	Quality string
 }
 
-type HivesWeightData struct {
-	Weight float64
+// HivesNoiseSpectrum — спектр шума: уровень Levels[i] (дБ) в полосе [Edges[i], Edges[i+1]) Гц.
+type HivesNoiseSpectrum struct {
 	Date   time.Time
+	Edges  []float64
+	Levels []float64
+}
+
+type HivesWeightData struct {
+	Weight  float64
+	Date    time.Time
+	Quality string
+}
+
+// TelemetrySample — сырой замер любой метрики с флагом качества (см. domain/quality).
+type TelemetrySample struct {
+	Value   float64
+	Date    time.Time
+	Quality string
+}
+
+// TelemetryExportRow — строка выгрузки телеметрии: замер метрики хаба.
+type TelemetryExportRow struct {
+	Hub     string
+	Metric  string
+	Date    time.Time
+	Value   float64
+	Quality string
+}
+
+// TelemetryPartition — месячная секция таблицы сырых замеров: [From, To).
+type TelemetryPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// TelemetryRollup — агрегат телеметрии за час или сутки.
+type TelemetryRollup struct {
+	Date  time.Time
+	Min   float64
+	Max   float64
+	Avg   float64
+	Count int
+}
+
+// TelemetryStats — статистика метрики за интервал группировки. Percentiles идут
+// в порядке stats.Percentiles.
+type TelemetryStats struct {
+	Metric      string
+	Bucket      time.Time
+	Count       int
+	Min         float64
+	Max         float64
+	Avg         float64
+	StdDev      float64
+	Percentiles []float64
+	First       float64
+	FirstAt     time.Time
+	Last        float64
+	LastAt      time.Time
+}
+
+// AnalyzerRun — один прогон фонового анализатора.
+type AnalyzerRun struct {
+	ID             int
+	Analyzer       string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	HivesProcessed int
+	AlertsRaised   int
+	Errors         []string
+}
+
+// AlertRules — сохранённые переопределения порогов алертов. nil — значение не задано
+// и наследуется (от правил пользователя или от значений по умолчанию). Owner — почта
+// владельца улья Hive; у правил по умолчанию пустая.
+type AlertRules struct {
+	Hive                 string
+	Owner                string
+	NoiseHigh            *float64
+	NoiseDelta           *float64
+	TemperatureNormal    *float64
+	TemperatureDeltaUp   *float64
+	TemperatureDeltaDown *float64
+	BatteryLow           *int
+	SignalLow            *int
+	UpdatedAt            time.Time
+}
+
+// Notification — запись во входящих уведомлениях пользователя. ReadAt == nil — не прочитано.
+type Notification struct {
+	ID   int64
+	Hive string
+	// HiveID — улей, к которому привязывается новое уведомление; 0 — без привязки
+	HiveID    int
+	Type      string
+	Severity  string
+	Title     string
+	Body      string
+	CreatedAt time.Time
+	ReadAt    *time.Time
+}
+
+// NotificationPreferences — какие уведомления, куда и когда получает пользователь.
+type NotificationPreferences struct {
+	// Channels — включённые каналы (см. notifyTypes.Channel*)
+	Channels      []string
+	WebhookURL    string
+	WebhookSecret string
+	// AlertTypes — настройки по типам алертов; типа нет в карте — он включён и идёт в Channels
+	AlertTypes map[string]AlertTypePreference
+	// MinSeverity — уведомления ниже этой важности не рассылаются (во входящих остаются)
+	MinSeverity string
+	// Timezone — IANA-зона пользователя, в ней задаются QuietHours
+	Timezone   string
+	QuietHours []QuietHours
+	// Digest — периодичность сводки на почту: off, daily, weekly
+	Digest    string
+	UpdatedAt time.Time
+}
+
+// DigestSubscriber — пользователь, подписанный на сводку по пасеке.
+type DigestSubscriber struct {
+	Email    string
+	Name     string
+	Period   string
+	Timezone string
+	// SentAt — когда ушла прошлая сводка; нулевое — ещё не отправлялась
+	SentAt time.Time
+}
+
+// AlertTypePreference — настройка одного типа алертов. Пустой Channels — каналы по умолчанию.
+type AlertTypePreference struct {
+	Enabled  bool     `json:"enabled"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// QuietHours — окно тихих часов "HH:MM"-"HH:MM" по местному времени.
+// Start позже End — окно переходит через полночь.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
 }
@@ -2,7 +2,10 @@
 // нужны, чтобы парсить данные с тела запроса и одним параметром передавать их в бд
 package httpType
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Registration struct {
 	Email    string `json:"email"`
@@ -46,24 +49,93 @@ type QueenRequest struct {
 }
 
 type NoiseLevel struct {
-	Level float64   `json:"level"`
-	Time  time.Time `json:"time"`
-	Email string    `json:"email"`
-	Hub   string    `json:"hub"`
+	Level   float64   `json:"level"`
+	Time    time.Time `json:"time"`
+	Email   string    `json:"email"`
+	Hub     string    `json:"hub"`
+	Quality string    `json:"-"`
 }
 
-type HubWeight struct {
-	Weight float64   `json:"weight"`
+// NoiseSpectrum — спектр шума хаба для записи в БД (см. mqttTypes.Spectrum).
+type NoiseSpectrum struct {
+	Edges  []float64 `json:"edges"`
+	Levels []float64 `json:"levels"`
 	Time   time.Time `json:"time"`
 	Email  string    `json:"email"`
 	Hub    string    `json:"hub"`
 }
 
+type HubWeight struct {
+	Weight  float64   `json:"weight"`
+	Time    time.Time `json:"time"`
+	Email   string    `json:"email"`
+	Hub     string    `json:"hub"`
+	Quality string    `json:"-"`
+}
+
 type Temperature struct {
 	Temperature float64   `json:"temperature"`
 	Time        time.Time `json:"time"`
 	Email       string    `json:"email"`
 	Hub         string    `json:"hub"`
+	Quality     string    `json:"-"`
+}
+
+// TelemetrySample — один замер в пакетной записи TelemetryBatch.
+// Пустой Quality записывается как quality.OK.
+type TelemetrySample struct {
+	Value   float64   `json:"value"`
+	Time    time.Time `json:"time"`
+	Quality string    `json:"-"`
+}
+
+// DeviceStatus — статус устройства для записи в историю. Отчёт приходит по
+// идентификатору датчика; -1 — значение устройство не сообщило.
+type DeviceStatus struct {
+	Email          string    `json:"email"`
+	Sensor         string    `json:"sensor"`
+	BatteryLevel   int       `json:"battery_level"`
+	SignalStrength int       `json:"signal_strength"`
+	Errors         []string  `json:"errors"`
+	Time           time.Time `json:"time"`
+}
+
+// TelemetryBatch — пачка замеров одной метрики для одного хаба.
+// Используется для массовой записи оффлайн-бэклога датчика.
+type TelemetryBatch struct {
+	Email   string            `json:"email"`
+	Hub     string            `json:"hub"`
+	Samples []TelemetrySample `json:"samples"`
+}
+
+// TelemetryImportReport — отчёт о проверке (dry run) или импорте файла телеметрии.
+// From и To — Unix-время первого и последнего принятого замера.
+type TelemetryImportReport struct {
+	DryRun     bool                    `json:"dry_run"`
+	Rows       int                     `json:"rows"`
+	From       int64                   `json:"from,omitempty"`
+	To         int64                   `json:"to,omitempty"`
+	Metrics    []TelemetryImportMetric `json:"metrics"`
+	ErrorCount int                     `json:"error_count"`
+	Errors     []TelemetryImportError  `json:"errors"`
+}
+
+// TelemetryImportMetric — итог импорта одной метрики. Imported и Duplicates
+// заполняются только при записи.
+type TelemetryImportMetric struct {
+	Metric string `json:"metric"`
+	// Samples — принято из файла, Flagged — из них отбраковано проверкой достоверности
+	Samples int `json:"samples"`
+	Flagged int `json:"flagged"`
+	// Imported — записано, Duplicates — пропущено: замер за это время у хаба уже есть
+	Imported   int `json:"imported"`
+	Duplicates int `json:"duplicates"`
+}
+
+type TelemetryImportError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column"`
+	Message string `json:"message"`
 }
 
 type Hive struct {
@@ -76,14 +148,22 @@ type HiveListItem struct {
 	Sensor string `json:"sensor"`
 	Hub    string `json:"hub"`
 	Queen  string `json:"queen"`
+	Apiary string `json:"apiary"`
+	// Owner — почта владельца улья, Role — роль пользователя в улье
+	Owner string `json:"owner"`
+	Role  string `json:"role"`
 }
 
 type HiveDetails struct {
-	Name   string `json:"name"`
-	Active bool   `json:"active"`
-	Sensor string `json:"sensor"`
-	Hub    string `json:"hub"`
-	Queen  string `json:"queen"`
+	Name       string `json:"name"`
+	Active     bool   `json:"active"`
+	Sensor     string `json:"sensor"`
+	Hub        string `json:"hub"`
+	Queen      string `json:"queen"`
+	Apiary     string `json:"apiary"`
+	SeasonMode string `json:"season_mode"`
+	Owner      string `json:"owner"`
+	Role       string `json:"role"`
 }
 
 type CreateHive struct {
@@ -92,19 +172,146 @@ type CreateHive struct {
 }
 
 type UpdateHive struct {
-	OldName string  `json:"old_name"`
+	OldName string `json:"old_name"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner   string  `json:"owner,omitempty"`
 	NewName *string `json:"new_name,omitempty"`
 	Active  *bool   `json:"active"`
 	Sensor  *string `json:"sensor,omitempty"`
+	// SeasonMode — auto, summer или winter
+	SeasonMode *string `json:"season_mode,omitempty"`
 }
 
 type DeleteHive struct {
 	Name string `json:"name"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
 }
 
+// TelemetryDataPoint — точка графика. Для агрегированных данных (resolution hour/day)
+// Value — среднее за интервал, а Min/Max/Count заполнены; для сырых данных они опущены.
+//
+// Quality у сырых данных выставлен только для отбракованных замеров
+// (out_of_range, spike, stuck), агрегаты считаются без них.
 type TelemetryDataPoint struct {
+	Time    int64    `json:"time"`
+	Value   float64  `json:"value"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Count   int      `json:"count,omitempty"`
+	Quality string   `json:"quality,omitempty"`
+}
+
+// TelemetryStats — статистика одной метрики хаба (/api/telemetry/stats).
+type TelemetryStats struct {
+	Metric  string                 `json:"metric"`
+	Buckets []TelemetryStatsBucket `json:"buckets"`
+}
+
+// TelemetryStatsBucket — статистика за интервал группировки; Time — его начало (Unix).
+// Percentiles — по ключам вида p10, p50, p90.
+type TelemetryStatsBucket struct {
+	Time        int64              `json:"time"`
+	Count       int                `json:"count"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Avg         float64            `json:"avg"`
+	StdDev      float64            `json:"stddev"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	First       float64            `json:"first"`
+	FirstTime   int64              `json:"first_time"`
+	Last        float64            `json:"last"`
+	LastTime    int64              `json:"last_time"`
+}
+
+// WeightAnalytics — аналитика веса улья (/api/telemetry/weight/analytics).
+// Даты — сутки по часовому поясу пользователя в формате YYYY-MM-DD.
+type WeightAnalytics struct {
+	Days        []WeightDay        `json:"days"`
+	NectarFlows []NectarFlow       `json:"nectar_flows"`
+	Consumption *WeightConsumption `json:"consumption,omitempty"`
+	Steps       []WeightStep       `json:"steps"`
+}
+
+// WeightDay — привес за сутки без учёта ступенек и вес улья на конец суток.
+type WeightDay struct {
+	Date   string  `json:"date"`
+	Net    float64 `json:"net"`
+	Weight float64 `json:"weight"`
+}
+
+// NectarFlow — период медосбора и суммарный привес за него.
+type NectarFlow struct {
+	From string  `json:"from"`
+	To   string  `json:"to"`
+	Gain float64 `json:"gain"`
+}
+
+// WeightConsumption — расход кормов. Stores и RunOut есть, только если передан вес пустого улья.
+type WeightConsumption struct {
+	PerDay float64  `json:"per_day"`
+	Days   int      `json:"days"`
+	Stores *float64 `json:"stores,omitempty"`
+	RunOut *string  `json:"run_out,omitempty"`
+}
+
+// WeightStep — ступенька веса. SuggestedEvents — что её могло вызвать;
+// Recorded — событие рядом с ней уже есть в хронологии улья.
+type WeightStep struct {
+	Time            int64    `json:"time"`
+	Before          float64  `json:"before"`
+	After           float64  `json:"after"`
+	Delta           float64  `json:"delta"`
+	SuggestedEvents []string `json:"suggested_events"`
+	Recorded        bool     `json:"recorded"`
+}
+
+// WeightStepEvent — запись найденной ступеньки веса в хронологию улья.
+type WeightStepEvent struct {
+	Hub   string  `json:"hub"`
 	Time  int64   `json:"time"`
-	Value float64 `json:"value"`
+	Type  string  `json:"type"`
+	Title string  `json:"title"`
+	Delta float64 `json:"delta"`
+}
+
+// SpectrumBand — уровень шума в полосе частот [Low, High) Гц.
+type SpectrumBand struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Level float64 `json:"level"`
+}
+
+// TelemetrySpectrumPoint — спектр шума в момент Time. RoarRatio — доля энергии
+// в полосе 200–500 Гц («рёв» безматочной семьи), 0..1.
+type TelemetrySpectrumPoint struct {
+	Time      int64          `json:"time"`
+	Bands     []SpectrumBand `json:"bands"`
+	RoarRatio float64        `json:"roar_ratio"`
+}
+
+// Типы событий потока /api/telemetry/stream.
+const (
+	TelemetryEventData   = "data"
+	TelemetryEventBatch  = "batch"
+	TelemetryEventStatus = "status"
+)
+
+// TelemetryEvent — событие потока телеметрии. Data — полезная нагрузка устройства
+// как есть: DeviceData, DeviceDataBatch или DeviceStatus в зависимости от Type.
+// HubID — хаб, которому принадлежит событие: по нему брокер выбирает подписчиков.
+type TelemetryEvent struct {
+	HubID int             `json:"hub_id"`
+	Hub   string          `json:"hub"`
+	Type  string          `json:"type"`
+	Time  int64           `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// StreamTicket — одноразовый билет на подключение к потоку телеметрии.
+type StreamTicket struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
 }
 
 type LastSensorReading struct {
@@ -114,6 +321,8 @@ type LastSensorReading struct {
 	NoiseTime       int64   `json:"noise_time"`
 	Weight          float64 `json:"weight"`
 	WeightTime      int64   `json:"weight_time"`
+	// Spectrum — последний спектр шума (снят вместе с Noise), если прошивка его шлёт
+	Spectrum []SpectrumBand `json:"spectrum,omitempty"`
 }
 
 type CreateHub struct {
@@ -121,14 +330,68 @@ type CreateHub struct {
 	Name string `json:"name"`
 }
 
+// HubSecret — секрет устройства для подключения к MQTT-брокеру.
+// Показывается один раз: при создании хаба и при перевыпуске секрета.
+type HubSecret struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type RotateHubSecret struct {
+	ID string `json:"id"`
+}
+
+// MQTTAuthRequest — запрос HTTP-бэкенда mosquitto-go-auth
+// (auth_opt_http_params_mode json). Для /user заполнены username, password и clientid,
+// для /superuser — только username, для /acl — username, clientid, topic и acc.
+type MQTTAuthRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ClientID string `json:"clientid"`
+	Topic    string `json:"topic"`
+	Acc      int    `json:"acc"`
+}
+
+// ShadowConfig — настраиваемые параметры хаба в device shadow (в секундах).
+// -1 — параметр не задан / не меняется.
+type ShadowConfig struct {
+	SamplingNoise int `json:"sampling_rate_noise"`
+	SamplingTemp  int `json:"sampling_rate_temperature"`
+	Frequency     int `json:"frequency_status"`
+}
+
+type SetHubShadow struct {
+	ID      string       `json:"id"`
+	Desired ShadowConfig `json:"desired"`
+}
+
+// HubShadow — состояние синхронизации конфигурации хаба.
+// Pending — параметры, которые ещё не применены устройством и будут
+// отправлены ему при следующем статусе.
+type HubShadow struct {
+	ID         string       `json:"id"`
+	Desired    ShadowConfig `json:"desired"`
+	Reported   ShadowConfig `json:"reported"`
+	Pending    ShadowConfig `json:"pending"`
+	InSync     bool         `json:"in_sync"`
+	DesiredAt  *time.Time   `json:"desired_at,omitempty"`
+	ReportedAt *time.Time   `json:"reported_at,omitempty"`
+}
+
 type HubListItem struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Online — датчик выходит на связь в ожидаемом интервале (см. analyzer/watchdog)
+	Online bool `json:"online"`
+	// LastSeen — время последнего пакета от датчика (UNIX Seconds), нет — ни разу не выходил на связь
+	LastSeen *int64 `json:"last_seen,omitempty"`
 }
 
 type HubDetails struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Online   bool   `json:"online"`
+	LastSeen *int64 `json:"last_seen,omitempty"`
 }
 
 type UpdateHub struct {
@@ -161,13 +424,61 @@ type UpdateQueen struct {
 	StartDate *string `json:"start_date,omitempty"`
 }
 
+// CreateApiary — новая пасека. Timezone — IANA-зона (Europe/Moscow), по умолчанию UTC.
+type CreateApiary struct {
+	Name      string   `json:"name"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+	Timezone  string   `json:"timezone,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+}
+
+// UpdateApiary меняет переданные поля пасеки.
+type UpdateApiary struct {
+	OldName   string   `json:"old_name"`
+	NewName   *string  `json:"new_name,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+	Timezone  *string  `json:"timezone,omitempty"`
+	Notes     *string  `json:"notes,omitempty"`
+}
+
+// ApiaryListItem — пасека со сводкой по её ульям: сколько ульев, открытых
+// инцидентов и хабов на связи.
+type ApiaryListItem struct {
+	Name          string   `json:"name"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	Altitude      *float64 `json:"altitude"`
+	Timezone      string   `json:"timezone"`
+	Notes         string   `json:"notes"`
+	HiveCount     int      `json:"hive_count"`
+	ActiveAlerts  int      `json:"active_alerts"`
+	SensorsOnline int      `json:"sensors_online"`
+	SensorsTotal  int      `json:"sensors_total"`
+	Owner         string   `json:"owner"`
+	Role          string   `json:"role"`
+}
+
+// ApiaryDetails — пасека со сводкой и списком ульев.
+type ApiaryDetails struct {
+	ApiaryListItem
+	Hives []HiveListItem `json:"hives"`
+}
+
 type LinkToHiveRequest struct {
 	HiveName   string `json:"hive_name"`
 	TargetName string `json:"target_name"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
 }
 
 type CreateTaskRequest struct {
-	HiveName    string `json:"hive_name"`
+	HiveName string `json:"hive_name"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner       string `json:"owner,omitempty"`
 	Title       string `json:"title"`
 	Description string `json:"description,omitempty"`
 }
@@ -229,3 +540,220 @@ type UpdateInstructionItemRequest struct {
 type ReorderInstructionItemsRequest struct {
 	Order []string `json:"order"`
 }
+
+type AnalyzerRun struct {
+	ID             int      `json:"id"`
+	Analyzer       string   `json:"analyzer"`
+	StartedAt      string   `json:"started_at"`
+	FinishedAt     string   `json:"finished_at"`
+	DurationMs     int64    `json:"duration_ms"`
+	HivesProcessed int      `json:"hives_processed"`
+	AlertsRaised   int      `json:"alerts_raised"`
+	Errors         []string `json:"errors"`
+}
+
+// AlertThresholds — пороги алертов. Поле null — значение не задано и наследуется.
+type AlertThresholds struct {
+	NoiseHigh            *float64 `json:"noise_high"`
+	NoiseDelta           *float64 `json:"noise_delta"`
+	TemperatureNormal    *float64 `json:"temperature_normal"`
+	TemperatureDeltaUp   *float64 `json:"temperature_delta_up"`
+	TemperatureDeltaDown *float64 `json:"temperature_delta_down"`
+	BatteryLow           *int     `json:"battery_low"`
+	SignalLow            *int     `json:"signal_low"`
+}
+
+// SetAlertRulesRequest — правила для улья; без hive — правила пользователя по умолчанию.
+type SetAlertRulesRequest struct {
+	Hive string `json:"hive,omitempty"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
+	AlertThresholds
+}
+
+type DeleteAlertRulesRequest struct {
+	Hive string `json:"hive,omitempty"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
+}
+
+// AlertRules — сохранённые переопределения и действующие с их учётом пороги.
+type AlertRules struct {
+	Hive      string          `json:"hive,omitempty"`
+	Owner     string          `json:"owner,omitempty"`
+	Rules     AlertThresholds `json:"rules"`
+	Effective AlertThresholds `json:"effective"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+}
+
+type Notification struct {
+	ID        int64  `json:"id"`
+	Hive      string `json:"hive,omitempty"`
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	ReadAt    string `json:"read_at,omitempty"`
+}
+
+// NotificationsPage — страница входящих уведомлений; Total и Unread считаются по всем записям.
+type NotificationsPage struct {
+	Items  []Notification `json:"items"`
+	Total  int            `json:"total"`
+	Unread int            `json:"unread"`
+}
+
+type MarkNotificationsReadRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type DeleteNotificationRequest struct {
+	ID int64 `json:"id"`
+}
+
+// NotificationPreferences — настройки уведомлений пользователя. Без webhook_secret сервер
+// сохраняет прежний секрет или генерирует новый.
+type NotificationPreferences struct {
+	Channels      []string                       `json:"channels"`
+	WebhookURL    string                         `json:"webhook_url,omitempty"`
+	WebhookSecret string                         `json:"webhook_secret,omitempty"`
+	AlertTypes    map[string]AlertTypePreference `json:"alert_types,omitempty"`
+	MinSeverity   string                         `json:"min_severity,omitempty"`
+	Timezone      string                         `json:"timezone,omitempty"`
+	QuietHours    []QuietHours                   `json:"quiet_hours,omitempty"`
+	Digest        string                         `json:"digest,omitempty"`
+	UpdatedAt     string                         `json:"updated_at,omitempty"`
+}
+
+type AlertTypePreference struct {
+	Enabled  bool     `json:"enabled"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// InviteMember — приглашение по почте на пасеку (apiary) или в улей (hive); задаётся одно из двух.
+type InviteMember struct {
+	Email  string `json:"email"`
+	Apiary string `json:"apiary,omitempty"`
+	Hive   string `json:"hive,omitempty"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
+	Role  string `json:"role"`
+}
+
+type UpdateMember struct {
+	Email  string `json:"email"`
+	Apiary string `json:"apiary,omitempty"`
+	Hive   string `json:"hive,omitempty"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
+	Role  string `json:"role"`
+}
+
+type RemoveMember struct {
+	Email  string `json:"email"`
+	Apiary string `json:"apiary,omitempty"`
+	Hive   string `json:"hive,omitempty"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner string `json:"owner,omitempty"`
+}
+
+// MemberItem — участник; для улья в список попадают и участники его пасеки (с заполненным Apiary).
+type MemberItem struct {
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	Apiary string `json:"apiary,omitempty"`
+	Hive   string `json:"hive,omitempty"`
+	// Since — когда участник принял приглашение; у владельца не задано
+	Since int64 `json:"since,omitempty"`
+}
+
+type InvitationItem struct {
+	ID        int64  `json:"id"`
+	Apiary    string `json:"apiary,omitempty"`
+	Hive      string `json:"hive,omitempty"`
+	Role      string `json:"role"`
+	InvitedBy string `json:"invited_by"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// InvitationAnswer — ответ на приглашение: по id из списка приглашений или по коду из письма.
+type InvitationAnswer struct {
+	ID    int64  `json:"id,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+// CreateInspection — осмотр улья hive. Date — день осмотра (YYYY-MM-DD), пусто — сегодня;
+// незаполненные показатели не оценивались.
+type CreateInspection struct {
+	Hive string `json:"hive"`
+	// Owner — почта владельца улья; нужна, когда у участника есть улей с тем же именем
+	Owner        string   `json:"owner,omitempty"`
+	Date         string   `json:"date,omitempty"`
+	BroodFrames  *float64 `json:"brood_frames,omitempty"`
+	HoneyFrames  *float64 `json:"honey_frames,omitempty"`
+	PollenFrames *float64 `json:"pollen_frames,omitempty"`
+	QueenSeen    *bool    `json:"queen_seen,omitempty"`
+	EggsSeen     *bool    `json:"eggs_seen,omitempty"`
+	QueenCells   *int     `json:"queen_cells,omitempty"`
+	Temperament  string   `json:"temperament,omitempty"`
+	VarroaCount  *int     `json:"varroa_count,omitempty"`
+	VarroaMethod string   `json:"varroa_method,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+}
+
+// UpdateInspection меняет только переданные поля осмотра.
+type UpdateInspection struct {
+	ID           int64    `json:"id"`
+	Date         *string  `json:"date,omitempty"`
+	BroodFrames  *float64 `json:"brood_frames,omitempty"`
+	HoneyFrames  *float64 `json:"honey_frames,omitempty"`
+	PollenFrames *float64 `json:"pollen_frames,omitempty"`
+	QueenSeen    *bool    `json:"queen_seen,omitempty"`
+	EggsSeen     *bool    `json:"eggs_seen,omitempty"`
+	QueenCells   *int     `json:"queen_cells,omitempty"`
+	Temperament  *string  `json:"temperament,omitempty"`
+	VarroaCount  *int     `json:"varroa_count,omitempty"`
+	VarroaMethod *string  `json:"varroa_method,omitempty"`
+	Notes        *string  `json:"notes,omitempty"`
+}
+
+type DeleteInspection struct {
+	ID int64 `json:"id"`
+}
+
+type InspectionItem struct {
+	ID           int64    `json:"id"`
+	Hive         string   `json:"hive"`
+	Owner        string   `json:"owner"`
+	Date         string   `json:"date"`
+	BroodFrames  *float64 `json:"brood_frames,omitempty"`
+	HoneyFrames  *float64 `json:"honey_frames,omitempty"`
+	PollenFrames *float64 `json:"pollen_frames,omitempty"`
+	QueenSeen    *bool    `json:"queen_seen,omitempty"`
+	EggsSeen     *bool    `json:"eggs_seen,omitempty"`
+	QueenCells   *int     `json:"queen_cells,omitempty"`
+	Temperament  string   `json:"temperament,omitempty"`
+	VarroaCount  *int     `json:"varroa_count,omitempty"`
+	VarroaMethod string   `json:"varroa_method,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+	Author       string   `json:"author,omitempty"`
+	CreatedAt    int64    `json:"created_at"`
+}
+
+// TimelineItem — запись хронологии улья: осмотр (kind = inspection) или событие
+// (kind = event), найденное анализатором или записанное пасечником.
+type TimelineItem struct {
+	Kind       string          `json:"kind"`
+	Time       int64           `json:"time"`
+	Type       string          `json:"type,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Title      string          `json:"title"`
+	Details    map[string]any  `json:"details,omitempty"`
+	Inspection *InspectionItem `json:"inspection,omitempty"`
+}
@@ -23,3 +23,19 @@ func TestNewDeviceConfig_Defaults(t *testing.T) {
 		t.Fatalf("expected Delete false")
 	}
 }
+
+func TestSpectrum_Valid(t *testing.T) {
+	if !(Spectrum{Edges: []float64{100, 200, 300}, Levels: []float64{40, 42}}).Valid() {
+		t.Fatalf("expected valid spectrum")
+	}
+	if (Spectrum{Edges: []float64{100, 300, 200}, Levels: []float64{40, 42}}).Valid() {
+		t.Fatalf("expected unordered edges to be rejected")
+	}
+	edges := make([]float64, MaxSpectrumBands+2)
+	for i := range edges {
+		edges[i] = float64(i * 10)
+	}
+	if (Spectrum{Edges: edges, Levels: make([]float64, len(edges)-1)}).Valid() {
+		t.Fatalf("expected more than %d bands to be rejected", MaxSpectrumBands)
+	}
+}
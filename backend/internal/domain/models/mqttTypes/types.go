@@ -20,6 +20,74 @@ type DeviceData struct {
 
 	// WeightTime - метка времени измерения веса (UNIX Seconds)
 	WeightTime int64 `json:"weight_time"`
+
+	// Spectrum - уровни шума по полосам частот, снятые в том же окне, что и Noise
+	// (метка времени — NoiseTime). Необязательное поле: его шлёт только прошивка с FFT
+	Spectrum *Spectrum `json:"spectrum,omitempty"`
+}
+
+// Spectrum — спектр шума в улье, сведённый к полосам частот.
+// Полоса i занимает [Edges[i], Edges[i+1]) Гц, её уровень — Levels[i] в дБ,
+// поэтому len(Levels) == len(Edges)-1. Набор полос задаёт прошивка.
+type Spectrum struct {
+	// Edges - границы полос в Гц по возрастанию
+	Edges []float64 `json:"edges"`
+
+	// Levels - уровень в каждой полосе в децибелах
+	Levels []float64 `json:"levels"`
+}
+
+// MaxSpectrumBands — больше полос прошивка не шлёт; ограничение не даёт одному пакету
+// раздуть строку noise_spectrum.
+const MaxSpectrumBands = 64
+
+// Valid проверяет, что полос не больше MaxSpectrumBands, границы полос возрастают
+// и каждой полосе соответствует уровень.
+func (s Spectrum) Valid() bool {
+	if len(s.Edges) < 2 || len(s.Edges) > MaxSpectrumBands+1 || len(s.Levels) != len(s.Edges)-1 || s.Edges[0] < 0 {
+		return false
+	}
+	for i := 1; i < len(s.Edges); i++ {
+		if s.Edges[i] <= s.Edges[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// SpectrumSample — спектр с меткой времени, элемент пакета DeviceDataBatch
+type SpectrumSample struct {
+	Spectrum
+
+	// Time - метка времени замера (UNIX Seconds)
+	Time int64 `json:"time"`
+}
+
+// Sample — один замер метрики с меткой времени, элемент пакета DeviceDataBatch
+type Sample struct {
+	// Value - значение замера. Значение -1 означает отсутствие данных
+	Value float64 `json:"value"`
+
+	// Time - метка времени замера (UNIX Seconds)
+	Time int64 `json:"time"`
+}
+
+// DeviceDataBatch представляет пакет накопленных замеров (топик /device/{id}/data)
+// Прошивка отправляет его при выгрузке оффлайн-буфера (ring_buffer.py): вместо
+// одного значения на метрику приходит массив замеров с метками времени.
+// Отличается от DeviceData тем, что поля temperature/noise/weight — массивы.
+type DeviceDataBatch struct {
+	// Temperature - замеры температуры в цельсиях
+	Temperature []Sample `json:"temperature"`
+
+	// Noise - замеры уровня шума в децибелах
+	Noise []Sample `json:"noise"`
+
+	// Weight - замеры веса улья в кг
+	Weight []Sample `json:"weight"`
+
+	// Spectrum - спектры шума (необязательно)
+	Spectrum []SpectrumSample `json:"spectrum"`
 }
 
 // DeviceStatus представляет статус датчика (топик /device/{id}/status)
@@ -36,6 +104,23 @@ type DeviceStatus struct {
 
 	// Errors - массив текстовых описаний ошибок. Пустой массив, если ошибок нет
 	Errors []string `json:"errors"`
+
+	// Config - применённая устройством конфигурация. nil, если прошивка её не сообщает
+	Config *AppliedConfig `json:"config,omitempty"`
+}
+
+// AppliedConfig представляет конфигурацию, которую устройство реально применило
+// (топик /device/{id}/config/ack или поле config в DeviceStatus).
+// Сервер сравнивает её с желаемой конфигурацией хаба (device shadow).
+type AppliedConfig struct {
+	// SamplingNoise - частота сбора данных о шуме в секундах. Значение -1 означает отсутствие данных
+	SamplingNoise int `json:"sampling_rate_noise"`
+
+	// SamplingTemp - частота сбора данных о температуре в секундах. Значение -1 означает отсутствие данных
+	SamplingTemp int `json:"sampling_rate_temperature"`
+
+	// Frequency - частота отправки статуса в секундах. Значение -1 означает отсутствие данных
+	Frequency int `json:"frequency_status"`
 }
 
 // DeviceConfig представляет конфигурацию для датчика (топик /device/{id}/config)
@@ -0,0 +1,36 @@
+package notifyTypes
+
+// Каналы доставки уведомлений.
+const (
+	ChannelPush    = "push"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Message — уведомление, независимое от канала доставки. JSON-теги — для хранения
+// придержанных на тихие часы уведомлений.
+type Message struct {
+	// Type — тип алерта (см. alerts.Type*)
+	Type     string `json:"type"`
+	Hive     string `json:"hive,omitempty"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	// Data — служебные поля для приложения (улей, notification_id и т.п.)
+	Data      map[string]string `json:"data,omitempty"`
+	Important bool              `json:"important,omitempty"`
+}
+
+// Held — уведомление, отложенное на тихие часы. ID — строка в held_notifications.
+type Held struct {
+	ID      int64
+	Message Message
+}
+
+// Recipient — кому и куда доставлять уведомление.
+type Recipient struct {
+	Email string
+	// WebhookURL и WebhookSecret — из настроек пользователя; пустой URL — вебхука нет
+	WebhookURL    string
+	WebhookSecret string
+}
@@ -0,0 +1,173 @@
+package mqtt
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/models/mqttTypes"
+	"BeeIOT/internal/domain/rollup"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// isBatchPayload определяет, пришёл ли в топик data пакет оффлайн-бэклога:
+// в пакете хотя бы одна из метрик передаётся массивом, а не числом.
+func isBatchPayload(payload []byte) bool {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return false
+	}
+	for _, key := range []string{"temperature", "noise", "weight"} {
+		if v, ok := raw[key]; ok && bytes.HasPrefix(bytes.TrimSpace(v), []byte("[")) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDeviceDataBatch записывает пакет накопленных замеров. Алерты по бэклогу не
+// отправляются — это исторические данные, а дубликаты при повторной выгрузке
+// отбрасываются на уровне БД по UNIQUE(hub_id, recorded_at).
+func (m *Client) handleDeviceDataBatch(topic, sensorId string, payload []byte) {
+	var batch mqttTypes.DeviceDataBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to unmarshal batch payload")
+		return
+	}
+
+	m.logger.Info().
+		Str("sensor", sensorId).
+		Int("temperature", len(batch.Temperature)).
+		Int("noise", len(batch.Noise)).
+		Int("weight", len(batch.Weight)).
+		Int("spectrum", len(batch.Spectrum)).
+		Msg("Received device data batch")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	exist, err := m.inMemDb.ExistSensor(ctx, sensorId)
+	if err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to check existence of sensor")
+		return
+	}
+	if !exist {
+		m.logger.Error().Str("topic", topic).Msg("Sensor does not exist")
+		return
+	}
+	if err := m.inMemDb.UpdateSensorTimestamp(ctx, sensorId, time.Now().Unix()); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to update timestamp")
+		return
+	}
+	m.cacheLatestFromBatch(ctx, sensorId, batch)
+
+	email, _, hubSensor, err := m.resolveSensorOwner(ctx, sensorId)
+	if err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Str("sensor", sensorId).Msg("Failed to resolve sensor owner")
+		return
+	}
+	if hubSensor == "" {
+		m.logger.Warn().Str("topic", topic).Str("sensor", sensorId).Msg("No hub for sensor, skipping telemetry storage")
+		return
+	}
+
+	if err := m.db.NewTemperatureBatch(ctx, m.checkBatchQuality(ctx, rollup.MetricTemperature, toTelemetryBatch(email, hubSensor, batch.Temperature))); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add temperature batch")
+	}
+	if err := m.db.NewNoiseBatch(ctx, m.checkBatchQuality(ctx, rollup.MetricNoise, toTelemetryBatch(email, hubSensor, batch.Noise))); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add noise batch")
+	}
+	if err := m.db.NewHiveWeightBatch(ctx, m.checkBatchQuality(ctx, rollup.MetricWeight, toTelemetryBatch(email, hubSensor, batch.Weight))); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add weight batch")
+	}
+	if err := m.db.NewNoiseSpectrumBatch(ctx, toSpectrumBatch(email, hubSensor, batch.Spectrum)); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add noise spectrum batch")
+	}
+	m.publishEvent(ctx, email, hubSensor, httpType.TelemetryEventBatch, payload)
+}
+
+// toTelemetryBatch переводит замеры из MQTT-пакета в формат записи в БД,
+// отбрасывая пустые значения (-1) и замеры без метки времени.
+func toTelemetryBatch(email, hubSensor string, samples []mqttTypes.Sample) httpType.TelemetryBatch {
+	batch := httpType.TelemetryBatch{Email: email, Hub: hubSensor}
+	for _, s := range samples {
+		if s.Value == -1 || s.Time == 0 {
+			continue
+		}
+		batch.Samples = append(batch.Samples, httpType.TelemetrySample{
+			Value: s.Value,
+			Time:  time.Unix(s.Time, 0),
+		})
+	}
+	return batch
+}
+
+// toSpectrumBatch переводит спектры из MQTT-пакета в формат записи в БД,
+// отбрасывая спектры без метки времени и с несогласованными полосами.
+func toSpectrumBatch(email, hubSensor string, samples []mqttTypes.SpectrumSample) []httpType.NoiseSpectrum {
+	var spectra []httpType.NoiseSpectrum
+	for _, s := range samples {
+		if s.Time == 0 || !s.Valid() {
+			continue
+		}
+		spectra = append(spectra, httpType.NoiseSpectrum{
+			Edges:  s.Edges,
+			Levels: s.Levels,
+			Time:   time.Unix(s.Time, 0),
+			Email:  email,
+			Hub:    hubSensor,
+		})
+	}
+	return spectra
+}
+
+// latestSample возвращает самый свежий валидный замер из пакета.
+func latestSample(samples []mqttTypes.Sample) (mqttTypes.Sample, bool) {
+	var latest mqttTypes.Sample
+	found := false
+	for _, s := range samples {
+		if s.Value == -1 || s.Time == 0 {
+			continue
+		}
+		if !found || s.Time > latest.Time {
+			latest = s
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// cacheLatestFromBatch обновляет кеш последних данных датчика, если в пакете
+// есть замеры свежее закешированных. Бэклог обычно старше живых данных,
+// поэтому метрики перезаписываются только по более новой метке времени.
+func (m *Client) cacheLatestFromBatch(ctx context.Context, sensorId string, batch mqttTypes.DeviceDataBatch) {
+	data := mqttTypes.DeviceData{Temperature: -1, Noise: -1, Weight: -1}
+	if existing, err := m.inMemDb.GetLastSensorData(ctx, sensorId); err == nil {
+		_ = json.Unmarshal([]byte(existing), &data)
+	}
+
+	changed := false
+	if s, ok := latestSample(batch.Temperature); ok && s.Time > data.TemperatureTime {
+		data.Temperature, data.TemperatureTime = s.Value, s.Time
+		changed = true
+	}
+	if s, ok := latestSample(batch.Noise); ok && s.Time > data.NoiseTime {
+		data.Noise, data.NoiseTime = s.Value, s.Time
+		changed = true
+	}
+	if s, ok := latestSample(batch.Weight); ok && s.Time > data.WeightTime {
+		data.Weight, data.WeightTime = s.Value, s.Time
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if err := m.inMemDb.SetLastSensorData(ctx, sensorId, string(b)); err != nil {
+		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to cache last sensor data")
+	}
+}
@@ -1,9 +1,12 @@
 package mqtt
 
 import (
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/models/httpType"
 	"BeeIOT/internal/domain/models/mqttTypes"
-	"BeeIOT/internal/domain/notification"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/rollup"
 	"context"
 	"encoding/json"
 	"errors"
@@ -24,6 +27,12 @@ func (m *Client) handleDeviceData(_ mqtt.Client, msg mqtt.Message) {
 	}
 	sensorId := parts[2]
 
+	// После оффлайна прошивка выгружает накопленный буфер пачкой
+	if isBatchPayload(msg.Payload()) {
+		m.handleDeviceDataBatch(topic, sensorId, msg.Payload())
+		return
+	}
+
 	var data mqttTypes.DeviceData
 	if err := json.Unmarshal(msg.Payload(), &data); err != nil {
 		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to unmarshal payload")
@@ -53,6 +62,7 @@ func (m *Client) handleDeviceData(_ mqtt.Client, msg mqtt.Message) {
 		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to update timestamp")
 		return
 	}
+	m.markOnline(ctx, sensorId)
 	// Cache last sensor data for quick retrieval, preserving weight from existing cache
 	cachePayload := msg.Payload()
 	if existing, err := m.inMemDb.GetLastSensorData(ctx, sensorId); err == nil {
@@ -75,8 +85,14 @@ func (m *Client) handleDeviceData(_ mqtt.Client, msg mqtt.Message) {
 		return
 	}
 
+	// Достоверность шума нужна уже для алерта: отвалившийся микрофон не должен будить пасечника.
+	noiseQuality := quality.OK
+	if data.Noise != -1 {
+		noiseQuality = m.checkQuality(ctx, email, hubSensor, rollup.MetricNoise, data.Noise, time.Unix(data.NoiseTime, 0))
+	}
+
 	// Если улей найден — отправляем пуш про высокий шум (если порог превышен).
-	if hiveName != "" {
+	if hiveName != "" && noiseQuality == quality.OK {
 		if err := m.checkNoiseLevel(ctx, email, hiveName, data); err != nil {
 			m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to check noise level")
 		}
@@ -87,15 +103,19 @@ func (m *Client) handleDeviceData(_ mqtt.Client, msg mqtt.Message) {
 		m.logger.Warn().Str("topic", topic).Str("sensor", sensorId).Msg("No hub for sensor, skipping telemetry storage")
 		return
 	}
-	if err := m.addNoise(ctx, email, hubSensor, data); err != nil {
+	if err := m.addNoise(ctx, email, hubSensor, data, noiseQuality); err != nil {
 		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add noise")
 	}
+	if err := m.addSpectrum(ctx, email, hubSensor, data); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add noise spectrum")
+	}
 	if err := m.addTemperature(ctx, email, hubSensor, data); err != nil {
 		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add temperature")
 	}
 	if err := m.addWeight(ctx, email, hubSensor, data); err != nil {
 		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to add weight")
 	}
+	m.publishEvent(ctx, email, hubSensor, httpType.TelemetryEventData, cachePayload)
 }
 
 // resolveSensorOwner находит email пользователя, имя улья и идентификатор hub-сенсора
@@ -124,15 +144,34 @@ func (m *Client) resolveSensorOwner(ctx context.Context, sensorId string) (email
 	return e, "", sensorId, nil
 }
 
-func (m *Client) addNoise(ctx context.Context, email, hubSensor string, data mqttTypes.DeviceData) error {
+func (m *Client) addNoise(ctx context.Context, email, hubSensor string, data mqttTypes.DeviceData, q string) error {
 	if data.Noise == -1 {
 		return nil
 	}
 	return m.db.NewNoise(ctx, httpType.NoiseLevel{
-		Level: data.Noise,
-		Time:  time.Unix(data.NoiseTime, 0),
-		Email: email,
-		Hub:   hubSensor,
+		Level:   data.Noise,
+		Time:    time.Unix(data.NoiseTime, 0),
+		Email:   email,
+		Hub:     hubSensor,
+		Quality: q,
+	})
+}
+
+// addSpectrum сохраняет спектр шума, если прошивка его прислала. Спектр снят
+// в том же окне, что и уровень шума, поэтому время берётся из NoiseTime.
+func (m *Client) addSpectrum(ctx context.Context, email, hubSensor string, data mqttTypes.DeviceData) error {
+	if data.Spectrum == nil || data.NoiseTime == 0 {
+		return nil
+	}
+	if !data.Spectrum.Valid() {
+		return fmt.Errorf("invalid spectrum: %d edges, %d levels", len(data.Spectrum.Edges), len(data.Spectrum.Levels))
+	}
+	return m.db.NewNoiseSpectrum(ctx, httpType.NoiseSpectrum{
+		Edges:  data.Spectrum.Edges,
+		Levels: data.Spectrum.Levels,
+		Time:   time.Unix(data.NoiseTime, 0),
+		Email:  email,
+		Hub:    hubSensor,
 	})
 }
 
@@ -140,11 +179,13 @@ func (m *Client) addTemperature(ctx context.Context, email, hubSensor string, da
 	if data.Temperature == -1 {
 		return nil
 	}
+	t := time.Unix(data.TemperatureTime, 0)
 	return m.db.NewTemperature(ctx, httpType.Temperature{
 		Temperature: data.Temperature,
-		Time:        time.Unix(data.TemperatureTime, 0),
+		Time:        t,
 		Email:       email,
 		Hub:         hubSensor,
+		Quality:     m.checkQuality(ctx, email, hubSensor, rollup.MetricTemperature, data.Temperature, t),
 	})
 }
 
@@ -152,11 +193,13 @@ func (m *Client) addWeight(ctx context.Context, email, hubSensor string, data mq
 	if data.Weight == -1 || data.WeightTime == 0 {
 		return nil
 	}
+	t := time.Unix(data.WeightTime, 0)
 	return m.db.NewHiveWeight(ctx, httpType.HubWeight{
-		Weight: data.Weight,
-		Time:   time.Unix(data.WeightTime, 0),
-		Email:  email,
-		Hub:    hubSensor,
+		Weight:  data.Weight,
+		Time:    t,
+		Email:   email,
+		Hub:     hubSensor,
+		Quality: m.checkQuality(ctx, email, hubSensor, rollup.MetricWeight, data.Weight, t),
 	})
 }
 
@@ -182,6 +225,84 @@ func (m *Client) handleDeviceStatus(_ mqtt.Client, msg mqtt.Message) {
 	}
 
 	m.handlingStatusData(DeviceStatus, sensorId)
+	m.publishStatusEvent(sensorId, msg.Payload())
+}
+
+// handleDeviceLWT обработчик топика /device/{id}/lwt.
+// Брокер публикует сюда Last Will датчика, если тот пропал без штатного disconnect
+// (села батарея, оборвалась связь посреди цикла). Не дожидаясь watchdog-а,
+// помечаем датчик офлайн и шлём одно уведомление на отключение.
+func (m *Client) handleDeviceLWT(_ mqtt.Client, msg mqtt.Message) {
+	topic := msg.Topic()
+	parts := strings.Split(topic, "/")
+	if len(parts) != 4 || parts[1] != "device" || parts[3] != "lwt" {
+		m.logger.Error().Str("topic", topic).Msg("Invalid topic format")
+		return
+	}
+	sensorId := parts[2]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exist, err := m.inMemDb.ExistSensor(ctx, sensorId)
+	if err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to check existence of sensor")
+		return
+	}
+	if !exist {
+		m.logger.Warn().Str("topic", topic).Msg("LWT from unknown sensor")
+		return
+	}
+
+	isNew, err := m.inMemDb.MarkSensorOffline(ctx, sensorId)
+	if err != nil {
+		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to mark sensor offline")
+		return
+	}
+	m.logger.Warn().Str("sensor", sensorId).Bool("new_outage", isNew).Msg("Received sensor last will")
+	if !isNew {
+		return
+	}
+
+	email, hive, _, err := m.resolveSensorOwner(ctx, sensorId)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("sensor", sensorId).Msg("Failed to resolve sensor owner for offline notification")
+		return
+	}
+	if hive == "" {
+		m.logger.Warn().Str("sensor", sensorId).Msg("Sensor is not linked to any hive, skipping offline notification")
+		return
+	}
+	if err := m.notifySensorOffline(ctx, sensorId, email, hive); err != nil {
+		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to send offline notification")
+	}
+}
+
+// markOnline снимает отметку «офлайн» при любом пакете от датчика.
+func (m *Client) markOnline(ctx context.Context, sensorId string) {
+	wasOffline, err := m.inMemDb.MarkSensorOnline(ctx, sensorId)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("sensor", sensorId).Msg("Failed to mark sensor online")
+		return
+	}
+	if !wasOffline {
+		return
+	}
+	m.logger.Info().Str("sensor", sensorId).Msg("Sensor is back online")
+
+	email, hive, _, err := m.resolveSensorOwner(ctx, sensorId)
+	if err != nil || hive == "" {
+		return
+	}
+	err = m.clearAlert(ctx, alerts.Alert{Type: alerts.TypeSensorOffline, Email: email, Hive: hive,
+		Data: notifyTypes.Message{
+			Title: fmt.Sprintf("Датчик в улье %s снова на связи", hive),
+			Body:  "Датчик возобновил передачу данных.",
+			Data:  map[string]string{"hive": hive, "sensor": sensorId},
+		}})
+	if err != nil {
+		m.logger.Warn().Err(err).Str("sensor", sensorId).Msg("Failed to resolve sensor offline alert")
+	}
 }
 
 // SendConfig отправляет конфигурацию датчику через топик /device/{id}/config
@@ -192,9 +313,32 @@ func (m *Client) SendConfig(deviceID string, config mqttTypes.DeviceConfig) erro
 		return fmt.Errorf("failed to publish config to device %s: %w", deviceID, err)
 	}
 	m.logger.Info().Msgf("Published config to device %s", deviceID)
+
+	// Запоминаем, как часто датчик должен выходить на связь — по этому интервалу
+	// watchdog решает, что датчик пропал.
+	if interval := expectedInterval(config); interval > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.inMemDb.SetSensorInterval(ctx, deviceID, interval); err != nil {
+			m.logger.Warn().Err(err).Str("sensor", deviceID).Msg("Failed to save sensor interval")
+		}
+	}
 	return nil
 }
 
+// expectedInterval возвращает наибольший из заданных в конфиге интервалов (в секундах).
+// Датчик публикует данные и статус за один цикл, поэтому на связь он выходит
+// не реже самого длинного из них. 0 — конфиг интервалы не меняет.
+func expectedInterval(config mqttTypes.DeviceConfig) int64 {
+	interval := 0
+	for _, v := range []int{config.SamplingNoise, config.SamplingTemp, config.Frequency} {
+		if v > interval {
+			interval = v
+		}
+	}
+	return int64(interval)
+}
+
 func (m *Client) publishJSON(client mqtt.Client, topic string, qos byte, retained bool, v any) error {
 	data, err := json.Marshal(v)
 	if err != nil {
@@ -213,16 +357,23 @@ func (m *Client) publishJSON(client mqtt.Client, topic string, qos byte, retaine
 	return nil
 }
 
-const (
-	batteryLowThreshold = 20
-	signalLowThreshold  = 10
-	// noiseHighThreshold — в дБ SPL. Фоновый шум здоровой пчелиной семьи
-	// сам по себе 60–75 дБ, поэтому пороги в районе 50–60 дБ давали бы
-	// постоянный спам. Реальная аномалия (роение, проникновение в улей,
-	// массовое возбуждение) — это уже 80+ дБ; на нём и поднимаем алерт.
-	noiseHighThreshold    = 80
-	defaultSamplingPeriod = 5 // seconds
-)
+const defaultSamplingPeriod = 5 // seconds
+
+// rulesFor возвращает пороги алертов улья (см. alerts.Rules). При сбое чтения
+// правил работаем по порогам по умолчанию, чтобы не пропустить алерт.
+func (m *Client) rulesFor(ctx context.Context, email, hive string) alerts.Rules {
+	var rules alerts.Rules
+	var err error
+	if m.alertRules != nil {
+		rules, err = m.alertRules.Get(ctx, email, hive)
+	} else {
+		rules, err = alerts.ForHive(ctx, m.db, email, hive)
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Str("hive", hive).Msg("Failed to get alert rules, using defaults")
+	}
+	return rules
+}
 
 // transientSensorErrors — теги в status.errors[], которые означают
 // «не удалось снять одно измерение», а не отказ устройства. Прошивка
@@ -274,40 +425,37 @@ func (m *Client) handlingStatusData(data mqttTypes.DeviceStatus, sensorId string
 		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to update timestamp")
 		return
 	}
+	m.markOnline(ctx, sensorId)
 
-	// Если status не требует ни одной из проверок — не дёргаем БД зря.
-	// Значение -1 означает «нет данных» (например, у нас нет монитора заряда),
-	// такие поля не считаем критичными и алерт по ним не шлём. Аналогично,
-	// чисто транзиентные сенсорные ошибки (*_read_error) в errors[] не
-	// считаются критическими — они отфильтровываются в checkErrors.
-	batteryCritical := data.BatteryLevel != -1 && data.BatteryLevel < batteryLowThreshold
-	signalCritical := data.SignalStrength != -1 && data.SignalStrength < signalLowThreshold
-	errorsCritical := false
-	for _, e := range data.Errors {
-		if _, transient := transientSensorErrors[e]; !transient {
-			errorsCritical = true
-			break
-		}
-	}
-	if !batteryCritical && !signalCritical && !errorsCritical {
-		return
+	// Device shadow: сохраняем применённый конфиг (если прошивка его прислала)
+	// и досылаем то, что устройство ещё не применило.
+	if data.Config != nil {
+		m.recordAppliedConfig(ctx, sensorId, *data.Config)
 	}
+	m.syncShadow(ctx, sensorId)
 
-	email, hive, _, err := m.resolveSensorOwner(ctx, sensorId)
+	// Проверяем каждый status, даже без проблем: пакет с нормальными значениями
+	// закрывает открытые инциденты (см. alerts.Notifier). Значение -1 означает
+	// «нет данных» (например, у нас нет монитора заряда), такие поля не проверяем.
+	// Пороги заряда и сигнала у каждого улья свои, поэтому сравниваем
+	// с ними уже после того, как нашли улей.
+	email, hive, hubSensor, err := m.resolveSensorOwner(ctx, sensorId)
 	if err != nil {
 		m.logger.Warn().Err(err).Str("sensor", sensorId).Msg("Failed to resolve sensor owner for status notifications")
 		return
 	}
+	m.recordStatus(ctx, email, hubSensor, data)
 	if hive == "" {
 		// Датчик не привязан ни к одному улью — отправлять пуш некуда (нет контекста).
-		m.logger.Warn().Str("sensor", sensorId).Msg("Sensor is not linked to any hive, skipping status notifications")
+		m.logger.Debug().Str("sensor", sensorId).Msg("Sensor is not linked to any hive, skipping status notifications")
 		return
 	}
+	rules := m.rulesFor(ctx, email, hive)
 
-	if err = m.checkBatteryLevel(ctx, sensorId, email, hive, data); err != nil {
+	if err = m.checkBatteryLevel(ctx, sensorId, email, hive, rules, data); err != nil {
 		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to check battery level")
 	}
-	if err = m.checkSignalStrength(ctx, sensorId, email, hive, data); err != nil {
+	if err = m.checkSignalStrength(ctx, sensorId, email, hive, rules, data); err != nil {
 		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to check signal level")
 	}
 	if err = m.checkErrors(ctx, sensorId, email, hive, data); err != nil {
@@ -315,68 +463,69 @@ func (m *Client) handlingStatusData(data mqttTypes.DeviceStatus, sensorId string
 	}
 }
 
-func (m *Client) checkBatteryLevel(ctx context.Context, sensorId, email, hive string, data mqttTypes.DeviceStatus) error {
-	// -1 = у датчика нет монитора заряда. Заряд мы не меряем — игнорируем,
-	// иначе на каждый status-пакет улетает пуш «низкий заряд (-1%)».
-	if data.BatteryLevel == -1 || data.BatteryLevel >= batteryLowThreshold {
-		return nil
+// recordStatus сохраняет статус в историю хаба (для выгрузки телеметрии).
+// Статус без метки времени записывается временем получения.
+func (m *Client) recordStatus(ctx context.Context, email, hubSensor string, data mqttTypes.DeviceStatus) {
+	if hubSensor == "" {
+		return
 	}
-	tokens, err := m.db.GetFirebaseToken(ctx, email)
+	t := time.Now()
+	if data.Timestamp != 0 {
+		t = time.Unix(data.Timestamp, 0)
+	}
+	err := m.db.NewDeviceStatus(ctx, httpType.DeviceStatus{
+		Email:          email,
+		Sensor:         hubSensor,
+		BatteryLevel:   data.BatteryLevel,
+		SignalStrength: data.SignalStrength,
+		Errors:         data.Errors,
+		Time:           t,
+	})
 	if err != nil {
-		return err
+		m.logger.Error().Err(err).Str("hub", hubSensor).Msg("Failed to save device status")
 	}
-	if m.notification == nil {
+}
+
+func (m *Client) checkBatteryLevel(ctx context.Context, sensorId, email, hive string, rules alerts.Rules,
+	data mqttTypes.DeviceStatus) error {
+	alert := alerts.Alert{Type: alerts.TypeBatteryLow, Email: email, Hive: hive}
+	// -1 = у датчика нет монитора заряда. Заряд мы не меряем — игнорируем,
+	// иначе на каждый status-пакет улетает пуш «низкий заряд (-1%)».
+	if data.BatteryLevel == -1 {
 		return nil
 	}
-	m.logger.Info().Str("sensor", sensorId).Int("battery", data.BatteryLevel).Str("email", email).Msg("Sending low battery notification")
-	badToken, err := m.notification.SendNotification(ctx, notification.Data{
+	if data.BatteryLevel >= rules.BatteryLow {
+		return m.clearAlert(ctx, alert)
+	}
+	m.logger.Info().Str("sensor", sensorId).Int("battery", data.BatteryLevel).Str("email", email).Msg("Low battery detected")
+	alert.Data = notifyTypes.Message{
 		Title:     fmt.Sprintf("Низкий уровень заряда батареи (%d%%) в улье", data.BatteryLevel),
 		Body:      "Пожалуйста, замените батарею в ближайшее время, чтобы обеспечить бесперебойную работу датчика.",
 		Data:      map[string]string{"hive": hive},
-		Tokens:    tokens,
 		Important: true,
-	})
-	switch {
-	case errors.Is(err, notification.ErrInvalidTokens):
-		err = m.db.DeleteFirebaseToken(ctx, email, badToken)
-		return err
-	case err != nil:
-		return fmt.Errorf("failed to send notification: %w", err)
 	}
-	m.logger.Info().Str("sensor", sensorId).Msg("Low battery notification sent successfully")
-	return nil
+	return m.raiseAlert(ctx, alert)
 }
 
-func (m *Client) checkSignalStrength(ctx context.Context, sensorId, email, hive string, data mqttTypes.DeviceStatus) error {
+func (m *Client) checkSignalStrength(ctx context.Context, sensorId, email, hive string, rules alerts.Rules,
+	data mqttTypes.DeviceStatus) error {
+	alert := alerts.Alert{Type: alerts.TypeSignalLow, Email: email, Hive: hive}
 	// -1 = «нет данных о сигнале» (например, отдельная Wi-Fi-сборка прошивки).
 	// Не алертим на отсутствие данных, иначе на каждом пакете прилетает пуш.
-	if data.SignalStrength == -1 || data.SignalStrength >= signalLowThreshold {
+	if data.SignalStrength == -1 {
 		return nil
 	}
-	tokens, err := m.db.GetFirebaseToken(ctx, email)
-	if err != nil {
-		return err
-	}
-	if m.notification == nil {
-		return nil
+	if data.SignalStrength >= rules.SignalLow {
+		return m.clearAlert(ctx, alert)
 	}
-	m.logger.Info().Str("sensor", sensorId).Int("signal", data.SignalStrength).Str("email", email).Msg("Sending low signal notification")
-	badToken, err := m.notification.SendNotification(ctx, notification.Data{
+	m.logger.Info().Str("sensor", sensorId).Int("signal", data.SignalStrength).Str("email", email).Msg("Low signal detected")
+	alert.Data = notifyTypes.Message{
 		Title:     fmt.Sprintf("Низкий уровень сигнала (%d%%) в улье", data.SignalStrength),
 		Body:      "Пожалуйста, проверьте расположение датчика и убедитесь, что он находится в зоне стабильного сигнала.",
 		Data:      map[string]string{"hive": hive},
-		Tokens:    tokens,
 		Important: true,
-	})
-	switch {
-	case errors.Is(err, notification.ErrInvalidTokens):
-		err = m.db.DeleteFirebaseToken(ctx, email, badToken)
-		return err
-	case err != nil:
-		return fmt.Errorf("failed to send notification: %w", err)
 	}
-	m.logger.Info().Str("sensor", sensorId).Msg("Low signal notification sent successfully")
-	return nil
+	return m.raiseAlert(ctx, alert)
 }
 
 func (m *Client) checkErrors(ctx context.Context, sensorId, email, hive string, data mqttTypes.DeviceStatus) error {
@@ -392,63 +541,81 @@ func (m *Client) checkErrors(ctx context.Context, sensorId, email, hive string,
 		critical = append(critical, e)
 	}
 	if len(critical) == 0 {
+		return m.clearAlert(ctx, alerts.Alert{Type: alerts.TypeDeviceErrors, Email: email, Hive: hive,
+			Data: notifyTypes.Message{
+				Title: fmt.Sprintf("Датчик в улье %s снова работает штатно", hive),
+				Body:  "Датчик больше не сообщает об ошибках.",
+				Data:  map[string]string{"hive": hive},
+			}})
+	}
+	m.logger.Info().Str("sensor", sensorId).Strs("errors", critical).Str("email", email).Msg("Device errors detected")
+	return m.raiseAlert(ctx, alerts.Alert{Type: alerts.TypeDeviceErrors, Email: email, Hive: hive,
+		Data: notifyTypes.Message{
+			Title: fmt.Sprintf("Ошибки датчика в улье %s", hive),
+			Body: fmt.Sprintf("Датчик сообщил об ошибках: %s. Пожалуйста, проверьте состояние датчика.",
+				strings.Join(critical, ", ")),
+			Data:      map[string]string{"hive": hive},
+			Important: true,
+		}})
+}
+
+// notifySensorOffline отправляет уведомление о том, что датчик перестал выходить на связь.
+func (m *Client) notifySensorOffline(ctx context.Context, sensorId, email, hive string) error {
+	m.logger.Info().Str("sensor", sensorId).Str("email", email).Msg("Sensor went offline")
+	return m.raiseAlert(ctx, alerts.Alert{Type: alerts.TypeSensorOffline, Email: email, Hive: hive,
+		Data: notifyTypes.Message{
+			Title:     fmt.Sprintf("Датчик в улье %s не на связи", hive),
+			Body:      "Датчик неожиданно отключился от сервера. Проверьте питание и связь датчика.",
+			Data:      map[string]string{"hive": hive, "sensor": sensorId},
+			Important: true,
+		}})
+}
+
+// checkNoiseLevel отправляет уведомление приложению, если уровень шума превышает порог улья (rules.NoiseHigh).
+func (m *Client) checkNoiseLevel(ctx context.Context, email, hive string, data mqttTypes.DeviceData) error {
+	if data.Noise == -1 {
 		return nil
 	}
-	tokens, err := m.db.GetFirebaseToken(ctx, email)
-	if err != nil {
-		return err
-	}
-	if m.notification == nil {
-		return nil
+	alert := alerts.Alert{Type: alerts.TypeNoiseHigh, Email: email, Hive: hive}
+	rules := m.rulesFor(ctx, email, hive)
+	if data.Noise <= rules.NoiseHigh {
+		return m.clearAlert(ctx, alert)
 	}
-	m.logger.Info().Str("sensor", sensorId).Strs("errors", critical).Str("email", email).Msg("Sending device errors notification")
-	badToken, err := m.notification.SendNotification(ctx, notification.Data{
-		Title: fmt.Sprintf("Ошибки датчика в улье %s", hive),
-		Body: fmt.Sprintf("Датчик сообщил об ошибках: %s. Пожалуйста, проверьте состояние датчика.",
-			strings.Join(critical, ", ")),
+	m.logger.Info().Str("hive", hive).Float64("noise", data.Noise).Str("email", email).Msg("High noise detected")
+	alert.Data = notifyTypes.Message{
+		Title: fmt.Sprintf("Высокий уровень шума в улье %s", hive),
+		Body: fmt.Sprintf("Зафиксирован уровень шума %.1f дБ, превышающий допустимый порог (%.0f дБ). Пожалуйста, проверьте состояние улья.",
+			data.Noise, rules.NoiseHigh),
 		Data:      map[string]string{"hive": hive},
-		Tokens:    tokens,
 		Important: true,
-	})
-	switch {
-	case errors.Is(err, notification.ErrInvalidTokens):
-		err = m.db.DeleteFirebaseToken(ctx, email, badToken)
-		return err
-	case err != nil:
-		return fmt.Errorf("failed to send notification: %w", err)
 	}
-	m.logger.Info().Str("sensor", sensorId).Msg("Device errors notification sent successfully")
-	return nil
+	return m.raiseAlert(ctx, alert)
 }
 
-// checkNoiseLevel отправляет уведомление приложению, если уровень шума превышает noiseHighThreshold дБ.
-func (m *Client) checkNoiseLevel(ctx context.Context, email, hive string, data mqttTypes.DeviceData) error {
-	if data.Noise == -1 || data.Noise <= noiseHighThreshold {
+// raiseAlert передаёт срабатывание в alerts.Notifier: повторы в пределах Cooldown
+// он гасит сам, так что вызывать можно на каждом пакете.
+func (m *Client) raiseAlert(ctx context.Context, alert alerts.Alert) error {
+	action, err := m.alerts.Raise(ctx, alert)
+	if errors.Is(err, alerts.ErrNotificationDisabled) {
 		return nil
 	}
-	tokens, err := m.db.GetFirebaseToken(ctx, email)
 	if err != nil {
 		return err
 	}
-	if m.notification == nil {
+	if action.Notify() {
+		m.logger.Info().Str("alert", alert.Type).Str("hive", alert.Hive).Msg("Alert notification sent")
+	}
+	return nil
+}
+
+// clearAlert закрывает инцидент, если показатель вернулся в норму.
+func (m *Client) clearAlert(ctx context.Context, alert alerts.Alert) error {
+	closed, err := m.alerts.Clear(ctx, alert)
+	if errors.Is(err, alerts.ErrNotificationDisabled) {
 		return nil
 	}
-	m.logger.Info().Str("hive", hive).Float64("noise", data.Noise).Str("email", email).Msg("Sending high noise notification")
-	badToken, err := m.notification.SendNotification(ctx, notification.Data{
-		Title: fmt.Sprintf("Высокий уровень шума в улье %s", hive),
-		Body: fmt.Sprintf("Зафиксирован уровень шума %.1f дБ, превышающий допустимый порог (%d дБ). Пожалуйста, проверьте состояние улья.",
-			data.Noise, noiseHighThreshold),
-		Data:      map[string]string{"hive": hive},
-		Tokens:    tokens,
-		Important: true,
-	})
-	switch {
-	case errors.Is(err, notification.ErrInvalidTokens):
-		err = m.db.DeleteFirebaseToken(ctx, email, badToken)
-		return err
-	case err != nil:
-		return fmt.Errorf("failed to send noise notification: %w", err)
+	if closed {
+		m.logger.Info().Str("alert", alert.Type).Str("hive", alert.Hive).Msg("Alert resolved")
 	}
-	m.logger.Info().Str("hive", hive).Msg("High noise notification sent successfully")
-	return nil
+	return err
 }
@@ -1,8 +1,8 @@
 package mqtt
 
 import (
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
-	"BeeIOT/internal/domain/notification"
 	"errors"
 	"fmt"
 	"os"
@@ -13,14 +13,15 @@ import (
 )
 
 type Client struct {
-	client       mqtt.Client
-	inMemDb      interfaces.InMemoryDB
-	db           interfaces.DB
-	notification *notification.Notification
-	logger       zerolog.Logger
+	client     mqtt.Client
+	inMemDb    interfaces.InMemoryDB
+	db         interfaces.DB
+	alerts     *alerts.Notifier
+	alertRules *alerts.Cache
+	logger     zerolog.Logger
 }
 
-func NewMQTTClient(db interfaces.DB, inMemDb interfaces.InMemoryDB, notifi *notification.Notification, logger zerolog.Logger) (*Client, error) {
+func NewMQTTClient(db interfaces.DB, inMemDb interfaces.InMemoryDB, notifier *alerts.Notifier, logger zerolog.Logger) (*Client, error) {
 	host := os.Getenv("MQTT_HOST")
 	port := os.Getenv("MQTT_PORT")
 	username := os.Getenv("MQTT_USERNAME")
@@ -33,7 +34,8 @@ func NewMQTTClient(db interfaces.DB, inMemDb interfaces.InMemoryDB, notifi *noti
 		return nil, errors.New("MQTT_PORT environment variable is not set")
 	}
 
-	mqttClient := &Client{inMemDb: inMemDb, db: db, logger: logger, notification: notifi}
+	mqttClient := &Client{inMemDb: inMemDb, db: db, logger: logger, alerts: notifier,
+		alertRules: alerts.NewCache(db)}
 
 	opts := mqtt.NewClientOptions().
 		AddBroker(fmt.Sprintf("tcp://%s:%s", host, port)).
@@ -89,6 +91,18 @@ func (m *Client) SubscribeToTopics() {
 	} else {
 		m.logger.Info().Str("topic", "status").Msg("subscribed to topic successfully")
 	}
+	token = m.client.Subscribe("/device/+/lwt", 1, m.handleDeviceLWT)
+	if token.Wait() && token.Error() != nil {
+		m.logger.Error().Err(token.Error()).Str("topic", "lwt").Msg("failed to subscribe to topic")
+	} else {
+		m.logger.Info().Str("topic", "lwt").Msg("subscribed to topic successfully")
+	}
+	token = m.client.Subscribe("/device/+/config/ack", 1, m.handleConfigAck)
+	if token.Wait() && token.Error() != nil {
+		m.logger.Error().Err(token.Error()).Str("topic", "config/ack").Msg("failed to subscribe to topic")
+	} else {
+		m.logger.Info().Str("topic", "config/ack").Msg("subscribed to topic successfully")
+	}
 }
 
 // IsConnected проверяет, подключен ли клиент
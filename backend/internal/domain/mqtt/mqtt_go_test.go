@@ -34,12 +34,14 @@ func TestSubscribeToTopics_Success(t *testing.T) {
 
 	client.SubscribeToTopics()
 
-	if len(mc.Subscribed) != 2 {
-		t.Fatalf("expected 2 subscribed topics, got %d", len(mc.Subscribed))
+	if len(mc.Subscribed) != 4 {
+		t.Fatalf("expected 4 subscribed topics, got %d", len(mc.Subscribed))
 	}
 	// check topics contain expected patterns
 	foundData := false
 	foundStatus := false
+	foundLWT := false
+	foundAck := false
 	for _, tpc := range mc.Subscribed {
 		if tpc == "/device/+/data" {
 			foundData = true
@@ -47,9 +49,15 @@ func TestSubscribeToTopics_Success(t *testing.T) {
 		if tpc == "/device/+/status" {
 			foundStatus = true
 		}
+		if tpc == "/device/+/lwt" {
+			foundLWT = true
+		}
+		if tpc == "/device/+/config/ack" {
+			foundAck = true
+		}
 	}
-	if !foundData || !foundStatus {
-		t.Fatalf("expected both data and status topics to be subscribed, got %v", mc.Subscribed)
+	if !foundData || !foundStatus || !foundLWT || !foundAck {
+		t.Fatalf("expected data, status, lwt and config/ack topics to be subscribed, got %v", mc.Subscribed)
 	}
 }
 
@@ -61,8 +69,8 @@ func TestSubscribeToTopics_Failure(t *testing.T) {
 
 	client.SubscribeToTopics()
 
-	if len(mc.Subscribed) != 2 {
-		t.Fatalf("expected 2 subscribed topics even on error, got %d", len(mc.Subscribed))
+	if len(mc.Subscribed) != 4 {
+		t.Fatalf("expected 4 subscribed topics even on error, got %d", len(mc.Subscribed))
 	}
 }
 
@@ -73,7 +81,7 @@ func TestOnConnect_CallsSubscribe(t *testing.T) {
 
 	client.onConnect(nil)
 
-	if len(mc.Subscribed) != 2 {
+	if len(mc.Subscribed) != 4 {
 		t.Fatalf("onConnect should call SubscribeToTopics, subscribed: %v", mc.Subscribed)
 	}
 }
@@ -1,9 +1,13 @@
 package mqtt
 
 import (
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"BeeIOT/internal/domain/models/mqttTypes"
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/rollup"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -27,6 +31,37 @@ type MockInMemoryDB struct {
 	// расширим MockInMemoryDB чтобы захватывать SetSensor вызовы
 	SetSensorCall bool
 	LastSetSensor string
+
+	// состояние онлайн/офлайн и интервалы для watchdog
+	Offline   map[string]bool
+	Intervals map[string]int64
+
+	// события потока телеметрии
+	Published []httpType.TelemetryEvent
+
+	// состояние инцидентов alerts.Notifier
+	AlertStates map[string]string
+}
+
+func (m *MockInMemoryDB) GetAlertState(_ context.Context, key string) (string, error) {
+	return m.AlertStates[key], nil
+}
+
+func (m *MockInMemoryDB) SetAlertState(_ context.Context, key, state string, _ time.Duration) error {
+	if m.AlertStates == nil {
+		m.AlertStates = map[string]string{}
+	}
+	m.AlertStates[key] = state
+	return nil
+}
+
+func (m *MockInMemoryDB) PublishTelemetry(_ context.Context, event string) error {
+	var ev httpType.TelemetryEvent
+	if err := json.Unmarshal([]byte(event), &ev); err != nil {
+		return err
+	}
+	m.Published = append(m.Published, ev)
+	return nil
 }
 
 func (m *MockInMemoryDB) ExistSensor(_ context.Context, _ string) (bool, error) {
@@ -54,6 +89,31 @@ func (m *MockInMemoryDB) GetLastSensorData(_ context.Context, _ string) (string,
 	return "", fmt.Errorf("redis: nil")
 }
 
+func (m *MockInMemoryDB) SetSensorInterval(_ context.Context, sensorID string, seconds int64) error {
+	if m.Intervals == nil {
+		m.Intervals = map[string]int64{}
+	}
+	m.Intervals[sensorID] = seconds
+	return nil
+}
+
+func (m *MockInMemoryDB) MarkSensorOffline(_ context.Context, sensorID string) (bool, error) {
+	if m.Offline == nil {
+		m.Offline = map[string]bool{}
+	}
+	if m.Offline[sensorID] {
+		return false, nil
+	}
+	m.Offline[sensorID] = true
+	return true, nil
+}
+
+func (m *MockInMemoryDB) MarkSensorOnline(_ context.Context, sensorID string) (bool, error) {
+	was := m.Offline[sensorID]
+	delete(m.Offline, sensorID)
+	return was, nil
+}
+
 func (m *MockInMemoryDB) SetLastDeviceStatus(_ context.Context, _ string, _ string) error {
 	return nil
 }
@@ -74,6 +134,41 @@ type MockDB struct {
 	NewNoiseError                     error
 	NewTemperatureError               error
 	NewHiveWeightError                error
+
+	// захват одиночных замеров и история для проверки достоверности
+	Temperatures []httpType.Temperature
+	Noises       []httpType.NoiseLevel
+	Recent       map[string][]dbTypes.TelemetrySample
+
+	// захват пакетной записи бэклога
+	TemperatureBatches []httpType.TelemetryBatch
+	NoiseBatches       []httpType.TelemetryBatch
+	WeightBatches      []httpType.TelemetryBatch
+	Spectra            []httpType.NoiseSpectrum
+
+	// device shadow
+	Shadow         *dbTypes.DeviceShadow
+	ReportedConfig *httpType.ShadowConfig
+
+	// история статусов устройств
+	Statuses []httpType.DeviceStatus
+
+	// пороги алертов и сохранённые во входящие уведомления
+	AlertRules    dbTypes.AlertRules
+	Notifications []dbTypes.Notification
+}
+
+func (m *MockDB) NewNotification(_ context.Context, _ string, n dbTypes.Notification) (int64, error) {
+	m.Notifications = append(m.Notifications, n)
+	return int64(len(m.Notifications)), nil
+}
+
+func (m *MockDB) GetAlertRecipients(_ context.Context, _, _ string) ([]dbTypes.Member, error) {
+	return nil, nil
+}
+
+func (m *MockDB) GetEffectiveAlertRules(_ context.Context, _, _, _ string) (dbTypes.AlertRules, error) {
+	return m.AlertRules, nil
 }
 
 func (m *MockDB) GetEmailHiveBySensorID(_ context.Context, _ string) (string, string, error) {
@@ -84,6 +179,10 @@ func (m *MockDB) GetHubSensorByHive(_ context.Context, _, _ string) (string, err
 	return m.GetHubSensorByHiveResult, m.GetHubSensorByHiveError
 }
 
+func (m *MockDB) GetHubBySensor(_ context.Context, email, sensor string) (dbTypes.Hub, error) {
+	return dbTypes.Hub{Id: 1, Email: email, Sensor: sensor}, nil
+}
+
 func (m *MockDB) GetEmailByHubSensor(_ context.Context, _ string) (string, error) {
 	return m.GetEmailByHubSensorResult, m.GetEmailByHubSensorError
 }
@@ -92,18 +191,72 @@ func (m *MockDB) GetEmailHiveByHubSensor(_ context.Context, _ string) (string, s
 	return "", "", context.Canceled
 }
 
-func (m *MockDB) NewNoise(_ context.Context, _ httpType.NoiseLevel) error {
+func (m *MockDB) NewNoise(_ context.Context, n httpType.NoiseLevel) error {
+	m.Noises = append(m.Noises, n)
 	return m.NewNoiseError
 }
 
-func (m *MockDB) NewTemperature(_ context.Context, _ httpType.Temperature) error {
+func (m *MockDB) NewTemperature(_ context.Context, temp httpType.Temperature) error {
+	m.Temperatures = append(m.Temperatures, temp)
 	return m.NewTemperatureError
 }
 
+func (m *MockDB) GetRecentTelemetry(_ context.Context, _, _, metric string, _, before time.Time, _ int) ([]dbTypes.TelemetrySample, error) {
+	var samples []dbTypes.TelemetrySample
+	for _, s := range m.Recent[metric] {
+		if s.Date.Before(before) {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
 func (m *MockDB) NewHiveWeight(_ context.Context, _ httpType.HubWeight) error {
 	return m.NewHiveWeightError
 }
 
+func (m *MockDB) NewTemperatureBatch(_ context.Context, batch httpType.TelemetryBatch) error {
+	m.TemperatureBatches = append(m.TemperatureBatches, batch)
+	return nil
+}
+
+func (m *MockDB) NewNoiseBatch(_ context.Context, batch httpType.TelemetryBatch) error {
+	m.NoiseBatches = append(m.NoiseBatches, batch)
+	return nil
+}
+
+func (m *MockDB) NewNoiseSpectrum(_ context.Context, s httpType.NoiseSpectrum) error {
+	m.Spectra = append(m.Spectra, s)
+	return nil
+}
+
+func (m *MockDB) NewNoiseSpectrumBatch(_ context.Context, spectra []httpType.NoiseSpectrum) error {
+	m.Spectra = append(m.Spectra, spectra...)
+	return nil
+}
+
+func (m *MockDB) NewHiveWeightBatch(_ context.Context, batch httpType.TelemetryBatch) error {
+	m.WeightBatches = append(m.WeightBatches, batch)
+	return nil
+}
+
+func (m *MockDB) GetShadowBySensor(_ context.Context, _ string) (dbTypes.DeviceShadow, error) {
+	if m.Shadow == nil {
+		return dbTypes.DeviceShadow{}, fmt.Errorf("no rows in result set")
+	}
+	return *m.Shadow, nil
+}
+
+func (m *MockDB) SetReportedConfig(_ context.Context, _ string, config httpType.ShadowConfig, _ time.Time) error {
+	m.ReportedConfig = &config
+	return nil
+}
+
+func (m *MockDB) NewDeviceStatus(_ context.Context, status httpType.DeviceStatus) error {
+	m.Statuses = append(m.Statuses, status)
+	return nil
+}
+
 func (m *MockDB) GetFirebaseToken(_ context.Context, _ string) ([]string, error) {
 	return nil, nil
 }
@@ -202,6 +355,168 @@ func TestHandleDeviceData(t *testing.T) {
 
 	// Since NewNoise/NewTemperature return nil error, just ensure no panic.
 	// We can enhance MockDB to capture calls if needed.
+
+	// Данные уходят в поток телеметрии хаба
+	if len(inMem.Published) != 1 {
+		t.Fatalf("expected 1 telemetry event, got %d", len(inMem.Published))
+	}
+	ev := inMem.Published[0]
+	var got mqttTypes.DeviceData
+	if ev.HubID != 1 || ev.Hub != "sensor123" || ev.Type != httpType.TelemetryEventData || json.Unmarshal(ev.Data, &got) != nil || got.Temperature != 25.5 {
+		t.Errorf("unexpected telemetry event: %+v", ev)
+	}
+}
+
+func TestIsBatchPayload(t *testing.T) {
+	cases := []struct {
+		payload string
+		want    bool
+	}{
+		{`{"temperature":25.5,"temperature_time":1700000000,"noise":50,"noise_time":1700000000}`, false},
+		{`{"temperature":[{"value":25.5,"time":1700000000}]}`, true},
+		{`{"noise": [ ]}`, true},
+		{`{"weight":[{"value":40.1,"time":1700000000}],"temperature":-1}`, true},
+		{`not json`, false},
+	}
+	for _, c := range cases {
+		if got := isBatchPayload([]byte(c.payload)); got != c.want {
+			t.Errorf("isBatchPayload(%s) = %v, want %v", c.payload, got, c.want)
+		}
+	}
+}
+
+func TestHandleDeviceData_Batch(t *testing.T) {
+	logger := zerolog.Nop()
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1", GetHubSensorByHiveResult: "hub1"}
+
+	client := &Client{inMemDb: inMem, db: db, logger: logger}
+
+	batch := mqttTypes.DeviceDataBatch{
+		Temperature: []mqttTypes.Sample{{Value: 25.5, Time: 1700000000}, {Value: -1, Time: 1700000300}, {Value: 26.0, Time: 1700000600}},
+		Noise:       []mqttTypes.Sample{{Value: 48.0, Time: 1700000000}},
+		Spectrum: []mqttTypes.SpectrumSample{
+			{Spectrum: mqttTypes.Spectrum{Edges: []float64{100, 200, 500}, Levels: []float64{40, 45}}, Time: 1700000000},
+			// уровней больше, чем полос
+			{Spectrum: mqttTypes.Spectrum{Edges: []float64{100, 200}, Levels: []float64{40, 45}}, Time: 1700000300},
+		},
+	}
+	payload, _ := json.Marshal(batch)
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: payload})
+
+	if len(db.TemperatureBatches) != 1 || len(db.NoiseBatches) != 1 || len(db.WeightBatches) != 1 {
+		t.Fatalf("expected one batch call per metric, got temp=%d noise=%d weight=%d",
+			len(db.TemperatureBatches), len(db.NoiseBatches), len(db.WeightBatches))
+	}
+	temp := db.TemperatureBatches[0]
+	if temp.Email != "test@test.com" || temp.Hub != "hub1" {
+		t.Errorf("unexpected owner: %s/%s", temp.Email, temp.Hub)
+	}
+	if len(temp.Samples) != 2 {
+		t.Fatalf("expected empty samples to be skipped, got %d samples", len(temp.Samples))
+	}
+	if !temp.Samples[1].Time.Equal(time.Unix(1700000600, 0)) || temp.Samples[1].Value != 26.0 {
+		t.Errorf("unexpected sample: %+v", temp.Samples[1])
+	}
+	if len(db.WeightBatches[0].Samples) != 0 {
+		t.Errorf("expected no weight samples, got %d", len(db.WeightBatches[0].Samples))
+	}
+	if len(db.Spectra) != 1 || db.Spectra[0].Hub != "hub1" || !db.Spectra[0].Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected only the valid spectrum to be stored, got %+v", db.Spectra)
+	}
+}
+
+func TestHandleDeviceData_Spectrum(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDError: context.Canceled, GetEmailByHubSensorResult: "test@test.com"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	data := mqttTypes.DeviceData{
+		Temperature: -1, Noise: 48, NoiseTime: 1700000000, Weight: -1,
+		Spectrum: &mqttTypes.Spectrum{Edges: []float64{100, 200, 500, 1000}, Levels: []float64{40, 46, 38}},
+	}
+	payload, _ := json.Marshal(data)
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: payload})
+
+	if len(db.Spectra) != 1 {
+		t.Fatalf("expected spectrum to be stored, got %d", len(db.Spectra))
+	}
+	s := db.Spectra[0]
+	if s.Hub != "sensor123" || len(s.Levels) != 3 || !s.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected spectrum: %+v", s)
+	}
+}
+
+func TestHandleDeviceData_FlagsImplausible(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	now := time.Unix(1700000000, 0)
+	var stuckNoise []dbTypes.TelemetrySample
+	for i := 11; i > 0; i-- {
+		stuckNoise = append(stuckNoise, dbTypes.TelemetrySample{Value: 95, Date: now.Add(-time.Duration(i) * 10 * time.Minute), Quality: quality.OK})
+	}
+	noiseHigh := 70.0
+	db := &MockDB{
+		GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1", GetHubSensorByHiveResult: "hub1",
+		AlertRules: dbTypes.AlertRules{NoiseHigh: &noiseHigh},
+		Recent:     map[string][]dbTypes.TelemetrySample{rollup.MetricNoise: stuckNoise},
+	}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop(),
+		alerts: alerts.NewNotifier(db, inMem, nil, zerolog.Nop())}
+
+	// отключённый DS18B20 и залипший микрофон
+	data := mqttTypes.DeviceData{Temperature: 85, TemperatureTime: now.Unix(), Noise: 95, NoiseTime: now.Unix(), Weight: -1}
+	payload, _ := json.Marshal(data)
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: payload})
+
+	if len(db.Temperatures) != 1 || db.Temperatures[0].Quality != quality.OutOfRange {
+		t.Errorf("expected temperature flagged out of range, got %+v", db.Temperatures)
+	}
+	if len(db.Noises) != 1 || db.Noises[0].Quality != quality.Stuck {
+		t.Errorf("expected noise flagged stuck, got %+v", db.Noises)
+	}
+	if len(inMem.AlertStates) != 0 {
+		t.Errorf("expected no noise alert for flagged sample, got %v", inMem.AlertStates)
+	}
+}
+
+func TestHandleDeviceData_BatchFlagsSpike(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1", GetHubSensorByHiveResult: "hub1"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	// выброс в середине бэклога; порядок в пакете не важен
+	batch := mqttTypes.DeviceDataBatch{Temperature: []mqttTypes.Sample{
+		{Value: 34.6, Time: 1700000900}, {Value: 34.5, Time: 1700000000}, {Value: 34.4, Time: 1700000300},
+		{Value: 34.5, Time: 1700000600}, {Value: 55, Time: 1700000750},
+	}}
+	payload, _ := json.Marshal(batch)
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: payload})
+
+	if len(db.TemperatureBatches) != 1 {
+		t.Fatalf("expected temperature batch, got %d", len(db.TemperatureBatches))
+	}
+	for _, s := range db.TemperatureBatches[0].Samples {
+		want := quality.OK
+		if s.Value == 55 {
+			want = quality.Spike
+		}
+		if s.Quality != want {
+			t.Errorf("sample %v: expected %s, got %s", s.Value, want, s.Quality)
+		}
+	}
+}
+
+func TestHandleDeviceData_BatchSensorNotExist(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: false}
+	db := &MockDB{}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	payload := []byte(`{"temperature":[{"value":25.5,"time":1700000000}]}`)
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: payload})
+
+	if len(db.TemperatureBatches) != 0 {
+		t.Errorf("expected no writes for unknown sensor")
+	}
 }
 
 func TestHandleDeviceData_SensorNotExist(t *testing.T) {
@@ -247,6 +562,35 @@ func TestHandleDeviceStatus(t *testing.T) {
 	// With nil notification, checkBatteryLevel returns nil immediately
 }
 
+func TestHandleDeviceStatus_PublishesEvent(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1",
+		GetHubSensorByHiveResult: "hub-1"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	payload, _ := json.Marshal(mqttTypes.DeviceStatus{BatteryLevel: 80, SignalStrength: 50, Timestamp: time.Now().Unix()})
+	client.handleDeviceStatus(nil, &MockMessage{topic: "/device/sensor123/status", payload: payload})
+
+	if len(inMem.Published) != 1 || inMem.Published[0].Hub != "hub-1" || inMem.Published[0].Type != httpType.TelemetryEventStatus {
+		t.Fatalf("expected status event for hub-1, got %+v", inMem.Published)
+	}
+	if len(db.Statuses) != 1 || db.Statuses[0].Sensor != "hub-1" || db.Statuses[0].Email != "test@test.com" ||
+		db.Statuses[0].BatteryLevel != 80 {
+		t.Fatalf("expected status saved to hub-1 history, got %+v", db.Statuses)
+	}
+
+	// Датчик без хаба — события нет
+	inMem.Published = nil
+	db.GetHubSensorByHiveResult = ""
+	client.handleDeviceStatus(nil, &MockMessage{topic: "/device/sensor123/status", payload: payload})
+	if len(inMem.Published) != 0 {
+		t.Errorf("expected no event without hub, got %+v", inMem.Published)
+	}
+	if len(db.Statuses) != 1 {
+		t.Errorf("expected no status history without hub, got %+v", db.Statuses)
+	}
+}
+
 func TestCheckSignalStrength(t *testing.T) {
 	logger := zerolog.Nop()
 	inMem := &MockInMemoryDB{}
@@ -259,7 +603,7 @@ func TestCheckSignalStrength(t *testing.T) {
 		Timestamp:      time.Now().Unix(),
 	}
 
-	err := client.checkSignalStrength(context.Background(), "sensor1", "test@test.com", "Hive1", status)
+	err := client.checkSignalStrength(context.Background(), "sensor1", "test@test.com", "Hive1", alerts.Default(), status)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -285,7 +629,8 @@ func TestCheckErrors(t *testing.T) {
 
 func TestSendConfig(t *testing.T) {
 	logger := zerolog.Nop()
-	client := &Client{logger: logger, client: &MockMqttClient{}}
+	inMem := &MockInMemoryDB{}
+	client := &Client{logger: logger, client: &MockMqttClient{}, inMemDb: inMem}
 
 	config := mqttTypes.DeviceConfig{
 		SamplingTemp: 60,
@@ -295,6 +640,42 @@ func TestSendConfig(t *testing.T) {
 	if err != nil {
 		t.Errorf("SendConfig failed: %v", err)
 	}
+	if inMem.Intervals["sensor1"] != 60 {
+		t.Errorf("expected sensor interval 60, got %d", inMem.Intervals["sensor1"])
+	}
+
+	// Конфиг без интервалов (health check) не трогает сохранённый интервал
+	if err := client.SendConfig("sensor1", mqttTypes.NewDeviceConfig()); err != nil {
+		t.Errorf("SendConfig failed: %v", err)
+	}
+	if inMem.Intervals["sensor1"] != 60 {
+		t.Errorf("expected sensor interval to stay 60, got %d", inMem.Intervals["sensor1"])
+	}
+}
+
+func TestHandleDeviceLWT(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	msg := &MockMessage{topic: "/device/sensor123/lwt", payload: []byte(`{"online":false}`)}
+	client.handleDeviceLWT(nil, msg)
+	if !inMem.Offline["sensor123"] {
+		t.Fatalf("expected sensor to be marked offline after LWT")
+	}
+
+	// Любой пакет от датчика возвращает его в онлайн
+	data, _ := json.Marshal(mqttTypes.DeviceData{Temperature: 25, TemperatureTime: time.Now().Unix(), Noise: -1, Weight: -1})
+	client.handleDeviceData(nil, &MockMessage{topic: "/device/sensor123/data", payload: data})
+	if inMem.Offline["sensor123"] {
+		t.Fatalf("expected sensor to be back online after data")
+	}
+
+	// Неверный топик — ничего не делаем
+	client.handleDeviceLWT(nil, &MockMessage{topic: "/device/sensor123/status"})
+	if inMem.Offline["sensor123"] {
+		t.Fatalf("expected invalid topic to be ignored")
+	}
 }
 
 func TestAddNoiseAndTemp(t *testing.T) {
@@ -305,13 +686,13 @@ func TestAddNoiseAndTemp(t *testing.T) {
 	ctx := context.Background()
 
 	// Noise -1 (ignored)
-	err := client.addNoise(ctx, "test@test.com", "Hive1", mqttTypes.DeviceData{Noise: -1})
+	err := client.addNoise(ctx, "test@test.com", "Hive1", mqttTypes.DeviceData{Noise: -1}, quality.OK)
 	if err != nil {
 		t.Error(err)
 	}
 
 	// Valid Noise
-	err = client.addNoise(ctx, "test@test.com", "Hive1", mqttTypes.DeviceData{Noise: 50, NoiseTime: 1234567890})
+	err = client.addNoise(ctx, "test@test.com", "Hive1", mqttTypes.DeviceData{Noise: 50, NoiseTime: 1234567890}, quality.OK)
 	if err != nil {
 		t.Error(err)
 	}
@@ -430,13 +811,13 @@ func TestCheckBatterySignal_NoNotifications(t *testing.T) {
 	client := &Client{logger: logger, inMemDb: inMem, db: d}
 
 	// battery ok
-	err := client.checkBatteryLevel(context.Background(), "s1", "e@e", "H", mqttTypes.DeviceStatus{BatteryLevel: 50, Timestamp: time.Now().Unix()})
+	err := client.checkBatteryLevel(context.Background(), "s1", "e@e", "H", alerts.Default(), mqttTypes.DeviceStatus{BatteryLevel: 50, Timestamp: time.Now().Unix()})
 	if err != nil {
 		t.Fatalf("expected no error for sufficient battery, got %v", err)
 	}
 
 	// signal ok
-	err = client.checkSignalStrength(context.Background(), "s1", "e@e", "H", mqttTypes.DeviceStatus{SignalStrength: 50, Timestamp: time.Now().Unix()})
+	err = client.checkSignalStrength(context.Background(), "s1", "e@e", "H", alerts.Default(), mqttTypes.DeviceStatus{SignalStrength: 50, Timestamp: time.Now().Unix()})
 	if err != nil {
 		t.Fatalf("expected no error for sufficient signal, got %v", err)
 	}
@@ -453,3 +834,120 @@ func TestCheckErrors_NoErrors(t *testing.T) {
 		t.Fatalf("expected nil when no errors present, got %v", err)
 	}
 }
+
+func TestHandleConfigAck(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop()}
+
+	payload := []byte(`{"sampling_rate_noise":3600,"sampling_rate_temperature":600,"frequency_status":-1}`)
+	client.handleConfigAck(nil, &MockMessage{topic: "/device/sensor123/config/ack", payload: payload})
+
+	if db.ReportedConfig == nil {
+		t.Fatal("expected reported config to be saved")
+	}
+	if db.ReportedConfig.SamplingNoise != 3600 || db.ReportedConfig.SamplingTemp != 600 || db.ReportedConfig.Frequency != -1 {
+		t.Errorf("unexpected reported config: %+v", db.ReportedConfig)
+	}
+
+	// Неверный топик
+	db.ReportedConfig = nil
+	client.handleConfigAck(nil, &MockMessage{topic: "/device/sensor123/ack", payload: payload})
+	if db.ReportedConfig != nil {
+		t.Error("expected invalid topic to be ignored")
+	}
+}
+
+func TestHandleDeviceStatus_ReportsConfigAndComputesDelta(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{Shadow: &dbTypes.DeviceShadow{
+		Sensor:       "sensor123",
+		DesiredNoise: 3600, DesiredTemp: 600, DesiredFrequency: -1,
+		ReportedNoise: 3600, ReportedTemp: 5, ReportedFrequency: 5,
+	}}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop(), client: &MockMqttClient{}}
+
+	status := mqttTypes.DeviceStatus{
+		BatteryLevel: -1, SignalStrength: -1, Timestamp: time.Now().Unix(),
+		Config: &mqttTypes.AppliedConfig{SamplingNoise: 3600, SamplingTemp: 5, Frequency: 5},
+	}
+	payload, _ := json.Marshal(status)
+	client.handleDeviceStatus(nil, &MockMessage{topic: "/device/sensor123/status", payload: payload})
+
+	if db.ReportedConfig == nil || db.ReportedConfig.SamplingTemp != 5 {
+		t.Fatalf("expected config from status to be saved, got %+v", db.ReportedConfig)
+	}
+
+	delta, pending := client.pendingDelta(context.Background(), "sensor123")
+	if !pending || delta.SamplingTemp != 600 || delta.SamplingNoise != -1 {
+		t.Errorf("unexpected delta: %+v, pending=%v", delta, pending)
+	}
+
+	// Хаба нет — дельты нет
+	db.Shadow = nil
+	if _, pending := client.pendingDelta(context.Background(), "sensor123"); pending {
+		t.Error("expected no delta without shadow")
+	}
+}
+
+func alertState(t *testing.T, inMem *MockInMemoryDB, key string) alerts.State {
+	t.Helper()
+	var state alerts.State
+	if raw := inMem.AlertStates[key]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &state); err != nil {
+			t.Fatalf("corrupted alert state %q: %v", raw, err)
+		}
+	}
+	return state
+}
+
+func TestHandlingStatusData_UsesHiveAlertRules(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop(),
+		alerts: alerts.NewNotifier(db, inMem, nil, zerolog.Nop())}
+	status := mqttTypes.DeviceStatus{BatteryLevel: 30, SignalStrength: 50, Timestamp: time.Now().Unix()}
+
+	// 30% выше порога по умолчанию (20%) — алерта нет
+	client.handlingStatusData(status, "sensor123")
+	if state := alertState(t, inMem, "test@test.com:Hive1:battery_low"); state.Status != "" {
+		t.Fatalf("expected no alert with default rules, got %+v", state)
+	}
+
+	// для улья порог поднят до 40% — алерт по заряду
+	battery := 40
+	db.AlertRules = dbTypes.AlertRules{BatteryLow: &battery}
+	client.handlingStatusData(status, "sensor123")
+	if state := alertState(t, inMem, "test@test.com:Hive1:battery_low"); state.Status != alerts.StatusOpen {
+		t.Fatalf("expected battery alert with hive rules, got %+v", state)
+	}
+}
+
+func TestHandlingStatusData_DedupAndResolve(t *testing.T) {
+	inMem := &MockInMemoryDB{ExistSensorResult: true}
+	db := &MockDB{GetEmailHiveBySensorIDResultEmail: "test@test.com", GetEmailHiveBySensorIDResultHive: "Hive1"}
+	client := &Client{inMemDb: inMem, db: db, logger: zerolog.Nop(),
+		alerts: alerts.NewNotifier(db, inMem, nil, zerolog.Nop())}
+	low := mqttTypes.DeviceStatus{BatteryLevel: 10, SignalStrength: 50, Timestamp: time.Now().Unix()}
+
+	// низкий заряд в каждом пакете — один инцидент, одно уведомление
+	for i := 0; i < 5; i++ {
+		client.handlingStatusData(low, "sensor123")
+	}
+	state := alertState(t, inMem, "test@test.com:Hive1:battery_low")
+	if state.Status != alerts.StatusOpen || state.Notifications != 1 {
+		t.Fatalf("expected single open incident, got %+v", state)
+	}
+	// уведомление сохранено во входящие, даже если push выключен
+	if len(db.Notifications) != 1 || db.Notifications[0].Type != alerts.TypeBatteryLow ||
+		db.Notifications[0].Severity != alerts.SeverityCritical {
+		t.Fatalf("expected one critical notification in inbox, got %+v", db.Notifications)
+	}
+
+	// заряд восстановился — инцидент закрыт
+	low.BatteryLevel = 90
+	client.handlingStatusData(low, "sensor123")
+	if state := alertState(t, inMem, "test@test.com:Hive1:battery_low"); state.Status != alerts.StatusResolved {
+		t.Fatalf("expected incident to be resolved, got %+v", state)
+	}
+}
@@ -0,0 +1,65 @@
+package mqtt
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/quality"
+	"context"
+	"slices"
+	"time"
+)
+
+// history возвращает историю метрики хаба до before для проверки нового замера: последние
+// замеры и начало текущей серии одинаковых значений (см. quality.Check).
+// Если историю получить не удалось, проверяется только диапазон — замер важнее проверки.
+func (m *Client) history(ctx context.Context, email, hubSensor, metric string, before time.Time) []quality.Sample {
+	if hubSensor == "" {
+		return nil
+	}
+	samples, err := m.db.GetRecentTelemetry(ctx, email, hubSensor, metric,
+		before.Add(-quality.Lookback(metric)), before, quality.HistorySize)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("hub", hubSensor).Str("metric", metric).Msg("Failed to get recent samples, checking range only")
+		return nil
+	}
+	history := make([]quality.Sample, len(samples))
+	for i, s := range samples {
+		history[i] = quality.Sample{Value: s.Value, Time: s.Date, Quality: s.Quality}
+	}
+	return history
+}
+
+// checkQuality оценивает достоверность одиночного замера.
+func (m *Client) checkQuality(ctx context.Context, email, hubSensor, metric string, value float64, t time.Time) string {
+	q := quality.Check(metric, m.history(ctx, email, hubSensor, metric, t), value, t)
+	if q != quality.OK {
+		m.logger.Warn().Str("hub", hubSensor).Str("metric", metric).Float64("value", value).
+			Str("quality", q).Msg("Implausible sample flagged")
+	}
+	return q
+}
+
+// checkBatchQuality проставляет флаги качества пачке замеров. Замеры проверяются
+// по порядку времени, и каждый проверенный становится историей для следующего.
+func (m *Client) checkBatchQuality(ctx context.Context, metric string, batch httpType.TelemetryBatch) httpType.TelemetryBatch {
+	if len(batch.Samples) == 0 {
+		return batch
+	}
+	samples := slices.Clone(batch.Samples)
+	slices.SortFunc(samples, func(a, b httpType.TelemetrySample) int {
+		return a.Time.Compare(b.Time)
+	})
+	series := make([]quality.Sample, len(samples))
+	for i, s := range samples {
+		series[i] = quality.Sample{Value: s.Value, Time: s.Time}
+	}
+	flagged := quality.CheckSeries(metric, m.history(ctx, batch.Email, batch.Hub, metric, samples[0].Time), series)
+	for i := range samples {
+		samples[i].Quality = series[i].Quality
+	}
+	if flagged > 0 {
+		m.logger.Warn().Str("hub", batch.Hub).Str("metric", metric).Int("flagged", flagged).
+			Int("total", len(samples)).Msg("Implausible samples flagged in batch")
+	}
+	batch.Samples = samples
+	return batch
+}
@@ -0,0 +1,88 @@
+package mqtt
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/models/mqttTypes"
+	"BeeIOT/internal/domain/shadow"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// handleConfigAck обработчик топика /device/{id}/config/ack — устройство сообщает,
+// какую конфигурацию оно реально применило.
+func (m *Client) handleConfigAck(_ mqtt.Client, msg mqtt.Message) {
+	topic := msg.Topic()
+	parts := strings.Split(topic, "/")
+	if len(parts) != 5 || parts[1] != "device" || parts[3] != "config" || parts[4] != "ack" {
+		m.logger.Error().Str("topic", topic).Msg("Invalid topic format")
+		return
+	}
+	sensorId := parts[2]
+
+	var applied mqttTypes.AppliedConfig
+	if err := json.Unmarshal(msg.Payload(), &applied); err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to unmarshal payload")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exist, err := m.inMemDb.ExistSensor(ctx, sensorId)
+	if err != nil {
+		m.logger.Error().Err(err).Str("topic", topic).Msg("Failed to check existence of sensor")
+		return
+	}
+	if !exist {
+		m.logger.Error().Str("topic", topic).Msg("Sensor does not exist")
+		return
+	}
+	m.recordAppliedConfig(ctx, sensorId, applied)
+}
+
+// recordAppliedConfig сохраняет в shadow конфигурацию, о которой отчиталось устройство.
+func (m *Client) recordAppliedConfig(ctx context.Context, sensorId string, applied mqttTypes.AppliedConfig) {
+	err := m.db.SetReportedConfig(ctx, sensorId, httpType.ShadowConfig{
+		SamplingNoise: applied.SamplingNoise,
+		SamplingTemp:  applied.SamplingTemp,
+		Frequency:     applied.Frequency,
+	}, time.Now())
+	if err != nil {
+		m.logger.Error().Err(err).Str("sensor", sensorId).Msg("Failed to save reported config")
+		return
+	}
+	m.logger.Debug().Str("sensor", sensorId).Interface("config", applied).Msg("Saved reported config")
+}
+
+// pendingDelta возвращает ещё не применённую устройством часть желаемой конфигурации.
+func (m *Client) pendingDelta(ctx context.Context, sensorId string) (mqttTypes.DeviceConfig, bool) {
+	s, err := m.db.GetShadowBySensor(ctx, sensorId)
+	if err != nil {
+		// Датчик без хаба — shadow для него не ведётся
+		m.logger.Debug().Err(err).Str("sensor", sensorId).Msg("No device shadow for sensor")
+		return mqttTypes.DeviceConfig{}, false
+	}
+	return shadow.Delta(s)
+}
+
+// syncShadow переотправляет устройству невыполненную дельту конфигурации.
+// Вызывается на каждый статус: устройство большую часть времени спит, и сразу
+// после статуса оно как раз слушает топик config (WAIT_CONFIG в прошивке).
+func (m *Client) syncShadow(ctx context.Context, sensorId string) {
+	delta, pending := m.pendingDelta(ctx, sensorId)
+	if !pending {
+		return
+	}
+	m.logger.Info().Str("sensor", sensorId).Interface("delta", delta).Msg("Re-publishing pending config delta")
+	// Публикуем вне обработчика сообщения: paho не даёт ждать токен публикации
+	// внутри колбэка подписки (см. отправку начального конфига в handlingStatusData).
+	go func() {
+		if err := m.SendConfig(sensorId, delta); err != nil {
+			m.logger.Warn().Err(err).Str("sensor", sensorId).Msg("Failed to publish config delta")
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package mqtt
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// publishEvent раздаёт событие телеметрии подписчикам /api/telemetry/stream через
+// Redis pub/sub: клиент может быть подключён к любой реплике сервера. Подписчики
+// выбираются по id хаба владельца email, а не по идентификатору датчика.
+func (m *Client) publishEvent(ctx context.Context, email, hubSensor, eventType string, payload []byte) {
+	hub, err := m.db.GetHubBySensor(ctx, email, hubSensor)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("hub", hubSensor).Msg("Failed to resolve hub for telemetry event")
+		return
+	}
+	event, err := json.Marshal(httpType.TelemetryEvent{
+		HubID: hub.Id,
+		Hub:   hubSensor,
+		Type:  eventType,
+		Time:  time.Now().Unix(),
+		Data:  payload,
+	})
+	if err != nil {
+		m.logger.Warn().Err(err).Str("hub", hubSensor).Msg("Failed to marshal telemetry event")
+		return
+	}
+	if err := m.inMemDb.PublishTelemetry(ctx, string(event)); err != nil {
+		m.logger.Warn().Err(err).Str("hub", hubSensor).Msg("Failed to publish telemetry event")
+	}
+}
+
+// publishStatusEvent публикует статус устройства в поток хаба, к которому относится датчик.
+func (m *Client) publishStatusEvent(sensorId string, payload []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	email, _, hubSensor, err := m.resolveSensorOwner(ctx, sensorId)
+	if err != nil || hubSensor == "" {
+		m.logger.Debug().Err(err).Str("sensor", sensorId).Msg("No hub for sensor, skipping status event")
+		return
+	}
+	m.publishEvent(ctx, email, hubSensor, httpType.TelemetryEventStatus, payload)
+}
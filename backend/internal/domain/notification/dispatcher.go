@@ -0,0 +1,206 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TypeQuietDigest — тип сводки уведомлений, отложенных на тихие часы.
+const TypeQuietDigest = "quiet_digest"
+
+// Dispatcher рассылает уведомление по каналам, которые выбрал пользователь,
+// с учётом его фильтров: отключённых типов, минимальной важности и тихих часов.
+type Dispatcher struct {
+	db       interfaces.DB
+	channels map[string]interfaces.Notifier
+	logger   zerolog.Logger
+	now      func() time.Time
+}
+
+func NewDispatcher(db interfaces.DB, logger zerolog.Logger, channels ...interfaces.Notifier) *Dispatcher {
+	d := &Dispatcher{db: db, channels: make(map[string]interfaces.Notifier, len(channels)), logger: logger, now: time.Now}
+	for _, ch := range channels {
+		d.channels[ch.Channel()] = ch
+	}
+	return d
+}
+
+// Dispatch доставляет уведомление по каналам пользователя. Отключённый тип и важность
+// ниже порога не рассылаются; в тихие часы всё, кроме Important, откладывается
+// до сводки (см. FlushHeld). Сбой одного канала не мешает остальным; ошибки
+// возвращаются вместе.
+func (d *Dispatcher) Dispatch(ctx context.Context, email string, msg notifyTypes.Message) error {
+	prefs, err := d.db.GetNotificationPreferences(ctx, email)
+	if err != nil {
+		// без настроек — хотя бы push, чтобы не потерять алерт
+		d.logger.Warn().Err(err).Str("email", email).Msg("Failed to get notification preferences, using push only")
+		return d.deliver(ctx, email, dbTypes.NotificationPreferences{}, []string{notifyTypes.ChannelPush}, msg)
+	}
+
+	if tp, ok := prefs.AlertTypes[msg.Type]; ok && !tp.Enabled {
+		d.logger.Debug().Str("email", email).Str("alert", msg.Type).Msg("Alert type disabled by user, skipping")
+		return nil
+	}
+	if prefs.MinSeverity != "" && severityRank(msg.Severity) < severityRank(prefs.MinSeverity) {
+		d.logger.Debug().Str("email", email).Str("severity", msg.Severity).Msg("Notification below user's min severity, skipping")
+		return nil
+	}
+	if !msg.Important && InQuietHours(prefs, d.now()) {
+		if err := d.db.HoldNotification(ctx, email, msg); err != nil {
+			return fmt.Errorf("hold notification: %w", err)
+		}
+		d.logger.Debug().Str("email", email).Str("alert", msg.Type).Msg("Notification held for quiet hours")
+		return nil
+	}
+	return d.deliver(ctx, email, prefs, channelsFor(prefs, msg.Type), msg)
+}
+
+// channelsFor — каналы уведомления типа typ: заданные для типа или, если их нет, общие.
+func channelsFor(prefs dbTypes.NotificationPreferences, typ string) []string {
+	if tp, ok := prefs.AlertTypes[typ]; ok && len(tp.Channels) > 0 {
+		return tp.Channels
+	}
+	return prefs.Channels
+}
+
+// FlushHeld рассылает сводку отложенных уведомлений тем пользователям, у кого
+// тихие часы закончились. Уведомление удаляется из отложенных только после того, как
+// его доставил хотя бы один канал; остальные ждут следующего прогона. Возвращает
+// число пользователей, которым ушла сводка.
+func (d *Dispatcher) FlushHeld(ctx context.Context) (int, error) {
+	users, err := d.db.GetHeldNotificationUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, email := range users {
+		prefs, err := d.db.GetNotificationPreferences(ctx, email)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if InQuietHours(prefs, d.now()) {
+			continue
+		}
+		delivered := false
+		err = d.db.ProcessHeldNotifications(ctx, email, func(held []notifyTypes.Held) []int64 {
+			done, err := d.deliverDigest(ctx, email, prefs, held)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", email, err))
+			}
+			delivered = len(done) > 0
+			return done
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", email, err))
+			continue
+		}
+		if delivered {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// deliverDigest раскладывает отложенные уведомления по каналам их типов (см. channelsFor)
+// и отправляет в каждый канал свою сводку. Возвращает id уведомлений, которые можно
+// удалить: доставленных хотя бы по одному каналу или без единого подключённого канала.
+func (d *Dispatcher) deliverDigest(ctx context.Context, email string, prefs dbTypes.NotificationPreferences,
+	held []notifyTypes.Held) ([]int64, error) {
+	byChannel := make(map[string][]notifyTypes.Held)
+	var order []string
+	done := make(map[int64]bool, len(held))
+	for _, h := range held {
+		routed := false
+		for _, name := range channelsFor(prefs, h.Message.Type) {
+			if _, ok := d.channels[name]; !ok {
+				continue
+			}
+			if _, ok := byChannel[name]; !ok {
+				order = append(order, name)
+			}
+			byChannel[name] = append(byChannel[name], h)
+			routed = true
+		}
+		if !routed {
+			d.logger.Debug().Str("email", email).Str("alert", h.Message.Type).Msg("No configured channel for held notification, dropping")
+			done[h.ID] = true
+		}
+	}
+
+	to := notifyTypes.Recipient{Email: email, WebhookURL: prefs.WebhookURL, WebhookSecret: prefs.WebhookSecret}
+	var errs []error
+	for _, name := range order {
+		group := byChannel[name]
+		msgs := make([]notifyTypes.Message, len(group))
+		for i, h := range group {
+			msgs[i] = h.Message
+		}
+		if err := d.channels[name].Notify(ctx, to, quietDigest(msgs)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		for _, h := range group {
+			done[h.ID] = true
+		}
+	}
+
+	ids := make([]int64, 0, len(done))
+	for _, h := range held {
+		if done[h.ID] {
+			ids = append(ids, h.ID)
+		}
+	}
+	return ids, errors.Join(errs...)
+}
+
+// quietDigest собирает отложенные уведомления в одно. Важность сводки — наибольшая из собранных.
+func quietDigest(held []notifyTypes.Message) notifyTypes.Message {
+	digest := notifyTypes.Message{
+		Type:     TypeQuietDigest,
+		Severity: held[0].Severity,
+		Title:    fmt.Sprintf("Пока действовали тихие часы: уведомлений — %d", len(held)),
+		Data:     map[string]string{"held": strconv.Itoa(len(held))},
+	}
+	var body strings.Builder
+	for _, m := range held {
+		if severityRank(m.Severity) > severityRank(digest.Severity) {
+			digest.Severity = m.Severity
+		}
+		body.WriteString("• ")
+		if m.Hive != "" {
+			body.WriteString(m.Hive + ": ")
+		}
+		body.WriteString(m.Title + "\n")
+	}
+	digest.Body = strings.TrimSuffix(body.String(), "\n")
+	return digest
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, email string, prefs dbTypes.NotificationPreferences,
+	channels []string, msg notifyTypes.Message) error {
+	to := notifyTypes.Recipient{Email: email, WebhookURL: prefs.WebhookURL, WebhookSecret: prefs.WebhookSecret}
+
+	var errs []error
+	for _, name := range channels {
+		ch, ok := d.channels[name]
+		if !ok {
+			d.logger.Debug().Str("channel", name).Str("email", email).Msg("Notification channel is not configured, skipping")
+			continue
+		}
+		if err := ch.Notify(ctx, to, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,332 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type mockDB struct {
+	interfaces.DB
+	prefs dbTypes.NotificationPreferences
+	err   error
+	held  []notifyTypes.Message
+}
+
+func (m *mockDB) GetNotificationPreferences(_ context.Context, _ string) (dbTypes.NotificationPreferences, error) {
+	return m.prefs, m.err
+}
+
+func (m *mockDB) HoldNotification(_ context.Context, _ string, msg notifyTypes.Message) error {
+	m.held = append(m.held, msg)
+	return nil
+}
+
+func (m *mockDB) GetHeldNotificationUsers(_ context.Context) ([]string, error) {
+	if len(m.held) == 0 {
+		return nil, nil
+	}
+	return []string{"a@b.c"}, nil
+}
+
+func (m *mockDB) ProcessHeldNotifications(_ context.Context, _ string, fn func(held []notifyTypes.Held) []int64) error {
+	if len(m.held) == 0 {
+		return nil
+	}
+	held := make([]notifyTypes.Held, len(m.held))
+	for i, msg := range m.held {
+		held[i] = notifyTypes.Held{ID: int64(i + 1), Message: msg}
+	}
+	done := fn(held)
+	var left []notifyTypes.Message
+	for _, h := range held {
+		if !slices.Contains(done, h.ID) {
+			left = append(left, h.Message)
+		}
+	}
+	m.held = left
+	return nil
+}
+
+type fakeChannel struct {
+	name string
+	err  error
+	got  []notifyTypes.Recipient
+	msgs []notifyTypes.Message
+}
+
+func (f *fakeChannel) Channel() string { return f.name }
+
+func (f *fakeChannel) Notify(_ context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error {
+	f.got = append(f.got, to)
+	f.msgs = append(f.msgs, msg)
+	return f.err
+}
+
+func TestDispatcher_FanOut(t *testing.T) {
+	push := &fakeChannel{name: notifyTypes.ChannelPush}
+	email := &fakeChannel{name: notifyTypes.ChannelEmail, err: errors.New("smtp down")}
+	db := &mockDB{prefs: dbTypes.NotificationPreferences{
+		Channels:   []string{notifyTypes.ChannelEmail, notifyTypes.ChannelPush, notifyTypes.ChannelWebhook},
+		WebhookURL: "https://example.com/hook",
+	}}
+	d := NewDispatcher(db, zerolog.Nop(), push, email)
+
+	// webhook не подключён — пропускается; ошибка email не мешает push
+	err := d.Dispatch(context.Background(), "a@b.c", notifyTypes.Message{Title: "t"})
+	if err == nil || len(push.got) != 1 || len(email.got) != 1 {
+		t.Fatalf("expected both channels called and email error, got %v, push=%d email=%d", err, len(push.got), len(email.got))
+	}
+	if push.got[0].Email != "a@b.c" || push.got[0].WebhookURL != "https://example.com/hook" {
+		t.Errorf("unexpected recipient %+v", push.got[0])
+	}
+
+	// настройки недоступны — только push
+	db.err = errors.New("db down")
+	if err := d.Dispatch(context.Background(), "a@b.c", notifyTypes.Message{}); err != nil {
+		t.Fatalf("expected push-only fallback without error, got %v", err)
+	}
+	if len(push.got) != 2 || len(email.got) != 1 {
+		t.Errorf("expected fallback to push only, got push=%d email=%d", len(push.got), len(email.got))
+	}
+}
+
+func TestDispatcher_Filters(t *testing.T) {
+	push := &fakeChannel{name: notifyTypes.ChannelPush}
+	email := &fakeChannel{name: notifyTypes.ChannelEmail}
+	db := &mockDB{prefs: dbTypes.NotificationPreferences{
+		Channels: []string{notifyTypes.ChannelPush},
+		AlertTypes: map[string]dbTypes.AlertTypePreference{
+			"signal_low":     {Enabled: false},
+			"sensor_offline": {Enabled: true, Channels: []string{notifyTypes.ChannelEmail}},
+		},
+		MinSeverity: "warning",
+		Timezone:    "Europe/Moscow",
+		QuietHours:  []dbTypes.QuietHours{{Start: "22:00", End: "07:00"}},
+	}}
+	d := NewDispatcher(db, zerolog.Nop(), push, email)
+	// 12:00 по Москве
+	now := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "signal_low", Severity: "warning"})
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "battery_low", Severity: "info"})
+	if len(push.msgs) != 0 {
+		t.Fatalf("expected disabled type and low severity to be skipped, got %+v", push.msgs)
+	}
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "sensor_offline", Severity: "warning"})
+	if len(email.msgs) != 1 || len(push.msgs) != 0 {
+		t.Fatalf("expected per-type channel to be used, got push=%d email=%d", len(push.msgs), len(email.msgs))
+	}
+
+	// 23:30 по Москве — тихие часы: обычное откладывается, важное уходит сразу
+	now = time.Date(2026, 6, 1, 20, 30, 0, 0, time.UTC)
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "battery_low", Hive: "Hive1", Severity: "warning", Title: "Заряд"})
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "temperature", Hive: "Hive2", Severity: "warning", Title: "Жарко"})
+	_ = d.Dispatch(ctx, "a@b.c", notifyTypes.Message{Type: "temperature", Severity: "critical", Important: true})
+	if len(db.held) != 2 || len(push.msgs) != 1 {
+		t.Fatalf("expected 2 held and 1 important sent, got held=%d push=%d", len(db.held), len(push.msgs))
+	}
+	if sent, err := d.FlushHeld(ctx); sent != 0 || err != nil || len(db.held) != 2 {
+		t.Fatalf("expected nothing flushed during quiet hours, got %d, %v", sent, err)
+	}
+
+	// 07:05 — сводка
+	now = time.Date(2026, 6, 2, 4, 5, 0, 0, time.UTC)
+	if sent, err := d.FlushHeld(ctx); sent != 1 || err != nil {
+		t.Fatalf("expected one digest, got %d, %v", sent, err)
+	}
+	digest := push.msgs[len(push.msgs)-1]
+	if digest.Type != TypeQuietDigest || digest.Body != "• Hive1: Заряд\n• Hive2: Жарко" || len(db.held) != 0 {
+		t.Errorf("unexpected digest %+v", digest)
+	}
+}
+
+func TestDispatcher_FlushHeldKeepsUndelivered(t *testing.T) {
+	push := &fakeChannel{name: notifyTypes.ChannelPush}
+	email := &fakeChannel{name: notifyTypes.ChannelEmail, err: errors.New("smtp down")}
+	db := &mockDB{
+		prefs: dbTypes.NotificationPreferences{
+			Channels: []string{notifyTypes.ChannelPush},
+			AlertTypes: map[string]dbTypes.AlertTypePreference{
+				"battery_low": {Enabled: true, Channels: []string{notifyTypes.ChannelEmail}},
+			},
+		},
+		held: []notifyTypes.Message{
+			{Type: "temperature", Hive: "Hive1", Severity: "warning", Title: "Жарко"},
+			{Type: "battery_low", Hive: "Hive2", Severity: "warning", Title: "Заряд"},
+		},
+	}
+	d := NewDispatcher(db, zerolog.Nop(), push, email)
+
+	// Каждый тип уходит в свои каналы; почта недоступна — её уведомление остаётся отложенным
+	sent, err := d.FlushHeld(context.Background())
+	if sent != 1 || err == nil {
+		t.Fatalf("expected one digest and email error, got %d, %v", sent, err)
+	}
+	if len(push.msgs) != 1 || push.msgs[0].Body != "• Hive1: Жарко" {
+		t.Errorf("expected push digest with temperature only, got %+v", push.msgs)
+	}
+	if len(email.msgs) != 1 || email.msgs[0].Body != "• Hive2: Заряд" {
+		t.Errorf("expected email digest with battery only, got %+v", email.msgs)
+	}
+	if len(db.held) != 1 || db.held[0].Type != "battery_low" {
+		t.Fatalf("expected undelivered notification to stay held, got %+v", db.held)
+	}
+
+	email.err = nil
+	if sent, err = d.FlushHeld(context.Background()); sent != 1 || err != nil || len(db.held) != 0 {
+		t.Errorf("expected retry to deliver the rest, got %d, %v, held=%d", sent, err, len(db.held))
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	p := dbTypes.NotificationPreferences{
+		Timezone:   "Asia/Novosibirsk",
+		QuietHours: []dbTypes.QuietHours{{Start: "13:00", End: "14:00"}, {Start: "23:00", End: "06:30"}},
+	}
+	tests := []struct {
+		utc  string
+		want bool
+	}{
+		{"2026-06-01T05:59:00Z", false}, // 12:59
+		{"2026-06-01T06:00:00Z", true},  // 13:00
+		{"2026-06-01T07:00:00Z", false}, // 14:00
+		{"2026-06-01T16:10:00Z", true},  // 23:10
+		{"2026-06-01T23:29:00Z", true},  // 06:29
+		{"2026-06-01T23:30:00Z", false}, // 06:30
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.utc)
+		if got := InQuietHours(p, now); got != tt.want {
+			t.Errorf("InQuietHours(%s) = %v, want %v", tt.utc, got, tt.want)
+		}
+	}
+}
+
+func TestWebhook_Signature(t *testing.T) {
+	var payload WebhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		if r.Header.Get(WebhookSignatureHeader) != SignWebhook("0123456789abcdef", ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &payload)
+	}))
+	defer srv.Close()
+
+	// тестовый сервер слушает loopback, поэтому проверку адреса здесь отключаем
+	wh := &Webhook{client: newWebhookClient(nil), now: func() time.Time { return time.Unix(1700000000, 0) }}
+	msg := notifyTypes.Message{Type: "battery_low", Hive: "Hive1", Severity: "critical", Title: "Низкий заряд"}
+
+	to := notifyTypes.Recipient{WebhookURL: srv.URL, WebhookSecret: "0123456789abcdef"}
+	if err := wh.Notify(context.Background(), to, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.Type != "battery_low" || payload.Hive != "Hive1" || payload.SentAt != "2023-11-14T22:13:20Z" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	to.WebhookSecret = "wrong-secret-wrong"
+	if err := wh.Notify(context.Background(), to, msg); err == nil {
+		t.Error("expected error on non-2xx response")
+	}
+	if err := wh.Notify(context.Background(), notifyTypes.Recipient{}, msg); err != nil {
+		t.Errorf("expected no-op for empty url, got %v", err)
+	}
+}
+
+func TestWebhook_RefusesInternalAndRedirects(t *testing.T) {
+	hits := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	msg := notifyTypes.Message{Type: "battery_low"}
+
+	err := NewWebhook().Notify(context.Background(), notifyTypes.Recipient{WebhookURL: target.URL}, msg)
+	if !errors.Is(err, ErrWebhookAddress) {
+		t.Errorf("expected loopback to be refused, got %v", err)
+	}
+	wh := &Webhook{client: newWebhookClient(nil), now: time.Now}
+	if err := wh.Notify(context.Background(), notifyTypes.Recipient{WebhookURL: redirect.URL}, msg); err == nil {
+		t.Error("expected redirect to fail delivery")
+	}
+	if hits != 0 {
+		t.Errorf("expected no requests to internal target, got %d", hits)
+	}
+}
+
+func TestValidatePreferences_WebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://93.184.216.34:8080/hook", true},
+		{"ftp://example.com/hook", false},
+		{"http://localhost:8000/api", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+	}
+	for _, tt := range tests {
+		err := ValidatePreferences(httpType.NotificationPreferences{WebhookURL: tt.url})
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidatePreferences(%s) = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+type mockSlowDispatcher struct {
+	release chan struct{}
+	done    chan string
+}
+
+func (m *mockSlowDispatcher) Dispatch(_ context.Context, email string, _ notifyTypes.Message) error {
+	<-m.release
+	m.done <- email
+	return nil
+}
+
+func TestQueue_DispatchDoesNotWait(t *testing.T) {
+	next := &mockSlowDispatcher{release: make(chan struct{}), done: make(chan string, 1)}
+	q := NewQueue(next, zerolog.Nop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx, 1)
+
+	if err := q.Dispatch(context.Background(), "a@b.c", notifyTypes.Message{Type: "battery_low"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(next.release)
+	select {
+	case email := <-next.done:
+		if email != "a@b.c" {
+			t.Errorf("unexpected recipient %q", email)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued notification was not delivered")
+	}
+}
@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"strings"
+)
+
+// Email доставляет уведомление письмом через SMTP.
+type Email struct {
+	sender interfaces.EmailSender
+}
+
+func NewEmail(sender interfaces.EmailSender) *Email {
+	return &Email{sender: sender}
+}
+
+func (e *Email) Channel() string {
+	return notifyTypes.ChannelEmail
+}
+
+func (e *Email) Notify(_ context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error {
+	var text strings.Builder
+	text.WriteString(msg.Body)
+	if msg.Hive != "" {
+		text.WriteString("\n\nУлей: " + msg.Hive)
+	}
+	text.WriteString("\n\nЭто письмо отправлено автоматически. Настроить уведомления можно в приложении.")
+	return e.sender.SendEmail(to.Email, msg.Title, text.String(), "")
+}
@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// FCM доставляет push через Firebase на все устройства пользователя и удаляет
+// FCM-токены, которые Firebase отверг как невалидные.
+type FCM struct {
+	client *Notification
+	db     interfaces.DB
+}
+
+func NewFCM(client *Notification, db interfaces.DB) *FCM {
+	return &FCM{client: client, db: db}
+}
+
+func (f *FCM) Channel() string {
+	return notifyTypes.ChannelPush
+}
+
+// Notify отправляет push. Пользователь без токенов — не ошибка.
+func (f *FCM) Notify(ctx context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error {
+	tokens, err := f.db.GetFirebaseToken(ctx, to.Email)
+	if err != nil {
+		return fmt.Errorf("failed to get firebase token: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	// SendNotification дописывает в Data свои поля — не трогаем карту вызывающего
+	data := make(map[string]string, len(msg.Data)+1)
+	maps.Copy(data, msg.Data)
+
+	badTokens, err := f.client.SendNotification(ctx, Data{
+		Title:     msg.Title,
+		Body:      msg.Body,
+		Data:      data,
+		Tokens:    tokens,
+		Important: msg.Important,
+	})
+	switch {
+	case errors.Is(err, ErrInvalidTokens):
+		if err := f.db.DeleteFirebaseToken(ctx, to.Email, badTokens); err != nil {
+			return fmt.Errorf("failed to delete invalid firebase token: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// Log вместо доставки пишет уведомление в лог. Подставляется вместо канала,
+// который не настроен (например, push без FIREBASE_DATA в dev и тестах).
+type Log struct {
+	channel string
+	logger  zerolog.Logger
+}
+
+func NewLog(channel string, logger zerolog.Logger) *Log {
+	return &Log{channel: channel, logger: logger}
+}
+
+func (l *Log) Channel() string {
+	return l.channel
+}
+
+func (l *Log) Notify(_ context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error {
+	l.logger.Info().
+		Str("channel", l.channel).
+		Str("email", to.Email).
+		Str("type", msg.Type).
+		Str("hive", msg.Hive).
+		Str("severity", msg.Severity).
+		Str("title", msg.Title).
+		Msg("Notification logged instead of delivery")
+	return nil
+}
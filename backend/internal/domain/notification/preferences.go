@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// minWebhookSecret — минимальная длина секрета вебхука, заданного пользователем.
+const minWebhookSecret = 16
+
+// Периодичность сводки на почту.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// maxQuietHours — сколько окон тихих часов можно задать.
+const maxQuietHours = 4
+
+var knownChannels = []string{notifyTypes.ChannelPush, notifyTypes.ChannelEmail, notifyTypes.ChannelWebhook}
+
+// severities — уровни важности по возрастанию.
+var severities = []string{alerts.SeverityInfo, alerts.SeverityWarning, alerts.SeverityCritical}
+
+// severityRank — место уровня важности в severities; неизвестный уровень ниже всех.
+func severityRank(s string) int {
+	return slices.Index(severities, s)
+}
+
+// ValidatePreferences проверяет настройки уведомлений; текст ошибки показывается пользователю.
+func ValidatePreferences(p httpType.NotificationPreferences) error {
+	if err := validateChannels(p.Channels); err != nil {
+		return err
+	}
+	for typ, tp := range p.AlertTypes {
+		if !slices.Contains(alerts.Types, typ) {
+			return fmt.Errorf("неизвестный тип алерта %q", typ)
+		}
+		if err := validateChannels(tp.Channels); err != nil {
+			return fmt.Errorf("%s: %w", typ, err)
+		}
+		if slices.Contains(tp.Channels, notifyTypes.ChannelWebhook) && p.WebhookURL == "" {
+			return errors.New("для канала webhook нужен адрес вебхука")
+		}
+	}
+	if p.MinSeverity != "" && severityRank(p.MinSeverity) < 0 {
+		return fmt.Errorf("неизвестный уровень важности %q", p.MinSeverity)
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("неизвестный часовой пояс %q", p.Timezone)
+		}
+	}
+	if p.Digest != "" && !slices.Contains([]string{DigestOff, DigestDaily, DigestWeekly}, p.Digest) {
+		return fmt.Errorf("сводка может быть off, daily или weekly, получено %q", p.Digest)
+	}
+	if len(p.QuietHours) > maxQuietHours {
+		return fmt.Errorf("можно задать не больше %d окон тихих часов", maxQuietHours)
+	}
+	for _, w := range p.QuietHours {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return errors.New("начало и конец тихих часов совпадают")
+		}
+	}
+	if p.WebhookURL != "" {
+		u, err := url.Parse(p.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("адрес вебхука должен быть http(s)-ссылкой")
+		}
+		if !webhookHostAllowed(u.Hostname()) {
+			return errors.New("адрес вебхука должен вести в интернет, а не во внутреннюю сеть")
+		}
+	} else if slices.Contains(p.Channels, notifyTypes.ChannelWebhook) {
+		return errors.New("для канала webhook нужен адрес вебхука")
+	}
+	if p.WebhookSecret != "" && len(p.WebhookSecret) < minWebhookSecret {
+		return fmt.Errorf("секрет вебхука должен быть не короче %d символов", minWebhookSecret)
+	}
+	return nil
+}
+
+// webhookHostAllowed отсекает очевидно внутренние адреса вебхука ещё при сохранении
+// настроек. Имя, которое разрешается во внутреннюю сеть, ловит уже клиент при отправке.
+func webhookHostAllowed(host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return IsPublicAddr(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+func validateChannels(channels []string) error {
+	for i, ch := range channels {
+		if !slices.Contains(knownChannels, ch) {
+			return fmt.Errorf("неизвестный канал уведомлений %q", ch)
+		}
+		if slices.Contains(channels[:i], ch) {
+			return fmt.Errorf("канал %q указан дважды", ch)
+		}
+	}
+	return nil
+}
+
+// NewWebhookSecret генерирует секрет для подписи вебхука.
+func NewWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrQueueFull — очередь уведомлений переполнена, уведомление не принято.
+var ErrQueueFull = errors.New("notification queue is full")
+
+const (
+	queueSize = 1024
+	// queueDeliveryTimeout ограничивает доставку одного уведомления по всем каналам.
+	queueDeliveryTimeout = 30 * time.Second
+)
+
+type queued struct {
+	email string
+	msg   notifyTypes.Message
+}
+
+// Queue доставляет уведомления в фоне: Dispatch только ставит уведомление в очередь,
+// поэтому медленный канал (вебхук, SMTP) не держит обработчик MQTT или анализатор.
+// Ошибки доставки пишутся в лог.
+type Queue struct {
+	next   interfaces.Dispatcher
+	jobs   chan queued
+	logger zerolog.Logger
+}
+
+func NewQueue(next interfaces.Dispatcher, logger zerolog.Logger) *Queue {
+	return &Queue{next: next, jobs: make(chan queued, queueSize), logger: logger}
+}
+
+// Start запускает workers обработчиков очереди; они работают до отмены ctx.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for range workers {
+		go q.run(ctx)
+	}
+}
+
+// Dispatch ставит уведомление в очередь и не ждёт доставки. Контекст вызывающего
+// не используется: доставка переживает обработку пакета, который её вызвал.
+func (q *Queue) Dispatch(_ context.Context, email string, msg notifyTypes.Message) error {
+	select {
+	case q.jobs <- queued{email: email, msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			dctx, cancel := context.WithTimeout(ctx, queueDeliveryTimeout)
+			if err := q.next.Dispatch(dctx, job.email, job.msg); err != nil {
+				q.logger.Warn().Err(err).Str("email", job.email).Str("alert", job.msg.Type).Msg("Failed to deliver notification")
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"fmt"
+	"time"
+)
+
+// InQuietHours сообщает, попадает ли now в одно из окон тихих часов пользователя.
+// Окна задаются по местному времени в p.Timezone; неизвестная зона считается UTC.
+func InQuietHours(p dbTypes.NotificationPreferences, now time.Time) bool {
+	if len(p.QuietHours) == 0 {
+		return false
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range p.QuietHours {
+		start, err1 := parseClock(w.Start)
+		end, err2 := parseClock(w.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start <= end {
+			if minute >= start && minute < end {
+				return true
+			}
+		} else if minute >= start || minute < end {
+			// окно через полночь, например 22:00–07:00
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock разбирает "HH:MM" в минуты от начала суток.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("время %q должно быть в формате ЧЧ:ММ", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Заголовки вебхука. Подпись — HMAC-SHA256 от "<timestamp>.<тело>" секретом пользователя;
+// метка времени в подписи не даёт переиграть старый запрос.
+const (
+	WebhookSignatureHeader = "X-BeeIOT-Signature"
+	WebhookTimestampHeader = "X-BeeIOT-Timestamp"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// ErrWebhookAddress — адрес вебхука ведёт во внутреннюю сеть.
+var ErrWebhookAddress = errors.New("webhook address is not public")
+
+// sharedAddressSpace — 100.64.0.0/10 (CGNAT): не частная сеть по RFC 1918,
+// но снаружи недоступна так же.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// WebhookPayload — тело запроса вебхука.
+type WebhookPayload struct {
+	Type      string            `json:"type"`
+	Hive      string            `json:"hive,omitempty"`
+	Severity  string            `json:"severity"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Important bool              `json:"important"`
+	Data      map[string]string `json:"data,omitempty"`
+	SentAt    string            `json:"sent_at"`
+}
+
+// Webhook доставляет уведомление POST-запросом на URL из настроек пользователя.
+type Webhook struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhook создаёт канал вебхуков. Адрес задаёт пользователь, поэтому клиент
+// ходит только на публичные адреса и не следует редиректам (см. newWebhookClient).
+func NewWebhook() *Webhook {
+	return &Webhook{client: newWebhookClient(publicOnly), now: time.Now}
+}
+
+// newWebhookClient — HTTP-клиент вебхуков. control проверяет каждый адрес, с которым
+// устанавливается соединение: он вызывается уже после разрешения имени, поэтому
+// DNS-запись, указывающая во внутреннюю сеть, проверку не обойдёт. Редирект
+// не выполняется: его ответ возвращается как есть и считается ошибкой доставки.
+// Прокси из окружения не используется — через него проверка адреса теряет смысл.
+func newWebhookClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicOnly запрещает соединения с loopback, частными, link-local и прочими
+// адресами, недоступными из интернета.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, ip)
+	}
+	return nil
+}
+
+// IsPublicAddr сообщает, можно ли слать вебхук на адрес ip.
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+func (wh *Webhook) Channel() string {
+	return notifyTypes.ChannelWebhook
+}
+
+// Notify отправляет уведомление на вебхук. Пустой URL — вебхук не настроен, не ошибка.
+func (wh *Webhook) Notify(ctx context.Context, to notifyTypes.Recipient, msg notifyTypes.Message) error {
+	if to.WebhookURL == "" {
+		return nil
+	}
+	now := wh.now()
+	body, err := json.Marshal(WebhookPayload{
+		Type:      msg.Type,
+		Hive:      msg.Hive,
+		Severity:  msg.Severity,
+		Title:     msg.Title,
+		Body:      msg.Body,
+		Important: msg.Important,
+		Data:      msg.Data,
+		SentAt:    now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(to.WebhookSecret, now.Unix(), body))
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook возвращает значение заголовка X-BeeIOT-Signature: "sha256=<hex>".
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
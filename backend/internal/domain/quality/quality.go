@@ -0,0 +1,172 @@
+// Package quality — проверка достоверности замеров на входе. Отключённый DS18B20
+// отдаёт 85 °C или -127 °C, залипший микрофон — одно и то же значение, а помеха
+// на тензодатчике — одиночный выброс. Такие замеры сохраняются с флагом качества:
+// анализаторы их не учитывают, а графики могут скрыть.
+package quality
+
+import (
+	"BeeIOT/internal/domain/rollup"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Флаги качества замера.
+const (
+	OK         = "ok"
+	OutOfRange = "out_of_range"
+	Spike      = "spike"
+	Stuck      = "stuck"
+)
+
+// HistorySize — сколько последних замеров нужно для проверки нового на выброс.
+// Для проверки залипания к ним добавляется начало текущей серии одинаковых
+// значений (см. Lookback): при замере раз в 5 секунд HistorySize замеров — это
+// всего четыре минуты.
+const HistorySize = 48
+
+const (
+	// spikeWindow — выброс ищем только относительно недавних замеров: после перерыва
+	// в связи значение могло честно уйти далеко.
+	spikeWindow = 2 * time.Hour
+	// spikeMinSamples — меньше замеров в окне — медиана ненадёжна, выброс не ищем.
+	spikeMinSamples = 3
+	spikeMaxSamples = 9
+)
+
+// Limits — пороги проверки одной метрики.
+type Limits struct {
+	// Min, Max — физически возможный диапазон
+	Min, Max float64
+	// Spike — допустимое отклонение от медианы недавних замеров; 0 — не проверять
+	Spike float64
+	// Stuck — сколько времени значение должно не меняться, чтобы считаться залипанием;
+	// 0 — не проверять
+	Stuck time.Duration
+}
+
+// limits — пороги по метрикам. Порог выброса веса выше любой честной ступеньки
+// (магазин, откачка): его ищет weightStats, и прятать её нельзя. Залипание веса
+// не проверяем: ночью вес улья действительно может не меняться часами.
+// Залипание меряем временем, а не числом замеров: DS18B20 различает шаги в 0,0625 °C,
+// и в стабильном расплоде прошивка, снимающая замер раз в 5 секунд, минутами
+// шлёт одно и то же значение.
+var limits = map[string]Limits{
+	rollup.MetricTemperature: {Min: -40, Max: 60, Spike: 10, Stuck: 6 * time.Hour},
+	rollup.MetricNoise:       {Min: 0, Max: 130, Spike: 35, Stuck: time.Hour},
+	rollup.MetricWeight:      {Min: -5, Max: 250, Spike: 40},
+}
+
+// Sample — ранее сохранённый замер метрики.
+type Sample struct {
+	Value   float64
+	Time    time.Time
+	Quality string
+}
+
+// Lookback — как далеко в прошлое искать начало серии одинаковых значений, чтобы
+// проверить залипание метрики. 0 — залипание метрики не проверяется.
+func Lookback(metric string) time.Duration {
+	return limits[metric].Stuck
+}
+
+// Flagged — замер отбракован. Пустой флаг считается достоверным.
+func Flagged(q string) bool {
+	return q != "" && q != OK
+}
+
+// Check оценивает замер value, снятый в момент t, по предыдущим замерам той же
+// метрики history (по возрастанию времени): последним HistorySize замерам и
+// первому замеру текущей серии одинаковых значений не раньше t-Lookback. Без
+// истории проверяется только диапазон.
+func Check(metric string, history []Sample, value float64, t time.Time) string {
+	l, ok := limits[metric]
+	if !ok {
+		return OK
+	}
+	if value < l.Min || value > l.Max {
+		return OutOfRange
+	}
+	if l.Stuck > 0 && stuck(history, value, t, l.Stuck) {
+		return Stuck
+	}
+	if l.Spike > 0 {
+		if m, ok := recentMedian(history, t); ok && math.Abs(value-m) > l.Spike {
+			return Spike
+		}
+	}
+	return OK
+}
+
+// CheckSeries проставляет флаги качества замерам samples (по возрастанию времени).
+// Замеры проверяются по порядку, и каждый проверенный становится историей для
+// следующего; history — замеры, сохранённые до первого из них. Возвращает число
+// отбракованных.
+func CheckSeries(metric string, history, samples []Sample) int {
+	history = slices.Clone(history)
+	flagged := 0
+	for i := range samples {
+		s := &samples[i]
+		s.Quality = Check(metric, history, s.Value, s.Time)
+		if s.Quality != OK {
+			flagged++
+		}
+		history = trim(append(history, *s))
+	}
+	return flagged
+}
+
+// trim оставляет от истории последние HistorySize замеров и первый замер текущей
+// серии одинаковых значений — столько же, сколько отдаёт база для одиночного замера.
+func trim(history []Sample) []Sample {
+	cut := len(history) - HistorySize
+	if cut <= 0 {
+		return history
+	}
+	start := len(history) - 1
+	for start > 0 && history[start-1].Value == history[start].Value {
+		start--
+	}
+	if start >= cut {
+		return history[cut:]
+	}
+	return append([]Sample{history[start]}, history[cut:]...)
+}
+
+// stuck — value не меняется хотя бы window: серия одинаковых замеров в конце истории
+// началась не позже t-window. Если серия занимает всю историю, а история по времени
+// короче window, залипание не доказано.
+func stuck(history []Sample, value float64, t time.Time, window time.Duration) bool {
+	start := t
+	for i := len(history) - 1; i >= 0 && history[i].Value == value; i-- {
+		start = history[i].Time
+	}
+	return t.Sub(start) >= window
+}
+
+// recentMedian — медиана последних замеров в окне spikeWindow до t. Замеры вне
+// диапазона в медиану не идут, а выбросы и залипания идут: после честного сдвига
+// уровня медиана догоняет его, и новые значения перестают считаться выбросом.
+func recentMedian(history []Sample, t time.Time) (float64, bool) {
+	var values []float64
+	for i := len(history) - 1; i >= 0 && len(values) < spikeMaxSamples; i-- {
+		s := history[i]
+		if t.Sub(s.Time) > spikeWindow {
+			break
+		}
+		if s.Quality == OutOfRange {
+			continue
+		}
+		values = append(values, s.Value)
+	}
+	if len(values) < spikeMinSamples {
+		return 0, false
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2, true
+	}
+	return values[mid], true
+}
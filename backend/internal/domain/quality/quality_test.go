@@ -0,0 +1,164 @@
+package quality
+
+import (
+	"BeeIOT/internal/domain/rollup"
+	"slices"
+	"testing"
+	"time"
+)
+
+func series(start time.Time, step time.Duration, values ...float64) []Sample {
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		samples[i] = Sample{Value: v, Time: start.Add(time.Duration(i) * step), Quality: OK}
+	}
+	return samples
+}
+
+func TestCheck_Range(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	// отключённый DS18B20
+	for _, v := range []float64{85, -127} {
+		if q := Check(rollup.MetricTemperature, nil, v, now); q != OutOfRange {
+			t.Errorf("expected %s for %v °C, got %s", OutOfRange, v, q)
+		}
+	}
+	if q := Check(rollup.MetricTemperature, nil, 34.5, now); q != OK {
+		t.Errorf("expected ok without history, got %s", q)
+	}
+	if q := Check("humidity", nil, 1000, now); q != OK {
+		t.Errorf("expected ok for unknown metric, got %s", q)
+	}
+}
+
+func TestCheck_Spike(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := series(now.Add(-50*time.Minute), 10*time.Minute, 34.5, 34.6, 34.4, 34.5, 34.7)
+
+	if q := Check(rollup.MetricTemperature, history, 52, now); q != Spike {
+		t.Errorf("expected spike, got %s", q)
+	}
+	if q := Check(rollup.MetricTemperature, history, 35, now); q != OK {
+		t.Errorf("expected ok, got %s", q)
+	}
+	// после долгого перерыва сравнивать не с чем
+	if q := Check(rollup.MetricTemperature, history, 52, now.Add(6*time.Hour)); q != OK {
+		t.Errorf("expected ok after gap, got %s", q)
+	}
+	// показания за пределами диапазона не сдвигают медиану
+	history = series(now.Add(-50*time.Minute), 10*time.Minute, 34.5, 85, 34.4, 85, 34.7)
+	history[1].Quality, history[3].Quality = OutOfRange, OutOfRange
+	if q := Check(rollup.MetricTemperature, history, 34.6, now); q != OK {
+		t.Errorf("expected ok, got %s", q)
+	}
+}
+
+func TestCheck_SpikeRecovers(t *testing.T) {
+	// магазин поставили: +15 кг — не выброс для веса
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := series(now.Add(-50*time.Minute), 10*time.Minute, 40, 40.1, 40.2, 40.1, 40)
+	if q := Check(rollup.MetricWeight, history, 55, now); q != OK {
+		t.Errorf("expected super to pass, got %s", q)
+	}
+
+	// шум честно вырос — медиана догоняет уровень за несколько замеров
+	history = series(now.Add(-50*time.Minute), 10*time.Minute, 40, 41, 40, 80, 81)
+	history[3].Quality, history[4].Quality = Spike, Spike
+	if q := Check(rollup.MetricNoise, history, 80, now); q != Spike {
+		t.Errorf("expected spike while median lags, got %s", q)
+	}
+	history = append(history, Sample{Value: 80, Time: now, Quality: Spike})
+	if q := Check(rollup.MetricNoise, history, 82, now.Add(10*time.Minute)); q != OK {
+		t.Errorf("expected ok once median caught up, got %s", q)
+	}
+}
+
+func TestCheck_Stuck(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	values := make([]float64, 11)
+	for i := range values {
+		values[i] = 47.3
+	}
+	history := series(now.Add(-110*time.Minute), 10*time.Minute, values...)
+	if q := Check(rollup.MetricNoise, history, 47.3, now); q != Stuck {
+		t.Errorf("expected stuck, got %s", q)
+	}
+	if q := Check(rollup.MetricNoise, history[6:], 47.3, now); q != OK {
+		t.Errorf("expected ok for shorter run, got %s", q)
+	}
+	// вес на залипание не проверяем
+	if q := Check(rollup.MetricWeight, history, 47.3, now); q != OK {
+		t.Errorf("expected ok for weight, got %s", q)
+	}
+}
+
+func TestCheck_StableBroodNotStuck(t *testing.T) {
+	// прошивка снимает температуру раз в 5 секунд, а в стабильном расплоде DS18B20
+	// часами стоит на одном шаге 0,0625 °C
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	values := make([]float64, HistorySize)
+	for i := range values {
+		values[i] = 34.56
+	}
+	history := series(now.Add(-time.Duration(HistorySize)*5*time.Second), 5*time.Second, values...)
+	if q := Check(rollup.MetricTemperature, history, 34.56, now); q != OK {
+		t.Errorf("expected stable brood temperature ok, got %s", q)
+	}
+
+	// залипший датчик не менялся больше шести часов
+	history = series(now.Add(-7*time.Hour), time.Hour, values[:7]...)
+	if q := Check(rollup.MetricTemperature, history, 34.56, now); q != Stuck {
+		t.Errorf("expected stuck after 7 hours, got %s", q)
+	}
+}
+
+func TestCheck_StuckAtFiveSeconds(t *testing.T) {
+	// так историю отдаёт база: начало серии одинаковых значений и последние HistorySize замеров
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	values := make([]float64, HistorySize)
+	for i := range values {
+		values[i] = 47.3
+	}
+	recent := series(now.Add(-time.Duration(HistorySize)*5*time.Second), 5*time.Second, values...)
+	history := append([]Sample{{Value: 47.3, Time: now.Add(-61 * time.Minute), Quality: OK}}, recent...)
+	if q := Check(rollup.MetricNoise, history, 47.3, now); q != Stuck {
+		t.Errorf("expected stuck after an hour of 5-second samples, got %s", q)
+	}
+	history[0].Time = now.Add(-59 * time.Minute)
+	if q := Check(rollup.MetricNoise, history, 47.3, now); q != OK {
+		t.Errorf("expected ok for a run shorter than an hour, got %s", q)
+	}
+}
+
+func TestCheckSeries_StuckAtFiveSeconds(t *testing.T) {
+	// микрофон залип: 70 минут одного и того же значения раз в 5 секунд
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	values := make([]float64, 70*12)
+	for i := range values {
+		values[i] = 47.3
+	}
+	samples := series(now, 5*time.Second, values...)
+	if flagged := CheckSeries(rollup.MetricNoise, nil, samples); flagged != 10*12 {
+		t.Errorf("expected the last 10 minutes flagged, got %d", flagged)
+	}
+	if samples[60*12-1].Quality != OK || samples[60*12].Quality != Stuck {
+		t.Errorf("expected stuck from the first hour on, got %s and %s",
+			samples[60*12-1].Quality, samples[60*12].Quality)
+	}
+}
+
+func TestCheckSeries(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	samples := series(now, 10*time.Minute, 34, 34.2, 34.1, 48, 85, 34.3)
+	if flagged := CheckSeries(rollup.MetricTemperature, nil, samples); flagged != 2 {
+		t.Errorf("expected 2 flagged, got %d", flagged)
+	}
+	var got []string
+	for _, s := range samples {
+		got = append(got, s.Quality)
+	}
+	want := []string{OK, OK, OK, Spike, OutOfRange, OK}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
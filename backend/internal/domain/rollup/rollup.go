@@ -0,0 +1,86 @@
+package rollup
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Метрики телеметрии, для которых ведутся агрегаты.
+const (
+	MetricTemperature = "temperature"
+	MetricNoise       = "noise"
+	MetricWeight      = "weight"
+)
+
+// Metrics — все агрегируемые метрики.
+var Metrics = []string{MetricTemperature, MetricNoise, MetricWeight}
+
+// Разрешения, в которых отдаётся телеметрия.
+const (
+	ResolutionRaw  = "raw"
+	ResolutionHour = "hour"
+	ResolutionDay  = "day"
+	ResolutionAuto = "auto"
+)
+
+// DefaultMaxPoints — сколько точек отдаём при resolution=auto без max_points.
+// Примерно столько помещается на графике телефона без потери читаемости.
+const DefaultMaxPoints = 1000
+
+// Query — параметры выборки телеметрии из запроса клиента.
+type Query struct {
+	Resolution string
+	MaxPoints  int
+}
+
+// ParseQuery разбирает параметры resolution и max_points. Без обоих параметров
+// отдаём сырые данные, как и раньше; max_points без resolution включает автовыбор.
+func ParseQuery(resolution, maxPoints string) (Query, error) {
+	q := Query{Resolution: resolution}
+	if maxPoints != "" {
+		n, err := strconv.Atoi(maxPoints)
+		if err != nil || n <= 0 {
+			return Query{}, fmt.Errorf("invalid max_points: %q", maxPoints)
+		}
+		q.MaxPoints = n
+	}
+	switch q.Resolution {
+	case "":
+		if q.MaxPoints == 0 {
+			q.Resolution = ResolutionRaw
+		} else {
+			q.Resolution = ResolutionAuto
+		}
+	case ResolutionRaw, ResolutionHour, ResolutionDay, ResolutionAuto:
+	default:
+		return Query{}, fmt.Errorf("invalid resolution: %q", resolution)
+	}
+	if q.Resolution == ResolutionAuto && q.MaxPoints == 0 {
+		q.MaxPoints = DefaultMaxPoints
+	}
+	return q, nil
+}
+
+// Choose выбирает разрешение для периода span. Для явно заданного разрешения
+// возвращает его же. Для auto берёт самое подробное, укладывающееся в MaxPoints:
+// сырые данные (их количество узнаём через countRaw), затем часовые, затем суточные.
+// countRaw вызывается только если период укладывается в MaxPoints часов — иначе
+// сырых точек заведомо больше, а считать их на длинном периоде дорого.
+func (q Query) Choose(span time.Duration, countRaw func() (int, error)) (string, error) {
+	if q.Resolution != ResolutionAuto {
+		return q.Resolution, nil
+	}
+	hours := int(span / time.Hour)
+	if hours > q.MaxPoints {
+		return ResolutionDay, nil
+	}
+	n, err := countRaw()
+	if err != nil {
+		return "", err
+	}
+	if n <= q.MaxPoints {
+		return ResolutionRaw, nil
+	}
+	return ResolutionHour, nil
+}
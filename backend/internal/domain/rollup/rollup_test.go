@@ -0,0 +1,63 @@
+package rollup
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		resolution, maxPoints string
+		want                  Query
+		wantErr               bool
+	}{
+		{"", "", Query{Resolution: ResolutionRaw}, false},
+		{"", "200", Query{Resolution: ResolutionAuto, MaxPoints: 200}, false},
+		{"auto", "", Query{Resolution: ResolutionAuto, MaxPoints: DefaultMaxPoints}, false},
+		{"hour", "", Query{Resolution: ResolutionHour}, false},
+		{"day", "50", Query{Resolution: ResolutionDay, MaxPoints: 50}, false},
+		{"minute", "", Query{}, true},
+		{"", "0", Query{}, true},
+		{"", "abc", Query{}, true},
+	}
+	for _, c := range cases {
+		got, err := ParseQuery(c.resolution, c.maxPoints)
+		if (err != nil) != c.wantErr {
+			t.Fatalf("ParseQuery(%q, %q) error = %v, wantErr %v", c.resolution, c.maxPoints, err, c.wantErr)
+		}
+		if got != c.want {
+			t.Errorf("ParseQuery(%q, %q) = %+v, want %+v", c.resolution, c.maxPoints, got, c.want)
+		}
+	}
+}
+
+func TestChoose(t *testing.T) {
+	count := func(n int) func() (int, error) {
+		return func() (int, error) { return n, nil }
+	}
+	q := Query{Resolution: ResolutionAuto, MaxPoints: 500}
+
+	// Сутки, мало точек — сырые данные
+	if res, _ := q.Choose(24*time.Hour, count(300)); res != ResolutionRaw {
+		t.Errorf("expected raw, got %s", res)
+	}
+	// Сутки при опросе раз в 5 секунд — часовые агрегаты
+	if res, _ := q.Choose(24*time.Hour, count(17280)); res != ResolutionHour {
+		t.Errorf("expected hour, got %s", res)
+	}
+	// Месяц — часов больше лимита, сырые даже не считаем
+	called := false
+	res, _ := q.Choose(30*24*time.Hour, func() (int, error) { called = true; return 0, nil })
+	if res != ResolutionDay || called {
+		t.Errorf("expected day without counting raw, got %s (counted=%v)", res, called)
+	}
+	// Явное разрешение не пересчитывается
+	if res, _ := (Query{Resolution: ResolutionHour}).Choose(365*24*time.Hour, count(0)); res != ResolutionHour {
+		t.Errorf("expected explicit hour, got %s", res)
+	}
+	// Ошибка подсчёта пробрасывается
+	if _, err := q.Choose(time.Hour, func() (int, error) { return 0, errors.New("db down") }); err == nil {
+		t.Error("expected error from countRaw")
+	}
+}
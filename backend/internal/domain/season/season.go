@@ -0,0 +1,120 @@
+// Package season определяет, живёт ли семья летом (расплод, гнездо 34–35 °C) или
+// зимним клубом. Зиму можно задать датами в окружении, иначе она оценивается по
+// широте пасеки; для отдельного улья режим можно выставить вручную.
+package season
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Режимы улья.
+const (
+	// ModeAuto — сезон по календарю пасеки
+	ModeAuto   = "auto"
+	ModeSummer = "summer"
+	ModeWinter = "winter"
+)
+
+// ValidMode проверяет режим сезона улья.
+func ValidMode(mode string) bool {
+	return mode == ModeAuto || mode == ModeSummer || mode == ModeWinter
+}
+
+// defaultLatitude — широта по умолчанию (средняя полоса), если APIARY_LATITUDE не задана.
+const defaultLatitude = 55.0
+
+// Зима по широте: клуб держится примерно ±75 дней вокруг середины января на 50°
+// и на 2 дня дольше с каждым градусом к полюсу. Ближе 30° к экватору зимы нет.
+const (
+	referenceLatitude = 50.0
+	referenceHalf     = 75
+	daysPerDegree     = 2
+	minHalf, maxHalf  = 30, 120
+	subtropics        = 30.0
+)
+
+type monthDay struct {
+	month time.Month
+	day   int
+}
+
+func (md monthDay) key() int {
+	return int(md.month)*100 + md.day
+}
+
+// Calendar — календарная зима пасеки: [start, end), может переходить через Новый год.
+type Calendar struct {
+	start, end monthDay
+	none       bool
+}
+
+// ForLatitude оценивает зиму по широте; в южном полушарии она сдвинута на полгода.
+func ForLatitude(lat float64) Calendar {
+	abs := math.Abs(lat)
+	if abs < subtropics {
+		return Calendar{none: true}
+	}
+	half := int(math.Round(referenceHalf + daysPerDegree*(abs-referenceLatitude)))
+	half = min(max(half, minHalf), maxHalf)
+	// невисокосный год: границы не должны зависеть от года
+	mid := time.Date(2001, time.January, 15, 0, 0, 0, 0, time.UTC)
+	if lat < 0 {
+		mid = mid.AddDate(0, 6, 0)
+	}
+	start, end := mid.AddDate(0, 0, -half), mid.AddDate(0, 0, half)
+	return Calendar{
+		start: monthDay{start.Month(), start.Day()},
+		end:   monthDay{end.Month(), end.Day()},
+	}
+}
+
+// CalendarFromEnv читает WINTER_START и WINTER_END (ММ-ДД). Если они не заданы или
+// неверны, зима оценивается по APIARY_LATITUDE.
+func CalendarFromEnv() Calendar {
+	start, okStart := parseMonthDay(os.Getenv("WINTER_START"))
+	end, okEnd := parseMonthDay(os.Getenv("WINTER_END"))
+	if okStart && okEnd && start != end {
+		return Calendar{start: start, end: end}
+	}
+	lat, err := strconv.ParseFloat(os.Getenv("APIARY_LATITUDE"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		lat = defaultLatitude
+	}
+	return ForLatitude(lat)
+}
+
+func parseMonthDay(s string) (monthDay, bool) {
+	t, err := time.Parse("01-02", s)
+	if err != nil {
+		return monthDay{}, false
+	}
+	return monthDay{t.Month(), t.Day()}, true
+}
+
+// IsWinter — попадает ли t в календарную зиму.
+func (c Calendar) IsWinter(t time.Time) bool {
+	if c.none {
+		return false
+	}
+	md := monthDay{t.Month(), t.Day()}.key()
+	start, end := c.start.key(), c.end.key()
+	if start < end {
+		return md >= start && md < end
+	}
+	return md >= start || md < end
+}
+
+// IsWinter учитывает режим улья: ручной режим важнее календаря.
+func IsWinter(mode string, c Calendar, t time.Time) bool {
+	switch mode {
+	case ModeWinter:
+		return true
+	case ModeSummer:
+		return false
+	default:
+		return c.IsWinter(t)
+	}
+}
@@ -0,0 +1,67 @@
+package season
+
+import (
+	"testing"
+	"time"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestForLatitude(t *testing.T) {
+	tests := []struct {
+		name string
+		lat  float64
+		at   time.Time
+		want bool
+	}{
+		{"moscow january", 55.7, date(time.January, 10), true},
+		{"moscow july", 55.7, date(time.July, 10), false},
+		// на 50° зима — с 1 ноября по 31 марта
+		{"50° late october", 50, date(time.October, 31), false},
+		{"50° november", 50, date(time.November, 1), true},
+		{"50° end of march", 50, date(time.March, 31), false},
+		// на севере зима длиннее
+		{"62° mid april", 62, date(time.April, 15), true},
+		{"southern hemisphere july", -40, date(time.July, 1), true},
+		{"southern hemisphere january", -40, date(time.January, 10), false},
+		{"subtropics", 25, date(time.January, 10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForLatitude(tt.lat).IsWinter(tt.at); got != tt.want {
+				t.Errorf("IsWinter(%v) = %v, want %v", tt.at.Format("01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarFromEnv(t *testing.T) {
+	t.Setenv("WINTER_START", "10-15")
+	t.Setenv("WINTER_END", "04-01")
+	c := CalendarFromEnv()
+	if !c.IsWinter(date(time.October, 20)) || c.IsWinter(date(time.April, 1)) {
+		t.Errorf("expected winter from WINTER_START/WINTER_END")
+	}
+
+	// без дат — по широте
+	t.Setenv("WINTER_START", "")
+	t.Setenv("APIARY_LATITUDE", "-45")
+	if c := CalendarFromEnv(); !c.IsWinter(date(time.July, 1)) || c.IsWinter(date(time.January, 1)) {
+		t.Errorf("expected southern winter from APIARY_LATITUDE")
+	}
+}
+
+func TestIsWinter_Mode(t *testing.T) {
+	c := ForLatitude(55)
+	if IsWinter(ModeSummer, c, date(time.January, 10)) {
+		t.Error("expected manual summer to override calendar")
+	}
+	if !IsWinter(ModeWinter, c, date(time.July, 10)) {
+		t.Error("expected manual winter to override calendar")
+	}
+	if !IsWinter("", c, date(time.January, 10)) {
+		t.Error("expected empty mode to follow calendar")
+	}
+}
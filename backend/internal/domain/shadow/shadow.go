@@ -0,0 +1,28 @@
+// Package shadow — сравнение желаемой и применённой устройством конфигурации хаба.
+package shadow
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/mqttTypes"
+)
+
+// Delta возвращает конфиг, в котором заданы только ещё не применённые устройством
+// параметры (остальные -1), и признак того, что такие параметры есть.
+// Команды (restart/health/delete) в shadow не хранятся и в дельту не попадают.
+func Delta(s dbTypes.DeviceShadow) (mqttTypes.DeviceConfig, bool) {
+	delta := mqttTypes.NewDeviceConfig()
+	pending := false
+	if s.DesiredNoise != -1 && s.DesiredNoise != s.ReportedNoise {
+		delta.SamplingNoise = s.DesiredNoise
+		pending = true
+	}
+	if s.DesiredTemp != -1 && s.DesiredTemp != s.ReportedTemp {
+		delta.SamplingTemp = s.DesiredTemp
+		pending = true
+	}
+	if s.DesiredFrequency != -1 && s.DesiredFrequency != s.ReportedFrequency {
+		delta.Frequency = s.DesiredFrequency
+		pending = true
+	}
+	return delta, pending
+}
@@ -0,0 +1,40 @@
+package shadow
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"testing"
+)
+
+func TestDelta(t *testing.T) {
+	// ничего не задано — синхронизировать нечего
+	if _, pending := Delta(dbTypes.DeviceShadow{
+		DesiredNoise: -1, DesiredTemp: -1, DesiredFrequency: -1,
+		ReportedNoise: 5, ReportedTemp: 5, ReportedFrequency: 5,
+	}); pending {
+		t.Error("expected no delta when nothing is desired")
+	}
+
+	// частично применено
+	delta, pending := Delta(dbTypes.DeviceShadow{
+		DesiredNoise: 3600, DesiredTemp: 600, DesiredFrequency: -1,
+		ReportedNoise: 3600, ReportedTemp: 5, ReportedFrequency: 5,
+	})
+	if !pending {
+		t.Fatal("expected pending delta")
+	}
+	if delta.SamplingNoise != -1 || delta.SamplingTemp != 600 || delta.Frequency != -1 {
+		t.Errorf("unexpected delta: %+v", delta)
+	}
+	if delta.Restart || delta.Health || delta.Delete {
+		t.Errorf("commands must not be part of the delta: %+v", delta)
+	}
+
+	// устройство ещё ни разу не отчитывалось
+	delta, pending = Delta(dbTypes.DeviceShadow{
+		DesiredNoise: 60, DesiredTemp: -1, DesiredFrequency: 60,
+		ReportedNoise: -1, ReportedTemp: -1, ReportedFrequency: -1,
+	})
+	if !pending || delta.SamplingNoise != 60 || delta.Frequency != 60 {
+		t.Errorf("unexpected delta for unreported device: %+v", delta)
+	}
+}
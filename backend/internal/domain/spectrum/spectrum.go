@@ -0,0 +1,42 @@
+// Package spectrum — признаки спектра шума улья, которые прошивка присылает
+// уровнями по полосам частот (см. mqttTypes.Spectrum).
+package spectrum
+
+import "math"
+
+// Metric — имя спектра шума среди таблиц телеметрии, которые секционирует и чистит
+// по сроку хранения фоновое задание (internal/analyzer/partition).
+const Metric = "spectrum"
+
+// Полоса «рёва»: безматочная семья гудит громче и выше обычного, и энергия
+// смещается в 200–500 Гц.
+const (
+	RoarLow  = 200.0
+	RoarHigh = 500.0
+)
+
+// BandPower — суммарная мощность (в линейных единицах) в диапазоне [low, high) Гц.
+// Полосы, частично попавшие в диапазон, учитываются пропорционально перекрытию.
+func BandPower(edges, levels []float64, low, high float64) float64 {
+	var power float64
+	for i, level := range levels {
+		from, to := math.Max(edges[i], low), math.Min(edges[i+1], high)
+		if to <= from {
+			continue
+		}
+		power += math.Pow(10, level/10) * (to - from) / (edges[i+1] - edges[i])
+	}
+	return power
+}
+
+// RoarRatio — доля мощности спектра, приходящаяся на полосу рёва, 0..1.
+func RoarRatio(edges, levels []float64) float64 {
+	if len(edges) < 2 {
+		return 0
+	}
+	total := BandPower(edges, levels, edges[0], edges[len(edges)-1])
+	if total == 0 {
+		return 0
+	}
+	return BandPower(edges, levels, RoarLow, RoarHigh) / total
+}
@@ -0,0 +1,32 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoarRatio(t *testing.T) {
+	edges := []float64{100, 200, 300, 500, 1000}
+
+	// равные уровни: рёв — полосы 200–300 и 300–500, т.е. две из четырёх
+	if r := RoarRatio(edges, []float64{40, 40, 40, 40}); math.Abs(r-0.5) > 1e-9 {
+		t.Errorf("expected 0.5 for flat spectrum, got %v", r)
+	}
+
+	// +10 дБ в полосе рёва — в 10 раз больше мощности
+	if r := RoarRatio(edges, []float64{40, 50, 50, 40}); math.Abs(r-20.0/22) > 1e-9 {
+		t.Errorf("expected roar to dominate, got %v", r)
+	}
+
+	// пустой спектр
+	if r := RoarRatio(nil, nil); r != 0 {
+		t.Errorf("expected 0 for empty spectrum, got %v", r)
+	}
+}
+
+func TestBandPower_PartialOverlap(t *testing.T) {
+	// полоса 0–1000 Гц на 0 дБ: в 200–500 попадает 30% её мощности
+	if p := BandPower([]float64{0, 1000}, []float64{0}, RoarLow, RoarHigh); math.Abs(p-0.3) > 1e-9 {
+		t.Errorf("expected 0.3, got %v", p)
+	}
+}
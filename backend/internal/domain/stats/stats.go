@@ -0,0 +1,85 @@
+// Package stats — параметры статистики телеметрии (/api/telemetry/stats). Сама
+// статистика считается в SQL по сырым замерам; здесь — разбор запроса и ограничения.
+package stats
+
+import (
+	"BeeIOT/internal/domain/rollup"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Интервалы группировки. Границы — по UTC, как у агрегатов telemetry_hourly/daily;
+// неделя начинается с понедельника.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week"
+)
+
+var bucketSizes = map[string]time.Duration{
+	BucketHour: time.Hour,
+	BucketDay:  24 * time.Hour,
+	BucketWeek: 7 * 24 * time.Hour,
+}
+
+// Percentiles — перцентили, которые считаются для каждого интервала.
+var Percentiles = []float64{0.1, 0.25, 0.5, 0.75, 0.9}
+
+// MaxBuckets — сколько интервалов можно запросить за раз на одну метрику. Год по часам
+// уже не влезает: для длинных периодов есть day и week.
+const MaxBuckets = 2000
+
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// Query — разобранные параметры запроса статистики.
+type Query struct {
+	Metrics []string
+	Bucket  string
+	Since   time.Time
+	Until   time.Time
+}
+
+// ParseQuery разбирает список метрик через запятую (пусто — все метрики) и интервал
+// группировки (пусто — сутки) и проверяет окно [since, until).
+func ParseQuery(metrics, bucket string, since, until time.Time) (Query, error) {
+	q := Query{Bucket: bucket, Since: since, Until: until}
+	if q.Bucket == "" {
+		q.Bucket = BucketDay
+	}
+	size, ok := bucketSizes[q.Bucket]
+	if !ok {
+		return Query{}, fmt.Errorf("invalid bucket: %q", bucket)
+	}
+	if metrics == "" {
+		q.Metrics = slices.Clone(rollup.Metrics)
+	}
+	for _, m := range strings.Split(metrics, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" || slices.Contains(q.Metrics, m) {
+			continue
+		}
+		if !slices.Contains(rollup.Metrics, m) {
+			return Query{}, fmt.Errorf("invalid metric: %q", m)
+		}
+		q.Metrics = append(q.Metrics, m)
+	}
+	if len(q.Metrics) == 0 {
+		return Query{}, fmt.Errorf("no metrics in %q", metrics)
+	}
+	if !until.After(since) {
+		return Query{}, fmt.Errorf("until %s is not after since %s", until, since)
+	}
+	// +1: окно может начинаться и заканчиваться посреди интервала
+	if int(until.Sub(since)/size)+1 > MaxBuckets {
+		return Query{}, ErrTooManyBuckets
+	}
+	return q, nil
+}
+
+// PercentileName — ключ перцентиля в ответе: 0.25 → "p25".
+func PercentileName(p float64) string {
+	return fmt.Sprintf("p%g", p*100)
+}
@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"BeeIOT/internal/domain/rollup"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	until := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	since := until.AddDate(0, 0, -30)
+
+	q, err := ParseQuery("", "", since, until)
+	if err != nil || q.Bucket != BucketDay || !slices.Equal(q.Metrics, rollup.Metrics) {
+		t.Fatalf("expected defaults, got %+v, %v", q, err)
+	}
+
+	q, err = ParseQuery("weight, temperature,weight", BucketHour, since, until)
+	if err != nil || !slices.Equal(q.Metrics, []string{rollup.MetricWeight, rollup.MetricTemperature}) {
+		t.Fatalf("expected requested metrics in order without duplicates, got %+v, %v", q, err)
+	}
+
+	for _, c := range []struct{ metrics, bucket string }{
+		{"humidity", BucketDay},
+		{"noise", "minute"},
+		{",", BucketDay},
+	} {
+		if _, err := ParseQuery(c.metrics, c.bucket, since, until); err == nil {
+			t.Errorf("expected error for metrics=%q bucket=%q", c.metrics, c.bucket)
+		}
+	}
+
+	if _, err := ParseQuery("", "", until, since); err == nil {
+		t.Errorf("expected error for reversed window")
+	}
+	if _, err := ParseQuery("", BucketHour, until.AddDate(-1, 0, 0), until); !errors.Is(err, ErrTooManyBuckets) {
+		t.Errorf("expected ErrTooManyBuckets for a year by hour, got %v", err)
+	}
+	if _, err := ParseQuery("", BucketWeek, until.AddDate(-1, 0, 0), until); err != nil {
+		t.Errorf("expected a year by week to pass, got %v", err)
+	}
+}
+
+func TestPercentileName(t *testing.T) {
+	for p, want := range map[float64]string{0.1: "p10", 0.25: "p25", 0.5: "p50", 0.9: "p90"} {
+		if got := PercentileName(p); got != want {
+			t.Errorf("PercentileName(%v) = %s, want %s", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// subscriberBuffer — сколько событий может ждать медленного клиента. Если клиент
+// не успевает их вычитывать, лишние события для него отбрасываются, чтобы один
+// зависший телефон не тормозил раздачу остальным.
+const subscriberBuffer = 64
+
+// resubscribeDelay — пауза перед повторной подпиской, если Redis недоступен.
+const resubscribeDelay = 5 * time.Second
+
+// Broker принимает события телеметрии из Redis pub/sub и раздаёт их клиентам,
+// подключённым к этой реплике сервера, по id хаба. Идентификатор датчика для этого
+// не годится: у разных пользователей хабы с одним датчиком — разные хабы.
+type Broker struct {
+	inMemDb interfaces.InMemoryDB
+	logger  zerolog.Logger
+
+	mu   sync.RWMutex
+	subs map[int]map[*Subscription]struct{}
+}
+
+// Subscription — подписка одного клиента на события своих хабов.
+type Subscription struct {
+	Events <-chan httpType.TelemetryEvent
+	events chan httpType.TelemetryEvent
+	hubs   []int
+}
+
+func NewBroker(inMemDb interfaces.InMemoryDB, logger zerolog.Logger) *Broker {
+	return &Broker{inMemDb: inMemDb, logger: logger, subs: make(map[int]map[*Subscription]struct{})}
+}
+
+// Start запускает приём событий из Redis до отмены ctx.
+func (b *Broker) Start(ctx context.Context) {
+	go func() {
+		for {
+			events, err := b.inMemDb.SubscribeTelemetry(ctx)
+			if err != nil {
+				b.logger.Error().Err(err).Msg("stream: failed to subscribe to telemetry events")
+			} else {
+				for raw := range events {
+					b.dispatch(raw)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeDelay):
+			}
+		}
+	}()
+}
+
+func (b *Broker) dispatch(raw string) {
+	var event httpType.TelemetryEvent
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		b.logger.Warn().Err(err).Msg("stream: failed to unmarshal telemetry event")
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs[event.HubID] {
+		select {
+		case sub.events <- event:
+		default:
+			b.logger.Warn().Str("hub", event.Hub).Msg("stream: subscriber is too slow, dropping event")
+		}
+	}
+}
+
+// Subscribe подписывает клиента на события хабов с перечисленными id.
+// После завершения нужно вызвать Unsubscribe.
+func (b *Broker) Subscribe(hubs []int) *Subscription {
+	events := make(chan httpType.TelemetryEvent, subscriberBuffer)
+	sub := &Subscription{Events: events, events: events, hubs: hubs}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, hub := range hubs {
+		if b.subs[hub] == nil {
+			b.subs[hub] = make(map[*Subscription]struct{})
+		}
+		b.subs[hub][sub] = struct{}{}
+	}
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, hub := range sub.hubs {
+		delete(b.subs[hub], sub)
+		if len(b.subs[hub]) == 0 {
+			delete(b.subs, hub)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"BeeIOT/internal/domain/interfaces"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type MockInMemoryDB struct {
+	interfaces.InMemoryDB
+	events chan string
+}
+
+func (m *MockInMemoryDB) SubscribeTelemetry(_ context.Context) (<-chan string, error) {
+	return m.events, nil
+}
+
+func TestBroker_RoutesEventsByHub(t *testing.T) {
+	inMem := &MockInMemoryDB{events: make(chan string)}
+	b := NewBroker(inMem, zerolog.Nop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.Start(ctx)
+
+	mine := b.Subscribe([]int{1, 2})
+	other := b.Subscribe([]int{3})
+	defer b.Unsubscribe(other)
+
+	// Хаб 3 — чужой хаб с тем же датчиком hub-2: событие хаба 2 к нему не попадает
+	inMem.events <- `{"hub_id":2,"hub":"hub-2","type":"data","time":1,"data":{"temperature":35}}`
+	select {
+	case ev := <-mine.Events:
+		if ev.Hub != "hub-2" || ev.Type != "data" || string(ev.Data) != `{"temperature":35}` {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	select {
+	case ev := <-other.Events:
+		t.Errorf("foreign hub event leaked: %+v", ev)
+	default:
+	}
+
+	// После отписки события не приходят, а пустые хабы вычищаются
+	b.Unsubscribe(mine)
+	inMem.events <- `{"hub_id":1,"hub":"hub-1","type":"status","time":2,"data":{}}`
+	inMem.events <- `not json`
+	b.mu.RLock()
+	_, left := b.subs[1]
+	b.mu.RUnlock()
+	if left {
+		t.Error("expected hub-1 subscribers to be removed")
+	}
+	select {
+	case ev := <-mine.Events:
+		t.Errorf("event after unsubscribe: %+v", ev)
+	default:
+	}
+}
+
+func TestBroker_DropsEventsForSlowSubscriber(t *testing.T) {
+	b := NewBroker(&MockInMemoryDB{}, zerolog.Nop())
+	sub := b.Subscribe([]int{1})
+	for i := 0; i < subscriberBuffer+10; i++ {
+		b.dispatch(`{"hub_id":1,"hub":"hub-1","type":"data","time":1,"data":{}}`)
+	}
+	if len(sub.Events) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(sub.Events))
+	}
+}
@@ -0,0 +1,270 @@
+// Package weightStats — аналитика веса улья: суточный привес, периоды медосбора,
+// расход кормов зимой и ступеньки веса от работы пасечника (поставили или сняли
+// магазин, отобрали мёд, подкормили).
+package weightStats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// StepThreshold — скачок веса (кг), который пчёлы сами не дают: мельче — это
+	// рой, дождь или роса, крупнее — руки пасечника.
+	StepThreshold = 4.0
+	// maxStepGap — ступенька засчитывается только между соседними замерами:
+	// после долгого перерыва в данных разница веса может набежать естественно.
+	maxStepGap = 2 * time.Hour
+	// stepWindow — сколько замеров до и после скачка сравниваем по медиане, чтобы
+	// приподнятая на минуту крышка не считалась ступенькой.
+	stepWindow = 3
+
+	// FlowThreshold — суточный привес (кг), начиная с которого день считается днём медосбора.
+	FlowThreshold = 0.5
+	// minFlowDays — сколько дней подряд нужно, чтобы говорить о медосборе, а не о случайности.
+	minFlowDays = 2
+
+	// consumptionDays — за сколько последних суток оценивается расход кормов.
+	consumptionDays = 14
+	// minConsumptionDays — меньше суток с данными — расход не считаем.
+	minConsumptionDays = 7
+)
+
+// Типы событий хронологии улья, которые предлагаются для ступенек веса.
+const (
+	EventSuperAdded   = "super_added"
+	EventSuperRemoved = "super_removed"
+	EventHoneyHarvest = "honey_harvest"
+	EventFeeding      = "feeding"
+)
+
+// EventTitles — названия событий для хронологии.
+var EventTitles = map[string]string{
+	EventSuperAdded:   "Поставлен магазин",
+	EventSuperRemoved: "Снят магазин",
+	EventHoneyHarvest: "Отбор мёда",
+	EventFeeding:      "Подкормка",
+}
+
+// Point — замер веса.
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+// Step — ступенька веса: уровень сменился и держится.
+type Step struct {
+	At     time.Time
+	Before float64
+	After  float64
+	Delta  float64
+	// Suggested — подходящие типы событий, первый — наиболее вероятный
+	Suggested []string
+}
+
+// Day — итог суток по местному времени. Net — привес (кг) без учёта ступенек.
+type Day struct {
+	Date   time.Time
+	Net    float64
+	Weight float64
+}
+
+// Flow — период медосбора: дни подряд с привесом не меньше FlowThreshold.
+type Flow struct {
+	From time.Time
+	To   time.Time
+	Gain float64
+}
+
+// Consumption — расход кормов по тренду последних consumptionDays суток.
+type Consumption struct {
+	// PerDay — сколько кг в сутки теряет улей
+	PerDay float64
+	Days   int
+	// Stores — остаток кормов (кг), если известен вес улья без запасов
+	Stores *float64
+	// RunOut — когда при таком расходе корма закончатся
+	RunOut *time.Time
+}
+
+// Report — аналитика веса за период.
+type Report struct {
+	Days        []Day
+	Flows       []Flow
+	Steps       []Step
+	Consumption *Consumption
+}
+
+// Analyze строит аналитику по замерам, упорядоченным по времени. Сутки
+// считаются по loc; emptyWeight — вес улья без кормов, 0 — неизвестен.
+func Analyze(points []Point, loc *time.Location, now time.Time, emptyWeight float64) Report {
+	var r Report
+	if len(points) == 0 {
+		return r
+	}
+	r.Steps = DetectSteps(points)
+	r.Days = daily(correct(points, r.Steps), points, loc)
+	r.Flows = flows(r.Days)
+	r.Consumption = consumption(r.Days, points[len(points)-1].Value, now, emptyWeight)
+	return r
+}
+
+// DetectSteps ищет ступеньки: соседние замеры отличаются хотя бы на StepThreshold,
+// и медианы stepWindow замеров до и после скачка отличаются так же.
+func DetectSteps(points []Point) []Step {
+	var steps []Step
+	for i := 1; i < len(points); i++ {
+		d := points[i].Value - points[i-1].Value
+		if math.Abs(d) < StepThreshold || points[i].At.Sub(points[i-1].At) > maxStepGap {
+			continue
+		}
+		before := median(points[max(0, i-stepWindow):i])
+		after := median(points[i:min(len(points), i+stepWindow)])
+		delta := after - before
+		if math.Abs(delta) < StepThreshold {
+			continue
+		}
+		steps = append(steps, Step{At: points[i].At, Before: before, After: after, Delta: delta, Suggested: suggest(delta)})
+		// растянутый на пару замеров скачок — одна ступенька
+		i += stepWindow - 1
+	}
+	return steps
+}
+
+func suggest(delta float64) []string {
+	if delta > 0 {
+		return []string{EventSuperAdded, EventFeeding}
+	}
+	return []string{EventHoneyHarvest, EventSuperRemoved}
+}
+
+// correct вычитает ступеньки из ряда: остаётся вес, который набрали или потеряли пчёлы.
+func correct(points []Point, steps []Step) []Point {
+	result := make([]Point, len(points))
+	var offset float64
+	s := 0
+	for i, p := range points {
+		for s < len(steps) && !p.At.Before(steps[s].At) {
+			offset += steps[s].Delta
+			s++
+		}
+		result[i] = Point{At: p.At, Value: p.Value - offset}
+	}
+	return result
+}
+
+// daily — привес за сутки: последний исправленный замер суток минус последний
+// замер предыдущих суток с данными (для первых суток — минус первый замер).
+func daily(corrected, raw []Point, loc *time.Location) []Day {
+	var days []Day
+	var prevLast float64
+	for i := 0; i < len(corrected); {
+		local := corrected[i].At.In(loc)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		next := date.AddDate(0, 0, 1)
+		j := i
+		for j < len(corrected) && corrected[j].At.Before(next) {
+			j++
+		}
+		last := corrected[j-1].Value
+		start := prevLast
+		if len(days) == 0 {
+			start = corrected[i].Value
+		}
+		days = append(days, Day{Date: date, Net: last - start, Weight: raw[j-1].Value})
+		prevLast = last
+		i = j
+	}
+	return days
+}
+
+// flows собирает периоды медосбора из идущих подряд дней с привесом.
+func flows(days []Day) []Flow {
+	var result []Flow
+	var current *Flow
+	var length int
+	flush := func() {
+		if current != nil && length >= minFlowDays {
+			result = append(result, *current)
+		}
+		current, length = nil, 0
+	}
+	for i, d := range days {
+		consecutive := i > 0 && days[i-1].Date.AddDate(0, 0, 1).Equal(d.Date)
+		if d.Net < FlowThreshold || (current != nil && !consecutive) {
+			flush()
+		}
+		if d.Net < FlowThreshold {
+			continue
+		}
+		if current == nil {
+			current = &Flow{From: d.Date}
+		}
+		current.To = d.Date
+		current.Gain += d.Net
+		length++
+	}
+	flush()
+	return result
+}
+
+// consumption оценивает расход по наклону прямой через исправленный вес за
+// последние сутки. Если улей не теряет вес — расхода нет.
+func consumption(days []Day, weight float64, now time.Time, emptyWeight float64) *Consumption {
+	from := now.AddDate(0, 0, -consumptionDays)
+	var xs, ys []float64
+	var level float64
+	for _, d := range days {
+		level += d.Net
+		if d.Date.Before(from) {
+			continue
+		}
+		xs = append(xs, d.Date.Sub(from).Hours()/24)
+		ys = append(ys, level)
+	}
+	if len(xs) < minConsumptionDays {
+		return nil
+	}
+	slope := slope(xs, ys)
+	if slope >= 0 {
+		return nil
+	}
+	c := &Consumption{PerDay: -slope, Days: len(xs)}
+	if emptyWeight > 0 {
+		stores := math.Max(weight-emptyWeight, 0)
+		runOut := now.Add(time.Duration(stores / c.PerDay * float64(24*time.Hour)))
+		c.Stores, c.RunOut = &stores, &runOut
+	}
+	return c
+}
+
+// slope — наклон прямой наименьших квадратов.
+func slope(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+func median(points []Point) float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,121 @@
+package weightStats
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// hourly строит почасовой ряд на days суток по функции f(часа от начала).
+func hourly(days int, f func(h int) float64) []Point {
+	points := make([]Point, days*24)
+	for h := range points {
+		points[h] = Point{At: start.Add(time.Duration(h) * time.Hour), Value: f(h)}
+	}
+	return points
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestDetectSteps(t *testing.T) {
+	points := hourly(3, func(h int) float64 {
+		switch {
+		case h == 10:
+			// приподняли крышку
+			return 52
+		case h >= 30 && h < 50:
+			// поставили магазин
+			return 48
+		case h >= 50:
+			// отобрали мёд
+			return 33
+		}
+		return 40
+	})
+	steps := DetectSteps(points)
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %+v", steps)
+	}
+	if !near(steps[0].Delta, 8) || steps[0].Suggested[0] != EventSuperAdded || !steps[0].At.Equal(start.Add(30*time.Hour)) {
+		t.Errorf("unexpected first step: %+v", steps[0])
+	}
+	if !near(steps[1].Delta, -15) || steps[1].Suggested[0] != EventHoneyHarvest {
+		t.Errorf("unexpected second step: %+v", steps[1])
+	}
+
+	// рой — 2 кг — ступенькой не считается
+	swarm := hourly(1, func(h int) float64 {
+		if h >= 12 {
+			return 38
+		}
+		return 40
+	})
+	if steps := DetectSteps(swarm); len(steps) != 0 {
+		t.Errorf("expected no steps for swarm, got %+v", steps)
+	}
+}
+
+func TestAnalyze_DailyAndFlows(t *testing.T) {
+	// 2 дня ровно, 3 дня медосбор по 1.2 кг/сутки, на 4-й день поставили магазин (+8 кг),
+	// затем 2 дня ровно
+	points := hourly(7, func(h int) float64 {
+		v := 40.0
+		if h >= 48 {
+			v += 1.2 * float64(min(h, 120)-48) / 24
+		}
+		if h >= 84 {
+			v += 8
+		}
+		return v
+	})
+	r := Analyze(points, time.UTC, start.AddDate(0, 0, 7), 0)
+
+	if len(r.Steps) != 1 || math.Abs(r.Steps[0].Delta-8) > 0.2 {
+		t.Fatalf("expected one step, got %+v", r.Steps)
+	}
+	if len(r.Days) != 7 {
+		t.Fatalf("expected 7 days, got %d", len(r.Days))
+	}
+	// день с магазином — привес медосбора, а не +8 кг. Медианы вокруг ступеньки
+	// захватывают пару часов привеса, поэтому допуск — 0.2 кг.
+	if d := r.Days[3]; math.Abs(d.Net-1.2) > 0.2 {
+		t.Errorf("expected step to be excluded from daily net, got %+v", d)
+	}
+	if len(r.Flows) != 1 {
+		t.Fatalf("expected one nectar flow, got %+v", r.Flows)
+	}
+	if f := r.Flows[0]; !f.From.Equal(start.AddDate(0, 0, 2)) || !f.To.Equal(start.AddDate(0, 0, 4)) || math.Abs(f.Gain-3.6) > 0.3 {
+		t.Errorf("unexpected flow: %+v", f)
+	}
+	if r.Consumption != nil {
+		t.Errorf("expected no consumption while gaining, got %+v", r.Consumption)
+	}
+}
+
+func TestAnalyze_Consumption(t *testing.T) {
+	// зима: улей теряет 0.25 кг в сутки, 30 кг при пустом улье 25 кг
+	points := hourly(20, func(h int) float64 { return 35 - 0.25*float64(h)/24 })
+	now := start.AddDate(0, 0, 20)
+	r := Analyze(points, time.UTC, now, 25)
+
+	c := r.Consumption
+	if c == nil || math.Abs(c.PerDay-0.25) > 0.01 || c.Days != consumptionDays {
+		t.Fatalf("unexpected consumption: %+v", c)
+	}
+	// осталось ~5 кг — хватит на ~20 суток
+	if c.Stores == nil || math.Abs(*c.Stores-5) > 0.1 {
+		t.Fatalf("unexpected stores: %v", c.Stores)
+	}
+	if days := c.RunOut.Sub(now).Hours() / 24; math.Abs(days-20) > 0.5 {
+		t.Errorf("expected run out in ~20 days, got %.1f", days)
+	}
+
+	// без веса пустого улья — только скорость расхода
+	if r := Analyze(points, time.UTC, now, 0); r.Consumption == nil || r.Consumption.RunOut != nil {
+		t.Errorf("expected rate without run out date, got %+v", r.Consumption)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetAlertRules возвращает пороги алертов улья (?hive=, владелец — ?owner=) или, без
+// параметра, пороги пользователя по умолчанию — вместе с действующими с учётом наследования значениями.
+func (h *Handler) GetAlertRules(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hive := r.URL.Query().Get("hive")
+	owner := r.URL.Query().Get("owner")
+	if hive != "" {
+		if _, err := h.db.GetHiveByName(r.Context(), email, hive, owner, nil); err != nil {
+			h.logger.Error().Err(err).Str("email", email).Str("hive_name", hive).Msg("error getting hive")
+			http.Error(w, "Улей не найден", http.StatusNotFound)
+			return
+		}
+	}
+
+	own, err := h.db.GetAlertRules(r.Context(), email, hive, owner)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive_name", hive).Msg("error getting alert rules")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	effective, err := h.db.GetEffectiveAlertRules(r.Context(), email, hive, owner)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive_name", hive).Msg("error getting effective alert rules")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBodyJSON(w, "Пороги алертов получены", alertRulesToHTTP(own, effective))
+}
+
+// GetAlertRulesList возвращает все сохранённые правила пользователя: по умолчанию и по ульям.
+func (h *Handler) GetAlertRulesList(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	list, err := h.db.ListAlertRules(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error listing alert rules")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]httpType.AlertRules, 0, len(list))
+	for _, own := range list {
+		effective, err := h.db.GetEffectiveAlertRules(r.Context(), email, own.Hive, own.Owner)
+		if err != nil {
+			h.logger.Error().Err(err).Str("email", email).Str("hive_name", own.Hive).Msg("error getting effective alert rules")
+			http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, alertRulesToHTTP(own, effective))
+	}
+	h.writeBodyJSON(w, "Пороги алертов получены", result)
+}
+
+// SetAlertRules сохраняет пороги улья или, без hive, пороги пользователя по умолчанию.
+// Правила заменяются целиком: null в поле снимает переопределение.
+func (h *Handler) SetAlertRules(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.SetAlertRulesRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	if err := alerts.Validate(req.AlertThresholds); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid alert rules")
+		http.Error(w, "Некорректный порог: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Hive != "" && !h.requireHiveRole(w, r, email, req.Hive, req.Owner, membership.RoleEditor) {
+		return
+	}
+
+	err = h.db.SetAlertRules(r.Context(), email, req.Hive, req.Owner, req.AlertThresholds)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("hive_name", req.Hive).Msg("hive not found for alert rules")
+		http.Error(w, "Улей не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive_name", req.Hive).Msg("error setting alert rules")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("hive_name", req.Hive).Msg("alert rules updated")
+
+	h.writeBodyJSON(w, "Пороги алертов сохранены", nil)
+}
+
+// DeleteAlertRules удаляет пороги улья (или пользователя без hive) —
+// дальше действуют унаследованные значения.
+func (h *Handler) DeleteAlertRules(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.DeleteAlertRulesRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	if req.Hive != "" && !h.requireHiveRole(w, r, email, req.Hive, req.Owner, membership.RoleEditor) {
+		return
+	}
+
+	if err := h.db.DeleteAlertRules(r.Context(), email, req.Hive, req.Owner); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive_name", req.Hive).Msg("error deleting alert rules")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("hive_name", req.Hive).Msg("alert rules deleted")
+
+	h.writeBodyJSON(w, "Пороги алертов сброшены", nil)
+}
+
+func alertRulesToHTTP(own, effective dbTypes.AlertRules) httpType.AlertRules {
+	res := httpType.AlertRules{
+		Hive:      own.Hive,
+		Owner:     effective.Owner,
+		Rules:     alerts.FromDB(own),
+		Effective: alerts.Resolve(effective).ToHTTP(),
+	}
+	if !own.UpdatedAt.IsZero() {
+		res.UpdatedAt = own.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return res
+}
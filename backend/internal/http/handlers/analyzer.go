@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAnalyzerRunsLimit = 50
+	maxAnalyzerRunsLimit     = 500
+)
+
+// GetAnalyzers возвращает последний прогон каждого фонового анализатора.
+func (h *Handler) GetAnalyzers(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.db.GetLatestAnalyzerRuns(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to get latest analyzer runs")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Состояние анализаторов получено", analyzerRunsToHTTP(runs))
+}
+
+// GetAnalyzerRuns возвращает историю прогонов; ?name= — фильтр по анализатору, ?limit= — число записей.
+func (h *Handler) GetAnalyzerRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAnalyzerRunsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAnalyzerRunsLimit {
+			http.Error(w, "Параметр \"limit\" должен быть от 1 до 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	name := r.URL.Query().Get("name")
+
+	runs, err := h.db.GetAnalyzerRuns(r.Context(), name, limit)
+	if err != nil {
+		h.logger.Error().Err(err).Str("analyzer", name).Msg("failed to get analyzer runs")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "История прогонов получена", analyzerRunsToHTTP(runs))
+}
+
+func analyzerRunsToHTTP(runs []dbTypes.AnalyzerRun) []httpType.AnalyzerRun {
+	result := make([]httpType.AnalyzerRun, len(runs))
+	for i, run := range runs {
+		errs := run.Errors
+		if errs == nil {
+			errs = []string{}
+		}
+		result[i] = httpType.AnalyzerRun{
+			ID:             run.ID,
+			Analyzer:       run.Analyzer,
+			StartedAt:      run.StartedAt.UTC().Format(time.RFC3339),
+			FinishedAt:     run.FinishedAt.UTC().Format(time.RFC3339),
+			DurationMs:     run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+			HivesProcessed: run.HivesProcessed,
+			AlertsRaised:   run.AlertsRaised,
+			Errors:         errs,
+		}
+	}
+	return result
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/apiary"
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// hivesOfApiary оставляет ульи, стоящие на пасеке name.
+func hivesOfApiary(hives []dbTypes.Hive, name string) []dbTypes.Hive {
+	result := make([]dbTypes.Hive, 0, len(hives))
+	for _, hive := range hives {
+		if hive.ApiaryName == name {
+			result = append(result, hive)
+		}
+	}
+	return result
+}
+
+// apiaryState — то, что нужно для сводки по пасекам: ульи пользователя,
+// открытые инциденты по ульям (ключ — владелец и имя улья) и состояние хабов.
+type apiaryState struct {
+	hives     []dbTypes.Hive
+	incidents map[hiveKey]int
+	lastSeen  map[string]int64
+	offline   map[string]bool
+}
+
+// hiveKey — улей однозначно: имена ульев уникальны только у одного владельца.
+type hiveKey struct {
+	owner, name string
+}
+
+// apiaryState собирает состояние для сводки. Инциденты и связь хабов живут в Redis:
+// если он недоступен, сводка считается без них.
+func (h *Handler) apiaryState(ctx context.Context, email string) (apiaryState, error) {
+	hives, err := h.db.GetHives(ctx, email, nil)
+	if err != nil {
+		return apiaryState{}, err
+	}
+	state := apiaryState{hives: hives, incidents: map[hiveKey]int{}}
+	incidents, err := h.incidents.HiveIncidents(ctx, hives)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("error getting open incidents")
+	}
+	for _, incident := range incidents {
+		state.incidents[hiveKey{incident.Owner, incident.Hive}]++
+	}
+	state.lastSeen, state.offline = h.sensorsState(ctx)
+	return state, nil
+}
+
+// summary — пасека со сводкой по её ульям. Хаб, к которому привязано несколько
+// ульев пасеки, считается один раз.
+func (s apiaryState) summary(a dbTypes.Apiary) (httpType.ApiaryListItem, []dbTypes.Hive) {
+	item := httpType.ApiaryListItem{
+		Name:      a.Name,
+		Latitude:  a.Latitude,
+		Longitude: a.Longitude,
+		Altitude:  a.Altitude,
+		Timezone:  a.Timezone,
+		Notes:     a.Notes,
+		Owner:     a.Email,
+		Role:      a.Role,
+	}
+	hives := hivesOfApiary(s.hives, a.Name)
+	hubs := map[string]bool{}
+	for _, hive := range hives {
+		item.HiveCount++
+		item.ActiveAlerts += s.incidents[hiveKey{hive.Email, hive.NameHive}]
+		if hive.HubName == "" || hubs[hive.HubName] {
+			continue
+		}
+		hubs[hive.HubName] = true
+		item.SensorsTotal++
+		if online, _ := hubOnline(hive.HubName, s.lastSeen, s.offline); online {
+			item.SensorsOnline++
+		}
+	}
+	return item, hives
+}
+
+func (h *Handler) CreateApiary(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.CreateApiary
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.Name == "" {
+		h.logger.Warn().Str("email", email).Msg("apiary name is empty")
+		http.Error(w, "Имя пасеки обязательно", http.StatusBadRequest)
+		return
+	}
+	if err := apiary.Validate(req.Latitude, req.Longitude, req.Altitude, req.Timezone); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid apiary")
+		http.Error(w, "Некорректные данные пасеки: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.NewApiary(r.Context(), email, req); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("apiary", req.Name).Msg("error creating apiary")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("apiary", req.Name).Msg("apiary created")
+
+	h.writeBodyJSON(w, "Пасека создана", nil)
+}
+
+// GetApiaries возвращает пасеки пользователя и пасеки, где он участник, со сводкой: число ульев,
+// открытых инцидентов и хабов на связи.
+func (h *Handler) GetApiaries(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	apiaries, err := h.db.GetApiaries(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting apiaries")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	state, err := h.apiaryState(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting hives for apiaries")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]httpType.ApiaryListItem, 0, len(apiaries))
+	for _, a := range apiaries {
+		item, _ := state.summary(a)
+		result = append(result, item)
+	}
+	h.writeBodyJSON(w, "Список пасек получен", result)
+}
+
+func (h *Handler) GetApiary(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		h.logger.Error().Msg("no \"name\" in request")
+		http.Error(w, "Параметр \"name\" обязателен", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.db.GetApiaryByName(r.Context(), email, name)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("apiary", name).Msg("error getting apiary")
+		http.Error(w, "Пасека не найдена", http.StatusNotFound)
+		return
+	}
+	state, err := h.apiaryState(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting hives for apiary")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	item, hives := state.summary(a)
+	h.writeBodyJSON(w, "Данные о пасеке получены", httpType.ApiaryDetails{
+		ApiaryListItem: item,
+		Hives:          dbHivesToListItems(hives),
+	})
+}
+
+func (h *Handler) UpdateApiary(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.UpdateApiary
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.OldName == "" {
+		h.logger.Warn().Str("email", email).Msg("old apiary name is empty")
+		http.Error(w, "Старое имя пасеки не может быть пустым", http.StatusBadRequest)
+		return
+	}
+	if req.NewName != nil && *req.NewName == "" {
+		h.logger.Warn().Str("email", email).Msg("new apiary name is empty")
+		http.Error(w, "Новое имя пасеки не может быть пустым", http.StatusBadRequest)
+		return
+	}
+	timezone := ""
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	if err := apiary.Validate(req.Latitude, req.Longitude, req.Altitude, timezone); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid apiary")
+		http.Error(w, "Некорректные данные пасеки: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.requireApiaryRole(w, r, email, req.OldName, membership.RoleEditor) {
+		return
+	}
+
+	err = h.db.UpdateApiary(r.Context(), email, req)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("apiary", req.OldName).Msg("apiary not found")
+		http.Error(w, "Пасека не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("apiary", req.OldName).Msg("error updating apiary")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("apiary", req.OldName).Msg("apiary updated")
+
+	h.writeBodyJSON(w, "Пасека обновлена", nil)
+}
+
+// DeleteApiary удаляет пасеку; её ульи остаются, но уже без пасеки.
+func (h *Handler) DeleteApiary(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.Name == "" {
+		h.logger.Warn().Str("email", email).Msg("apiary name is empty")
+		http.Error(w, "Имя пасеки обязательно", http.StatusBadRequest)
+		return
+	}
+	if !h.requireApiaryRole(w, r, email, req.Name, membership.RoleOwner) {
+		return
+	}
+
+	if err := h.db.DeleteApiary(r.Context(), email, req.Name); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("apiary", req.Name).Msg("error deleting apiary")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("apiary", req.Name).Msg("apiary deleted")
+
+	h.writeBodyJSON(w, "Пасека удалена", nil)
+}
@@ -1,18 +1,30 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/deviceAuth"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"BeeIOT/internal/domain/passwords" // Added import
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/stream"
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
@@ -67,7 +79,7 @@ func TestRegistration(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, _ := NewHandler(mockDB, mockSender, mockInMem, nil, mockPasswordKeeper, logger)
+	h, _ := NewHandler(mockDB, mockSender, mockInMem, nil, nil, mockPasswordKeeper, logger)
 
 	// Case 1: Successful registration (confirmation code sent)
 	body := []byte(`{"email": "new@test.com", "password": "password"}`)
@@ -104,7 +116,7 @@ func TestLogin(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 
 	// Setup Handler
-	h, _ := NewHandler(mockDB, nil, mockInMem, nil, nil, logger)
+	h, _ := NewHandler(mockDB, nil, mockInMem, nil, nil, nil, logger)
 
 	// Prepare hashed password
 	hashedPassword, _ := passwords.HashPassword("password123")
@@ -172,6 +184,32 @@ type MockDB struct {
 	CreatedTaskID   string
 	TaskData        dbTypes.Task
 	TasksList       []dbTypes.Task
+	HubSecretHash   string
+	DesiredConfig   *httpType.ShadowConfig
+	RawCount        int
+	RollupRequested string
+	AnalyzerRuns    []dbTypes.AnalyzerRun
+	RunsRequested   string
+	AlertRules      map[string]dbTypes.AlertRules
+	Notifications   []dbTypes.Notification
+	Preferences     *dbTypes.NotificationPreferences
+	Weights         []dbTypes.HivesWeightData
+	Temperatures    []dbTypes.HivesTemperatureData
+	Stats           []dbTypes.TelemetryStats
+	StatsRequested  []string
+	ExportRows      []dbTypes.TelemetryExportRow
+	ExportErr       error
+	ExportRequested []string
+	Imported        map[string]httpType.TelemetryBatch
+	HiveEvents      []dbTypes.HiveEvent
+	Hives           []dbTypes.Hive
+	Apiaries        []dbTypes.Apiary
+	// Roles — роль пользователя по имени улья, хаба или пасеки; нет записи — владелец
+	Roles       map[string]string
+	Invitations []dbTypes.Invitation
+	Invited     []httpType.InviteMember
+	Members     []dbTypes.Member
+	Inspections []dbTypes.Inspection
 }
 
 func (m *MockDB) IsExistUser(_ context.Context, _ string) (bool, error) {
@@ -195,10 +233,168 @@ func (m *MockDB) NewHive(_ context.Context, _, _, _ string) error {
 }
 
 func (m *MockDB) GetHives(_ context.Context, _ string, _ *bool) ([]dbTypes.Hive, error) {
+	if m.Hives != nil {
+		return m.Hives, nil
+	}
 	return []dbTypes.Hive{{Id: 1, NameHive: "Test Hive"}}, nil
 }
 
-func (m *MockDB) GetHiveByName(_ context.Context, _ string, _ string, _ *bool) (dbTypes.Hive, error) {
+func (m *MockDB) NewApiary(_ context.Context, email string, data httpType.CreateApiary) error {
+	m.Apiaries = append(m.Apiaries, dbTypes.Apiary{Email: email, Name: data.Name, Latitude: data.Latitude,
+		Longitude: data.Longitude, Altitude: data.Altitude, Timezone: data.Timezone, Notes: data.Notes, Role: "owner"})
+	return nil
+}
+
+func (m *MockDB) GetApiaries(_ context.Context, _ string) ([]dbTypes.Apiary, error) {
+	return m.Apiaries, nil
+}
+
+func (m *MockDB) role(name string) (string, error) {
+	if role, ok := m.Roles[name]; ok {
+		if role == "" {
+			return "", pgx.ErrNoRows
+		}
+		return role, nil
+	}
+	return "owner", nil
+}
+
+// GetHiveRole ищет роль сначала по "владелец/улей", затем по имени улья.
+func (m *MockDB) GetHiveRole(_ context.Context, _, hive, owner string) (string, error) {
+	if _, ok := m.Roles[owner+"/"+hive]; ok && owner != "" {
+		return m.role(owner + "/" + hive)
+	}
+	return m.role(hive)
+}
+
+func (m *MockDB) GetHubRole(_ context.Context, _, sensor string) (string, error) {
+	return m.role(sensor)
+}
+
+func (m *MockDB) GetApiaryRole(_ context.Context, _, apiary string) (string, error) {
+	if m.Apiaries != nil && !slices.ContainsFunc(m.Apiaries, func(a dbTypes.Apiary) bool { return a.Name == apiary }) {
+		return "", pgx.ErrNoRows
+	}
+	return m.role(apiary)
+}
+
+func (m *MockDB) CreateInvitation(_ context.Context, inviter string, inv httpType.InviteMember, _ string, expires time.Time) error {
+	m.Invited = append(m.Invited, inv)
+	m.Invitations = append(m.Invitations, dbTypes.Invitation{
+		Id: int64(len(m.Invitations) + 1), Email: inv.Email, Apiary: inv.Apiary, Hive: inv.Hive,
+		Role: inv.Role, InvitedBy: inviter, ExpiresAt: expires,
+	})
+	return nil
+}
+
+func (m *MockDB) GetInvitations(_ context.Context, email string) ([]dbTypes.Invitation, error) {
+	var result []dbTypes.Invitation
+	for _, inv := range m.Invitations {
+		if inv.Email == email {
+			result = append(result, inv)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockDB) AcceptInvitation(_ context.Context, email string, id int64, _ string) (dbTypes.Invitation, error) {
+	for i, inv := range m.Invitations {
+		if inv.Id == id && inv.Email == email {
+			m.Invitations = slices.Delete(m.Invitations, i, i+1)
+			m.Members = append(m.Members, dbTypes.Member{Email: email, Role: inv.Role, Apiary: inv.Apiary, Hive: inv.Hive})
+			return inv, nil
+		}
+	}
+	return dbTypes.Invitation{}, pgx.ErrNoRows
+}
+
+func (m *MockDB) DeclineInvitation(_ context.Context, email string, id int64, _ string) error {
+	for i, inv := range m.Invitations {
+		if inv.Id == id && inv.Email == email {
+			m.Invitations = slices.Delete(m.Invitations, i, i+1)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) GetMembers(_ context.Context, _, _, _, _ string) ([]dbTypes.Member, error) {
+	return m.Members, nil
+}
+
+func (m *MockDB) SetMemberRole(_ context.Context, _ string, data httpType.UpdateMember) error {
+	for i, mem := range m.Members {
+		if mem.Email == data.Email {
+			m.Members[i].Role = data.Role
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) RemoveMember(_ context.Context, _ string, data httpType.RemoveMember) error {
+	for i, mem := range m.Members {
+		if mem.Email == data.Email {
+			m.Members = slices.Delete(m.Members, i, i+1)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) GetApiaryByName(_ context.Context, _, name string) (dbTypes.Apiary, error) {
+	for _, a := range m.Apiaries {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return dbTypes.Apiary{}, pgx.ErrNoRows
+}
+
+func (m *MockDB) UpdateApiary(_ context.Context, _ string, data httpType.UpdateApiary) error {
+	for i, a := range m.Apiaries {
+		if a.Name != data.OldName {
+			continue
+		}
+		if data.Timezone != nil {
+			m.Apiaries[i].Timezone = *data.Timezone
+		}
+		if data.Latitude != nil {
+			m.Apiaries[i].Latitude = data.Latitude
+		}
+		return nil
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) DeleteApiary(_ context.Context, _, name string) error {
+	m.Apiaries = slices.DeleteFunc(m.Apiaries, func(a dbTypes.Apiary) bool { return a.Name == name })
+	return nil
+}
+
+func (m *MockDB) LinkApiaryToHive(_ context.Context, _, hiveName, _, apiaryName string) error {
+	for i := range m.Hives {
+		if m.Hives[i].NameHive == hiveName {
+			m.Hives[i].ApiaryName = apiaryName
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) GetHiveByName(_ context.Context, _ string, _ string, _ string, _ *bool) (dbTypes.Hive, error) {
+	return dbTypes.Hive{Id: 1, NameHive: "Test Hive"}, nil
+}
+
+// LinkHubToHive: хаб other-hub принадлежит не владельцу улья.
+func (m *MockDB) LinkHubToHive(_ context.Context, _, _, _, hub string) error {
+	if hub == "other-hub" {
+		return fmt.Errorf("failed to link hub to hive: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+func (m *MockDB) GetHiveByHub(_ context.Context, _, _ string) (dbTypes.Hive, error) {
 	return dbTypes.Hive{Id: 1, NameHive: "Test Hive"}, nil
 }
 
@@ -206,7 +402,7 @@ func (m *MockDB) UpdateHive(_ context.Context, _ string, _ httpType.UpdateHive)
 	return nil
 }
 
-func (m *MockDB) DeleteHive(_ context.Context, _ string, _ string) error {
+func (m *MockDB) DeleteHive(_ context.Context, _, _, _ string) error {
 	return nil
 }
 
@@ -214,10 +410,26 @@ func (m *MockDB) GetEmailHiveBySensorID(_ context.Context, _ string) (string, st
 	return "test@example.com", "Test Hive", nil
 }
 
-func (m *MockDB) NewHub(_ context.Context, _, _, _ string) error {
+func (m *MockDB) NewHub(_ context.Context, _, _, _, secretHash string) error {
+	if m.HubSecretHash != "" {
+		return deviceAuth.ErrHubTaken
+	}
+	m.HubSecretHash = secretHash
+	return nil
+}
+
+func (m *MockDB) UpdateHubSecret(_ context.Context, _, _, secretHash string) error {
+	m.HubSecretHash = secretHash
 	return nil
 }
 
+func (m *MockDB) GetHubSecretHash(_ context.Context, _ string) (string, error) {
+	if m.HubSecretHash == "" {
+		return "", pgx.ErrNoRows
+	}
+	return m.HubSecretHash, nil
+}
+
 func (m *MockDB) GetHubs(_ context.Context, _ string) ([]dbTypes.Hub, error) {
 	return []dbTypes.Hub{{Id: 1, NameHub: "Test Hub", Sensor: "hub-001"}}, nil
 }
@@ -234,6 +446,19 @@ func (m *MockDB) UpdateHub(_ context.Context, _ string, _ httpType.UpdateHub) er
 	return nil
 }
 
+func (m *MockDB) GetShadow(_ context.Context, _, sensor string) (dbTypes.DeviceShadow, error) {
+	return dbTypes.DeviceShadow{
+		HubId: 1, Sensor: sensor,
+		DesiredNoise: 3600, DesiredTemp: -1, DesiredFrequency: 600,
+		ReportedNoise: 3600, ReportedTemp: 5, ReportedFrequency: 5,
+	}, nil
+}
+
+func (m *MockDB) SetDesiredConfig(_ context.Context, _, _ string, config httpType.ShadowConfig) error {
+	m.DesiredConfig = &config
+	return nil
+}
+
 func (m *MockDB) NewQueen(_ context.Context, _, _, _ string) error {
 	return nil
 }
@@ -264,6 +489,120 @@ func (m *MockDB) GetTemperaturesSinceTimeById(_ context.Context, _ int, _ time.T
 	return []dbTypes.HivesTemperatureData{{Temperature: 25.0}}, nil
 }
 
+func (m *MockDB) GetTemperaturesSinceTime(_ context.Context, _, _ string, _ time.Time) ([]dbTypes.HivesTemperatureData, error) {
+	if m.Temperatures != nil {
+		return m.Temperatures, nil
+	}
+	return []dbTypes.HivesTemperatureData{{Temperature: 25.0, Date: time.Unix(1700000000, 0)}}, nil
+}
+
+func (m *MockDB) GetTelemetryStats(_ context.Context, _, _ string, metrics []string, bucket string, _, _ time.Time, _ []float64) ([]dbTypes.TelemetryStats, error) {
+	m.StatsRequested = append(slices.Clone(metrics), bucket)
+	return m.Stats, nil
+}
+
+func (m *MockDB) ExportTelemetry(_ context.Context, _ string, hubs, metrics []string, _, _ time.Time, fn func(dbTypes.TelemetryExportRow) error) error {
+	m.ExportRequested = append(slices.Clone(hubs), metrics...)
+	for _, row := range m.ExportRows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return m.ExportErr
+}
+
+func (m *MockDB) ImportTelemetry(_ context.Context, metric string, batch httpType.TelemetryBatch) (int, error) {
+	if m.Imported == nil {
+		m.Imported = map[string]httpType.TelemetryBatch{}
+	}
+	m.Imported[metric] = batch
+	// первый замер у хаба уже есть
+	return len(batch.Samples) - 1, nil
+}
+
+func (m *MockDB) GetWeightSinceTime(_ context.Context, _, _ string, _ time.Time) ([]dbTypes.HivesWeightData, error) {
+	return m.Weights, nil
+}
+
+func (m *MockDB) GetEmailHiveByHubSensor(_ context.Context, _ string) (string, string, error) {
+	return "test@example.com", "Test Hive", nil
+}
+
+func (m *MockDB) GetHiveEvents(_ context.Context, _ int, _ time.Time) ([]dbTypes.HiveEvent, error) {
+	return m.HiveEvents, nil
+}
+
+func (m *MockDB) NewInspection(_ context.Context, email, hive, owner string, i dbTypes.Inspection) (int64, error) {
+	i.Id = int64(len(m.Inspections) + 1)
+	i.Hive = hive
+	i.Owner = owner
+	i.Author = email
+	i.CreatedAt = time.Now()
+	m.Inspections = append(m.Inspections, i)
+	return i.Id, nil
+}
+
+func (m *MockDB) GetInspections(_ context.Context, _, hive, owner string, since time.Time) ([]dbTypes.Inspection, error) {
+	var result []dbTypes.Inspection
+	for _, i := range slices.Backward(m.Inspections) {
+		if (hive == "" || i.Hive == hive && (owner == "" || i.Owner == owner)) && !i.Date.Before(since.Truncate(24*time.Hour)) {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockDB) GetInspection(_ context.Context, _ string, id int64) (dbTypes.Inspection, error) {
+	for _, i := range m.Inspections {
+		if i.Id == id {
+			return i, nil
+		}
+	}
+	return dbTypes.Inspection{}, pgx.ErrNoRows
+}
+
+func (m *MockDB) UpdateInspection(_ context.Context, _ string, i dbTypes.Inspection) error {
+	for k := range m.Inspections {
+		if m.Inspections[k].Id == i.Id {
+			m.Inspections[k] = i
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) DeleteInspection(_ context.Context, _ string, id int64) error {
+	for k, i := range m.Inspections {
+		if i.Id == id {
+			m.Inspections = slices.Delete(m.Inspections, k, k+1)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) NewHiveEvent(_ context.Context, _ int, e dbTypes.HiveEvent) (int64, error) {
+	m.HiveEvents = append(m.HiveEvents, e)
+	return int64(len(m.HiveEvents)), nil
+}
+
+func (m *MockDB) GetNoiseSpectrumSinceTime(_ context.Context, _, _ string, _ time.Time) ([]dbTypes.HivesNoiseSpectrum, error) {
+	return []dbTypes.HivesNoiseSpectrum{{
+		Date:   time.Unix(1700000000, 0),
+		Edges:  []float64{100, 200, 300, 500, 1000},
+		Levels: []float64{40, 40, 40, 40},
+	}}, nil
+}
+
+func (m *MockDB) CountTelemetrySinceTime(_ context.Context, _, _, _ string, _ time.Time) (int, error) {
+	return m.RawCount, nil
+}
+
+func (m *MockDB) GetTelemetryRollup(_ context.Context, _, _, _, resolution string, _ time.Time) ([]dbTypes.TelemetryRollup, error) {
+	m.RollupRequested = resolution
+	return []dbTypes.TelemetryRollup{{Date: time.Unix(1699999200, 0), Min: 20, Max: 30, Avg: 25, Count: 720}}, nil
+}
+
 func (m *MockDB) GetUserByEmail(_ context.Context, _ string) (string, string, error) {
 	return m.UserEmail, m.UserName, nil
 }
@@ -320,9 +659,129 @@ func (m *MockDB) ReorderInstructionItems(_ context.Context, _ []string) ([]dbTyp
 	return nil, nil
 }
 
+func (m *MockDB) GetLatestAnalyzerRuns(_ context.Context) ([]dbTypes.AnalyzerRun, error) {
+	return m.AnalyzerRuns, nil
+}
+
+func (m *MockDB) GetAnalyzerRuns(_ context.Context, analyzer string, limit int) ([]dbTypes.AnalyzerRun, error) {
+	m.RunsRequested = fmt.Sprintf("%s:%d", analyzer, limit)
+	return m.AnalyzerRuns, nil
+}
+
+func (m *MockDB) SetAlertRules(_ context.Context, _, hive, _ string, t httpType.AlertThresholds) error {
+	if hive == "Missing" {
+		return fmt.Errorf("failed to set alert rules: %w", pgx.ErrNoRows)
+	}
+	if m.AlertRules == nil {
+		m.AlertRules = map[string]dbTypes.AlertRules{}
+	}
+	m.AlertRules[hive] = dbTypes.AlertRules{Hive: hive, NoiseHigh: t.NoiseHigh, NoiseDelta: t.NoiseDelta,
+		TemperatureNormal: t.TemperatureNormal, TemperatureDeltaUp: t.TemperatureDeltaUp,
+		TemperatureDeltaDown: t.TemperatureDeltaDown, BatteryLow: t.BatteryLow, SignalLow: t.SignalLow}
+	return nil
+}
+
+func (m *MockDB) GetAlertRules(_ context.Context, _, hive, _ string) (dbTypes.AlertRules, error) {
+	if r, ok := m.AlertRules[hive]; ok {
+		return r, nil
+	}
+	return dbTypes.AlertRules{Hive: hive}, nil
+}
+
+// GetEffectiveAlertRules наследует поля улья от правил пользователя (только battery_low и noise_high — для тестов достаточно).
+func (m *MockDB) GetEffectiveAlertRules(_ context.Context, _, hive, _ string) (dbTypes.AlertRules, error) {
+	own, def := m.AlertRules[hive], m.AlertRules[""]
+	if own.BatteryLow == nil {
+		own.BatteryLow = def.BatteryLow
+	}
+	if own.NoiseHigh == nil {
+		own.NoiseHigh = def.NoiseHigh
+	}
+	return own, nil
+}
+
+func (m *MockDB) ListAlertRules(_ context.Context, _ string) ([]dbTypes.AlertRules, error) {
+	var list []dbTypes.AlertRules
+	for _, r := range m.AlertRules {
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (m *MockDB) DeleteAlertRules(_ context.Context, _, hive, _ string) error {
+	delete(m.AlertRules, hive)
+	return nil
+}
+
+func (m *MockDB) GetNotifications(_ context.Context, _ string, unreadOnly bool, limit, offset int) ([]dbTypes.Notification, error) {
+	var list []dbTypes.Notification
+	for _, n := range m.Notifications {
+		if !unreadOnly || n.ReadAt == nil {
+			list = append(list, n)
+		}
+	}
+	if offset >= len(list) {
+		return nil, nil
+	}
+	return list[offset:min(offset+limit, len(list))], nil
+}
+
+func (m *MockDB) CountNotifications(_ context.Context, _ string) (int, int, error) {
+	unread := 0
+	for _, n := range m.Notifications {
+		if n.ReadAt == nil {
+			unread++
+		}
+	}
+	return len(m.Notifications), unread, nil
+}
+
+func (m *MockDB) MarkNotificationsRead(_ context.Context, _ string, ids []int64) (int64, error) {
+	now := time.Now()
+	var marked int64
+	for i, n := range m.Notifications {
+		if n.ReadAt == nil && (len(ids) == 0 || slices.Contains(ids, n.ID)) {
+			m.Notifications[i].ReadAt = &now
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (m *MockDB) DeleteNotification(_ context.Context, _ string, id int64) error {
+	for i, n := range m.Notifications {
+		if n.ID == id {
+			m.Notifications = append(m.Notifications[:i], m.Notifications[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *MockDB) GetNotificationPreferences(_ context.Context, _ string) (dbTypes.NotificationPreferences, error) {
+	if m.Preferences == nil {
+		return dbTypes.NotificationPreferences{Channels: []string{"push"}}, nil
+	}
+	return *m.Preferences, nil
+}
+
+func (m *MockDB) SetNotificationPreferences(_ context.Context, _ string, p dbTypes.NotificationPreferences) error {
+	m.Preferences = &p
+	return nil
+}
+
 type MockConfirmSender struct {
-	LastEmail string
-	LastCode  string
+	LastEmail   string
+	LastCode    string
+	LastSubject string
+	LastText    string
+}
+
+func (m *MockConfirmSender) SendEmail(toEmail, subject, text, _ string) error {
+	m.LastEmail = toEmail
+	m.LastSubject = subject
+	m.LastText = text
+	return nil
 }
 
 func (m *MockConfirmSender) SendConfirmationCode(toEmail, code string) error {
@@ -333,6 +792,35 @@ func (m *MockConfirmSender) SendConfirmationCode(toEmail, code string) error {
 
 type MockInMemoryDB struct {
 	interfaces.InMemoryDB
+	Sensors     map[string]int64
+	Offline     map[string]bool
+	Events      chan string
+	AlertStates map[string]string
+	Tickets     map[string]string
+	TicketTTL   time.Duration
+}
+
+func (m *MockInMemoryDB) SetStreamTicket(_ context.Context, ticket, email string, ttl time.Duration) error {
+	if m.Tickets == nil {
+		m.Tickets = map[string]string{}
+	}
+	m.Tickets[ticket] = email
+	m.TicketTTL = ttl
+	return nil
+}
+
+func (m *MockInMemoryDB) GetAlertStates(_ context.Context, prefix string) (map[string]string, error) {
+	result := map[string]string{}
+	for key, value := range m.AlertStates {
+		if strings.HasPrefix(key, prefix) {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+func (m *MockInMemoryDB) SubscribeTelemetry(_ context.Context) (<-chan string, error) {
+	return m.Events, nil
 }
 
 func (m *MockInMemoryDB) SetNotification(_ context.Context, _ string, _ httpType.NotificationData) error {
@@ -347,6 +835,14 @@ func (m *MockInMemoryDB) DeleteAllJwts(_ context.Context, _ string) error {
 	return nil
 }
 
+func (m *MockInMemoryDB) GetAllSensors(_ context.Context) (map[string]int64, error) {
+	return m.Sensors, nil
+}
+
+func (m *MockInMemoryDB) GetOfflineSensors(_ context.Context) (map[string]bool, error) {
+	return m.Offline, nil
+}
+
 func (m *MockInMemoryDB) SetLastDeviceStatus(_ context.Context, _, _ string) error {
 	return nil
 }
@@ -434,7 +930,7 @@ func TestRefreshToken(t *testing.T) {
 	mockDB := &MockDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, _ := NewHandler(mockDB, mockConfirm, nil, nil, mockPasswordKeeper, logger)
+	h, _ := NewHandler(mockDB, mockConfirm, nil, nil, nil, mockPasswordKeeper, logger)
 
 	body := []byte(`{"email": "refresh@test.com", "password": "pass"}`)
 	req := httptest.NewRequest("POST", "/api/auth/refresh/token", bytes.NewBuffer(body))
@@ -519,6 +1015,15 @@ func TestUpdateHive(t *testing.T) {
 	if w.Result().StatusCode != http.StatusOK {
 		t.Errorf("Expected 200, got %d", w.Result().StatusCode)
 	}
+
+	// Неизвестный режим сезона
+	body = []byte(`{"old_name": "Old Hive", "season_mode": "spring"}`)
+	req = httptest.NewRequest("POST", "/api/hive/update", bytes.NewBuffer(body)).WithContext(ctx)
+	w = httptest.NewRecorder()
+	h.UpdateHive(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid season mode, got %d", w.Result().StatusCode)
+	}
 }
 
 func TestDeleteHive(t *testing.T) {
@@ -547,7 +1052,7 @@ func TestConfirmRegistration(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -580,7 +1085,7 @@ func TestConfirmChangePassword(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -613,7 +1118,7 @@ func TestChangePassword(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, mockSender, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -637,7 +1142,7 @@ func TestGetNoiseAndTemp(t *testing.T) {
 	t.Setenv("JWT_SECRET", "testsecret")
 	logger := zerolog.Nop()
 	mockDB := &MockDB{}
-	h, err := NewHandler(mockDB, nil, nil, nil, nil, logger)
+	h, err := NewHandler(mockDB, nil, nil, nil, nil, nil, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -667,7 +1172,7 @@ func TestGetMe(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, nil, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, nil, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -701,7 +1206,7 @@ func TestCreateTask(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, nil, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, nil, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -740,7 +1245,7 @@ func TestGetTasks(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, nil, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, nil, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -773,7 +1278,7 @@ func TestUpdateTask(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, nil, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, nil, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -809,7 +1314,7 @@ func TestDeleteTask(t *testing.T) {
 	mockInMem := &MockInMemoryDB{}
 	mockPasswordKeeper := &MockPasswordKeeper{}
 
-	h, err := NewHandler(mockDB, nil, mockInMem, nil, mockPasswordKeeper, logger)
+	h, err := NewHandler(mockDB, nil, mockInMem, nil, nil, mockPasswordKeeper, logger)
 	if err != nil {
 		t.Fatalf("NewHandler failed: %v", err)
 	}
@@ -850,6 +1355,19 @@ func TestCreateHub(t *testing.T) {
 		t.Errorf("Expected 200, got %d", w.Result().StatusCode)
 	}
 
+	var created struct {
+		Data httpType.HubSecret `json:"data"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Data.Secret == "" {
+		t.Error("Expected device secret in response")
+	}
+	if !passwords.CheckPasswordHash(created.Data.Secret, mockDB.HubSecretHash) {
+		t.Error("Stored hash does not match issued secret")
+	}
+
 	// Пустой ID — должен вернуть 400
 	body = []byte(`{"id": "", "name": "Мой хаб"}`)
 	req = httptest.NewRequest("POST", "/api/hub/create", bytes.NewBuffer(body))
@@ -873,12 +1391,156 @@ func TestCreateHub(t *testing.T) {
 	if w.Result().StatusCode != http.StatusBadRequest {
 		t.Errorf("Expected 400 for empty hub name, got %d", w.Result().StatusCode)
 	}
+
+	// Идентификатор уже занят (в том числе чужим хабом) — 409, секрет не перевыпускается
+	hash := mockDB.HubSecretHash
+	body = []byte(`{"id": "hub-001", "name": "Чужой хаб"}`)
+	req = httptest.NewRequest("POST", "/api/hub/create", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(context.Background(), "email", "other@example.com"))
+	w = httptest.NewRecorder()
+
+	h.CreateHub(w, req)
+
+	if w.Result().StatusCode != http.StatusConflict || mockDB.HubSecretHash != hash {
+		t.Errorf("Expected 409 for taken hub id, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestRotateHubSecret(t *testing.T) {
+	logger := zerolog.Nop()
+	mockDB := &MockDB{HubSecretHash: "old"}
+	h := &Handler{logger: logger, db: mockDB}
+
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	body := []byte(`{"id": "hub-001"}`)
+	req := httptest.NewRequest("POST", "/api/hub/secret/rotate", bytes.NewBuffer(body))
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	h.RotateHubSecret(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	var rotated struct {
+		Data httpType.HubSecret `json:"data"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&rotated); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !passwords.CheckPasswordHash(rotated.Data.Secret, mockDB.HubSecretHash) {
+		t.Error("Stored hash does not match rotated secret")
+	}
+
+	// Пустой ID — 400
+	req = httptest.NewRequest("POST", "/api/hub/secret/rotate", bytes.NewBuffer([]byte(`{"id": ""}`)))
+	req = req.WithContext(ctx)
+	w = httptest.NewRecorder()
+
+	h.RotateHubSecret(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty hub id, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestMQTTAuthEndpoints(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	t.Setenv("MQTT_USERNAME", "server")
+	t.Setenv("MQTT_PASSWORD", "server-pass")
+
+	hash, _ := passwords.HashPassword("device-secret")
+	mockDB := &MockDB{HubSecretHash: hash}
+	h, _ := NewHandler(mockDB, nil, &MockInMemoryDB{}, nil, nil, nil, zerolog.Nop())
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		want    int
+	}{
+		{"device ok", h.MQTTAuthUser, `{"username":"hub-001","password":"device-secret"}`, http.StatusOK},
+		{"device wrong secret", h.MQTTAuthUser, `{"username":"hub-001","password":"nope"}`, http.StatusForbidden},
+		{"server ok", h.MQTTAuthUser, `{"username":"server","password":"server-pass"}`, http.StatusOK},
+		{"superuser", h.MQTTAuthSuperuser, `{"username":"server"}`, http.StatusOK},
+		{"device not superuser", h.MQTTAuthSuperuser, `{"username":"hub-001"}`, http.StatusForbidden},
+		{"own topic", h.MQTTAuthACL, `{"username":"hub-001","topic":"/device/hub-001/data","acc":2}`, http.StatusOK},
+		{"foreign topic", h.MQTTAuthACL, `{"username":"hub-001","topic":"/device/hub-002/data","acc":2}`, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/api/mqtt-auth", bytes.NewBufferString(c.body))
+		w := httptest.NewRecorder()
+		c.handler(w, req)
+		if w.Result().StatusCode != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestHubShadow(t *testing.T) {
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+
+	req := httptest.NewRequest("GET", "/api/hub/shadow?id=hub-001", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	h.GetHubShadow(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	var got struct {
+		Data httpType.HubShadow `json:"data"`
+	}
+	if err := json.NewDecoder(w.Result().Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got.Data.InSync {
+		t.Error("Expected hub to be out of sync")
+	}
+	if got.Data.Pending.Frequency != 600 || got.Data.Pending.SamplingNoise != -1 || got.Data.Pending.SamplingTemp != -1 {
+		t.Errorf("Unexpected pending delta: %+v", got.Data.Pending)
+	}
+
+	// Сохранение желаемой конфигурации
+	body := []byte(`{"id": "hub-001", "desired": {"sampling_rate_noise": 1800, "sampling_rate_temperature": -1, "frequency_status": -1}}`)
+	req = httptest.NewRequest("PUT", "/api/hub/shadow", bytes.NewBuffer(body)).WithContext(ctx)
+	w = httptest.NewRecorder()
+	h.SetHubShadow(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	if mockDB.DesiredConfig == nil || mockDB.DesiredConfig.SamplingNoise != 1800 {
+		t.Errorf("Expected desired config to be saved, got %+v", mockDB.DesiredConfig)
+	}
+
+	// Нулевые и пропущенные интервалы не меняются
+	body = []byte(`{"id": "hub-001", "desired": {"sampling_rate_noise": 0, "frequency_status": 900}}`)
+	req = httptest.NewRequest("PUT", "/api/hub/shadow", bytes.NewBuffer(body)).WithContext(ctx)
+	w = httptest.NewRecorder()
+	h.SetHubShadow(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	want := httpType.ShadowConfig{SamplingNoise: -1, SamplingTemp: -1, Frequency: 900}
+	if mockDB.DesiredConfig == nil || *mockDB.DesiredConfig != want {
+		t.Errorf("Expected %+v, got %+v", want, mockDB.DesiredConfig)
+	}
+
+	// Отрицательный интервал — 400
+	body = []byte(`{"id": "hub-001", "desired": {"sampling_rate_noise": -5}}`)
+	req = httptest.NewRequest("PUT", "/api/hub/shadow", bytes.NewBuffer(body)).WithContext(ctx)
+	w = httptest.NewRecorder()
+	h.SetHubShadow(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for negative interval, got %d", w.Result().StatusCode)
+	}
 }
 
 func TestGetHubs(t *testing.T) {
 	logger := zerolog.Nop()
 	mockDB := &MockDB{}
-	h := &Handler{logger: logger, db: mockDB}
+	mockInMem := &MockInMemoryDB{Sensors: map[string]int64{"hub-001": 1700000000}}
+	h := &Handler{logger: logger, db: mockDB, inMemDb: mockInMem}
 
 	ctx := context.WithValue(context.Background(), "email", "test@example.com")
 	req := httptest.NewRequest("GET", "/api/hubs", nil)
@@ -891,19 +1553,37 @@ func TestGetHubs(t *testing.T) {
 		t.Errorf("Expected 200, got %d", w.Result().StatusCode)
 	}
 
-	var response Response
+	var response struct {
+		Status string                 `json:"status"`
+		Data   []httpType.HubListItem `json:"data"`
+	}
 	if err := json.NewDecoder(w.Result().Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 	if response.Status != "ok" {
 		t.Errorf("Expected status ok, got %s", response.Status)
 	}
+	if len(response.Data) != 1 || !response.Data[0].Online || response.Data[0].LastSeen == nil {
+		t.Errorf("Expected online hub with last_seen, got %+v", response.Data)
+	}
+
+	// Watchdog пометил хаб офлайн
+	mockInMem.Offline = map[string]bool{"hub-001": true}
+	w = httptest.NewRecorder()
+	h.GetHubs(w, req)
+	response.Data = nil
+	if err := json.NewDecoder(w.Result().Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Online {
+		t.Errorf("Expected offline hub, got %+v", response.Data)
+	}
 }
 
 func TestGetHub(t *testing.T) {
 	logger := zerolog.Nop()
 	mockDB := &MockDB{}
-	h := &Handler{logger: logger, db: mockDB}
+	h := &Handler{logger: logger, db: mockDB, inMemDb: &MockInMemoryDB{}}
 
 	ctx := context.WithValue(context.Background(), "email", "test@example.com")
 
@@ -1142,3 +1822,1077 @@ func TestDeleteQueen(t *testing.T) {
 		t.Errorf("Expected 400 for empty queen name, got %d", w.Result().StatusCode)
 	}
 }
+
+func TestGetTemperatureSinceTime_Resolution(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	since := strconv.FormatInt(time.Now().Add(-24*time.Hour).Unix(), 10)
+
+	get := func(h *Handler, query string) (*httptest.ResponseRecorder, []httpType.TelemetryDataPoint) {
+		req := httptest.NewRequest("GET", "/api/telemetry/temperature/get?hub=hub-001&since="+since+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetTemperatureSinceTime(w, req)
+		var got struct {
+			Data []httpType.TelemetryDataPoint `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w, got.Data
+	}
+
+	// Без параметров — сырые данные, как раньше
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	w, points := get(h, "")
+	if w.Result().StatusCode != http.StatusOK || len(points) != 1 || points[0].Min != nil {
+		t.Fatalf("Expected one raw point, got %d %+v", w.Result().StatusCode, points)
+	}
+
+	// Сутки при опросе раз в 5 секунд не влезают в 500 точек — часовые агрегаты
+	mockDB = &MockDB{RawCount: 17280}
+	h = &Handler{logger: zerolog.Nop(), db: mockDB}
+	w, points = get(h, "&max_points=500")
+	if w.Result().StatusCode != http.StatusOK || mockDB.RollupRequested != "hour" {
+		t.Fatalf("Expected hourly rollup, got %d %q", w.Result().StatusCode, mockDB.RollupRequested)
+	}
+	if len(points) != 1 || points[0].Value != 25 || points[0].Min == nil || *points[0].Max != 30 || points[0].Count != 720 {
+		t.Errorf("Unexpected rollup point: %+v", points)
+	}
+
+	// Явное разрешение
+	mockDB = &MockDB{}
+	h = &Handler{logger: zerolog.Nop(), db: mockDB}
+	if _, _ = get(h, "&resolution=day"); mockDB.RollupRequested != "day" {
+		t.Errorf("Expected daily rollup, got %q", mockDB.RollupRequested)
+	}
+
+	// Неизвестное разрешение — 400
+	if w, _ = get(h, "&resolution=minute"); w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestGetTemperatureSinceTime_Quality(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	mockDB := &MockDB{Temperatures: []dbTypes.HivesTemperatureData{
+		{Temperature: 34.5, Date: time.Unix(1700000000, 0), Quality: quality.OK},
+		{Temperature: 85, Date: time.Unix(1700000300, 0), Quality: quality.OutOfRange},
+		{Temperature: 34.6, Date: time.Unix(1700000600, 0), Quality: quality.OK},
+	}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	get := func(query string) []httpType.TelemetryDataPoint {
+		req := httptest.NewRequest("GET", "/api/telemetry/temperature/get?hub=hub-001"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetTemperatureSinceTime(w, req)
+		var got struct {
+			Data []httpType.TelemetryDataPoint `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return got.Data
+	}
+
+	// по умолчанию отбракованный замер отдаётся с флагом
+	points := get("")
+	if len(points) != 3 || points[0].Quality != "" || points[1].Quality != quality.OutOfRange {
+		t.Fatalf("Expected flagged point to be marked, got %+v", points)
+	}
+	if points = get("&hide_flagged=true"); len(points) != 2 || points[1].Value != 34.6 {
+		t.Errorf("Expected flagged point to be hidden, got %+v", points)
+	}
+}
+
+func TestGetTelemetryStats(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	day := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{Stats: []dbTypes.TelemetryStats{{
+		Metric: "weight", Bucket: day, Count: 288, Min: 41.2, Max: 43.9, Avg: 42.567, StdDev: 0.8123,
+		Percentiles: []float64{41.5, 42, 42.5, 43, 43.5},
+		First:       41.3, FirstAt: day.Add(time.Minute), Last: 43.8, LastAt: day.Add(23 * time.Hour),
+	}}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	get := func(query string) (*httptest.ResponseRecorder, []httpType.TelemetryStats) {
+		req := httptest.NewRequest("GET", "/api/telemetry/stats?"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetTelemetryStats(w, req)
+		var got struct {
+			Data []httpType.TelemetryStats `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w, got.Data
+	}
+
+	w, data := get("hub=hub-001&metrics=temperature,weight")
+	if w.Result().StatusCode != http.StatusOK || !slices.Equal(mockDB.StatsRequested, []string{"temperature", "weight", "day"}) {
+		t.Fatalf("Expected daily stats for two metrics, got %d %v", w.Result().StatusCode, mockDB.StatsRequested)
+	}
+	if len(data) != 2 || data[0].Metric != "temperature" || len(data[0].Buckets) != 0 {
+		t.Fatalf("Expected empty temperature stats first, got %+v", data)
+	}
+	b := data[1].Buckets
+	if len(b) != 1 || b[0].Time != day.Unix() || b[0].Avg != 42.57 || b[0].StdDev != 0.81 ||
+		b[0].Percentiles["p50"] != 42.5 || b[0].Percentiles["p90"] != 43.5 || b[0].LastTime != day.Add(23*time.Hour).Unix() {
+		t.Errorf("Unexpected weight stats: %+v", b)
+	}
+
+	yearAgo := strconv.FormatInt(time.Now().AddDate(-1, 0, 0).Unix(), 10)
+	for _, query := range []string{
+		"metrics=weight",
+		"hub=hub-001&metrics=humidity",
+		"hub=hub-001&bucket=minute",
+		"hub=hub-001&since=" + yearAgo + "&bucket=hour",
+		"hub=hub-001&until=abc",
+	} {
+		if w, _ := get(query); w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestGetTelemetryExport(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	at := time.Date(2026, 6, 1, 21, 30, 0, 0, time.UTC)
+	mockDB := &MockDB{ExportRows: []dbTypes.TelemetryExportRow{
+		{Hub: "hub-001", Metric: "temperature", Date: at, Value: 34.5, Quality: quality.OK},
+		{Hub: "hub-001", Metric: "battery", Date: at, Value: 80, Quality: quality.OK},
+	}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/telemetry/export?"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetTelemetryExport(w, req)
+		return w
+	}
+
+	since := strconv.FormatInt(at.Add(-time.Hour).Unix(), 10)
+	until := strconv.FormatInt(at.Add(time.Hour).Unix(), 10)
+	w := get("metrics=temperature,status&tz=Europe/Moscow&since=" + since + "&until=" + until)
+	if w.Code != http.StatusOK || !slices.Equal(mockDB.ExportRequested, []string{"hub-001", "temperature", "battery", "signal"}) {
+		t.Fatalf("Expected export of all hubs, got %d %v", w.Code, mockDB.ExportRequested)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="beeiot-telemetry-20260601-20260602.csv"` {
+		t.Errorf("Unexpected Content-Disposition: %q", got)
+	}
+	want := "hub,metric,time,value,quality\n" +
+		"hub-001,temperature,2026-06-02T00:30:00+03:00,34.5,ok\n" +
+		"hub-001,battery,2026-06-02T00:30:00+03:00,80,ok\n"
+	if w.Body.String() != want {
+		t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+	}
+
+	w = get("hubs=hub-001&format=ndjson")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" || strings.Count(w.Body.String(), "\n") != 2 {
+		t.Errorf("Expected two NDJSON lines, got %d %q", w.Code, w.Body.String())
+	}
+
+	if w := get("hubs=hub-001,hub-404"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for foreign hub, got %d", w.Code)
+	}
+	for _, query := range []string{
+		"metrics=humidity",
+		"format=xlsx",
+		"tz=Mars/Olympus",
+		"since=" + until + "&until=" + since,
+	} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, w.Code)
+		}
+	}
+
+	// Сбой до первого байта — честный 500
+	mockDB.ExportRows, mockDB.ExportErr = nil, errors.New("connection reset")
+	if w := get(""); w.Code != http.StatusInternalServerError || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected 500 before any bytes, got %d", w.Code)
+	}
+	// Сбой посреди файла — обрыв соединения, а не «успешный» неполный ответ
+	for range 500 {
+		mockDB.ExportRows = append(mockDB.ExportRows, dbTypes.TelemetryExportRow{Hub: "hub-001", Metric: "temperature", Date: at, Value: 34.5, Quality: quality.OK})
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("Expected http.ErrAbortHandler panic mid-stream, got %v", r)
+			}
+		}()
+		get("")
+	}()
+}
+
+func TestImportTelemetry(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	post := func(file string, fields map[string]string) (*httptest.ResponseRecorder, httpType.TelemetryImportReport) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "log.csv")
+		_, _ = part.Write([]byte(file))
+		for k, v := range fields {
+			_ = form.WriteField(k, v)
+		}
+		_ = form.Close()
+		req := httptest.NewRequest("POST", "/api/telemetry/import", &body).WithContext(ctx)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		h.ImportTelemetry(w, req)
+		var got struct {
+			Data httpType.TelemetryImportReport `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w, got.Data
+	}
+
+	file := "Дата;Вес\n01.05.2026 08:00;41,5\n01.05.2026 20:00;41,2\n02.05.2026 08:00;?\n"
+	fields := map[string]string{"hub": "hub-001", "columns": "weight=Вес", "time_column": "Дата", "tz": "Europe/Moscow", "dry_run": "true"}
+	w, report := post(file, fields)
+	if w.Code != http.StatusOK || !report.DryRun || report.ErrorCount != 1 || len(report.Metrics) != 1 || report.Metrics[0].Samples != 2 {
+		t.Fatalf("Unexpected dry-run: %d %+v", w.Code, report)
+	}
+	if mockDB.Imported != nil {
+		t.Fatal("Dry run must not write")
+	}
+
+	delete(fields, "dry_run")
+	if w, _ := post(file, fields); w.Code != http.StatusUnprocessableEntity || mockDB.Imported != nil {
+		t.Fatalf("Expected 422 for file with errors, got %d", w.Code)
+	}
+
+	fields["skip_invalid"] = "true"
+	w, report = post(file, fields)
+	if w.Code != http.StatusOK || report.DryRun || report.Metrics[0].Imported != 1 || report.Metrics[0].Duplicates != 1 {
+		t.Fatalf("Unexpected import: %d %+v", w.Code, report)
+	}
+	batch := mockDB.Imported["weight"]
+	if batch.Email != "test@example.com" || batch.Hub != "hub-001" || len(batch.Samples) != 2 ||
+		!batch.Samples[0].Time.Equal(time.Date(2026, 5, 1, 5, 0, 0, 0, time.UTC)) || batch.Samples[0].Value != 41.5 {
+		t.Errorf("Unexpected batch: %+v", batch)
+	}
+
+	for name, f := range map[string]map[string]string{
+		"no hub":      {"columns": "weight=Вес"},
+		"bad unit":    {"hub": "hub-001", "units": "weight=stone"},
+		"bad columns": {"hub": "hub-001", "columns": "weight=Масса"},
+	} {
+		if w, _ := post(file, f); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestGetNoiseSpectrumSinceTime(t *testing.T) {
+	h := &Handler{logger: zerolog.Nop(), db: &MockDB{}}
+	req := httptest.NewRequest("GET", "/api/telemetry/spectrum/get?hub=hub-001", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "email", "test@example.com"))
+	w := httptest.NewRecorder()
+	h.GetNoiseSpectrumSinceTime(w, req)
+
+	var got struct {
+		Data []httpType.TelemetrySpectrumPoint `json:"data"`
+	}
+	_ = json.NewDecoder(w.Result().Body).Decode(&got)
+	if w.Result().StatusCode != http.StatusOK || len(got.Data) != 1 {
+		t.Fatalf("Expected one spectrum, got %d %+v", w.Result().StatusCode, got.Data)
+	}
+	p := got.Data[0]
+	if p.Time != 1700000000 || len(p.Bands) != 4 || p.Bands[1].Low != 200 || p.Bands[1].High != 300 || p.RoarRatio != 0.5 {
+		t.Errorf("Unexpected spectrum point: %+v", p)
+	}
+
+	// без hub — 400
+	req = httptest.NewRequest("GET", "/api/telemetry/spectrum/get", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "email", "test@example.com"))
+	w = httptest.NewRecorder()
+	h.GetNoiseSpectrumSinceTime(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestWeightAnalytics(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	// неделя почасовых замеров: 40 кг, через двое суток поставили магазин (+8 кг)
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Hour)
+	mockDB := &MockDB{}
+	for i := 0; i < 7*24; i++ {
+		v := 40.0
+		if i >= 48 {
+			v = 48
+		}
+		mockDB.Weights = append(mockDB.Weights, dbTypes.HivesWeightData{Weight: v, Date: start.Add(time.Duration(i) * time.Hour)})
+	}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	get := func(query string) (*httptest.ResponseRecorder, httpType.WeightAnalytics) {
+		req := httptest.NewRequest("GET", "/api/telemetry/weight/analytics?"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetWeightAnalytics(w, req)
+		var got struct {
+			Data httpType.WeightAnalytics `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w, got.Data
+	}
+
+	w, got := get("hub=hub-001")
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	if len(got.Steps) != 1 || got.Steps[0].Delta != 8 || got.Steps[0].Recorded || got.Steps[0].SuggestedEvents[0] != "super_added" {
+		t.Fatalf("Expected one unrecorded step, got %+v", got.Steps)
+	}
+	for _, d := range got.Days {
+		if d.Net != 0 {
+			t.Errorf("Expected step to be excluded from daily net, got %+v", d)
+		}
+	}
+
+	// Пасечник подтверждает ступеньку — она попадает в хронологию и помечается записанной
+	body := fmt.Sprintf(`{"hub":"hub-001","time":%d,"type":"super_added","delta":8}`, got.Steps[0].Time)
+	req := httptest.NewRequest("POST", "/api/telemetry/weight/events", strings.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	h.RecordWeightStep(rec, req)
+	if rec.Result().StatusCode != http.StatusOK || len(mockDB.HiveEvents) != 1 {
+		t.Fatalf("Expected event to be recorded, got %d %+v", rec.Result().StatusCode, mockDB.HiveEvents)
+	}
+	if e := mockDB.HiveEvents[0]; e.Title != "Поставлен магазин" || e.Source != "user" {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if _, got = get("hub=hub-001"); !got.Steps[0].Recorded {
+		t.Errorf("Expected step to be marked as recorded")
+	}
+
+	// Неизвестный тип события
+	req = httptest.NewRequest("POST", "/api/telemetry/weight/events", strings.NewReader(`{"hub":"hub-001","time":1,"type":"swarm"}`)).WithContext(ctx)
+	rec = httptest.NewRecorder()
+	h.RecordWeightStep(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Result().StatusCode)
+	}
+
+	// Неверный вес пустого улья
+	if w, _ = get("hub=hub-001&empty_weight=-3"); w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestCreateStreamTicket(t *testing.T) {
+	mockInMem := &MockInMemoryDB{}
+	h := &Handler{logger: zerolog.Nop(), inMemDb: mockInMem}
+	req := httptest.NewRequest("POST", "/api/telemetry/stream/ticket", nil).
+		WithContext(context.WithValue(context.Background(), "email", "test@example.com"))
+	w := httptest.NewRecorder()
+	h.CreateStreamTicket(w, req)
+
+	var resp struct {
+		Data httpType.StreamTicket `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with ticket, got %d %v", w.Code, err)
+	}
+	if len(resp.Data.Ticket) != 64 || mockInMem.Tickets[resp.Data.Ticket] != "test@example.com" {
+		t.Errorf("Ticket is not stored for the user: %+v", resp.Data)
+	}
+	if mockInMem.TicketTTL != streamTicketTTL || resp.Data.ExpiresIn != int(streamTicketTTL.Seconds()) {
+		t.Errorf("Unexpected ticket lifetime: %v, %d", mockInMem.TicketTTL, resp.Data.ExpiresIn)
+	}
+}
+
+func TestStreamTelemetry(t *testing.T) {
+	mockInMem := &MockInMemoryDB{Events: make(chan string)}
+	broker := stream.NewBroker(mockInMem, zerolog.Nop())
+	brokerCtx, stopBroker := context.WithCancel(context.Background())
+	defer stopBroker()
+	broker.Start(brokerCtx)
+
+	h := &Handler{logger: zerolog.Nop(), db: &MockDB{}, inMemDb: mockInMem, stream: broker}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.StreamTelemetry(w, r.WithContext(context.WithValue(r.Context(), "email", "test@example.com")))
+	}))
+	defer srv.Close()
+
+	// Чужой хаб — 404
+	resp, err := http.Get(srv.URL + "?hub=hub-999")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected 404 for foreign hub, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+	reader := bufio.NewReader(resp.Body)
+	// Дожидаемся подписки: первая строка пишется уже после неё
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, ": connected") {
+		t.Fatalf("Unexpected first line: %q", line)
+	}
+
+	// Чужой хаб с тем же датчиком — другой id, его события в поток не попадают
+	mockInMem.Events <- `{"hub_id":7,"hub":"hub-001","type":"data","time":1,"data":{"temperature":1}}`
+	mockInMem.Events <- `{"hub_id":1,"hub":"hub-001","type":"data","time":2,"data":{"temperature":35.5}}`
+
+	var eventLine, dataLine string
+	for dataLine == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventLine = strings.TrimSpace(line)
+		case strings.HasPrefix(line, "data: "):
+			dataLine = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+		}
+	}
+	if eventLine != "event: data" {
+		t.Errorf("Unexpected event line: %q", eventLine)
+	}
+	var ev httpType.TelemetryEvent
+	if err := json.Unmarshal([]byte(dataLine), &ev); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if ev.Hub != "hub-001" || string(ev.Data) != `{"temperature":35.5}` {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+}
+
+func TestGetAnalyzers(t *testing.T) {
+	started := time.Date(2026, 5, 10, 3, 0, 0, 0, time.UTC)
+	mockDB := &MockDB{AnalyzerRuns: []dbTypes.AnalyzerRun{
+		{ID: 7, Analyzer: "temperature", StartedAt: started, FinishedAt: started.Add(1500 * time.Millisecond),
+			HivesProcessed: 3, AlertsRaised: 1},
+	}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+
+	w := httptest.NewRecorder()
+	h.GetAnalyzers(w, httptest.NewRequest("GET", "/api/admin/analyzers", nil))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	var got struct {
+		Data []httpType.AnalyzerRun `json:"data"`
+	}
+	_ = json.NewDecoder(w.Result().Body).Decode(&got)
+	if len(got.Data) != 1 || got.Data[0].StartedAt != "2026-05-10T03:00:00Z" || got.Data[0].DurationMs != 1500 ||
+		got.Data[0].Errors == nil {
+		t.Fatalf("Unexpected analyzers: %+v", got.Data)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetAnalyzerRuns(w, httptest.NewRequest("GET", "/api/admin/analyzers/runs?name=noise&limit=10", nil))
+	if w.Result().StatusCode != http.StatusOK || mockDB.RunsRequested != "noise:10" {
+		t.Fatalf("Expected runs for noise, got %d %q", w.Result().StatusCode, mockDB.RunsRequested)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetAnalyzerRuns(w, httptest.NewRequest("GET", "/api/admin/analyzers/runs?limit=0", nil))
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad limit, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+
+	put := func(body string) int {
+		req := httptest.NewRequest("PUT", "/api/hive/alerts", strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.SetAlertRules(w, req)
+		return w.Result().StatusCode
+	}
+	get := func(query string) httpType.AlertRules {
+		req := httptest.NewRequest("GET", "/api/hive/alerts"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		h.GetAlertRules(w, req)
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+		}
+		var got struct {
+			Data httpType.AlertRules `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return got.Data
+	}
+
+	// пользовательский порог по умолчанию и переопределение для улья
+	if code := put(`{"battery_low": 30}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 for user defaults, got %d", code)
+	}
+	if code := put(`{"hive": "Test Hive", "noise_high": 90}`); code != http.StatusOK {
+		t.Fatalf("Expected 200 for hive rules, got %d", code)
+	}
+
+	rules := get("?hive=Test%20Hive")
+	if rules.Rules.BatteryLow != nil || *rules.Rules.NoiseHigh != 90 {
+		t.Errorf("Unexpected own rules: %+v", rules.Rules)
+	}
+	if *rules.Effective.BatteryLow != 30 || *rules.Effective.NoiseHigh != 90 || *rules.Effective.SignalLow != 10 {
+		t.Errorf("Expected inherited battery, own noise and default signal, got %+v", rules.Effective)
+	}
+
+	// валидация и неизвестный улей
+	if code := put(`{"noise_high": 500}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for 500 dB, got %d", code)
+	}
+	if code := put(`{"hive": "Missing", "noise_high": 90}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown hive, got %d", code)
+	}
+
+	// сброс правил улья — остаются унаследованные
+	req := httptest.NewRequest("DELETE", "/api/hive/alerts", strings.NewReader(`{"hive": "Test Hive"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	h.DeleteAlertRules(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	if rules = get("?hive=Test%20Hive"); *rules.Effective.NoiseHigh != 80 {
+		t.Errorf("Expected default noise after reset, got %v", *rules.Effective.NoiseHigh)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	now := time.Now()
+	mockDB := &MockDB{Notifications: []dbTypes.Notification{
+		{ID: 3, Hive: "Test Hive", Type: "battery_low", Severity: "critical", Title: "Низкий заряд", CreatedAt: now},
+		{ID: 2, Hive: "Test Hive", Type: "temperature", Severity: "warning", Title: "Температура", CreatedAt: now},
+		{ID: 1, Type: "sensor_offline", Severity: "info", Title: "Датчик на связи", CreatedAt: now, ReadAt: &now},
+	}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+
+	list := func(query string) (int, httpType.NotificationsPage) {
+		w := httptest.NewRecorder()
+		h.GetNotifications(w, httptest.NewRequest("GET", "/api/notifications"+query, nil).WithContext(ctx))
+		var got struct {
+			Data httpType.NotificationsPage `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w.Result().StatusCode, got.Data
+	}
+
+	code, page := list("?limit=2&offset=1")
+	if code != http.StatusOK || len(page.Items) != 2 || page.Items[0].ID != 2 || page.Total != 3 || page.Unread != 2 {
+		t.Fatalf("Unexpected page: %d %+v", code, page)
+	}
+	if page.Items[1].ReadAt == "" || page.Items[0].ReadAt != "" {
+		t.Errorf("Expected read_at only on read notification, got %+v", page.Items)
+	}
+	if _, page = list("?unread=true"); len(page.Items) != 2 {
+		t.Errorf("Expected 2 unread notifications, got %+v", page.Items)
+	}
+	if code, _ = list("?limit=1000"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad limit, got %d", code)
+	}
+
+	// отметка одного, затем всех
+	w := httptest.NewRecorder()
+	h.MarkNotificationsRead(w, httptest.NewRequest("POST", "/api/notifications/read", strings.NewReader(`{"ids": [3]}`)).WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Result().StatusCode)
+	}
+	if _, page = list(""); page.Unread != 1 {
+		t.Errorf("Expected 1 unread after marking, got %d", page.Unread)
+	}
+	w = httptest.NewRecorder()
+	h.MarkAllNotificationsRead(w, httptest.NewRequest("POST", "/api/notifications/read/all", nil).WithContext(ctx))
+	if _, page = list(""); w.Result().StatusCode != http.StatusOK || page.Unread != 0 {
+		t.Errorf("Expected all read, got %d unread", page.Unread)
+	}
+
+	// удаление
+	w = httptest.NewRecorder()
+	h.DeleteNotification(w, httptest.NewRequest("DELETE", "/api/notifications", strings.NewReader(`{"id": 2}`)).WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK || len(mockDB.Notifications) != 2 {
+		t.Fatalf("Expected notification to be deleted, got %d", w.Result().StatusCode)
+	}
+	w = httptest.NewRecorder()
+	h.DeleteNotification(w, httptest.NewRequest("DELETE", "/api/notifications", strings.NewReader(`{"id": 2}`)).WithContext(ctx))
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing notification, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+
+	put := func(body string) (int, httpType.NotificationPreferences) {
+		w := httptest.NewRecorder()
+		h.SetNotificationPreferences(w, httptest.NewRequest("PUT", "/api/auth/me/preferences", strings.NewReader(body)).WithContext(ctx))
+		var got struct {
+			Data httpType.NotificationPreferences `json:"data"`
+		}
+		_ = json.NewDecoder(w.Result().Body).Decode(&got)
+		return w.Result().StatusCode, got.Data
+	}
+
+	w := httptest.NewRecorder()
+	h.GetNotificationPreferences(w, httptest.NewRequest("GET", "/api/auth/me/preferences", nil).WithContext(ctx))
+	if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `"channels":["push"]`) {
+		t.Fatalf("Expected default push channel, got %d %s", w.Result().StatusCode, w.Body.String())
+	}
+
+	if code, _ := put(`{"channels": ["sms"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown channel, got %d", code)
+	}
+	if code, _ := put(`{"channels": ["webhook"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for webhook without url, got %d", code)
+	}
+
+	// секрет не передан — генерируется, при повторном сохранении остаётся прежним
+	code, prefs := put(`{"channels": ["push", "webhook"], "webhook_url": "https://example.com/hook"}`)
+	if code != http.StatusOK || len(prefs.WebhookSecret) < 16 || mockDB.Preferences.WebhookSecret != prefs.WebhookSecret {
+		t.Fatalf("Expected generated webhook secret, got %d %+v", code, prefs)
+	}
+	secret := prefs.WebhookSecret
+	if _, prefs = put(`{"channels": ["webhook"], "webhook_url": "https://example.com/hook2"}`); prefs.WebhookSecret != secret {
+		t.Errorf("Expected webhook secret to be kept, got %q", prefs.WebhookSecret)
+	}
+	if prefs.MinSeverity != "info" || prefs.Timezone != "UTC" || prefs.Digest != "off" {
+		t.Errorf("Expected default filters, got %+v", prefs)
+	}
+
+	// фильтры и тихие часы
+	for _, body := range []string{
+		`{"channels": ["push"], "alert_types": {"unknown": {"enabled": true}}}`,
+		`{"channels": ["push"], "min_severity": "fatal"}`,
+		`{"channels": ["push"], "timezone": "Mars/Olympus"}`,
+		`{"channels": ["push"], "quiet_hours": [{"start": "25:00", "end": "07:00"}]}`,
+		`{"channels": ["push"], "quiet_hours": [{"start": "07:00", "end": "07:00"}]}`,
+		`{"channels": ["push"], "digest": "monthly"}`,
+	} {
+		if code, _ := put(body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, code)
+		}
+	}
+	code, prefs = put(`{"channels": ["push"], "alert_types": {"signal_low": {"enabled": false}},
+		"min_severity": "warning", "timezone": "Europe/Moscow", "quiet_hours": [{"start": "22:00", "end": "07:00"}],
+		"digest": "weekly"}`)
+	if code != http.StatusOK || prefs.AlertTypes["signal_low"].Enabled || len(prefs.QuietHours) != 1 ||
+		mockDB.Preferences.Timezone != "Europe/Moscow" || mockDB.Preferences.MinSeverity != "warning" ||
+		mockDB.Preferences.Digest != "weekly" {
+		t.Errorf("Expected filters to be saved, got %d %+v", code, prefs)
+	}
+}
+
+func TestApiaries(t *testing.T) {
+	mockDB := &MockDB{Hives: []dbTypes.Hive{
+		{Id: 1, NameHive: "Hive1", Email: "test@example.com", HubName: "hub-001"},
+		{Id: 2, NameHive: "Hive2", Email: "friend@example.com", HubName: "hub-001"},
+		{Id: 3, NameHive: "Hive3", Email: "test@example.com", HubName: "hub-002"},
+		{Id: 4, NameHive: "Hive4", Email: "test@example.com"},
+	}}
+	mockInMem := &MockInMemoryDB{
+		Sensors: map[string]int64{"hub-001": 1700000000, "hub-002": 1700000000},
+		Offline: map[string]bool{"hub-002": true},
+		AlertStates: map[string]string{
+			"test@example.com:Hive1:temperature":  `{"status":"open"}`,
+			"test@example.com:Hive3:noise_change": `{"status":"escalated"}`,
+			"test@example.com:Hive3:battery":      `{"status":"resolved"}`,
+			// Hive2 — улей друга, где пользователь участник: его инциденты хранятся у владельца
+			"friend@example.com:Hive2:temperature": `{"status":"open"}`,
+			"friend@example.com:Hive5:temperature": `{"status":"open"}`,
+		},
+	}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB, inMemDb: mockInMem,
+		incidents: alerts.NewNotifier(mockDB, mockInMem, nil, zerolog.Nop())}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	do := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := do(h.CreateApiary, "POST", "/api/apiary/create", `{"name": "Лесная", "latitude": 55.7, "timezone": "Europe/Moscow"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	for _, body := range []string{
+		`{"latitude": 55}`,
+		`{"name": "Полевая", "latitude": 95}`,
+		`{"name": "Полевая", "longitude": -200}`,
+		`{"name": "Полевая", "timezone": "Mars/Olympus"}`,
+	} {
+		if w := do(h.CreateApiary, "POST", "/api/apiary/create", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	for _, hive := range []string{"Hive1", "Hive2", "Hive3"} {
+		if w := do(h.LinkApiaryToHive, "POST", "/api/hive/link/apiary", `{"hive_name": "`+hive+`", "target_name": "Лесная"}`); w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 linking %s, got %d", hive, w.Code)
+		}
+	}
+	if w := do(h.LinkApiaryToHive, "POST", "/api/hive/link/apiary", `{"hive_name": "Missing", "target_name": "Лесная"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown hive, got %d", w.Code)
+	}
+
+	// три улья, три открытых инцидента, два хаба, из которых на связи один
+	w := do(h.GetApiaries, "GET", "/api/apiary/list", "")
+	var list struct {
+		Data []httpType.ApiaryListItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	want := httpType.ApiaryListItem{Name: "Лесная", Timezone: "Europe/Moscow", Owner: "test@example.com", Role: "owner", HiveCount: 3, ActiveAlerts: 3, SensorsOnline: 1, SensorsTotal: 2}
+	if len(list.Data) != 1 {
+		t.Fatalf("Expected one apiary, got %+v", list.Data)
+	}
+	got := list.Data[0]
+	got.Latitude = nil
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	w = do(h.GetApiary, "GET", "/api/apiary/?name=%D0%9B%D0%B5%D1%81%D0%BD%D0%B0%D1%8F", "")
+	var details struct {
+		Data httpType.ApiaryDetails `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(details.Data.Hives) != 3 || details.Data.Hives[0].Apiary != "Лесная" || *details.Data.Latitude != 55.7 {
+		t.Errorf("Unexpected apiary details: %+v", details.Data)
+	}
+	if w := do(h.GetApiary, "GET", "/api/apiary/?name=Missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown apiary, got %d", w.Code)
+	}
+
+	// фильтр списка ульев по пасеке
+	w = do(h.GetHives, "GET", "/api/hive/list?apiary=%D0%9B%D0%B5%D1%81%D0%BD%D0%B0%D1%8F", "")
+	var hives struct {
+		Data []httpType.HiveListItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&hives); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(hives.Data) != 3 {
+		t.Errorf("Expected 3 hives on apiary, got %+v", hives.Data)
+	}
+
+	if w := do(h.UpdateApiary, "PUT", "/api/apiary/update", `{"old_name": "Лесная", "timezone": "Asia/Novosibirsk"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if mockDB.Apiaries[0].Timezone != "Asia/Novosibirsk" {
+		t.Errorf("Expected timezone updated, got %q", mockDB.Apiaries[0].Timezone)
+	}
+	if w := do(h.UpdateApiary, "PUT", "/api/apiary/update", `{"old_name": "Лесная", "latitude": -91}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for latitude -91, got %d", w.Code)
+	}
+	if w := do(h.UpdateApiary, "PUT", "/api/apiary/update", `{"old_name": "Missing", "notes": "x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown apiary, got %d", w.Code)
+	}
+
+	if w := do(h.DeleteApiary, "DELETE", "/api/apiary/delete", `{"name": "Лесная"}`); w.Code != http.StatusOK || len(mockDB.Apiaries) != 0 {
+		t.Errorf("Expected apiary deleted, got %d, %+v", w.Code, mockDB.Apiaries)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	mockDB := &MockDB{}
+	mockSender := &MockConfirmSender{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB, mailer: mockSender}
+	do := func(email string, handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		ctx := context.WithValue(context.Background(), "email", email)
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"email": "", "hive": "Hive1", "role": "viewer"}`,
+		`{"email": "owner@example.com", "hive": "Hive1", "role": "viewer"}`,
+		`{"email": "friend@example.com", "hive": "Hive1", "role": "admin"}`,
+		`{"email": "friend@example.com", "role": "viewer"}`,
+		`{"email": "friend@example.com", "hive": "Hive1", "apiary": "Лесная", "role": "viewer"}`,
+	} {
+		if w := do("owner@example.com", h.InviteMember, "POST", "/api/members/invite", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := do("owner@example.com", h.InviteMember, "POST", "/api/members/invite",
+		`{"email": "friend@example.com", "hive": "Hive1", "role": "editor"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if mockSender.LastEmail != "friend@example.com" || len(mockDB.Invitations) != 1 {
+		t.Fatalf("Expected invitation to be stored and mailed, got %+v, %s", mockDB.Invitations, mockSender.LastEmail)
+	}
+
+	// чужое приглашение не видно и не принимается
+	w = do("stranger@example.com", h.GetInvitations, "GET", "/api/invitations/list", "")
+	var invitations struct {
+		Data []httpType.InvitationItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&invitations); err != nil || len(invitations.Data) != 0 {
+		t.Errorf("Expected no invitations for stranger, got %+v, %v", invitations.Data, err)
+	}
+	if w := do("stranger@example.com", h.AcceptInvitation, "POST", "/api/invitations/accept", `{"id": 1}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for foreign invitation, got %d", w.Code)
+	}
+	if w := do("friend@example.com", h.AcceptInvitation, "POST", "/api/invitations/accept", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without id and token, got %d", w.Code)
+	}
+
+	w = do("friend@example.com", h.GetInvitations, "GET", "/api/invitations/list", "")
+	if err := json.NewDecoder(w.Body).Decode(&invitations); err != nil || len(invitations.Data) != 1 ||
+		invitations.Data[0].Role != "editor" || invitations.Data[0].InvitedBy != "owner@example.com" {
+		t.Fatalf("Expected one invitation for friend, got %+v, %v", invitations.Data, err)
+	}
+	if w := do("friend@example.com", h.AcceptInvitation, "POST", "/api/invitations/accept", `{"id": 1}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if len(mockDB.Members) != 1 || mockDB.Members[0].Role != "editor" || len(mockDB.Invitations) != 0 {
+		t.Fatalf("Expected friend to become editor, got %+v", mockDB.Members)
+	}
+
+	if w := do("owner@example.com", h.UpdateMember, "PUT", "/api/members/update",
+		`{"email": "friend@example.com", "hive": "Hive1", "role": "viewer"}`); w.Code != http.StatusOK || mockDB.Members[0].Role != "viewer" {
+		t.Errorf("Expected role change, got %d %+v", w.Code, mockDB.Members)
+	}
+	if w := do("owner@example.com", h.UpdateMember, "PUT", "/api/members/update",
+		`{"email": "nobody@example.com", "hive": "Hive1", "role": "viewer"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown member, got %d", w.Code)
+	}
+
+	// участник с ролью ниже владельца не управляет участниками, но может выйти сам
+	mockDB.Roles = map[string]string{"Hive1": "viewer"}
+	if w := do("friend@example.com", h.InviteMember, "POST", "/api/members/invite",
+		`{"email": "other@example.com", "hive": "Hive1", "role": "viewer"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for viewer invite, got %d", w.Code)
+	}
+	if w := do("friend@example.com", h.RemoveMember, "DELETE", "/api/members/delete",
+		`{"email": "other@example.com", "hive": "Hive1"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for removing someone else, got %d", w.Code)
+	}
+	if w := do("friend@example.com", h.GetMembers, "GET", "/api/members/list?hive=Hive1", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected viewer to list members, got %d", w.Code)
+	}
+	if w := do("friend@example.com", h.RemoveMember, "DELETE", "/api/members/delete",
+		`{"email": "friend@example.com", "hive": "Hive1"}`); w.Code != http.StatusOK || len(mockDB.Members) != 0 {
+		t.Errorf("Expected member to leave, got %d %+v", w.Code, mockDB.Members)
+	}
+
+	// недоступный улей — 404, чтобы не раскрывать его существование
+	mockDB.Roles = map[string]string{"Hive1": ""}
+	if w := do("friend@example.com", h.GetMembers, "GET", "/api/members/list?hive=Hive1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for foreign hive, got %d", w.Code)
+	}
+}
+
+func TestMemberRoles(t *testing.T) {
+	mockDB := &MockDB{Roles: map[string]string{"Hive1": "viewer", "Hive2": "editor", "hub-001": "editor", "hub-002": "viewer"}}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "friend@example.com")
+	do := func(handler http.HandlerFunc, method, target, body string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Code
+	}
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+		want    int
+	}{
+		{"viewer updates hive", h.UpdateHive, "PUT", `{"old_name": "Hive1", "new_name": "X"}`, http.StatusForbidden},
+		{"editor updates hive", h.UpdateHive, "PUT", `{"old_name": "Hive2", "new_name": "X"}`, http.StatusOK},
+		{"editor deletes hive", h.DeleteHive, "DELETE", `{"name": "Hive2"}`, http.StatusForbidden},
+		{"viewer links hub", h.LinkHubToHive, "POST", `{"hive_name": "Hive1", "target_name": "hub-001"}`, http.StatusForbidden},
+		{"editor links owner's hub", h.LinkHubToHive, "POST", `{"hive_name": "Hive2", "target_name": "hub-001"}`, http.StatusOK},
+		{"editor links hub of another user", h.LinkHubToHive, "POST", `{"hive_name": "Hive2", "target_name": "other-hub"}`, http.StatusNotFound},
+		{"editor deletes hub", h.DeleteHub, "DELETE", `{"id": "hub-001"}`, http.StatusForbidden},
+		{"editor rotates hub secret", h.RotateHubSecret, "POST", `{"id": "hub-001"}`, http.StatusForbidden},
+		{"viewer records weight step", h.RecordWeightStep, "POST",
+			`{"hub": "hub-002", "time": 1746100000, "type": "honey_harvest"}`, http.StatusForbidden},
+		{"viewer sends config", h.MQTTSendConfig, "POST", `{"sensor": "hub-002", "config": {"frequency": 60}}`, http.StatusForbidden},
+		{"viewer sends health check", h.MQTTSendHealthCheck, "POST", `{"sensor": "hub-002"}`, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		if got := do(c.handler, c.method, "/", c.body); got != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, got)
+		}
+	}
+}
+
+func TestInspections(t *testing.T) {
+	mockDB := &MockDB{}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+	do := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"date": "2026-05-01"}`,
+		`{"hive": "Hive1", "date": "01.05.2026"}`,
+		`{"hive": "Hive1", "brood_frames": -1}`,
+		`{"hive": "Hive1", "temperament": "angry"}`,
+		`{"hive": "Hive1", "varroa_count": 4}`,
+	} {
+		if w := do(h.CreateInspection, "POST", "/api/hive/inspections", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	w := do(h.CreateInspection, "POST", "/api/hive/inspections", `{"hive": "Hive1", "date": "2026-05-01",
+		"brood_frames": 6, "honey_frames": 2.5, "queen_seen": true, "eggs_seen": true, "queen_cells": 0,
+		"temperament": "calm", "varroa_count": 3, "varroa_method": "alcohol_wash", "notes": "Рамки с вощиной отстроены"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Data httpType.InspectionItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Data.ID != 1 || created.Data.Date != "2026-05-01" || *created.Data.BroodFrames != 6 ||
+		created.Data.PollenFrames != nil || created.Data.Author != "test@example.com" {
+		t.Errorf("Unexpected inspection %+v", created.Data)
+	}
+	do(h.CreateInspection, "POST", "/api/hive/inspections", `{"hive": "Hive2", "date": "2026-05-03"}`)
+
+	w = do(h.GetInspections, "GET", "/api/hive/inspections?hive=Hive1", "")
+	var list struct {
+		Data []httpType.InspectionItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list.Data) != 1 || list.Data[0].Hive != "Hive1" {
+		t.Errorf("Expected one inspection of Hive1, got %+v, %v", list.Data, err)
+	}
+	if w := do(h.GetInspections, "GET", "/api/hive/inspections?since=abc", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad since, got %d", w.Code)
+	}
+
+	// частичное обновление: остальные поля сохраняются
+	if w := do(h.UpdateInspection, "PUT", "/api/hive/inspections", `{"id": 1, "pollen_frames": 1, "notes": ""}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	got := mockDB.Inspections[0]
+	if *got.PollenFrames != 1 || *got.BroodFrames != 6 || got.Notes != "" || got.Temperament != "calm" {
+		t.Errorf("Unexpected updated inspection %+v", got)
+	}
+	for body, code := range map[string]int{
+		`{"pollen_frames": 1}`:                      http.StatusBadRequest,
+		`{"id": 1, "varroa_method": ""}`:            http.StatusBadRequest,
+		`{"id": 1, "date": ""}`:                     http.StatusBadRequest,
+		`{"id": 1, "date": "2999-01-01"}`:           http.StatusBadRequest,
+		`{"id": 42, "notes": "нет такого осмотра"}`: http.StatusNotFound,
+	} {
+		if w := do(h.UpdateInspection, "PUT", "/api/hive/inspections", body); w.Code != code {
+			t.Errorf("Expected %d for %s, got %d", code, body, w.Code)
+		}
+	}
+
+	// наблюдатель читает, но не меняет
+	mockDB.Roles = map[string]string{"Hive1": "viewer"}
+	if w := do(h.GetInspections, "GET", "/api/hive/inspections?hive=Hive1", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected viewer to read inspections, got %d", w.Code)
+	}
+	if w := do(h.CreateInspection, "POST", "/api/hive/inspections", `{"hive": "Hive1"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for viewer create, got %d", w.Code)
+	}
+	if w := do(h.DeleteInspection, "DELETE", "/api/hive/inspections", `{"id": 1}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for viewer delete, got %d", w.Code)
+	}
+	mockDB.Roles = map[string]string{"Hive1": ""}
+	if w := do(h.GetInspections, "GET", "/api/hive/inspections?hive=Hive1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for foreign hive, got %d", w.Code)
+	}
+
+	// у пользователя свой Hive1 и чужой Hive1, где он наблюдатель: права на осмотр
+	// проверяются в улье его владельца, а не в одноимённом своём
+	mockDB.Roles = map[string]string{"friend@example.com/Hive1": "viewer"}
+	mockDB.Inspections[0].Owner = "friend@example.com"
+	if w := do(h.UpdateInspection, "PUT", "/api/hive/inspections", `{"id": 1, "notes": "чужой улей"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for inspection of viewer's hive with the same name, got %d", w.Code)
+	}
+	if w := do(h.CreateInspection, "POST", "/api/hive/inspections", `{"hive": "Hive1", "owner": "friend@example.com"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for create in viewer's hive with the same name, got %d", w.Code)
+	}
+	mockDB.Inspections[0].Owner = ""
+	mockDB.Roles = nil
+
+	if w := do(h.DeleteInspection, "DELETE", "/api/hive/inspections", `{"id": 2}`); w.Code != http.StatusOK || len(mockDB.Inspections) != 1 {
+		t.Errorf("Expected inspection to be deleted, got %d, %d left", w.Code, len(mockDB.Inspections))
+	}
+	if w := do(h.DeleteInspection, "DELETE", "/api/hive/inspections", `{"id": 2}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for deleted inspection, got %d", w.Code)
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+func TestHiveTimeline(t *testing.T) {
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -3)
+	mockDB := &MockDB{
+		Inspections: []dbTypes.Inspection{
+			{Id: 1, Hive: "Test Hive", Date: day.AddDate(0, 0, -20), QueenSeen: ptrTo(true)},
+			{Id: 2, Hive: "Test Hive", Date: day, VarroaCount: ptrTo(5), VarroaMethod: "sugar_roll"},
+			{Id: 3, Hive: "Test Hive", Date: day.AddDate(-2, 0, 0)},
+		},
+		HiveEvents: []dbTypes.HiveEvent{
+			{ID: 7, Type: "swarm_risk", Source: "analyzer", Title: "Риск роения", OccurredAt: day.Add(15 * time.Hour),
+				Details: map[string]any{"noise": 62.5}},
+			{ID: 8, Type: "honey_harvest", Source: "user", Title: "Откачка мёда", OccurredAt: day.AddDate(0, 0, -10)},
+		},
+	}
+	h := &Handler{logger: zerolog.Nop(), db: mockDB}
+	ctx := context.WithValue(context.Background(), "email", "test@example.com")
+
+	w := httptest.NewRecorder()
+	h.GetHiveTimeline(w, httptest.NewRequest("GET", "/api/hive/timeline", nil).WithContext(ctx))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without name, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetHiveTimeline(w, httptest.NewRequest("GET", "/api/hive/timeline?name=Test+Hive", nil).WithContext(ctx))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data []httpType.TimelineItem `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// осмотр двухлетней давности не попадает в хронологию за год
+	want := []string{"event:Риск роения", "inspection:Осмотр: клещей 5", "event:Откачка мёда", "inspection:Осмотр: матка видна"}
+	if len(resp.Data) != len(want) {
+		t.Fatalf("Expected %d items, got %+v", len(want), resp.Data)
+	}
+	for i, item := range resp.Data {
+		if got := item.Kind + ":" + item.Title; got != want[i] {
+			t.Errorf("item %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+	if resp.Data[0].Details["noise"] != 62.5 || resp.Data[0].Source != "analyzer" {
+		t.Errorf("Expected event details, got %+v", resp.Data[0])
+	}
+	if resp.Data[1].Inspection == nil || resp.Data[1].Inspection.ID != 2 || resp.Data[1].Time != day.Unix() {
+		t.Errorf("Expected inspection details, got %+v", resp.Data[1])
+	}
+}
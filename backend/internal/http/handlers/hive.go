@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/season"
+	"errors"
 	"net/http"
+
+	"github.com/jackc/pgx/v5"
 )
 
 func dbHiveToListItem(h dbTypes.Hive) httpType.HiveListItem {
@@ -12,6 +17,9 @@ func dbHiveToListItem(h dbTypes.Hive) httpType.HiveListItem {
 		Sensor: h.SensorID,
 		Hub:    h.HubName,
 		Queen:  h.QueenName,
+		Apiary: h.ApiaryName,
+		Owner:  h.Email,
+		Role:   h.Role,
 	}
 }
 
@@ -25,11 +33,15 @@ func dbHivesToListItems(hives []dbTypes.Hive) []httpType.HiveListItem {
 
 func dbHiveToDetails(h dbTypes.Hive) httpType.HiveDetails {
 	return httpType.HiveDetails{
-		Name:   h.NameHive,
-		Active: h.Status,
-		Sensor: h.SensorID,
-		Hub:    h.HubName,
-		Queen:  h.QueenName,
+		Name:       h.NameHive,
+		Active:     h.Status,
+		Sensor:     h.SensorID,
+		Hub:        h.HubName,
+		Queen:      h.QueenName,
+		Apiary:     h.ApiaryName,
+		SeasonMode: h.SeasonMode,
+		Owner:      h.Email,
+		Role:       h.Role,
 	}
 }
 
@@ -55,6 +67,8 @@ func (h *Handler) CreateHive(w http.ResponseWriter, r *http.Request) {
 	h.writeBodyJSON(w, "Улей успешно создан", nil)
 }
 
+// GetHives возвращает ульи пользователя и ульи, где он участник; active и apiary
+// (имя пасеки) сужают список.
 func (h *Handler) GetHives(w http.ResponseWriter, r *http.Request) {
 	email, err := h.getEmailFromContext(w, r)
 	if err != nil {
@@ -73,6 +87,9 @@ func (h *Handler) GetHives(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
 		return
 	}
+	if apiaryName := r.URL.Query().Get("apiary"); apiaryName != "" {
+		hives = hivesOfApiary(hives, apiaryName)
+	}
 	h.logger.Debug().Str("email", email).Int("hive_count", len(hives)).Msg("hives retrieved successfully")
 
 	h.writeBodyJSON(w, "Список ульев успешно получен", dbHivesToListItems(hives))
@@ -97,7 +114,7 @@ func (h *Handler) GetHive(w http.ResponseWriter, r *http.Request) {
 		active = &val
 	}
 
-	hive, err := h.db.GetHiveByName(r.Context(), email, hiveName, active)
+	hive, err := h.db.GetHiveByName(r.Context(), email, hiveName, r.URL.Query().Get("owner"), active)
 	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).Str("hive_name", hiveName).Msg("error getting hive")
 		http.Error(w, "Улей не найден", http.StatusNotFound)
@@ -130,6 +147,15 @@ func (h *Handler) UpdateHive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if updateData.SeasonMode != nil && !season.ValidMode(*updateData.SeasonMode) {
+		h.logger.Warn().Str("email", email).Str("season_mode", *updateData.SeasonMode).Msg("invalid season mode")
+		http.Error(w, "Неверный режим сезона (auto, summer, winter)", http.StatusBadRequest)
+		return
+	}
+	if !h.requireHiveRole(w, r, email, updateData.OldName, updateData.Owner, membership.RoleEditor) {
+		return
+	}
+
 	if err := h.db.UpdateHive(r.Context(), email, updateData); err != nil {
 		h.logger.Error().Err(err).Str("email", email).
 			Str("old_name", updateData.OldName).Msg("error updating hive")
@@ -157,8 +183,11 @@ func (h *Handler) DeleteHive(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Имя улья не может быть пустым", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHiveRole(w, r, email, deleteData.Name, deleteData.Owner, membership.RoleOwner) {
+		return
+	}
 
-	if err := h.db.DeleteHive(r.Context(), email, deleteData.Name); err != nil {
+	if err := h.db.DeleteHive(r.Context(), email, deleteData.Name, deleteData.Owner); err != nil {
 		h.logger.Error().Err(err).Str("email", email).
 			Str("hive_name", deleteData.Name).Msg("error deleting hive")
 		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
@@ -186,8 +215,17 @@ func (h *Handler) LinkHubToHive(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Имя улья обязательно", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHiveRole(w, r, email, req.HiveName, req.Owner, membership.RoleEditor) {
+		return
+	}
 
-	if err := h.db.LinkHubToHive(r.Context(), email, req.HiveName, req.TargetName); err != nil {
+	err = h.db.LinkHubToHive(r.Context(), email, req.HiveName, req.Owner, req.TargetName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("hive", req.HiveName).Str("hub", req.TargetName).Msg("hive or owner's hub not found")
+		http.Error(w, "Улей или хаб владельца улья не найдены", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).
 			Str("hive", req.HiveName).Str("hub", req.TargetName).Msg("error linking hub to hive")
 		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
@@ -214,8 +252,11 @@ func (h *Handler) LinkQueenToHive(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Имя улья обязательно", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHiveRole(w, r, email, req.HiveName, req.Owner, membership.RoleEditor) {
+		return
+	}
 
-	if err := h.db.LinkQueenToHive(r.Context(), email, req.HiveName, req.TargetName); err != nil {
+	if err := h.db.LinkQueenToHive(r.Context(), email, req.HiveName, req.Owner, req.TargetName); err != nil {
 		h.logger.Error().Err(err).Str("email", email).
 			Str("hive", req.HiveName).Str("queen", req.TargetName).Msg("error linking queen to hive")
 		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
@@ -225,3 +266,40 @@ func (h *Handler) LinkQueenToHive(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug().Str("email", email).Str("hive", req.HiveName).Str("queen", req.TargetName).Msg("queen linked/unlinked")
 	h.writeBodyJSON(w, "Привязка успешна", nil)
 }
+
+func (h *Handler) LinkApiaryToHive(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.LinkToHiveRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.HiveName == "" {
+		h.logger.Warn().Str("email", email).Msg("hive name is empty in link apiary request")
+		http.Error(w, "Имя улья обязательно", http.StatusBadRequest)
+		return
+	}
+	if !h.requireHiveRole(w, r, email, req.HiveName, req.Owner, membership.RoleEditor) {
+		return
+	}
+
+	err = h.db.LinkApiaryToHive(r.Context(), email, req.HiveName, req.Owner, req.TargetName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("hive", req.HiveName).Str("apiary", req.TargetName).Msg("hive or apiary not found")
+		http.Error(w, "Улей или пасека не найдены", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).
+			Str("hive", req.HiveName).Str("apiary", req.TargetName).Msg("error linking apiary to hive")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Debug().Str("email", email).Str("hive", req.HiveName).Str("apiary", req.TargetName).Msg("apiary linked/unlinked")
+	h.writeBodyJSON(w, "Привязка успешна", nil)
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/deviceAuth"
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/shadow"
+	"context"
+	"errors"
 	"net/http"
 )
 
@@ -27,7 +32,20 @@ func (h *Handler) CreateHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.NewHub(r.Context(), email, createData.Name, createData.ID); err != nil {
+	secret, secretHash, err := deviceAuth.NewSecret()
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error generating hub secret")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.db.NewHub(r.Context(), email, createData.Name, createData.ID, secretHash)
+	if errors.Is(err, deviceAuth.ErrHubTaken) {
+		h.logger.Warn().Str("email", email).Str("hub_id", createData.ID).Msg("hub id is already registered")
+		http.Error(w, "Хаб с таким идентификатором уже зарегистрирован", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).
 			Str("hub_id", createData.ID).Msg("error creating hub")
 		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
@@ -35,7 +53,48 @@ func (h *Handler) CreateHub(w http.ResponseWriter, r *http.Request) {
 	}
 	h.logger.Debug().Str("email", email).Str("hub_id", createData.ID).Msg("hub created")
 
-	h.writeBodyJSON(w, "Хаб успешно создан", nil)
+	h.writeBodyJSON(w, "Хаб успешно создан", httpType.HubSecret{ID: createData.ID, Secret: secret})
+}
+
+// RotateHubSecret перевыпускает секрет устройства. Старый секрет перестаёт
+// действовать сразу: датчик нужно перепрошить с новым паролем.
+func (h *Handler) RotateHubSecret(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var rotateData httpType.RotateHubSecret
+	if err := h.readBodyJSON(w, r, &rotateData); err != nil {
+		return
+	}
+
+	if rotateData.ID == "" {
+		h.logger.Warn().Str("email", email).Msg("hub id is empty")
+		http.Error(w, "Идентификатор хаба обязателен", http.StatusBadRequest)
+		return
+	}
+
+	if !h.requireHubRole(w, r, email, rotateData.ID, membership.RoleOwner) {
+		return
+	}
+
+	secret, secretHash, err := deviceAuth.NewSecret()
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error generating hub secret")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.db.UpdateHubSecret(r.Context(), email, rotateData.ID, secretHash); err != nil {
+		h.logger.Error().Err(err).Str("email", email).
+			Str("hub_id", rotateData.ID).Msg("error rotating hub secret")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("email", email).Str("hub_id", rotateData.ID).Msg("hub secret rotated")
+
+	h.writeBodyJSON(w, "Секрет хаба успешно перевыпущен", httpType.HubSecret{ID: rotateData.ID, Secret: secret})
 }
 
 func (h *Handler) GetHubs(w http.ResponseWriter, r *http.Request) {
@@ -51,11 +110,15 @@ func (h *Handler) GetHubs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastSeen, offline := h.sensorsState(r.Context())
 	result := make([]httpType.HubListItem, 0, len(hubs))
 	for _, hb := range hubs {
+		online, seen := hubOnline(hb.Sensor, lastSeen, offline)
 		result = append(result, httpType.HubListItem{
-			ID:   hb.Sensor,
-			Name: hb.NameHub,
+			ID:       hb.Sensor,
+			Name:     hb.NameHub,
+			Online:   online,
+			LastSeen: seen,
 		})
 	}
 	h.writeBodyJSON(w, "Список хабов успешно получен", result)
@@ -81,12 +144,42 @@ func (h *Handler) GetHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastSeen, offline := h.sensorsState(r.Context())
+	online, seen := hubOnline(hub.Sensor, lastSeen, offline)
 	h.writeBodyJSON(w, "Хаб успешно получен", httpType.HubDetails{
-		ID:   hub.Sensor,
-		Name: hub.NameHub,
+		ID:       hub.Sensor,
+		Name:     hub.NameHub,
+		Online:   online,
+		LastSeen: seen,
 	})
 }
 
+// sensorsState читает из Redis время последнего пакета и список офлайн-датчиков.
+// Ошибка Redis не ломает ответ — хабы просто вернутся без статуса связи.
+func (h *Handler) sensorsState(ctx context.Context) (map[string]int64, map[string]bool) {
+	lastSeen, err := h.inMemDb.GetAllSensors(ctx)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("error getting sensors last seen")
+		return nil, nil
+	}
+	offline, err := h.inMemDb.GetOfflineSensors(ctx)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("error getting offline sensors")
+		return lastSeen, nil
+	}
+	return lastSeen, offline
+}
+
+// hubOnline считает хаб онлайн, если он хоть раз выходил на связь и watchdog
+// не пометил его как пропавший.
+func hubOnline(sensor string, lastSeen map[string]int64, offline map[string]bool) (bool, *int64) {
+	ts, ok := lastSeen[sensor]
+	if !ok || ts == 0 {
+		return false, nil
+	}
+	return !offline[sensor], &ts
+}
+
 func (h *Handler) DeleteHub(w http.ResponseWriter, r *http.Request) {
 	email, err := h.getEmailFromContext(w, r)
 	if err != nil {
@@ -103,6 +196,9 @@ func (h *Handler) DeleteHub(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Идентификатор хаба обязателен", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHubRole(w, r, email, deleteData.ID, membership.RoleOwner) {
+		return
+	}
 
 	if err := h.db.DeleteHub(r.Context(), email, deleteData.ID); err != nil {
 		h.logger.Error().Err(err).Str("email", email).
@@ -131,6 +227,9 @@ func (h *Handler) UpdateHub(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Идентификатор хаба не может быть пустым", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHubRole(w, r, email, updateData.ID, membership.RoleEditor) {
+		return
+	}
 
 	if err := h.db.UpdateHub(r.Context(), email, updateData); err != nil {
 		h.logger.Error().Err(err).Str("email", email).
@@ -141,4 +240,90 @@ func (h *Handler) UpdateHub(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug().Str("email", email).Str("hub_id", updateData.ID).Msg("hub updated")
 
 	h.writeBodyJSON(w, "Хаб успешно обновлен", nil)
-}
\ No newline at end of file
+}
+
+// GetHubShadow возвращает желаемую и применённую устройством конфигурацию хаба.
+func (h *Handler) GetHubShadow(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hubID := r.URL.Query().Get("id")
+	if hubID == "" {
+		h.logger.Error().Msg("no \"id\" in request")
+		http.Error(w, "Параметр \"id\" обязателен", http.StatusBadRequest)
+		return
+	}
+
+	s, err := h.db.GetShadow(r.Context(), email, hubID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub_id", hubID).Msg("error getting hub shadow")
+		http.Error(w, "Хаб не найден", http.StatusNotFound)
+		return
+	}
+
+	delta, pending := shadow.Delta(s)
+	h.writeBodyJSON(w, "Конфигурация хаба успешно получена", httpType.HubShadow{
+		ID: s.Sensor,
+		Desired: httpType.ShadowConfig{
+			SamplingNoise: s.DesiredNoise,
+			SamplingTemp:  s.DesiredTemp,
+			Frequency:     s.DesiredFrequency,
+		},
+		Reported: httpType.ShadowConfig{
+			SamplingNoise: s.ReportedNoise,
+			SamplingTemp:  s.ReportedTemp,
+			Frequency:     s.ReportedFrequency,
+		},
+		Pending: httpType.ShadowConfig{
+			SamplingNoise: delta.SamplingNoise,
+			SamplingTemp:  delta.SamplingTemp,
+			Frequency:     delta.Frequency,
+		},
+		InSync:     !pending,
+		DesiredAt:  s.DesiredAt,
+		ReportedAt: s.ReportedAt,
+	})
+}
+
+// SetHubShadow сохраняет желаемую конфигурацию хаба. Устройство получит её,
+// когда в следующий раз пришлёт статус. Незаданные параметры (0, -1 или поле
+// не передано) не меняются.
+func (h *Handler) SetHubShadow(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var setData httpType.SetHubShadow
+	if err := h.readBodyJSON(w, r, &setData); err != nil {
+		return
+	}
+
+	if setData.ID == "" {
+		h.logger.Warn().Str("email", email).Msg("hub id is empty")
+		http.Error(w, "Идентификатор хаба обязателен", http.StatusBadRequest)
+		return
+	}
+	for _, v := range []int{setData.Desired.SamplingNoise, setData.Desired.SamplingTemp, setData.Desired.Frequency} {
+		if v < -1 {
+			h.logger.Warn().Str("email", email).Str("hub_id", setData.ID).Msg("invalid shadow interval")
+			http.Error(w, "Интервалы должны быть положительными (0 или -1, чтобы не менять)", http.StatusBadRequest)
+			return
+		}
+	}
+	if !h.requireHubRole(w, r, email, setData.ID, membership.RoleEditor) {
+		return
+	}
+
+	if err := h.db.SetDesiredConfig(r.Context(), email, setData.ID, positiveOnly(setData.Desired)); err != nil {
+		h.logger.Error().Err(err).Str("email", email).
+			Str("hub_id", setData.ID).Msg("error setting desired config")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("hub_id", setData.ID).Msg("desired config updated")
+
+	h.writeBodyJSON(w, "Конфигурация будет применена при следующем выходе датчика на связь", nil)
+}
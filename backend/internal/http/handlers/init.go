@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/alerts"
 	"BeeIOT/internal/domain/confirm"
+	"BeeIOT/internal/domain/deviceAuth"
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/jwtToken"
 	"BeeIOT/internal/domain/mqtt"
+	"BeeIOT/internal/domain/stream"
 	"encoding/json"
 	"net/http"
 
@@ -18,11 +21,18 @@ type Handler struct {
 	inMemDb  interfaces.InMemoryDB
 	logger   zerolog.Logger
 	mqtt     *mqtt.Client
+	devAuth  *deviceAuth.Authorizer
+	stream   *stream.Broker
+	// incidents — только для чтения открытых инцидентов, уведомления отсюда не шлются
+	incidents *alerts.Notifier
+	// mailer шлёт приглашения участникам
+	mailer interfaces.EmailSender
 }
 
-func NewHandler(db interfaces.DB, codeSender interfaces.ConfirmSender,
-	inMem interfaces.InMemoryDB, mqtt *mqtt.Client, passwordStore interfaces.PasswordKeeper, logger zerolog.Logger) (*Handler, error) {
-	conf, err := confirm.NewConfirm(codeSender, passwordStore)
+func NewHandler(db interfaces.DB, mailer interfaces.Mailer,
+	inMem interfaces.InMemoryDB, mqtt *mqtt.Client, broker *stream.Broker, passwordStore interfaces.PasswordKeeper,
+	logger zerolog.Logger) (*Handler, error) {
+	conf, err := confirm.NewConfirm(mailer, passwordStore)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create confirm service")
 		return nil, err
@@ -35,7 +45,8 @@ func NewHandler(db interfaces.DB, codeSender interfaces.ConfirmSender,
 		return nil, err
 	}
 	logger.Info().Msg("jwt token created successfully")
-	return &Handler{db: db, conf: conf, tokenJWT: jw, inMemDb: inMem, logger: logger, mqtt: mqtt}, nil
+	return &Handler{db: db, conf: conf, tokenJWT: jw, inMemDb: inMem, logger: logger, mqtt: mqtt,
+		devAuth: deviceAuth.NewAuthorizer(db), stream: broker, incidents: alerts.NewNotifier(db, inMem, nil, logger), mailer: mailer}, nil
 }
 
 type Response struct {
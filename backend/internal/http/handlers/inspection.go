@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/inspection"
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// timelinePeriod — за сколько по умолчанию отдаётся хронология улья: один сезон с запасом.
+const timelinePeriod = 365 * 24 * time.Hour
+
+func dbInspectionToItem(i dbTypes.Inspection) httpType.InspectionItem {
+	return httpType.InspectionItem{
+		ID:           i.Id,
+		Hive:         i.Hive,
+		Owner:        i.Owner,
+		Date:         i.Date.Format(inspection.DateLayout),
+		BroodFrames:  i.BroodFrames,
+		HoneyFrames:  i.HoneyFrames,
+		PollenFrames: i.PollenFrames,
+		QueenSeen:    i.QueenSeen,
+		EggsSeen:     i.EggsSeen,
+		QueenCells:   i.QueenCells,
+		Temperament:  i.Temperament,
+		VarroaCount:  i.VarroaCount,
+		VarroaMethod: i.VarroaMethod,
+		Notes:        i.Notes,
+		Author:       i.Author,
+		CreatedAt:    i.CreatedAt.Unix(),
+	}
+}
+
+// CreateInspection записывает осмотр улья; записывать осмотры может редактор.
+func (h *Handler) CreateInspection(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.CreateInspection
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.Hive == "" {
+		h.logger.Warn().Str("email", email).Msg("hive name is empty")
+		http.Error(w, "Имя улья обязательно", http.StatusBadRequest)
+		return
+	}
+	date, err := inspection.ParseDate(req.Date, time.Now())
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("date", req.Date).Msg("invalid inspection date")
+		http.Error(w, "Некорректные данные осмотра: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	record := dbTypes.Inspection{
+		Date:         date,
+		BroodFrames:  req.BroodFrames,
+		HoneyFrames:  req.HoneyFrames,
+		PollenFrames: req.PollenFrames,
+		QueenSeen:    req.QueenSeen,
+		EggsSeen:     req.EggsSeen,
+		QueenCells:   req.QueenCells,
+		Temperament:  req.Temperament,
+		VarroaCount:  req.VarroaCount,
+		VarroaMethod: req.VarroaMethod,
+		Notes:        req.Notes,
+	}
+	if err := inspection.Validate(record); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid inspection")
+		http.Error(w, "Некорректные данные осмотра: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.requireHiveRole(w, r, email, req.Hive, req.Owner, membership.RoleEditor) {
+		return
+	}
+
+	id, err := h.db.NewInspection(r.Context(), email, req.Hive, req.Owner, record)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("hive", req.Hive).Msg("hive not found")
+		http.Error(w, "Улей не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive", req.Hive).Msg("error creating inspection")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("hive", req.Hive).Int64("inspection", id).Msg("inspection created")
+
+	created, err := h.db.GetInspection(r.Context(), email, id)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("inspection", id).Msg("error getting created inspection")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Осмотр записан", dbInspectionToItem(created))
+}
+
+// GetInspections возвращает осмотры доступных пользователю ульев, от новых к старым;
+// hive (владелец — owner) сужает список до одного улья, since (Unix) — до осмотров не раньше этого дня.
+func (h *Handler) GetInspections(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var since time.Time
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var ok bool
+		if since, ok = parseSince(sinceStr); !ok {
+			h.logger.Warn().Str("email", email).Str("since", sinceStr).Msg("invalid since")
+			http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+			return
+		}
+	}
+	hiveName, owner := r.URL.Query().Get("hive"), r.URL.Query().Get("owner")
+	if hiveName != "" && !h.requireHiveRole(w, r, email, hiveName, owner, membership.RoleViewer) {
+		return
+	}
+
+	inspections, err := h.db.GetInspections(r.Context(), email, hiveName, owner, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting inspections")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]httpType.InspectionItem, 0, len(inspections))
+	for _, i := range inspections {
+		result = append(result, dbInspectionToItem(i))
+	}
+	h.writeBodyJSON(w, "Список осмотров получен", result)
+}
+
+// inspectionForEdit находит осмотр и проверяет, что пользователь может его менять.
+// Возвращает false, если ответ уже записан.
+func (h *Handler) inspectionForEdit(w http.ResponseWriter, r *http.Request, email string, id int64) (dbTypes.Inspection, bool) {
+	if id == 0 {
+		h.logger.Warn().Str("email", email).Msg("inspection id is empty")
+		http.Error(w, "Поле \"id\" обязательно", http.StatusBadRequest)
+		return dbTypes.Inspection{}, false
+	}
+	record, err := h.db.GetInspection(r.Context(), email, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Int64("inspection", id).Msg("inspection not found")
+		http.Error(w, "Осмотр не найден", http.StatusNotFound)
+		return record, false
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("inspection", id).Msg("error getting inspection")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return record, false
+	}
+	return record, h.requireHiveRole(w, r, email, record.Hive, record.Owner, membership.RoleEditor)
+}
+
+// UpdateInspection меняет переданные поля осмотра; менять осмотры может редактор.
+func (h *Handler) UpdateInspection(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.UpdateInspection
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	record, ok := h.inspectionForEdit(w, r, email, req.ID)
+	if !ok {
+		return
+	}
+	if req.Date != nil {
+		if *req.Date == "" {
+			h.logger.Warn().Str("email", email).Msg("inspection date is empty")
+			http.Error(w, "Дата осмотра не может быть пустой", http.StatusBadRequest)
+			return
+		}
+		if record.Date, err = inspection.ParseDate(*req.Date, time.Now()); err != nil {
+			h.logger.Warn().Err(err).Str("email", email).Str("date", *req.Date).Msg("invalid inspection date")
+			http.Error(w, "Некорректные данные осмотра: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.BroodFrames != nil {
+		record.BroodFrames = req.BroodFrames
+	}
+	if req.HoneyFrames != nil {
+		record.HoneyFrames = req.HoneyFrames
+	}
+	if req.PollenFrames != nil {
+		record.PollenFrames = req.PollenFrames
+	}
+	if req.QueenSeen != nil {
+		record.QueenSeen = req.QueenSeen
+	}
+	if req.EggsSeen != nil {
+		record.EggsSeen = req.EggsSeen
+	}
+	if req.QueenCells != nil {
+		record.QueenCells = req.QueenCells
+	}
+	if req.Temperament != nil {
+		record.Temperament = *req.Temperament
+	}
+	if req.VarroaCount != nil {
+		record.VarroaCount = req.VarroaCount
+	}
+	if req.VarroaMethod != nil {
+		record.VarroaMethod = *req.VarroaMethod
+	}
+	if req.Notes != nil {
+		record.Notes = *req.Notes
+	}
+	if err := inspection.Validate(record); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Int64("inspection", req.ID).Msg("invalid inspection")
+		http.Error(w, "Некорректные данные осмотра: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.UpdateInspection(r.Context(), email, record)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Int64("inspection", req.ID).Msg("inspection not found")
+		http.Error(w, "Осмотр не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("inspection", req.ID).Msg("error updating inspection")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Int64("inspection", req.ID).Msg("inspection updated")
+
+	h.writeBodyJSON(w, "Осмотр обновлён", dbInspectionToItem(record))
+}
+
+// DeleteInspection удаляет осмотр; удалять осмотры может редактор.
+func (h *Handler) DeleteInspection(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.DeleteInspection
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if _, ok := h.inspectionForEdit(w, r, email, req.ID); !ok {
+		return
+	}
+
+	err = h.db.DeleteInspection(r.Context(), email, req.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Int64("inspection", req.ID).Msg("inspection not found")
+		http.Error(w, "Осмотр не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("inspection", req.ID).Msg("error deleting inspection")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Int64("inspection", req.ID).Msg("inspection deleted")
+
+	h.writeBodyJSON(w, "Осмотр удалён", nil)
+}
+
+// GetHiveTimeline возвращает хронологию улья name владельца owner: осмотры вместе с событиями,
+// найденными анализаторами или записанными пасечником, от новых к старым.
+// По умолчанию — за последний год, since (Unix) задаёт начало.
+func (h *Handler) GetHiveTimeline(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hiveName := r.URL.Query().Get("name")
+	if hiveName == "" {
+		h.logger.Warn().Str("email", email).Msg("no \"name\" in request")
+		http.Error(w, "Параметр \"name\" обязателен", http.StatusBadRequest)
+		return
+	}
+	since := time.Now().Add(-timelinePeriod)
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var ok bool
+		if since, ok = parseSince(sinceStr); !ok {
+			h.logger.Warn().Str("email", email).Str("since", sinceStr).Msg("invalid since")
+			http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	owner := r.URL.Query().Get("owner")
+	hive, err := h.db.GetHiveByName(r.Context(), email, hiveName, owner, nil)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("hive", hiveName).Msg("hive not found")
+		http.Error(w, "Улей не найден", http.StatusNotFound)
+		return
+	}
+	inspections, err := h.db.GetInspections(r.Context(), email, hiveName, hive.Email, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hive", hiveName).Msg("error getting inspections")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	events, err := h.db.GetHiveEvents(r.Context(), hive.Id, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int("hiveId", hive.Id).Msg("error getting hive events")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	entries := inspection.Timeline(inspections, events)
+	result := make([]httpType.TimelineItem, 0, len(entries))
+	for _, e := range entries {
+		item := httpType.TimelineItem{Time: e.At.Unix()}
+		if e.Inspection != nil {
+			details := dbInspectionToItem(*e.Inspection)
+			item.Kind = "inspection"
+			item.Title = inspection.Title(*e.Inspection)
+			item.Inspection = &details
+		} else {
+			item.Kind = "event"
+			item.Type = e.Event.Type
+			item.Source = e.Event.Source
+			item.Title = e.Event.Title
+			item.Details = e.Event.Details
+		}
+		result = append(result, item)
+	}
+	h.writeBodyJSON(w, "Хронология улья получена", result)
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// requireRole проверяет, хватает ли роли пользователя для действия, требующего min:
+// нет доступа — 404 с сообщением notFound, роль ниже — 403. Возвращает false, если
+// ответ уже записан.
+func (h *Handler) requireRole(w http.ResponseWriter, email, name, min, notFound, role string, err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("target", name).Msg("target not found or not shared")
+		http.Error(w, notFound, http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("target", name).Msg("error getting role")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return false
+	}
+	if !membership.Allows(role, min) {
+		h.logger.Warn().Str("email", email).Str("target", name).Str("role", role).Str("required", min).Msg("insufficient role")
+		http.Error(w, "Недостаточно прав", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireHiveRole — requireRole для улья hive владельца owner; пустой owner — улей,
+// который выбирает accessibleHive (при совпадении имён — свой).
+func (h *Handler) requireHiveRole(w http.ResponseWriter, r *http.Request, email, hive, owner, min string) bool {
+	role, err := h.db.GetHiveRole(r.Context(), email, hive, owner)
+	return h.requireRole(w, email, hive, min, "Улей не найден", role, err)
+}
+
+func (h *Handler) requireHubRole(w http.ResponseWriter, r *http.Request, email, hub, min string) bool {
+	role, err := h.db.GetHubRole(r.Context(), email, hub)
+	return h.requireRole(w, email, hub, min, "Хаб не найден", role, err)
+}
+
+func (h *Handler) requireApiaryRole(w http.ResponseWriter, r *http.Request, email, apiary, min string) bool {
+	role, err := h.db.GetApiaryRole(r.Context(), email, apiary)
+	return h.requireRole(w, email, apiary, min, "Пасека не найдена", role, err)
+}
+
+// requireTargetRole — requireRole для пасеки apiary или улья hive владельца owner: задаётся ровно одно.
+func (h *Handler) requireTargetRole(w http.ResponseWriter, r *http.Request, email, apiary, hive, owner, min string) bool {
+	if (apiary == "") == (hive == "") {
+		h.logger.Warn().Str("email", email).Msg("membership target must be either apiary or hive")
+		http.Error(w, "Укажите пасеку (apiary) или улей (hive)", http.StatusBadRequest)
+		return false
+	}
+	if apiary != "" {
+		return h.requireApiaryRole(w, r, email, apiary, min)
+	}
+	return h.requireHiveRole(w, r, email, hive, owner, min)
+}
+
+// InviteMember приглашает пользователя по почте на пасеку или в улей. Приглашать
+// может только владелец; письмо с кодом приглашения уходит на указанный адрес.
+func (h *Handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.InviteMember
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		h.logger.Warn().Str("email", email).Msg("invitee email is empty")
+		http.Error(w, "Почта приглашаемого обязательна", http.StatusBadRequest)
+		return
+	}
+	if strings.EqualFold(req.Email, email) {
+		h.logger.Warn().Str("email", email).Msg("user invites themselves")
+		http.Error(w, "Нельзя пригласить самого себя", http.StatusBadRequest)
+		return
+	}
+	if !membership.ValidRole(req.Role) {
+		h.logger.Warn().Str("email", email).Str("role", req.Role).Msg("invalid member role")
+		http.Error(w, "Неверная роль (viewer, editor, owner)", http.StatusBadRequest)
+		return
+	}
+	if !h.requireTargetRole(w, r, email, req.Apiary, req.Hive, req.Owner, membership.RoleOwner) {
+		return
+	}
+
+	token, hash, err := membership.NewToken()
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error generating invitation token")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	expires := time.Now().Add(membership.InvitationTTL)
+	if err := h.db.CreateInvitation(r.Context(), email, req, hash, expires); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("invitee", req.Email).Msg("error creating invitation")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	subject, text := membership.InvitationEmail(email, membership.Target{Apiary: req.Apiary, Hive: req.Hive}, req.Role, token, expires)
+	if err := h.mailer.SendEmail(req.Email, subject, text, ""); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("invitee", req.Email).Msg("error sending invitation email")
+		http.Error(w, "Не удалось отправить приглашение", http.StatusBadGateway)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("invitee", req.Email).Str("role", req.Role).Msg("invitation sent")
+
+	h.writeBodyJSON(w, "Приглашение отправлено", nil)
+}
+
+// GetMembers возвращает участников пасеки (?apiary=) или улья (?hive=, владелец — ?owner=),
+// начиная с владельца.
+func (h *Handler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	apiaryName, hiveName, owner := r.URL.Query().Get("apiary"), r.URL.Query().Get("hive"), r.URL.Query().Get("owner")
+	if !h.requireTargetRole(w, r, email, apiaryName, hiveName, owner, membership.RoleViewer) {
+		return
+	}
+
+	members, err := h.db.GetMembers(r.Context(), email, apiaryName, hiveName, owner)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting members")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]httpType.MemberItem, 0, len(members))
+	for _, m := range members {
+		result = append(result, dbMemberToItem(m))
+	}
+	h.writeBodyJSON(w, "Список участников получен", result)
+}
+
+func dbMemberToItem(m dbTypes.Member) httpType.MemberItem {
+	item := httpType.MemberItem{Email: m.Email, Role: m.Role, Apiary: m.Apiary, Hive: m.Hive}
+	if m.CreatedAt != nil {
+		item.Since = m.CreatedAt.Unix()
+	}
+	return item
+}
+
+// UpdateMember меняет роль участника; менять роли может только владелец.
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.UpdateMember
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.Email == "" {
+		h.logger.Warn().Str("email", email).Msg("member email is empty")
+		http.Error(w, "Почта участника обязательна", http.StatusBadRequest)
+		return
+	}
+	if !membership.ValidRole(req.Role) {
+		h.logger.Warn().Str("email", email).Str("role", req.Role).Msg("invalid member role")
+		http.Error(w, "Неверная роль (viewer, editor, owner)", http.StatusBadRequest)
+		return
+	}
+	if !h.requireTargetRole(w, r, email, req.Apiary, req.Hive, req.Owner, membership.RoleOwner) {
+		return
+	}
+
+	err = h.db.SetMemberRole(r.Context(), email, req)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("member", req.Email).Msg("member not found")
+		http.Error(w, "Участник не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("member", req.Email).Msg("error updating member role")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("member", req.Email).Str("role", req.Role).Msg("member role updated")
+
+	h.writeBodyJSON(w, "Роль участника изменена", nil)
+}
+
+// RemoveMember исключает участника. Владелец исключает любого, участник может выйти сам.
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.RemoveMember
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if req.Email == "" {
+		h.logger.Warn().Str("email", email).Msg("member email is empty")
+		http.Error(w, "Почта участника обязательна", http.StatusBadRequest)
+		return
+	}
+	min := membership.RoleOwner
+	if strings.EqualFold(req.Email, email) {
+		min = membership.RoleViewer
+	}
+	if !h.requireTargetRole(w, r, email, req.Apiary, req.Hive, req.Owner, min) {
+		return
+	}
+
+	err = h.db.RemoveMember(r.Context(), email, req)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Str("member", req.Email).Msg("member not found")
+		http.Error(w, "Участник не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("member", req.Email).Msg("error removing member")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Str("member", req.Email).Msg("member removed")
+
+	h.writeBodyJSON(w, "Участник исключён", nil)
+}
+
+// GetInvitations возвращает действующие приглашения на почту пользователя.
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	invitations, err := h.db.GetInvitations(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("error getting invitations")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]httpType.InvitationItem, 0, len(invitations))
+	for _, inv := range invitations {
+		result = append(result, dbInvitationToItem(inv))
+	}
+	h.writeBodyJSON(w, "Список приглашений получен", result)
+}
+
+func dbInvitationToItem(inv dbTypes.Invitation) httpType.InvitationItem {
+	return httpType.InvitationItem{
+		ID:        inv.Id,
+		Apiary:    inv.Apiary,
+		Hive:      inv.Hive,
+		Role:      inv.Role,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.ExpiresAt.Unix(),
+	}
+}
+
+// readInvitationAnswer читает ответ на приглашение и возвращает id и хеш кода из письма.
+func (h *Handler) readInvitationAnswer(w http.ResponseWriter, r *http.Request, email string) (int64, string, bool) {
+	var req httpType.InvitationAnswer
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return 0, "", false
+	}
+	if req.ID == 0 && strings.TrimSpace(req.Token) == "" {
+		h.logger.Warn().Str("email", email).Msg("invitation id and token are empty")
+		http.Error(w, "Укажите id или код приглашения", http.StatusBadRequest)
+		return 0, "", false
+	}
+	var hash string
+	if strings.TrimSpace(req.Token) != "" {
+		hash = membership.HashToken(req.Token)
+	}
+	return req.ID, hash, true
+}
+
+// AcceptInvitation принимает приглашение. Принять его может только пользователь
+// с той почтой, на которую оно отправлено.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, hash, ok := h.readInvitationAnswer(w, r, email)
+	if !ok {
+		return
+	}
+
+	inv, err := h.db.AcceptInvitation(r.Context(), email, id, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Int64("invitation", id).Msg("invitation not found")
+		http.Error(w, "Приглашение не найдено или истекло", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("invitation", id).Msg("error accepting invitation")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Int64("invitation", inv.Id).Str("role", inv.Role).Msg("invitation accepted")
+
+	h.writeBodyJSON(w, "Приглашение принято", dbInvitationToItem(inv))
+}
+
+func (h *Handler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	id, hash, ok := h.readInvitationAnswer(w, r, email)
+	if !ok {
+		return
+	}
+
+	err = h.db.DeclineInvitation(r.Context(), email, id, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.logger.Warn().Str("email", email).Int64("invitation", id).Msg("invitation not found")
+		http.Error(w, "Приглашение не найдено", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int64("invitation", id).Msg("error declining invitation")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Int64("invitation", id).Msg("invitation declined")
+
+	h.writeBodyJSON(w, "Приглашение отклонено", nil)
+}
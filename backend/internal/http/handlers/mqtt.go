@@ -1,13 +1,22 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/httpType"
 	"BeeIOT/internal/domain/models/mqttTypes"
 	"encoding/json"
 	"net/http"
 	"time"
 )
 
+// MQTTSendConfig публикует конфигурацию хабу. Отправлять конфигурацию может
+// владелец хаба или участник с ролью не ниже редактора.
 func (h *Handler) MQTTSendConfig(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	var data struct {
 		Sensor string                 `json:"sensor"`
 		Config mqttTypes.DeviceConfig `json:"config"`
@@ -19,6 +28,24 @@ func (h *Handler) MQTTSendConfig(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Имя датчика обязательно", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHubRole(w, r, email, data.Sensor, membership.RoleEditor) {
+		return
+	}
+
+	// Интервалы из конфига запоминаем как желаемое состояние хаба: если датчик
+	// спит и пропустит публикацию, сервер дошлёт их при следующем статусе.
+	desired := httpType.ShadowConfig{
+		SamplingNoise: data.Config.SamplingNoise,
+		SamplingTemp:  data.Config.SamplingTemp,
+		Frequency:     data.Config.Frequency,
+	}
+	if desired.SamplingNoise > 0 || desired.SamplingTemp > 0 || desired.Frequency > 0 {
+		if err := h.db.SetDesiredConfig(r.Context(), email, data.Sensor, positiveOnly(desired)); err != nil {
+			h.logger.Error().Err(err).Str("email", email).Str("sensor", data.Sensor).Msg("failed to save desired config")
+			http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	h.logger.Info().Str("sensor", data.Sensor).Msg("sending MQTT config")
 	if err := h.mqtt.SendConfig(data.Sensor, data.Config); err != nil {
@@ -29,7 +56,25 @@ func (h *Handler) MQTTSendConfig(w http.ResponseWriter, r *http.Request) {
 	h.writeBodyJSON(w, "Конфигурация успешно отправлена", nil)
 }
 
+// positiveOnly заменяет незаданные (нулевые и отрицательные) интервалы на -1,
+// чтобы они не перезаписали желаемое состояние.
+func positiveOnly(c httpType.ShadowConfig) httpType.ShadowConfig {
+	for _, v := range []*int{&c.SamplingNoise, &c.SamplingTemp, &c.Frequency} {
+		if *v <= 0 {
+			*v = -1
+		}
+	}
+	return c
+}
+
+// MQTTSendHealthCheck запрашивает у хаба статус и ждёт свежий ответ. Health check
+// будит датчик, поэтому, как и конфигурацию, его отправляет владелец или редактор.
 func (h *Handler) MQTTSendHealthCheck(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
 	var data struct {
 		Sensor string `json:"sensor"`
 	}
@@ -40,6 +85,9 @@ func (h *Handler) MQTTSendHealthCheck(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Имя датчика обязательно", http.StatusBadRequest)
 		return
 	}
+	if !h.requireHubRole(w, r, email, data.Sensor, membership.RoleEditor) {
+		return
+	}
 
 	// Get current cached status timestamp to detect fresh response
 	var beforeTs int64
@@ -106,7 +154,7 @@ func (h *Handler) GetNoiseAndTemp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
 		return
 	}
-	hive, err := h.db.GetHiveByName(r.Context(), email, nameHive, nil)
+	hive, err := h.db.GetHiveByName(r.Context(), email, nameHive, email, nil)
 	if err != nil {
 		h.logger.Error().Err(err).Str("sensor", sensor).Msg("failed to get hive by name")
 		http.Error(w, "внутренняя ошибка сервера", http.StatusInternalServerError)
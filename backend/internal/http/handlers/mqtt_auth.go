@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"net/http"
+)
+
+// Эндпоинты HTTP-бэкенда mosquitto-go-auth (auth_opt_http_response_mode status):
+// 200 — доступ разрешён, 403 — запрещён. Вызываются только брокером изнутри
+// docker-сети, снаружи nginx их не проксирует.
+
+// MQTTAuthUser проверяет логин/пароль при подключении клиента к брокеру.
+func (h *Handler) MQTTAuthUser(w http.ResponseWriter, r *http.Request) {
+	var req httpType.MQTTAuthRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	ok, err := h.devAuth.Authenticate(r.Context(), req.Username, req.Password)
+	if err != nil {
+		h.logger.Error().Err(err).Str("username", req.Username).Msg("error authenticating mqtt client")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		h.logger.Warn().Str("username", req.Username).Str("client_id", req.ClientID).Msg("mqtt authentication failed")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// MQTTAuthSuperuser сообщает брокеру, является ли клиент серверной учёткой.
+func (h *Handler) MQTTAuthSuperuser(w http.ResponseWriter, r *http.Request) {
+	var req httpType.MQTTAuthRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if !h.devAuth.IsSuperuser(req.Username) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// MQTTAuthACL разрешает устройству публикацию и подписку только на свои топики.
+func (h *Handler) MQTTAuthACL(w http.ResponseWriter, r *http.Request) {
+	var req httpType.MQTTAuthRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+
+	if !h.devAuth.CheckACL(req.Username, req.Topic) {
+		h.logger.Warn().Str("username", req.Username).Str("topic", req.Topic).
+			Int("acc", req.Acc).Msg("mqtt acl denied")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 200
+)
+
+// GetNotifications возвращает входящие уведомления от новых к старым.
+// ?limit= и ?offset= — страница, ?unread=true — только непрочитанные.
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultNotificationsLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxNotificationsLimit {
+			http.Error(w, "Параметр \"limit\" должен быть от 1 до 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Параметр \"offset\" должен быть неотрицательным числом", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+	unreadOnly := false
+	if v := query.Get("unread"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Параметр \"unread\" должен быть true или false", http.StatusBadRequest)
+			return
+		}
+		unreadOnly = b
+	}
+
+	list, err := h.db.GetNotifications(r.Context(), email, unreadOnly, limit, offset)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to get notifications")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	total, unread, err := h.db.CountNotifications(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to count notifications")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBodyJSON(w, "Уведомления получены", httpType.NotificationsPage{
+		Items:  notificationsToHTTP(list),
+		Total:  total,
+		Unread: unread,
+	})
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления из тела запроса.
+func (h *Handler) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.MarkNotificationsReadRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	if len(req.IDs) == 0 {
+		http.Error(w, "Список уведомлений пуст", http.StatusBadRequest)
+		return
+	}
+
+	h.markNotificationsRead(w, r, email, req.IDs)
+}
+
+// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя.
+func (h *Handler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	h.markNotificationsRead(w, r, email, nil)
+}
+
+func (h *Handler) markNotificationsRead(w http.ResponseWriter, r *http.Request, email string, ids []int64) {
+	marked, err := h.db.MarkNotificationsRead(r.Context(), email, ids)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to mark notifications read")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Уведомления отмечены прочитанными", map[string]int64{"marked": marked})
+}
+
+// DeleteNotification удаляет уведомление пользователя по ID из тела запроса.
+func (h *Handler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.DeleteNotificationRequest
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	if req.ID <= 0 {
+		http.Error(w, "ID уведомления обязателен", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteNotification(r.Context(), email, req.ID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, "Уведомление не найдено", http.StatusNotFound)
+		return
+	case err != nil:
+		h.logger.Error().Err(err).Str("email", email).Int64("id", req.ID).Msg("failed to delete notification")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Уведомление удалено", nil)
+}
+
+func notificationsToHTTP(list []dbTypes.Notification) []httpType.Notification {
+	result := make([]httpType.Notification, len(list))
+	for i, n := range list {
+		result[i] = httpType.Notification{
+			ID:        n.ID,
+			Hive:      n.Hive,
+			Type:      n.Type,
+			Severity:  n.Severity,
+			Title:     n.Title,
+			Body:      n.Body,
+			CreatedAt: n.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if n.ReadAt != nil {
+			result[i].ReadAt = n.ReadAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/alerts"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/notification"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetNotificationPreferences возвращает настройки уведомлений пользователя.
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	prefs, err := h.db.GetNotificationPreferences(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to get notification preferences")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Настройки уведомлений получены", preferencesToHTTP(prefs))
+}
+
+// SetNotificationPreferences сохраняет настройки уведомлений целиком: каналы, настройки
+// по типам алертов, минимальную важность, тихие часы и подписку на сводку. Секрет вебхука, если не передан,
+// сохраняется прежний или генерируется новый.
+func (h *Handler) SetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.NotificationPreferences
+	if err := h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	if err := notification.ValidatePreferences(req); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid notification preferences")
+		http.Error(w, "Некорректные настройки уведомлений: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prefs := dbTypes.NotificationPreferences{
+		Channels:      req.Channels,
+		WebhookURL:    req.WebhookURL,
+		WebhookSecret: req.WebhookSecret,
+		AlertTypes:    make(map[string]dbTypes.AlertTypePreference, len(req.AlertTypes)),
+		MinSeverity:   req.MinSeverity,
+		Timezone:      req.Timezone,
+		Digest:        req.Digest,
+	}
+	for typ, tp := range req.AlertTypes {
+		prefs.AlertTypes[typ] = dbTypes.AlertTypePreference{Enabled: tp.Enabled, Channels: tp.Channels}
+	}
+	for _, q := range req.QuietHours {
+		prefs.QuietHours = append(prefs.QuietHours, dbTypes.QuietHours{Start: q.Start, End: q.End})
+	}
+	if prefs.MinSeverity == "" {
+		prefs.MinSeverity = alerts.SeverityInfo
+	}
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if prefs.Digest == "" {
+		prefs.Digest = notification.DigestOff
+	}
+	if prefs.WebhookURL != "" && prefs.WebhookSecret == "" {
+		if prefs.WebhookSecret, err = h.webhookSecret(r, email); err != nil {
+			h.logger.Error().Err(err).Str("email", email).Msg("failed to prepare webhook secret")
+			http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = h.db.SetNotificationPreferences(r.Context(), email, prefs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Пользователь не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to set notification preferences")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug().Str("email", email).Strs("channels", prefs.Channels).Msg("notification preferences updated")
+
+	prefs.UpdatedAt = time.Now()
+	h.writeBodyJSON(w, "Настройки уведомлений сохранены", preferencesToHTTP(prefs))
+}
+
+// webhookSecret возвращает сохранённый секрет вебхука или новый, если его ещё нет.
+func (h *Handler) webhookSecret(r *http.Request, email string) (string, error) {
+	current, err := h.db.GetNotificationPreferences(r.Context(), email)
+	if err != nil {
+		return "", err
+	}
+	if current.WebhookSecret != "" {
+		return current.WebhookSecret, nil
+	}
+	return notification.NewWebhookSecret()
+}
+
+func preferencesToHTTP(p dbTypes.NotificationPreferences) httpType.NotificationPreferences {
+	res := httpType.NotificationPreferences{
+		Channels:      p.Channels,
+		WebhookURL:    p.WebhookURL,
+		WebhookSecret: p.WebhookSecret,
+		MinSeverity:   p.MinSeverity,
+		Timezone:      p.Timezone,
+		Digest:        p.Digest,
+	}
+	if res.Channels == nil {
+		res.Channels = []string{}
+	}
+	if len(p.AlertTypes) > 0 {
+		res.AlertTypes = make(map[string]httpType.AlertTypePreference, len(p.AlertTypes))
+		for typ, tp := range p.AlertTypes {
+			res.AlertTypes[typ] = httpType.AlertTypePreference{Enabled: tp.Enabled, Channels: tp.Channels}
+		}
+	}
+	for _, q := range p.QuietHours {
+		res.QuietHours = append(res.QuietHours, httpType.QuietHours{Start: q.Start, End: q.End})
+	}
+	if p.UpdatedAt.Unix() > 0 {
+		res.UpdatedAt = p.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// streamHeartbeat — период пустых комментариев в потоке. Держит соединение живым
+// за nginx (proxy_read_timeout для потока — 60 секунд) и выявляет ушедших клиентов.
+const streamHeartbeat = 25 * time.Second
+
+// streamTicketTTL — сколько живёт билет на подключение к потоку. Клиент подключается
+// сразу после получения билета, поэтому хватает нескольких секунд.
+const streamTicketTTL = 30 * time.Second
+
+// CreateStreamTicket выдаёт одноразовый билет для подключения к /api/telemetry/stream
+// из браузерного EventSource, который не умеет передавать заголовок Authorization.
+func (h *Handler) CreateStreamTicket(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to generate stream ticket")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	ticket := hex.EncodeToString(buf)
+	if err := h.inMemDb.SetStreamTicket(r.Context(), ticket, email, streamTicketTTL); err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to save stream ticket")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.writeBodyJSON(w, "Билет потока выдан", httpType.StreamTicket{
+		Ticket:    ticket,
+		ExpiresIn: int(streamTicketTTL.Seconds()),
+	})
+}
+
+// StreamTelemetry отдаёт Server-Sent Events с новыми данными и статусами хабов
+// пользователя. Параметр hub ограничивает поток одним хабом. Список хабов
+// фиксируется при подключении: хаб, созданный позже, попадёт в поток после переподключения.
+func (h *Handler) StreamTelemetry(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || h.stream == nil {
+		h.logger.Error().Str("email", email).Msg("telemetry streaming is not supported")
+		http.Error(w, "Потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	hubs, err := h.db.GetHubs(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to get hubs for stream")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	only := r.URL.Query().Get("hub")
+	ids := make([]int, 0, len(hubs))
+	for _, hub := range hubs {
+		if only == "" || hub.Sensor == only {
+			ids = append(ids, hub.Id)
+		}
+	}
+	if only != "" && len(ids) == 0 {
+		h.logger.Warn().Str("email", email).Str("hub", only).Msg("hub not found for stream")
+		http.Error(w, "Хаб не найден", http.StatusNotFound)
+		return
+	}
+
+	sub := h.stream.Subscribe(ids)
+	defer h.stream.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Отключаем буферизацию ответа в nginx, иначе события копятся в прокси
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	h.logger.Info().Str("email", email).Int("hubs", len(ids)).Msg("telemetry stream opened")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			h.logger.Info().Str("email", email).Msg("telemetry stream closed")
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-sub.Events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Warn().Err(err).Str("hub", event.Hub).Msg("failed to marshal telemetry event")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
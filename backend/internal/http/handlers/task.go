@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/httpType"
 	"net/http"
 )
@@ -27,11 +28,8 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем существование улья
-	_, err = h.db.GetHiveByName(r.Context(), email, req.HiveName, nil)
-	if err != nil {
-		h.logger.Warn().Str("email", email).Str("hive_name", req.HiveName).Msg("hive not found")
-		http.Error(w, "Улей не найден", http.StatusBadRequest)
+	// Проверяем существование улья и право добавлять работы
+	if !h.requireHiveRole(w, r, email, req.HiveName, req.Owner, membership.RoleEditor) {
 		return
 	}
 
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/httpType"
 	"BeeIOT/internal/domain/models/mqttTypes"
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/rollup"
+	"BeeIOT/internal/domain/spectrum"
 	"context"
 	"encoding/json"
 	"net/http"
@@ -22,6 +26,9 @@ func (h *Handler) SetHiveWeight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	weight.Email = email
+	if !h.requireHubRole(w, r, email, weight.Hub, membership.RoleEditor) {
+		return
+	}
 
 	err = h.db.NewHiveWeight(r.Context(), weight)
 	if err != nil {
@@ -75,6 +82,9 @@ func (h *Handler) DeleteHiveWeight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	weight.Email = email
+	if !h.requireHubRole(w, r, email, weight.Hub, membership.RoleEditor) {
+		return
+	}
 
 	err = h.db.DeleteHiveWeight(r.Context(), weight)
 	if err != nil {
@@ -106,6 +116,15 @@ func (h *Handler) GetWeightSinceTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resolution, ok := h.telemetryResolution(w, r, email, hubID, rollup.MetricWeight, since)
+	if !ok {
+		return
+	}
+	if resolution != rollup.ResolutionRaw {
+		h.writeTelemetryRollup(w, r, email, hubID, rollup.MetricWeight, resolution, since, "Данные веса успешно получены")
+		return
+	}
+
 	weights, err := h.db.GetWeightSinceTime(r.Context(), email, hubID, since)
 	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get weight data")
@@ -113,9 +132,13 @@ func (h *Handler) GetWeightSinceTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := make([]httpType.TelemetryDataPoint, len(weights))
-	for i, wt := range weights {
-		response[i] = httpType.TelemetryDataPoint{Time: wt.Date.Unix(), Value: wt.Weight}
+	hide := hideFlagged(r)
+	response := make([]httpType.TelemetryDataPoint, 0, len(weights))
+	for _, wt := range weights {
+		if hide && quality.Flagged(wt.Quality) {
+			continue
+		}
+		response = append(response, rawPoint(wt.Date, wt.Weight, wt.Quality))
 	}
 
 	h.writeBodyJSON(w, "Данные веса успешно получены", response)
@@ -141,6 +164,15 @@ func (h *Handler) GetNoiseSinceTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resolution, ok := h.telemetryResolution(w, r, email, hubID, rollup.MetricNoise, since)
+	if !ok {
+		return
+	}
+	if resolution != rollup.ResolutionRaw {
+		h.writeTelemetryRollup(w, r, email, hubID, rollup.MetricNoise, resolution, since, "Данные шума успешно получены")
+		return
+	}
+
 	noiseLevels, err := h.db.GetNoiseSinceTime(r.Context(), email, hubID, since)
 	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get noise data")
@@ -148,14 +180,67 @@ func (h *Handler) GetNoiseSinceTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := make([]httpType.TelemetryDataPoint, len(noiseLevels))
-	for i, n := range noiseLevels {
-		response[i] = httpType.TelemetryDataPoint{Time: n.Date.Unix(), Value: n.Level}
+	hide := hideFlagged(r)
+	response := make([]httpType.TelemetryDataPoint, 0, len(noiseLevels))
+	for _, n := range noiseLevels {
+		if hide && quality.Flagged(n.Quality) {
+			continue
+		}
+		response = append(response, rawPoint(n.Date, n.Level, n.Quality))
 	}
 
 	h.writeBodyJSON(w, "Данные шума успешно получены", response)
 }
 
+// GetNoiseSpectrumSinceTime отдаёт спектры шума хаба по полосам частот. Агрегатов
+// для спектра нет: полосы у разных прошивок различаются, поэтому отдаются сырые данные.
+func (h *Handler) GetNoiseSpectrumSinceTime(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hubID := r.URL.Query().Get("hub")
+	if hubID == "" {
+		h.logger.Warn().Str("email", email).Msg("missing query param 'hub'")
+		http.Error(w, "Параметр \"hub\" обязателен", http.StatusBadRequest)
+		return
+	}
+
+	since, ok := parseSince(r.URL.Query().Get("since"))
+	if !ok {
+		h.logger.Warn().Str("email", email).Str("since", r.URL.Query().Get("since")).Msg("invalid since")
+		http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+		return
+	}
+
+	spectra, err := h.db.GetNoiseSpectrumSinceTime(r.Context(), email, hubID, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get noise spectrum")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]httpType.TelemetrySpectrumPoint, len(spectra))
+	for i, s := range spectra {
+		response[i] = httpType.TelemetrySpectrumPoint{
+			Time:      s.Date.Unix(),
+			Bands:     spectrumBands(s.Edges, s.Levels),
+			RoarRatio: spectrum.RoarRatio(s.Edges, s.Levels),
+		}
+	}
+
+	h.writeBodyJSON(w, "Спектр шума успешно получен", response)
+}
+
+func spectrumBands(edges, levels []float64) []httpType.SpectrumBand {
+	bands := make([]httpType.SpectrumBand, len(levels))
+	for i, level := range levels {
+		bands[i] = httpType.SpectrumBand{Low: edges[i], High: edges[i+1], Level: level}
+	}
+	return bands
+}
+
 func (h *Handler) GetTemperatureSinceTime(w http.ResponseWriter, r *http.Request) {
 	email, err := h.getEmailFromContext(w, r)
 	if err != nil {
@@ -176,6 +261,15 @@ func (h *Handler) GetTemperatureSinceTime(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	resolution, ok := h.telemetryResolution(w, r, email, hubID, rollup.MetricTemperature, since)
+	if !ok {
+		return
+	}
+	if resolution != rollup.ResolutionRaw {
+		h.writeTelemetryRollup(w, r, email, hubID, rollup.MetricTemperature, resolution, since, "Данные температуры успешно получены")
+		return
+	}
+
 	temperatures, err := h.db.GetTemperaturesSinceTime(r.Context(), email, hubID, since)
 	if err != nil {
 		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get temperature data")
@@ -183,14 +277,74 @@ func (h *Handler) GetTemperatureSinceTime(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	response := make([]httpType.TelemetryDataPoint, len(temperatures))
-	for i, t := range temperatures {
-		response[i] = httpType.TelemetryDataPoint{Time: t.Date.Unix(), Value: t.Temperature}
+	hide := hideFlagged(r)
+	response := make([]httpType.TelemetryDataPoint, 0, len(temperatures))
+	for _, t := range temperatures {
+		if hide && quality.Flagged(t.Quality) {
+			continue
+		}
+		response = append(response, rawPoint(t.Date, t.Temperature, t.Quality))
 	}
 
 	h.writeBodyJSON(w, "Данные температуры успешно получены", response)
 }
 
+// telemetryResolution разбирает параметры resolution/max_points и выбирает, отдавать
+// сырые данные или агрегаты. При ошибке сам пишет ответ и возвращает false.
+func (h *Handler) telemetryResolution(w http.ResponseWriter, r *http.Request, email, hubID, metric string, since time.Time) (string, bool) {
+	query, err := rollup.ParseQuery(r.URL.Query().Get("resolution"), r.URL.Query().Get("max_points"))
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid telemetry resolution")
+		http.Error(w, "Неверный параметр resolution (raw, hour, day, auto) или max_points", http.StatusBadRequest)
+		return "", false
+	}
+	resolution, err := query.Choose(time.Since(since), func() (int, error) {
+		return h.db.CountTelemetrySinceTime(r.Context(), email, hubID, metric, since)
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Str("metric", metric).Msg("failed to choose telemetry resolution")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return "", false
+	}
+	return resolution, true
+}
+
+func (h *Handler) writeTelemetryRollup(w http.ResponseWriter, r *http.Request, email, hubID, metric, resolution string, since time.Time, message string) {
+	rollups, err := h.db.GetTelemetryRollup(r.Context(), email, hubID, metric, resolution, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Str("metric", metric).Msg("failed to get telemetry rollup")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]httpType.TelemetryDataPoint, len(rollups))
+	for i, rl := range rollups {
+		response[i] = httpType.TelemetryDataPoint{
+			Time:  rl.Date.Unix(),
+			Value: rl.Avg,
+			Min:   &rl.Min,
+			Max:   &rl.Max,
+			Count: rl.Count,
+		}
+	}
+
+	h.writeBodyJSON(w, message, response)
+}
+
+// hideFlagged — параметр hide_flagged=true: не отдавать отбракованные замеры.
+// По умолчанию они отдаются с полем quality, чтобы приложение могло их подсветить.
+func hideFlagged(r *http.Request) bool {
+	return r.URL.Query().Get("hide_flagged") == "true"
+}
+
+func rawPoint(t time.Time, value float64, q string) httpType.TelemetryDataPoint {
+	point := httpType.TelemetryDataPoint{Time: t.Unix(), Value: value}
+	if quality.Flagged(q) {
+		point.Quality = q
+	}
+	return point
+}
+
 func parseSince(sinceStr string) (time.Time, bool) {
 	if sinceStr == "" {
 		return time.Now().AddDate(0, 0, -1), true
@@ -244,6 +398,9 @@ func (h *Handler) GetLastSensorReading(w http.ResponseWriter, r *http.Request) {
 		Weight:          sensorData.Weight,
 		WeightTime:      sensorData.WeightTime,
 	}
+	if sensorData.Spectrum != nil && sensorData.Spectrum.Valid() {
+		lastReading.Spectrum = spectrumBands(sensorData.Spectrum.Edges, sensorData.Spectrum.Levels)
+	}
 
 	h.writeBodyJSON(w, "Последние данные датчика получены", lastReading)
 }
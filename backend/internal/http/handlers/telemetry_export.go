@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/export"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exportTimeout ограничивает выгрузку: общий таймаут сервера на неё не действует
+// (см. withoutStreams), а выгрузка за годы идёт минутами.
+const exportTimeout = 30 * time.Minute
+
+// GetTelemetryExport выгружает сырые замеры (температура, шум, вес, заряд и сигнал)
+// выбранных хабов за период файлом CSV, NDJSON или Parquet. Строки читаются из БД
+// курсором и сразу пишутся в ответ, поэтому выгрузка не собирается в памяти.
+func (h *Handler) GetTelemetryExport(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+	query := r.URL.Query()
+
+	since, ok := parseSince(query.Get("since"))
+	if !ok {
+		h.logger.Warn().Str("email", email).Str("since", query.Get("since")).Msg("invalid since")
+		http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+		return
+	}
+	until := time.Now()
+	if raw := query.Get("until"); raw != "" {
+		if until, ok = parseSince(raw); !ok {
+			h.logger.Warn().Str("email", email).Str("until", raw).Msg("invalid until")
+			http.Error(w, "Неверный параметр until (ожидается Unix timestamp)", http.StatusBadRequest)
+			return
+		}
+	}
+	if !until.After(since) {
+		h.logger.Warn().Str("email", email).Time("since", since).Time("until", until).Msg("empty export window")
+		http.Error(w, "Параметр until должен быть позже since", http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := export.ParseMetrics(query.Get("metrics"))
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid export metrics")
+		http.Error(w, "Неверный параметр metrics (temperature, noise, weight, status)", http.StatusBadRequest)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	loc := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			h.logger.Warn().Err(err).Str("email", email).Str("tz", tz).Msg("invalid export timezone")
+			http.Error(w, "Неверный параметр tz (ожидается зона IANA, например Europe/Moscow)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	hubs, ok := h.exportHubs(w, r, email, query.Get("hubs"))
+	if !ok {
+		return
+	}
+
+	out := &countingWriter{w: w}
+	writer, err := export.NewWriter(format, out, loc)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid export format")
+		http.Error(w, "Неверный параметр format (csv, ndjson, parquet)", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"beeiot-telemetry-%s-%s.%s\"",
+		since.In(loc).Format("20060102"), until.In(loc).Format("20060102"), format))
+
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+	rows := 0
+	err = h.db.ExportTelemetry(ctx, email, hubs, metrics, since, until, func(row dbTypes.TelemetryExportRow) error {
+		rows++
+		return writer.Write(export.Row{
+			Hub:     row.Hub,
+			Metric:  row.Metric,
+			Time:    row.Date,
+			Value:   row.Value,
+			Quality: row.Quality,
+		})
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if out.n == 0 {
+			// Ответ ещё не начат — можно честно вернуть ошибку
+			w.Header().Del("Content-Disposition")
+			h.logger.Error().Err(err).Str("email", email).Msg("failed to export telemetry")
+			http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		// Заголовки уже ушли: рвём соединение, чтобы клиент увидел обрыв,
+		// а не принял неполный файл за целый (middleware.Recoverer такую панику пропускает)
+		h.logger.Error().Err(err).Str("email", email).Int("rows", rows).Int64("bytes", out.n).Msg("telemetry export interrupted")
+		panic(http.ErrAbortHandler)
+	}
+	h.logger.Info().Str("email", email).Strs("hubs", hubs).Str("format", format).Int("rows", rows).Int64("bytes", out.n).Msg("telemetry exported")
+}
+
+// exportHubs возвращает хабы для выгрузки: перечисленные через запятую в raw
+// или, если raw пуст, все хабы пользователя. Чужой или несуществующий хаб — 404.
+func (h *Handler) exportHubs(w http.ResponseWriter, r *http.Request, email, raw string) ([]string, bool) {
+	owned, err := h.db.GetHubs(r.Context(), email)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Msg("failed to get hubs for export")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return nil, false
+	}
+	sensors := make([]string, 0, len(owned))
+	for _, hub := range owned {
+		sensors = append(sensors, hub.Sensor)
+	}
+	if raw == "" {
+		return sensors, true
+	}
+	var hubs []string
+	for _, hub := range strings.Split(raw, ",") {
+		hub = strings.TrimSpace(hub)
+		if hub == "" || slices.Contains(hubs, hub) {
+			continue
+		}
+		if !slices.Contains(sensors, hub) {
+			h.logger.Warn().Str("email", email).Str("hub", hub).Msg("export of unknown hub")
+			http.Error(w, fmt.Sprintf("Хаб %q не найден", hub), http.StatusNotFound)
+			return nil, false
+		}
+		hubs = append(hubs, hub)
+	}
+	return hubs, true
+}
+
+// countingWriter считает записанные в ответ байты: пока их нет, об ошибке ещё
+// можно сообщить статусом.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/importer"
+	"BeeIOT/internal/domain/membership"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// importMaxBytes — размер загружаемого файла: годовой минутный журнал
+	// трёх метрик занимает десятки мегабайт.
+	importMaxBytes = 64 << 20
+	// importTimeout ограничивает импорт: общий таймаут сервера на него не действует
+	// (см. withoutStreams), а COPY миллиона строк с пересчётом агрегатов идёт дольше.
+	importTimeout = 10 * time.Minute
+)
+
+// ImportTelemetry загружает телеметрию хаба из CSV (multipart, поле file): журналы
+// автономных логгеров и рукописные журналы весов. Столбцы, единицы и зона времени
+// задаются полями формы. С dry_run=true файл только проверяется и возвращается отчёт.
+// Файл с ошибками в строках записывается, только если передан skip_invalid=true.
+func (h *Handler) ImportTelemetry(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid import upload")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Файл больше %d МБ", importMaxBytes>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Ожидается multipart/form-data с файлом в поле \"file\"", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	hubID := r.FormValue("hub")
+	if hubID == "" {
+		h.logger.Warn().Str("email", email).Msg("missing form field 'hub'")
+		http.Error(w, "Параметр \"hub\" обязателен", http.StatusBadRequest)
+		return
+	}
+	if !h.requireHubRole(w, r, email, hubID, membership.RoleEditor) {
+		return
+	}
+
+	opts, err := importer.ParseOptions(r.FormValue("columns"), r.FormValue("units"), r.FormValue("time_column"),
+		r.FormValue("time_format"), r.FormValue("tz"), r.FormValue("delimiter"))
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid import options")
+		http.Error(w, "Некорректные параметры импорта: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := importer.Parse(file, opts, time.Now())
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to parse import file")
+		http.Error(w, "Некорректный файл: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("dry_run") == "true" {
+		h.writeBodyJSON(w, "Файл проверен, данные не записаны", result.Report(nil))
+		return
+	}
+	if result.ErrorCount > 0 && r.FormValue("skip_invalid") != "true" {
+		h.logger.Warn().Str("email", email).Str("hub", hubID).Int("errors", result.ErrorCount).Msg("import file has invalid rows")
+		http.Error(w, fmt.Sprintf("В файле ошибок: %d. Исправьте их или передайте skip_invalid=true; список — в отчёте dry_run", result.ErrorCount),
+			http.StatusUnprocessableEntity)
+		return
+	}
+	if result.Accepted() == 0 {
+		h.logger.Warn().Str("email", email).Str("hub", hubID).Msg("import file has no samples")
+		http.Error(w, "В файле нет замеров", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), importTimeout)
+	defer cancel()
+	imported, err := importer.Write(ctx, h.db, email, hubID, result)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Interface("imported", imported).Msg("failed to import telemetry")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	h.logger.Info().Str("email", email).Str("hub", hubID).Int("rows", result.Rows).Interface("imported", imported).Msg("telemetry imported")
+	h.writeBodyJSON(w, "Телеметрия импортирована", result.Report(imported))
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/stats"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+)
+
+// GetTelemetryStats отдаёт статистику метрик хаба (min/max/avg/stddev, перцентили,
+// первое и последнее значение, число замеров) по часам, суткам или неделям.
+// Считается в БД по сырым замерам, без отбракованных.
+func (h *Handler) GetTelemetryStats(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hubID := r.URL.Query().Get("hub")
+	if hubID == "" {
+		h.logger.Warn().Str("email", email).Msg("missing query param 'hub'")
+		http.Error(w, "Параметр \"hub\" обязателен", http.StatusBadRequest)
+		return
+	}
+
+	since, ok := parseSince(r.URL.Query().Get("since"))
+	if !ok {
+		h.logger.Warn().Str("email", email).Str("since", r.URL.Query().Get("since")).Msg("invalid since")
+		http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+		return
+	}
+	until := time.Now()
+	if raw := r.URL.Query().Get("until"); raw != "" {
+		if until, ok = parseSince(raw); !ok {
+			h.logger.Warn().Str("email", email).Str("until", raw).Msg("invalid until")
+			http.Error(w, "Неверный параметр until (ожидается Unix timestamp)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, err := stats.ParseQuery(r.URL.Query().Get("metrics"), r.URL.Query().Get("bucket"), since, until)
+	if errors.Is(err, stats.ErrTooManyBuckets) {
+		h.logger.Warn().Err(err).Str("email", email).Msg("stats window is too long for bucket")
+		http.Error(w, "Слишком много интервалов: укрупните bucket или сократите период", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("invalid stats query")
+		http.Error(w, "Неверные параметры: metrics (temperature, noise, weight), bucket (hour, day, week), until позже since", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.GetTelemetryStats(r.Context(), email, hubID, query.Metrics, query.Bucket, query.Since, query.Until, stats.Percentiles)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get telemetry stats")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBodyJSON(w, "Статистика телеметрии получена", telemetryStatsToHTTP(query.Metrics, rows))
+}
+
+// telemetryStatsToHTTP раскладывает строки статистики по метрикам в порядке запроса.
+// Метрика без замеров в окне отдаётся с пустым списком интервалов.
+func telemetryStatsToHTTP(metrics []string, rows []dbTypes.TelemetryStats) []httpType.TelemetryStats {
+	result := make([]httpType.TelemetryStats, len(metrics))
+	index := make(map[string]int, len(metrics))
+	for i, m := range metrics {
+		result[i] = httpType.TelemetryStats{Metric: m, Buckets: []httpType.TelemetryStatsBucket{}}
+		index[m] = i
+	}
+	for _, row := range rows {
+		i, ok := index[row.Metric]
+		if !ok {
+			continue
+		}
+		percentiles := make(map[string]float64, len(row.Percentiles))
+		for j, v := range row.Percentiles {
+			if j < len(stats.Percentiles) {
+				percentiles[stats.PercentileName(stats.Percentiles[j])] = roundStat(v)
+			}
+		}
+		result[i].Buckets = append(result[i].Buckets, httpType.TelemetryStatsBucket{
+			Time:        row.Bucket.Unix(),
+			Count:       row.Count,
+			Min:         row.Min,
+			Max:         row.Max,
+			Avg:         roundStat(row.Avg),
+			StdDev:      roundStat(row.StdDev),
+			Percentiles: percentiles,
+			First:       row.First,
+			FirstTime:   row.FirstAt.Unix(),
+			Last:        row.Last,
+			LastTime:    row.LastAt.Unix(),
+		})
+	}
+	return result
+}
+
+// roundStat — расчётные величины до сотых: точнее датчики всё равно не меряют.
+func roundStat(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/quality"
+	"BeeIOT/internal/domain/weightStats"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// weightAnalyticsPeriod — период аналитики веса по умолчанию.
+const weightAnalyticsPeriod = 30 * 24 * time.Hour
+
+// stepEventTolerance — событие в хронологии ближе этого к ступеньке считается её записью.
+const stepEventTolerance = 2 * time.Hour
+
+// GetWeightAnalytics считает по весу хаба суточный привес, периоды медосбора,
+// расход кормов и находит ступеньки веса, которые стоит отметить в хронологии улья.
+func (h *Handler) GetWeightAnalytics(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	hubID := r.URL.Query().Get("hub")
+	if hubID == "" {
+		h.logger.Warn().Str("email", email).Msg("missing query param 'hub'")
+		http.Error(w, "Параметр \"hub\" обязателен", http.StatusBadRequest)
+		return
+	}
+
+	since := time.Now().Add(-weightAnalyticsPeriod)
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		var ok bool
+		if since, ok = parseSince(raw); !ok {
+			h.logger.Warn().Str("email", email).Str("since", raw).Msg("invalid since")
+			http.Error(w, "Неверный параметр since (ожидается Unix timestamp)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var emptyWeight float64
+	if raw := r.URL.Query().Get("empty_weight"); raw != "" {
+		emptyWeight, err = strconv.ParseFloat(raw, 64)
+		if err != nil || emptyWeight < 0 {
+			h.logger.Warn().Str("email", email).Str("empty_weight", raw).Msg("invalid empty_weight")
+			http.Error(w, "Неверный параметр empty_weight (ожидается вес улья без кормов в кг)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.db.GetHubBySensor(r.Context(), email, hubID); err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("hub", hubID).Msg("hub not found")
+		http.Error(w, "Хаб не найден", http.StatusNotFound)
+		return
+	}
+
+	weights, err := h.db.GetWeightSinceTime(r.Context(), email, hubID, since)
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Str("hub", hubID).Msg("failed to get weight data")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	// аналитика, как и анализаторы, считается только по достоверным замерам
+	points := make([]weightStats.Point, 0, len(weights))
+	for _, wt := range weights {
+		if quality.Flagged(wt.Quality) {
+			continue
+		}
+		points = append(points, weightStats.Point{At: wt.Date, Value: wt.Weight})
+	}
+
+	loc := h.userLocation(r.Context(), email)
+	report := weightStats.Analyze(points, loc, time.Now(), emptyWeight)
+
+	var events []dbTypes.HiveEvent
+	if len(report.Steps) > 0 {
+		if hive, err := h.hiveByHub(r.Context(), email, hubID); err == nil {
+			events, err = h.db.GetHiveEvents(r.Context(), hive.Id, since.Add(-stepEventTolerance))
+			if err != nil {
+				h.logger.Error().Err(err).Str("email", email).Int("hiveId", hive.Id).Msg("failed to get hive events")
+				http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	h.writeBodyJSON(w, "Аналитика веса получена", weightAnalyticsToHTTP(report, events, loc))
+}
+
+// RecordWeightStep записывает найденную ступеньку веса в хронологию улья,
+// к которому привязан хаб.
+func (h *Handler) RecordWeightStep(w http.ResponseWriter, r *http.Request) {
+	email, err := h.getEmailFromContext(w, r)
+	if err != nil {
+		return
+	}
+
+	var req httpType.WeightStepEvent
+	if err = h.readBodyJSON(w, r, &req); err != nil {
+		return
+	}
+	title, ok := weightStats.EventTitles[req.Type]
+	if !ok {
+		h.logger.Warn().Str("email", email).Str("type", req.Type).Msg("unknown weight step event type")
+		http.Error(w, "Неизвестный тип события (super_added, super_removed, honey_harvest, feeding)", http.StatusBadRequest)
+		return
+	}
+	if req.Hub == "" || req.Time <= 0 {
+		h.logger.Warn().Str("email", email).Msg("missing hub or time of weight step")
+		http.Error(w, "Поля \"hub\" и \"time\" обязательны", http.StatusBadRequest)
+		return
+	}
+	if req.Title != "" {
+		title = req.Title
+	}
+
+	if !h.requireHubRole(w, r, email, req.Hub, membership.RoleEditor) {
+		return
+	}
+
+	hive, err := h.hiveByHub(r.Context(), email, req.Hub)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Str("hub", req.Hub).Msg("hive for hub not found")
+		http.Error(w, "Улей с этим хабом не найден", http.StatusNotFound)
+		return
+	}
+
+	id, err := h.db.NewHiveEvent(r.Context(), hive.Id, dbTypes.HiveEvent{
+		Type:       req.Type,
+		Source:     "user",
+		Title:      title,
+		Details:    map[string]any{"delta": roundWeight(req.Delta), "detected": true},
+		OccurredAt: time.Unix(req.Time, 0).UTC(),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Str("email", email).Int("hiveId", hive.Id).Msg("failed to record weight step")
+		http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBodyJSON(w, "Событие записано в хронологию улья", map[string]int64{"id": id})
+}
+
+// hiveByHub находит улей, к которому привязан хаб, если хаб доступен пользователю
+// (свой или участника улья).
+func (h *Handler) hiveByHub(ctx context.Context, email, hubSensor string) (dbTypes.Hive, error) {
+	if _, err := h.db.GetHubRole(ctx, email, hubSensor); err != nil {
+		return dbTypes.Hive{}, err
+	}
+	return h.db.GetHiveByHub(ctx, email, hubSensor)
+}
+
+// userLocation — часовой пояс пользователя из настроек уведомлений; по нему считаются сутки.
+func (h *Handler) userLocation(ctx context.Context, email string) *time.Location {
+	prefs, err := h.db.GetNotificationPreferences(ctx, email)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("email", email).Msg("failed to get user timezone, using UTC")
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func weightAnalyticsToHTTP(report weightStats.Report, events []dbTypes.HiveEvent, loc *time.Location) httpType.WeightAnalytics {
+	const layout = "2006-01-02"
+	result := httpType.WeightAnalytics{
+		Days:        make([]httpType.WeightDay, len(report.Days)),
+		NectarFlows: make([]httpType.NectarFlow, len(report.Flows)),
+		Steps:       make([]httpType.WeightStep, len(report.Steps)),
+	}
+	for i, d := range report.Days {
+		result.Days[i] = httpType.WeightDay{Date: d.Date.Format(layout), Net: roundWeight(d.Net), Weight: roundWeight(d.Weight)}
+	}
+	for i, f := range report.Flows {
+		result.NectarFlows[i] = httpType.NectarFlow{From: f.From.Format(layout), To: f.To.Format(layout), Gain: roundWeight(f.Gain)}
+	}
+	if c := report.Consumption; c != nil {
+		result.Consumption = &httpType.WeightConsumption{PerDay: math.Round(c.PerDay*1000) / 1000, Days: c.Days}
+		if c.Stores != nil {
+			stores := roundWeight(*c.Stores)
+			runOut := c.RunOut.In(loc).Format(layout)
+			result.Consumption.Stores, result.Consumption.RunOut = &stores, &runOut
+		}
+	}
+	for i, s := range report.Steps {
+		result.Steps[i] = httpType.WeightStep{
+			Time:            s.At.Unix(),
+			Before:          roundWeight(s.Before),
+			After:           roundWeight(s.After),
+			Delta:           roundWeight(s.Delta),
+			SuggestedEvents: s.Suggested,
+			Recorded:        stepRecorded(s, events),
+		}
+	}
+	return result
+}
+
+// stepRecorded — есть ли рядом со ступенькой событие подходящего типа.
+func stepRecorded(s weightStats.Step, events []dbTypes.HiveEvent) bool {
+	for _, e := range events {
+		if _, ok := weightStats.EventTitles[e.Type]; !ok {
+			continue
+		}
+		if d := e.OccurredAt.Sub(s.At); d <= stepEventTolerance && d >= -stepEventTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func roundWeight(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -50,36 +50,73 @@ func (m *MiddleWares) CheckAuth(next http.Handler) http.Handler {
 			http.Error(w, "Пустой токен авторизации", http.StatusUnauthorized)
 			return
 		}
-		email, err := m.jwt.ParseToken(token)
-		switch {
-		case errors.Is(err, jwt.ErrTokenExpired):
-			m.logger.Warn().Str("email", email).Msg("jwt token has expired")
-			http.Error(w, "Срок действия токена истек или он невалидный", http.StatusUnauthorized)
+		m.authorize(w, r, next, token)
+	})
+}
+
+// CheckStreamAuth — CheckAuth для потоковых эндпоинтов. Браузерный EventSource не умеет
+// передавать заголовки, поэтому вместо них принимается параметр ticket — одноразовый
+// короткоживущий билет (см. handlers.CreateStreamTicket). JWT в адресе не принимается:
+// адреса оседают в логах прокси и истории браузера.
+func (m *MiddleWares) CheckStreamAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			m.CheckAuth(next).ServeHTTP(w, r)
 			return
-		case err != nil:
-			m.logger.Error().Err(err).Msg("failed to parse JWT token")
-			http.Error(w, "Внутрення ошибка сервера", http.StatusInternalServerError)
+		}
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			m.logger.Warn().Msg("no authorization header or stream ticket")
+			http.Error(w, "Отсутствует заголовок авторизации", http.StatusUnauthorized)
 			return
-		default:
-			m.logger.Info().Str("email", email).Msg("jwt token valid")
-			exist, err := m.inMemDb.ExistJwt(r.Context(), email, token)
-			if err != nil {
-				m.logger.Error().Err(err).Msg("failed to check being jwt in in-memory db")
-				http.Error(w, "Внутрення ошибка сервера", http.StatusInternalServerError)
-				return
-			}
-			if !exist {
-				m.logger.Warn().Str("email", email).Msg("jwt token not found in in-memory db")
-				http.Error(w, "Срок действия токена истек или он невалидный", http.StatusUnauthorized)
-				return
-			}
-			ctx := context.WithValue(r.Context(), "email", email)
-			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		email, err := m.inMemDb.TakeStreamTicket(r.Context(), ticket)
+		if err != nil {
+			m.logger.Error().Err(err).Msg("failed to take stream ticket from in-memory db")
+			http.Error(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
 			return
 		}
+		if email == "" {
+			m.logger.Warn().Msg("stream ticket not found or already used")
+			http.Error(w, "Билет потока недействителен или уже использован", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), "email", email)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// authorize проверяет JWT и его наличие в белом списке Redis, после чего
+// передаёт запрос дальше с email пользователя в контексте.
+func (m *MiddleWares) authorize(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	email, err := m.jwt.ParseToken(token)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		m.logger.Warn().Str("email", email).Msg("jwt token has expired")
+		http.Error(w, "Срок действия токена истек или он невалидный", http.StatusUnauthorized)
+		return
+	case err != nil:
+		m.logger.Error().Err(err).Msg("failed to parse JWT token")
+		http.Error(w, "Внутрення ошибка сервера", http.StatusInternalServerError)
+		return
+	default:
+		m.logger.Info().Str("email", email).Msg("jwt token valid")
+		exist, err := m.inMemDb.ExistJwt(r.Context(), email, token)
+		if err != nil {
+			m.logger.Error().Err(err).Msg("failed to check being jwt in in-memory db")
+			http.Error(w, "Внутрення ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		if !exist {
+			m.logger.Warn().Str("email", email).Msg("jwt token not found in in-memory db")
+			http.Error(w, "Срок действия токена истек или он невалидный", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), "email", email)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
 func (m *MiddleWares) CheckAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		email, ok := r.Context().Value("email").(string)
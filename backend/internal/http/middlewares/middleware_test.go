@@ -14,6 +14,13 @@ import (
 
 type MockInMemoryDB struct {
 	interfaces.InMemoryDB
+	tickets map[string]string
+}
+
+func (m *MockInMemoryDB) TakeStreamTicket(_ context.Context, ticket string) (string, error) {
+	email := m.tickets[ticket]
+	delete(m.tickets, ticket)
+	return email, nil
 }
 
 func (m *MockInMemoryDB) ExistJwt(ctx context.Context, email, jwtId string) (bool, error) {
@@ -73,3 +80,66 @@ func TestCheckAuth(t *testing.T) {
 		t.Errorf("Expected 200 for valid token, got %d", w.Code)
 	}
 }
+
+func TestCheckStreamAuth(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	inMem := &MockInMemoryDB{tickets: map[string]string{"ticket-1": "test@example.com"}}
+	mw, err := NewMiddleWares(&MockDB{}, inMem, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewMiddleWares failed: %v", err)
+	}
+	jwtService, _ := jwtToken.NewJWTToken()
+	token, _ := jwtService.GenerateToken("test@example.com")
+
+	var gotEmail string
+	handler := mw.CheckStreamAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEmail, _ = r.Context().Value("email").(string)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Без токена
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/telemetry/stream", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", w.Code)
+	}
+
+	// Билет параметром запроса (EventSource) действует один раз
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/telemetry/stream?ticket=ticket-1", nil))
+	if w.Code != http.StatusOK || gotEmail != "test@example.com" {
+		t.Errorf("Expected 200 with email from ticket, got %d %q", w.Code, gotEmail)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/telemetry/stream?ticket=ticket-1", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for reused ticket, got %d", w.Code)
+	}
+
+	// JWT в адресе больше не принимается
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/telemetry/stream?access_token="+token, nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for access_token in query, got %d", w.Code)
+	}
+
+	// Заголовок с JWT по-прежнему работает
+	req := httptest.NewRequest("GET", "/api/telemetry/stream", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for bearer header, got %d", w.Code)
+	}
+
+	// Заголовок проверяется как в CheckAuth
+	req = httptest.NewRequest("GET", "/api/telemetry/stream", nil)
+	req.Header.Set("Authorization", "InvalidFormat")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for invalid header, got %d", w.Code)
+	}
+}
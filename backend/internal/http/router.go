@@ -3,6 +3,7 @@ package http
 import (
 	"BeeIOT/internal/domain/interfaces"
 	"BeeIOT/internal/domain/mqtt"
+	"BeeIOT/internal/domain/stream"
 	"BeeIOT/internal/http/handlers"
 	"BeeIOT/internal/http/middlewares"
 	"context"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,10 +23,10 @@ import (
 
 const serverPort = ":8000"
 
-func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb interfaces.InMemoryDB,
-	mqtt *mqtt.Client, passwordStore interfaces.PasswordKeeper, logger zerolog.Logger) {
+func StartServer(db interfaces.DB, sender interfaces.Mailer, inMemDb interfaces.InMemoryDB,
+	mqtt *mqtt.Client, broker *stream.Broker, passwordStore interfaces.PasswordKeeper, logger zerolog.Logger) {
 	r := chi.NewRouter()
-	h, err := handlers.NewHandler(db, sender, inMemDb, mqtt, passwordStore, logger)
+	h, err := handlers.NewHandler(db, sender, inMemDb, mqtt, broker, passwordStore, logger)
 	if err != nil {
 		logger.Error().Err(err).Msg("could not create new handler")
 		return
@@ -37,7 +39,7 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(5 * time.Second))
+	r.Use(withoutStreams(middleware.Timeout(5 * time.Second)))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -55,6 +57,8 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 			r.Post("/confirm/password", h.ConfirmChangePassword)
 			r.Post("/refresh/token", h.RefreshToken)
 			r.With(m.CheckAuth).Get("/me", h.GetMe)
+			r.With(m.CheckAuth).Get("/me/preferences", h.GetNotificationPreferences)
+			r.With(m.CheckAuth).Put("/me/preferences", h.SetNotificationPreferences)
 			r.With(m.CheckAuth).Delete("/delete/user", h.DeleteUser)
 			r.With(m.CheckAuth).Delete("/logout", h.Logout)
 			r.With(m.CheckAuth).Post("/change/name", h.ChangeName)
@@ -69,6 +73,16 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 			r.Delete("/delete", h.DeleteHive)
 			r.Post("/link/hub", h.LinkHubToHive)
 			r.Post("/link/queen", h.LinkQueenToHive)
+			r.Post("/link/apiary", h.LinkApiaryToHive)
+			r.Get("/alerts", h.GetAlertRules)
+			r.Get("/alerts/list", h.GetAlertRulesList)
+			r.Put("/alerts", h.SetAlertRules)
+			r.Delete("/alerts", h.DeleteAlertRules)
+			r.Post("/inspections", h.CreateInspection)
+			r.Get("/inspections", h.GetInspections)
+			r.Put("/inspections", h.UpdateInspection)
+			r.Delete("/inspections", h.DeleteInspection)
+			r.Get("/timeline", h.GetHiveTimeline)
 		})
 		r.Route("/hub", func(r chi.Router) {
 			r.Use(m.CheckAuth)
@@ -77,6 +91,9 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 			r.Get("/", h.GetHub)
 			r.Put("/update", h.UpdateHub)
 			r.Delete("/delete", h.DeleteHub)
+			r.Post("/secret/rotate", h.RotateHubSecret)
+			r.Get("/shadow", h.GetHubShadow)
+			r.Put("/shadow", h.SetHubShadow)
 		})
 		r.Route("/queen", func(r chi.Router) {
 			r.Use(m.CheckAuth)
@@ -86,17 +103,53 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 			r.Put("/update", h.UpdateQueen)
 			r.Delete("/delete", h.DeleteQueen)
 		})
+		r.Route("/apiary", func(r chi.Router) {
+			r.Use(m.CheckAuth)
+			r.Post("/create", h.CreateApiary)
+			r.Get("/list", h.GetApiaries)
+			r.Get("/", h.GetApiary)
+			r.Put("/update", h.UpdateApiary)
+			r.Delete("/delete", h.DeleteApiary)
+		})
+		r.Route("/members", func(r chi.Router) {
+			r.Use(m.CheckAuth)
+			r.Post("/invite", h.InviteMember)
+			r.Get("/list", h.GetMembers)
+			r.Put("/update", h.UpdateMember)
+			r.Delete("/delete", h.RemoveMember)
+		})
+		r.Route("/invitations", func(r chi.Router) {
+			r.Use(m.CheckAuth)
+			r.Get("/list", h.GetInvitations)
+			r.Post("/accept", h.AcceptInvitation)
+			r.Post("/decline", h.DeclineInvitation)
+		})
 		r.Route("/mqtt", func(r chi.Router) {
 			r.Use(m.CheckAuth)
 			r.Post("/config", h.MQTTSendConfig)
 			r.Post("/health", h.MQTTSendHealthCheck)
 			r.Get("/data", h.GetNoiseAndTemp)
 		})
+		// Аутентификация клиентов MQTT-брокера (mosquitto-go-auth), без JWT
+		r.Route("/mqtt-auth", func(r chi.Router) {
+			r.Post("/user", h.MQTTAuthUser)
+			r.Post("/superuser", h.MQTTAuthSuperuser)
+			r.Post("/acl", h.MQTTAuthACL)
+		})
+		// Поток живёт вне группы /telemetry: ему нужна своя проверка токена (см. CheckStreamAuth)
+		r.With(m.CheckStreamAuth).Get("/telemetry/stream", h.StreamTelemetry)
 		r.Route("/telemetry", func(r chi.Router) {
 			r.Use(m.CheckAuth)
+			r.Post("/stream/ticket", h.CreateStreamTicket)
 			r.Get("/noise/get", h.GetNoiseSinceTime)
+			r.Get("/spectrum/get", h.GetNoiseSpectrumSinceTime)
 			r.Get("/weight/get", h.GetWeightSinceTime)
+			r.Get("/weight/analytics", h.GetWeightAnalytics)
+			r.Post("/weight/events", h.RecordWeightStep)
 			r.Get("/temperature/get", h.GetTemperatureSinceTime)
+			r.Get("/stats", h.GetTelemetryStats)
+			r.Get("/export", h.GetTelemetryExport)
+			r.Post("/import", h.ImportTelemetry)
 			r.Get("/sensor/last", h.GetLastSensorReading)
 			r.Post("/weight/set", h.SetHiveWeight)
 			r.Delete("/weight/delete", h.DeleteHiveWeight)
@@ -108,6 +161,13 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 			r.Put("/update", h.UpdateTask)
 			r.Delete("/delete", h.DeleteTask)
 		})
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(m.CheckAuth)
+			r.Get("/", h.GetNotifications)
+			r.Post("/read", h.MarkNotificationsRead)
+			r.Post("/read/all", h.MarkAllNotificationsRead)
+			r.Delete("/", h.DeleteNotification)
+		})
 		r.Get("/app-description", h.GetAppDescription)
 		r.Get("/instruction/items", h.GetInstructionItems)
 
@@ -124,6 +184,9 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 				r.Put("/{id}", h.UpdateInstructionItem)
 				r.Delete("/{id}", h.DeleteInstructionItem)
 			})
+
+			r.Get("/analyzers", h.GetAnalyzers)
+			r.Get("/analyzers/runs", h.GetAnalyzerRuns)
 		})
 	})
 
@@ -151,3 +214,20 @@ func StartServer(db interfaces.DB, sender interfaces.ConfirmSender, inMemDb inte
 	}
 	logger.Info().Msg("server gracefully stopped")
 }
+
+// withoutStreams применяет mw ко всем запросам, кроме долгоживущих потоков (SSE),
+// выгрузки и импорта телеметрии: общий таймаут оборвал бы их через несколько секунд.
+// Выгрузка и импорт ограничивают себя сами (см. handlers.GetTelemetryExport и ImportTelemetry).
+func withoutStreams(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/stream") || strings.HasSuffix(r.URL.Path, "/export") ||
+				strings.HasSuffix(r.URL.Path, "/import") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
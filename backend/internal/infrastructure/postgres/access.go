@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"fmt"
+	"strings"
+)
+
+// Условия доступа к ресурсам с учётом участников (см. membership). Ульи, хабы,
+// матки и задачи по-прежнему принадлежат владельцу (hives.user_id, hubs.email,
+// queens.email, tasks.email); участник пасеки или улья видит их через членство.
+// Хабы, матки и задачи доступны через ульи, к которым они относятся.
+//
+// Параметры — плейсхолдеры запроса ($1), роль — минимальная требуемая.
+
+// rolesAtLeast — SQL-список ролей не ниже role.
+func rolesAtLeast(role string) string {
+	roles := membership.AtLeast(role)
+	for i, r := range roles {
+		roles[i] = "'" + r + "'"
+	}
+	return strings.Join(roles, ", ")
+}
+
+// hiveAccess — у пользователя с почтой email есть доступ к улью alias: улей его
+// или он участник улья либо пасеки улья с ролью не ниже role.
+func hiveAccess(alias, email, role string) string {
+	return fmt.Sprintf(`(%[1]s.user_id = (SELECT id FROM users WHERE email = %[2]s)
+	    OR EXISTS (SELECT 1 FROM memberships acc_m JOIN users acc_u ON acc_u.id = acc_m.user_id
+	               WHERE acc_u.email = %[2]s AND acc_m.role IN (%[3]s)
+	                 AND (acc_m.hive_id = %[1]s.id OR acc_m.apiary_id = %[1]s.apiary_id)))`,
+		alias, email, rolesAtLeast(role))
+}
+
+// hiveRole — роль пользователя в улье alias: owner для своего улья, иначе
+// наивысшая из ролей участника улья и его пасеки; NULL — доступа нет.
+func hiveRole(alias, email string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s.user_id = (SELECT id FROM users WHERE email = %[2]s) THEN 'owner'
+	    ELSE (SELECT acc_m.role FROM memberships acc_m JOIN users acc_u ON acc_u.id = acc_m.user_id
+	          WHERE acc_u.email = %[2]s AND (acc_m.hive_id = %[1]s.id OR acc_m.apiary_id = %[1]s.apiary_id)
+	          ORDER BY %[3]s DESC LIMIT 1) END`, alias, email, roleRank("acc_m.role"))
+}
+
+// roleRank — SQL-выражение ранга роли для сортировки.
+func roleRank(column string) string {
+	var b strings.Builder
+	b.WriteString("CASE " + column)
+	for i, role := range membership.Roles {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", role, i+1)
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
+
+// accessibleHive — id улья с именем name, доступного пользователю с ролью не ниже
+// role. Имена уникальны только у одного владельца: непустой owner (почта владельца)
+// выбирает улей этого владельца, а без него при совпадении предпочитается свой улей.
+func accessibleHive(email, name, owner, role string) string {
+	return fmt.Sprintf(`(SELECT ah.id FROM hives ah
+	    WHERE ah.name = %[2]s AND %[4]s
+	      AND (%[3]s = '' OR ah.user_id = (SELECT id FROM users WHERE email = %[3]s))
+	    ORDER BY ah.user_id = (SELECT id FROM users WHERE email = %[1]s) DESC, ah.id LIMIT 1)`,
+		email, name, owner, hiveAccess("ah", email, role))
+}
+
+// hubAccess — у пользователя есть доступ к хабу alias: хаб его или привязан
+// к доступному ему улью.
+func hubAccess(alias, email, role string) string {
+	return fmt.Sprintf(`(%[1]s.email = %[2]s
+	    OR EXISTS (SELECT 1 FROM hives acc_h WHERE acc_h.hub_id = %[1]s.id AND %[3]s))`,
+		alias, email, hiveAccess("acc_h", email, role))
+}
+
+// accessibleHub — id хаба с идентификатором sensor, доступного пользователю;
+// при совпадении предпочитается свой хаб.
+func accessibleHub(email, sensor, role string) string {
+	return fmt.Sprintf(`(SELECT ahu.id FROM hubs ahu
+	    WHERE ahu.sensor = %[2]s AND %[3]s
+	    ORDER BY ahu.email = %[1]s DESC, ahu.id LIMIT 1)`,
+		email, sensor, hubAccess("ahu", email, role))
+}
+
+// queenAccess — у пользователя есть доступ к матке alias: матка его или
+// привязана к доступному ему улью.
+func queenAccess(alias, email, role string) string {
+	return fmt.Sprintf(`(%[1]s.email = %[2]s
+	    OR EXISTS (SELECT 1 FROM hives acc_h WHERE acc_h.queen_id = %[1]s.id AND %[3]s))`,
+		alias, email, hiveAccess("acc_h", email, role))
+}
+
+// accessibleQueen — id матки с именем name, доступной пользователю.
+func accessibleQueen(email, name, role string) string {
+	return fmt.Sprintf(`(SELECT aq.id FROM queens aq
+	    WHERE aq.name = %[2]s AND %[3]s
+	    ORDER BY aq.email = %[1]s DESC, aq.id LIMIT 1)`,
+		email, name, queenAccess("aq", email, role))
+}
+
+// apiaryAccess — у пользователя есть доступ к пасеке alias: пасека его или он её участник.
+func apiaryAccess(alias, email, role string) string {
+	return fmt.Sprintf(`(%[1]s.user_id = (SELECT id FROM users WHERE email = %[2]s)
+	    OR EXISTS (SELECT 1 FROM memberships acc_m JOIN users acc_u ON acc_u.id = acc_m.user_id
+	               WHERE acc_u.email = %[2]s AND acc_m.role IN (%[3]s) AND acc_m.apiary_id = %[1]s.id))`,
+		alias, email, rolesAtLeast(role))
+}
+
+// apiaryRole — роль пользователя на пасеке alias; NULL — доступа нет.
+func apiaryRole(alias, email string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s.user_id = (SELECT id FROM users WHERE email = %[2]s) THEN 'owner'
+	    ELSE (SELECT acc_m.role FROM memberships acc_m JOIN users acc_u ON acc_u.id = acc_m.user_id
+	          WHERE acc_u.email = %[2]s AND acc_m.apiary_id = %[1]s.id) END`, alias, email)
+}
+
+// accessibleApiary — id пасеки с именем name, доступной пользователю;
+// при совпадении предпочитается своя пасека.
+func accessibleApiary(email, name, role string) string {
+	return fmt.Sprintf(`(SELECT aa.id FROM apiaries aa
+	    WHERE aa.name = %[2]s AND %[3]s
+	    ORDER BY aa.user_id = (SELECT id FROM users WHERE email = %[1]s) DESC, aa.id LIMIT 1)`,
+		email, name, apiaryAccess("aa", email, role))
+}
+
+// taskAccess — у пользователя есть доступ к задаче alias: задача его или
+// относится к доступному ему улью владельца задачи.
+func taskAccess(alias, email, role string) string {
+	return fmt.Sprintf(`(%[1]s.email = %[2]s
+	    OR EXISTS (SELECT 1 FROM hives acc_h JOIN users acc_o ON acc_o.id = acc_h.user_id
+	               WHERE acc_o.email = %[1]s.email AND acc_h.name = %[1]s.hive_name AND %[3]s))`,
+		alias, email, hiveAccess("acc_h", email, role))
+}
+
+// Доступ к хабу по параметрам запроса: $1 — почта пользователя, $2 — идентификатор хаба.
+var (
+	viewerHub = accessibleHub("$1", "$2", membership.RoleViewer)
+	editorHub = accessibleHub("$1", "$2", membership.RoleEditor)
+	ownerHub  = accessibleHub("$1", "$2", membership.RoleOwner)
+	// viewerHubs — условие на хабы h, доступные пользователю $1
+	viewerHubs = hubAccess("h", "$1", membership.RoleViewer)
+)
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const alertRulesColumns = `noise_high, noise_delta, temperature_normal, temperature_delta_up,
+	temperature_delta_down, battery_low, signal_low`
+
+func alertRulesArgs(t httpType.AlertThresholds) []any {
+	return []any{t.NoiseHigh, t.NoiseDelta, t.TemperatureNormal, t.TemperatureDeltaUp,
+		t.TemperatureDeltaDown, t.BatteryLow, t.SignalLow}
+}
+
+// SetAlertRules сохраняет пороги улья hive владельца owner (пустой owner — см. accessibleHive);
+// пустой hive — пороги пользователя по умолчанию.
+// Правила перезаписываются целиком: поле nil снимает переопределение. Пороги улья
+// принадлежат его владельцу, менять их может и редактор.
+// Если улья нет, возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) SetAlertRules(ctx context.Context, email, hive, owner string, t httpType.AlertThresholds) error {
+	set := `ON CONFLICT %s DO UPDATE SET
+	            noise_high = EXCLUDED.noise_high, noise_delta = EXCLUDED.noise_delta,
+	            temperature_normal = EXCLUDED.temperature_normal,
+	            temperature_delta_up = EXCLUDED.temperature_delta_up,
+	            temperature_delta_down = EXCLUDED.temperature_delta_down,
+	            battery_low = EXCLUDED.battery_low, signal_low = EXCLUDED.signal_low,
+	            updated_at = now()`
+	var q string
+	args := []any{email}
+	if hive == "" {
+		q = `INSERT INTO alert_rules (user_id, hive_id, ` + alertRulesColumns + `)
+		     SELECT u.id, NULL, $2, $3, $4, $5, $6, $7, $8 FROM users u WHERE u.email = $1 ` +
+			fmt.Sprintf(set, "(user_id) WHERE hive_id IS NULL")
+		args = append(args, alertRulesArgs(t)...)
+	} else {
+		q = `INSERT INTO alert_rules (user_id, hive_id, ` + alertRulesColumns + `)
+		     SELECT h.user_id, h.id, $3, $4, $5, $6, $7, $8, $9
+		     FROM hives h
+		     WHERE h.id = ` + accessibleHive("$1", "$2", "$10", membership.RoleEditor) + ` ` +
+			fmt.Sprintf(set, "(hive_id)")
+		args = append(append(append(args, hive), alertRulesArgs(t)...), owner)
+	}
+	res, err := db.pull.Exec(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to set alert rules: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to set alert rules: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// GetAlertRules возвращает собственные пороги улья hive владельца owner (или пользователя
+// при пустом hive) без наследования. Если ничего не задано — пустые правила без ошибки.
+func (db *Postgres) GetAlertRules(ctx context.Context, email, hive, owner string) (dbTypes.AlertRules, error) {
+	q := `SELECT h.name, o.email, ` + alertRulesColumns + `, r.updated_at
+	      FROM alert_rules r
+	      JOIN hives h ON r.hive_id = h.id
+	      JOIN users o ON o.id = h.user_id
+	      WHERE h.id = ` + accessibleHive("$1", "$2", "$3", membership.RoleViewer)
+	args := []any{email, hive, owner}
+	if hive == "" {
+		q = `SELECT $2::text, '', ` + alertRulesColumns + `, r.updated_at
+		     FROM alert_rules r
+		     JOIN users u ON r.user_id = u.id
+		     WHERE u.email = $1 AND r.hive_id IS NULL`
+		args = args[:2]
+	}
+	rules, err := scanAlertRules(db.pull.QueryRow(ctx, q, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dbTypes.AlertRules{Hive: hive, Owner: owner}, nil
+	}
+	if err != nil {
+		return rules, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ListAlertRules возвращает все сохранённые правила пользователя: правила по умолчанию
+// (с пустым Hive) и правила доступных ему ульев.
+func (db *Postgres) ListAlertRules(ctx context.Context, email string) ([]dbTypes.AlertRules, error) {
+	q := `SELECT COALESCE(h.name, ''), COALESCE(o.email, ''), ` + alertRulesColumns + `, r.updated_at
+	      FROM alert_rules r
+	      LEFT JOIN hives h ON r.hive_id = h.id
+	      LEFT JOIN users o ON o.id = h.user_id
+	      WHERE (r.hive_id IS NULL AND r.user_id = (SELECT id FROM users WHERE email = $1))
+	         OR (r.hive_id IS NOT NULL AND ` + hiveAccess("h", "$1", membership.RoleViewer) + `)
+	      ORDER BY r.hive_id NULLS FIRST, h.name`
+	rows, err := db.pull.Query(ctx, q, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	defer rows.Close()
+	var result []dbTypes.AlertRules
+	for rows.Next() {
+		rules, err := scanAlertRules(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rules: %w", err)
+		}
+		result = append(result, rules)
+	}
+	return result, rows.Err()
+}
+
+// DeleteAlertRules удаляет пороги улья hive владельца owner (или пользователя при пустом
+// hive) — дальше действуют унаследованные значения.
+func (db *Postgres) DeleteAlertRules(ctx context.Context, email, hive, owner string) error {
+	q := `DELETE FROM alert_rules WHERE hive_id = ` + accessibleHive("$1", "$2", "$3", membership.RoleEditor)
+	args := []any{email, hive, owner}
+	if hive == "" {
+		q = `DELETE FROM alert_rules WHERE hive_id IS NULL AND user_id = (SELECT id FROM users WHERE email = $1)`
+		args = args[:1]
+	}
+	if _, err := db.pull.Exec(ctx, q, args...); err != nil {
+		return fmt.Errorf("failed to delete alert rules: %w", err)
+	}
+	return nil
+}
+
+// GetEffectiveAlertRules возвращает пороги улья hive владельца owner с наследованием от
+// правил пользователя: каждое поле берётся из правил улья, а если там не задано — из
+// правил по умолчанию владельца улья (у чужого улья — не пользователя, а владельца).
+// Значения по умолчанию самого приложения накладываются в alerts.Resolve.
+func (db *Postgres) GetEffectiveAlertRules(ctx context.Context, email, hive, owner string) (dbTypes.AlertRules, error) {
+	q := `SELECT $2::text, COALESCE(o.email, ''),
+	             COALESCE(hr.noise_high, dr.noise_high), COALESCE(hr.noise_delta, dr.noise_delta),
+	             COALESCE(hr.temperature_normal, dr.temperature_normal),
+	             COALESCE(hr.temperature_delta_up, dr.temperature_delta_up),
+	             COALESCE(hr.temperature_delta_down, dr.temperature_delta_down),
+	             COALESCE(hr.battery_low, dr.battery_low), COALESCE(hr.signal_low, dr.signal_low),
+	             GREATEST(hr.updated_at, dr.updated_at, 'epoch'::timestamptz)
+	      FROM users u
+	      LEFT JOIN hives h ON h.id = ` + accessibleHive("$1", "$2", "$3", membership.RoleViewer) + `
+	      LEFT JOIN users o ON o.id = h.user_id
+	      LEFT JOIN alert_rules dr ON dr.user_id = COALESCE(h.user_id, u.id) AND dr.hive_id IS NULL
+	      LEFT JOIN alert_rules hr ON hr.hive_id = h.id
+	      WHERE u.email = $1
+	      LIMIT 1`
+	rules, err := scanAlertRules(db.pull.QueryRow(ctx, q, email, hive, owner))
+	if err != nil {
+		return rules, fmt.Errorf("failed to get effective alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func scanAlertRules(row pgx.Row) (dbTypes.AlertRules, error) {
+	var r dbTypes.AlertRules
+	err := row.Scan(&r.Hive, &r.Owner, &r.NoiseHigh, &r.NoiseDelta, &r.TemperatureNormal, &r.TemperatureDeltaUp,
+		&r.TemperatureDeltaDown, &r.BatteryLow, &r.SignalLow, &r.UpdatedAt)
+	return r, err
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *Postgres) NewAnalyzerRun(ctx context.Context, run dbTypes.AnalyzerRun) error {
+	errs := run.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	q := `INSERT INTO analyzer_runs (analyzer, started_at, finished_at, hives_processed, alerts_raised, errors)
+	      VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := db.pull.Exec(ctx, q, run.Analyzer, run.StartedAt, run.FinishedAt,
+		run.HivesProcessed, run.AlertsRaised, errs)
+	if err != nil {
+		return fmt.Errorf("failed to insert analyzer run: %w", err)
+	}
+	return nil
+}
+
+// GetAnalyzerRuns возвращает последние прогоны анализатора, от новых к старым.
+// Пустое имя — прогоны всех анализаторов.
+func (db *Postgres) GetAnalyzerRuns(ctx context.Context, analyzer string, limit int) ([]dbTypes.AnalyzerRun, error) {
+	q := `SELECT id, analyzer, started_at, finished_at, hives_processed, alerts_raised, errors
+	      FROM analyzer_runs
+	      WHERE $1 = '' OR analyzer = $1
+	      ORDER BY started_at DESC, id DESC
+	      LIMIT $2`
+	rows, err := db.pull.Query(ctx, q, analyzer, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analyzer runs: %w", err)
+	}
+	return scanAnalyzerRuns(rows)
+}
+
+// GetLatestAnalyzerRuns возвращает последний прогон каждого анализатора.
+func (db *Postgres) GetLatestAnalyzerRuns(ctx context.Context) ([]dbTypes.AnalyzerRun, error) {
+	q := `SELECT DISTINCT ON (analyzer) id, analyzer, started_at, finished_at, hives_processed, alerts_raised, errors
+	      FROM analyzer_runs
+	      ORDER BY analyzer, started_at DESC, id DESC`
+	rows, err := db.pull.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest analyzer runs: %w", err)
+	}
+	return scanAnalyzerRuns(rows)
+}
+
+// DeleteAnalyzerRunsBefore удаляет прогоны, начавшиеся раньше before.
+func (db *Postgres) DeleteAnalyzerRunsBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := db.pull.Exec(ctx, `DELETE FROM analyzer_runs WHERE started_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete analyzer runs: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+func scanAnalyzerRuns(rows pgx.Rows) ([]dbTypes.AnalyzerRun, error) {
+	defer rows.Close()
+	var result []dbTypes.AnalyzerRun
+	for rows.Next() {
+		var run dbTypes.AnalyzerRun
+		if err := rows.Scan(&run.ID, &run.Analyzer, &run.StartedAt, &run.FinishedAt,
+			&run.HivesProcessed, &run.AlertsRaised, &run.Errors); err != nil {
+			return nil, fmt.Errorf("failed to scan analyzer run: %w", err)
+		}
+		result = append(result, run)
+	}
+	return result, rows.Err()
+}
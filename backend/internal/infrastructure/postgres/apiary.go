@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (db *Postgres) NewApiary(ctx context.Context, email string, data httpType.CreateApiary) error {
+	timezone := data.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	q := `INSERT INTO apiaries (user_id, name, latitude, longitude, altitude, timezone, notes)
+	      SELECT u.id, $2, $3, $4, $5, $6, $7 FROM users u WHERE u.email = $1`
+	_, err := db.pull.Exec(ctx, q, email, data.Name, data.Latitude, data.Longitude, data.Altitude, timezone, data.Notes)
+	if err != nil {
+		return fmt.Errorf("failed to insert apiary: %w", err)
+	}
+	return nil
+}
+
+// apiaryColumns — столбцы пасеки и роль в ней пользователя $1.
+var apiaryColumns = `a.id, u.email, a.name, a.latitude, a.longitude, a.altitude, a.timezone, a.notes, ` +
+	`COALESCE(` + apiaryRole("a", "$1") + `, '')`
+
+func scanApiary(row pgx.Row) (dbTypes.Apiary, error) {
+	var a dbTypes.Apiary
+	err := row.Scan(&a.Id, &a.Email, &a.Name, &a.Latitude, &a.Longitude, &a.Altitude, &a.Timezone, &a.Notes, &a.Role)
+	return a, err
+}
+
+// GetApiaries возвращает пасеки, доступные пользователю: свои и те, где он участник.
+func (db *Postgres) GetApiaries(ctx context.Context, email string) ([]dbTypes.Apiary, error) {
+	q := `SELECT ` + apiaryColumns + `
+	      FROM apiaries a JOIN users u ON a.user_id = u.id
+	      WHERE ` + apiaryAccess("a", "$1", membership.RoleViewer) + `
+	      ORDER BY a.name, a.id`
+	rows, err := db.pull.Query(ctx, q, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get apiaries: %w", err)
+	}
+	defer rows.Close()
+
+	var apiaries []dbTypes.Apiary
+	for rows.Next() {
+		a, err := scanApiary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan apiary: %w", err)
+		}
+		apiaries = append(apiaries, a)
+	}
+	return apiaries, rows.Err()
+}
+
+// GetApiaryByName возвращает доступную пользователю пасеку; если её нет — ошибку,
+// обёртывающую pgx.ErrNoRows.
+func (db *Postgres) GetApiaryByName(ctx context.Context, email, name string) (dbTypes.Apiary, error) {
+	q := `SELECT ` + apiaryColumns + `
+	      FROM apiaries a JOIN users u ON a.user_id = u.id
+	      WHERE a.id = ` + accessibleApiary("$1", "$2", membership.RoleViewer)
+	a, err := scanApiary(db.pull.QueryRow(ctx, q, email, name))
+	if err != nil {
+		return a, fmt.Errorf("failed to get apiary by name: %w", err)
+	}
+	return a, nil
+}
+
+// UpdateApiary меняет переданные поля пасеки. Если пасеки нет, возвращает ошибку,
+// обёртывающую pgx.ErrNoRows.
+func (db *Postgres) UpdateApiary(ctx context.Context, email string, data httpType.UpdateApiary) error {
+	q := `UPDATE apiaries a SET
+	          name = COALESCE($3, a.name),
+	          latitude = COALESCE($4, a.latitude),
+	          longitude = COALESCE($5, a.longitude),
+	          altitude = COALESCE($6, a.altitude),
+	          timezone = COALESCE(NULLIF($7, ''), a.timezone),
+	          notes = COALESCE($8, a.notes)
+	      WHERE a.id = ` + accessibleApiary("$1", "$2", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, email, data.OldName, data.NewName, data.Latitude, data.Longitude, data.Altitude,
+		data.Timezone, data.Notes)
+	if err != nil {
+		return fmt.Errorf("failed to update apiary: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update apiary: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// DeleteApiary удаляет пасеку; её ульи остаются без пасеки. Удалить пасеку может только владелец.
+func (db *Postgres) DeleteApiary(ctx context.Context, email, name string) error {
+	q := `DELETE FROM apiaries WHERE id = ` + accessibleApiary("$1", "$2", membership.RoleOwner)
+	_, err := db.pull.Exec(ctx, q, email, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete apiary: %w", err)
+	}
+	return nil
+}
+
+// LinkApiaryToHive ставит улей hiveName владельца owner на пасеку; пустое apiaryName
+// снимает его с пасеки. Если нет улья или пасеки, возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) LinkApiaryToHive(ctx context.Context, email, hiveName, owner, apiaryName string) error {
+	var q string
+	args := []any{email, hiveName, owner}
+	hive := accessibleHive("$1", "$2", "$3", membership.RoleEditor)
+	if apiaryName == "" {
+		q = `UPDATE hives SET apiary_id = NULL WHERE id = ` + hive
+	} else {
+		q = `UPDATE hives h SET apiary_id = a.id
+		     FROM apiaries a
+		     WHERE h.id = ` + hive + ` AND a.id = ` + accessibleApiary("$1", "$4", membership.RoleEditor)
+		args = append(args, apiaryName)
+	}
+	res, err := db.pull.Exec(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to link apiary to hive: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to link apiary to hive: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"BeeIOT/internal/domain/quality"
+	"cmp"
+	"time"
+)
+
+// splitSamples раскладывает пачку замеров на три параллельных массива
+// (значения, время и флаги качества), которые затем разворачиваются в SQL через unnest.
+func splitSamples(samples []httpType.TelemetrySample) ([]float64, []time.Time, []string) {
+	levels := make([]float64, len(samples))
+	times := make([]time.Time, len(samples))
+	qualities := make([]string, len(samples))
+	for i, s := range samples {
+		levels[i] = s.Value
+		times[i] = s.Time
+		qualities[i] = qualityOrOK(s.Quality)
+	}
+	return levels, times, qualities
+}
+
+// qualityOrOK — замеры без флага (ручной ввод веса, старые клиенты) считаются достоверными.
+func qualityOrOK(q string) string {
+	return cmp.Or(q, quality.OK)
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/export"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// exportFetchSize — строк за один FETCH из курсора выгрузки.
+const exportFetchSize = 1000
+
+// statusColumns сопоставляет метрики статуса устройства со столбцами device_status.
+var statusColumns = map[string]string{
+	export.MetricBattery: "battery_level",
+	export.MetricSignal:  "signal_strength",
+}
+
+// NewDeviceStatus записывает статус устройства в историю хаба пользователя.
+// Повторный статус с той же меткой времени игнорируется.
+func (db *Postgres) NewDeviceStatus(ctx context.Context, status httpType.DeviceStatus) error {
+	errs := status.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	q := `INSERT INTO device_status (hub_id, battery_level, signal_strength, errors, recorded_at)
+	      SELECT id, $3, $4, $5, $6 FROM hubs WHERE email = $1 AND sensor = $2
+	      ON CONFLICT (hub_id, recorded_at) DO NOTHING`
+	_, err := db.pull.Exec(ctx, q, status.Email, status.Sensor, status.BatteryLevel, status.SignalStrength, errs, status.Time)
+	if err != nil {
+		return fmt.Errorf("failed to insert device status: %w", err)
+	}
+	return nil
+}
+
+// ExportTelemetry читает замеры метрик хабов пользователя в окне [since, until)
+// серверным курсором и передаёт их в fn по одному, в порядке хаб, метрика, время.
+// Каждая пара (хаб, метрика) читается своим курсором по индексу (hub_id, recorded_at),
+// поэтому строки идут в нужном порядке без сортировки всей выгрузки и первые байты
+// уходят клиенту сразу. В памяти одновременно держится не больше exportFetchSize строк.
+// Отбракованные замеры выгружаются вместе с флагом качества; заряд и сигнал, которые
+// устройство не сообщило (-1), пропускаются. Ошибка fn прерывает выгрузку и возвращается как есть.
+func (db *Postgres) ExportTelemetry(ctx context.Context, email string, hubs, metrics []string, since, until time.Time, fn func(dbTypes.TelemetryExportRow) error) error {
+	queries := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		// имена таблиц и столбцов берутся только из rawTables и statusColumns
+		if table, ok := rawTables[metric]; ok {
+			queries = append(queries, fmt.Sprintf(`SELECT recorded_at, level, quality FROM %s
+			      WHERE hub_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+			      ORDER BY recorded_at`, table))
+			continue
+		}
+		if column, ok := statusColumns[metric]; ok {
+			queries = append(queries, fmt.Sprintf(`SELECT recorded_at, %s::float8, 'ok' FROM device_status
+			      WHERE hub_id = $1 AND recorded_at >= $2 AND recorded_at < $3 AND %s >= 0
+			      ORDER BY recorded_at`, column, column))
+			continue
+		}
+		return fmt.Errorf("unknown metric: %s", metric)
+	}
+
+	// Курсор живёт только внутри транзакции; REPEATABLE READ даёт всем курсорам один снимок
+	tx, err := db.pull.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	q := fmt.Sprintf(`SELECT h.id, h.sensor FROM hubs h
+	      WHERE h.sensor = ANY($2) AND %s
+	      ORDER BY h.sensor, h.id`, viewerHubs)
+	rows, err := tx.Query(ctx, q, email, hubs)
+	if err != nil {
+		return fmt.Errorf("failed to get export hubs: %w", err)
+	}
+	type exportHub struct {
+		id     int
+		sensor string
+	}
+	var targets []exportHub
+	for rows.Next() {
+		var hub exportHub
+		if err := rows.Scan(&hub.id, &hub.sensor); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan export hub: %w", err)
+		}
+		targets = append(targets, hub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get export hubs: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM telemetry_export", exportFetchSize)
+	for _, hub := range targets {
+		for i, metric := range metrics {
+			if _, err = tx.Exec(ctx, "DECLARE telemetry_export NO SCROLL CURSOR FOR "+queries[i], hub.id, since, until); err != nil {
+				return fmt.Errorf("failed to declare export cursor: %w", err)
+			}
+			for {
+				n, err := db.fetchExportChunk(ctx, tx, fetch, hub.sensor, metric, fn)
+				if err != nil {
+					return err
+				}
+				if n < exportFetchSize {
+					break
+				}
+			}
+			if _, err = tx.Exec(ctx, "CLOSE telemetry_export"); err != nil {
+				return fmt.Errorf("failed to close export cursor: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// fetchExportChunk читает из курсора выгрузки одну порцию строк и возвращает их число.
+func (db *Postgres) fetchExportChunk(ctx context.Context, tx pgx.Tx, fetch, hub, metric string, fn func(dbTypes.TelemetryExportRow) error) (int, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch export rows: %w", err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		row := dbTypes.TelemetryExportRow{Hub: hub, Metric: metric}
+		if err := rows.Scan(&row.Date, &row.Value, &row.Quality); err != nil {
+			return n, err
+		}
+		n++
+		if err := fn(row); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
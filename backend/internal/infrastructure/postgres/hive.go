@@ -1,9 +1,11 @@
 package postgres
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -20,36 +22,50 @@ func (db *Postgres) NewHive(ctx context.Context, email, nameHive, sensorName str
 	return err
 }
 
-func (db *Postgres) DeleteHive(ctx context.Context, email, nameHive string) error {
-	text := `DELETE FROM hives h
-			 USING users u
-			 WHERE h.user_id = u.id
-			   AND u.email = $1
-		       AND h.name = $2;`
-	_, err := db.pull.Exec(ctx, text, email, nameHive)
+// DeleteHive удаляет улей; удалить его может только владелец.
+func (db *Postgres) DeleteHive(ctx context.Context, email, nameHive, owner string) error {
+	text := `DELETE FROM hives WHERE id = ` + accessibleHive("$1", "$2", "$3", membership.RoleOwner)
+	_, err := db.pull.Exec(ctx, text, email, nameHive, owner)
 	return err
 }
 
-func (db *Postgres) GetHives(ctx context.Context, email string, active *bool) ([]dbTypes.Hive, error) {
-	base := `SELECT h.id, h.name, u.email, h.temperature_check, h.noise_check, COALESCE(s.sensor_id, ''), h.status, h.hub_id, COALESCE(hu.sensor, ''), COALESCE(q.name, '')
+// hiveSelect — столбцы улья; role — SQL-выражение роли пользователя в улье.
+func hiveSelect(role string) string {
+	return `SELECT h.id, h.name, u.email, h.temperature_check, h.noise_check, COALESCE(s.sensor_id, ''), h.status, h.hub_id, COALESCE(hu.sensor, ''), COALESCE(q.name, ''), h.season_mode,
+	               h.apiary_id, COALESCE(a.name, ''), a.latitude, COALESCE(a.timezone, ''), COALESCE(` + role + `, '')
 	        FROM hives h
 	        JOIN users u ON h.user_id = u.id
 	        LEFT JOIN sensors s ON h.sensor_id = s.id
 	        LEFT JOIN hubs hu ON h.hub_id = hu.id
-	        LEFT JOIN queens q ON h.queen_id = q.id`
+	        LEFT JOIN queens q ON h.queen_id = q.id
+	        LEFT JOIN apiaries a ON h.apiary_id = a.id`
+}
+
+func scanHive(row pgx.Row) (dbTypes.Hive, error) {
+	var hive dbTypes.Hive
+	err := row.Scan(&hive.Id, &hive.NameHive, &hive.Email, &hive.DateTemperature, &hive.DateNoise, &hive.SensorID, &hive.Status, &hive.HubID, &hive.HubName, &hive.QueenName, &hive.SeasonMode,
+		&hive.ApiaryID, &hive.ApiaryName, &hive.Latitude, &hive.Timezone, &hive.Role)
+	return hive, err
+}
+
+// GetHives возвращает ульи, доступные пользователю: свои и те, где он участник.
+// Пустой email — все ульи (для анализаторов), роль у них не заполняется.
+func (db *Postgres) GetHives(ctx context.Context, email string, active *bool) ([]dbTypes.Hive, error) {
+	all := hiveSelect("NULL")
+	own := hiveSelect(hiveRole("h", "$1")) + " WHERE " + hiveAccess("h", "$1", membership.RoleViewer)
 
 	var rows pgx.Rows
 	var err error
 
 	switch {
 	case email == "" && active == nil:
-		rows, err = db.pull.Query(ctx, base)
+		rows, err = db.pull.Query(ctx, all)
 	case email == "" && active != nil:
-		rows, err = db.pull.Query(ctx, base+" WHERE h.status = $1", *active)
+		rows, err = db.pull.Query(ctx, all+" WHERE h.status = $1", *active)
 	case email != "" && active == nil:
-		rows, err = db.pull.Query(ctx, base+" WHERE u.email = $1", email)
+		rows, err = db.pull.Query(ctx, own, email)
 	default:
-		rows, err = db.pull.Query(ctx, base+" WHERE u.email = $1 AND h.status = $2", email, *active)
+		rows, err = db.pull.Query(ctx, own+" AND h.status = $2", email, *active)
 	}
 	if err != nil {
 		return nil, err
@@ -57,8 +73,7 @@ func (db *Postgres) GetHives(ctx context.Context, email string, active *bool) ([
 	defer rows.Close()
 	var hives []dbTypes.Hive
 	for rows.Next() {
-		var hive dbTypes.Hive
-		err := rows.Scan(&hive.Id, &hive.NameHive, &hive.Email, &hive.DateTemperature, &hive.DateNoise, &hive.SensorID, &hive.Status, &hive.HubID, &hive.HubName, &hive.QueenName)
+		hive, err := scanHive(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -67,29 +82,32 @@ func (db *Postgres) GetHives(ctx context.Context, email string, active *bool) ([
 	return hives, nil
 }
 
-func (db *Postgres) GetHiveByName(ctx context.Context, email, nameHive string, active *bool) (dbTypes.Hive, error) {
-	base := `SELECT h.id, h.name, u.email, h.temperature_check, h.noise_check, COALESCE(s.sensor_id, ''), h.status, h.hub_id, COALESCE(hu.sensor, ''), COALESCE(q.name, '')
-	        FROM hives h
-	        INNER JOIN users u ON h.user_id = u.id
-	        LEFT JOIN sensors s ON h.sensor_id = s.id
-	        LEFT JOIN hubs hu ON h.hub_id = hu.id
-	        LEFT JOIN queens q ON h.queen_id = q.id
-	        WHERE h.name = $2 AND u.email = $1`
+// GetHiveByName возвращает доступный пользователю улей владельца owner; при пустом
+// owner и совпадении имён предпочитается свой.
+func (db *Postgres) GetHiveByName(ctx context.Context, email, nameHive, owner string, active *bool) (dbTypes.Hive, error) {
+	base := hiveSelect(hiveRole("h", "$1")) + " WHERE h.id = " + accessibleHive("$1", "$2", "$3", membership.RoleViewer)
 
 	var row pgx.Row
 	if active != nil {
-		row = db.pull.QueryRow(ctx, base+" AND h.status = $3", email, nameHive, *active)
+		row = db.pull.QueryRow(ctx, base+" AND h.status = $4", email, nameHive, owner, *active)
 	} else {
-		row = db.pull.QueryRow(ctx, base, email, nameHive)
+		row = db.pull.QueryRow(ctx, base, email, nameHive, owner)
 	}
-	var hive dbTypes.Hive
-	err := row.Scan(&hive.Id, &hive.NameHive, &hive.Email, &hive.DateTemperature, &hive.DateNoise, &hive.SensorID, &hive.Status, &hive.HubID, &hive.HubName, &hive.QueenName)
+	hive, err := scanHive(row)
 	if err != nil {
 		return dbTypes.Hive{}, err
 	}
 	return hive, nil
 }
 
+// GetHiveByHub возвращает доступный пользователю улей, к которому привязан хаб hubSensor.
+// Улей определяется по хабу, а не по имени: имена ульев разных владельцев могут совпадать.
+func (db *Postgres) GetHiveByHub(ctx context.Context, email, hubSensor string) (dbTypes.Hive, error) {
+	q := hiveSelect(hiveRole("h", "$1")) + `
+	      WHERE h.hub_id = (SELECT id FROM hubs WHERE sensor = $2) AND ` + hiveAccess("h", "$1", membership.RoleViewer)
+	return scanHive(db.pull.QueryRow(ctx, q, email, hubSensor))
+}
+
 // проверить
 func (db *Postgres) UpdateHive(ctx context.Context, email string, data httpType.UpdateHive) error {
 	tx, err := db.pull.Begin(ctx)
@@ -101,7 +119,7 @@ func (db *Postgres) UpdateHive(ctx context.Context, email string, data httpType.
 	}()
 
 	var hiveID int
-	err = tx.QueryRow(ctx, `SELECT h.id FROM hives h JOIN users u ON h.user_id = u.id WHERE u.email = $1 AND h.name = $2`, email, data.OldName).Scan(&hiveID)
+	err = tx.QueryRow(ctx, `SELECT id FROM hives WHERE id = `+accessibleHive("$1", "$2", "$3", membership.RoleEditor), email, data.OldName, data.Owner).Scan(&hiveID)
 	if err != nil {
 		return err
 	}
@@ -120,9 +138,17 @@ func (db *Postgres) UpdateHive(ctx context.Context, email string, data httpType.
 		}
 	}
 
+	if data.SeasonMode != nil {
+		_, err = tx.Exec(ctx, `UPDATE hives SET season_mode = $1 WHERE id = $2`, *data.SeasonMode, hiveID)
+		if err != nil {
+			return err
+		}
+	}
+
 	if data.Sensor != nil && *data.Sensor != "" {
 		var sensorID int
-		err = tx.QueryRow(ctx, `SELECT s.id FROM sensors s JOIN users u ON s.user_id = u.id WHERE u.email = $1 AND s.sensor_id = $2`, email, *data.Sensor).Scan(&sensorID)
+		// датчики принадлежат владельцу улья
+		err = tx.QueryRow(ctx, `SELECT s.id FROM sensors s JOIN hives h ON s.user_id = h.user_id WHERE h.id = $1 AND s.sensor_id = $2`, hiveID, *data.Sensor).Scan(&sensorID)
 		if err != nil {
 			return err
 		}
@@ -215,29 +241,44 @@ WHERE u.email = $1 AND h.name = $2`
 	return tr.Commit(ctx)
 }
 
-func (db *Postgres) LinkHubToHive(ctx context.Context, email, hiveName, hubName string) error {
-	var err error
+// LinkHubToHive привязывает к улью владельца owner хаб, доступный пользователю; пустое
+// hubName отвязывает хаб. Хаб должен принадлежать владельцу улья: телеметрия и алерты
+// хаба ведутся от имени его владельца, и с чужим хабом улей получил бы чужие алерты.
+// Если нет улья или такого хаба у владельца улья, возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) LinkHubToHive(ctx context.Context, email, hiveName, owner, hubName string) error {
+	hive := accessibleHive("$1", "$2", "$3", membership.RoleEditor)
+	var q string
+	args := []any{email, hiveName, owner}
 	if hubName == "" {
-		q := `UPDATE hives SET hub_id = NULL WHERE user_id = (SELECT id FROM users WHERE email = $1) AND name = $2`
-		_, err = db.pull.Exec(ctx, q, email, hiveName)
+		q = `UPDATE hives SET hub_id = NULL WHERE id = ` + hive
 	} else {
-		q := `UPDATE hives SET hub_id = (SELECT id FROM hubs WHERE email = $1 AND sensor = $3) WHERE user_id = (SELECT id FROM users WHERE email = $1) AND name = $2`
-		_, err = db.pull.Exec(ctx, q, email, hiveName, hubName)
+		q = `UPDATE hives h SET hub_id = hu.id
+		     FROM hubs hu JOIN users o ON o.email = hu.email
+		     WHERE h.id = ` + hive + ` AND hu.sensor = $4 AND o.id = h.user_id
+		       AND ` + hubAccess("hu", "$1", membership.RoleEditor)
+		args = append(args, hubName)
 	}
+	res, err := db.pull.Exec(ctx, q, args...)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to link hub to hive: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to link hub to hive: %w", pgx.ErrNoRows)
 	}
 	return nil
 }
 
-func (db *Postgres) LinkQueenToHive(ctx context.Context, email, hiveName, queenName string) error {
+// LinkQueenToHive подсаживает в улей владельца owner матку, доступную пользователю;
+// пустое queenName убирает матку.
+func (db *Postgres) LinkQueenToHive(ctx context.Context, email, hiveName, owner, queenName string) error {
+	hive := accessibleHive("$1", "$2", "$3", membership.RoleEditor)
 	var err error
 	if queenName == "" {
-		q := `UPDATE hives SET queen_id = NULL WHERE user_id = (SELECT id FROM users WHERE email = $1) AND name = $2`
-		_, err = db.pull.Exec(ctx, q, email, hiveName)
+		q := `UPDATE hives SET queen_id = NULL WHERE id = ` + hive
+		_, err = db.pull.Exec(ctx, q, email, hiveName, owner)
 	} else {
-		q := `UPDATE hives SET queen_id = (SELECT id FROM queens WHERE email = $1 AND name = $3) WHERE user_id = (SELECT id FROM users WHERE email = $1) AND name = $2`
-		_, err = db.pull.Exec(ctx, q, email, hiveName, queenName)
+		q := `UPDATE hives SET queen_id = ` + accessibleQueen("$1", "$4", membership.RoleEditor) + ` WHERE id = ` + hive
+		_, err = db.pull.Exec(ctx, q, email, hiveName, owner, queenName)
 	}
 	if err != nil {
 		return err
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// NewHiveEvent добавляет событие в хронологию улья.
+func (db *Postgres) NewHiveEvent(ctx context.Context, hiveId int, e dbTypes.HiveEvent) (int64, error) {
+	q := `INSERT INTO hive_events (hive_id, type, source, title, details, occurred_at)
+	      VALUES ($1, $2, $3, $4, $5, $6)
+	      RETURNING id`
+	details := e.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	var id int64
+	err := db.pull.QueryRow(ctx, q, hiveId, e.Type, e.Source, e.Title, details, e.OccurredAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert hive event: %w", err)
+	}
+	return id, nil
+}
+
+// GetHiveEvents возвращает события улья начиная с since, от новых к старым.
+func (db *Postgres) GetHiveEvents(ctx context.Context, hiveId int, since time.Time) ([]dbTypes.HiveEvent, error) {
+	q := `SELECT e.id, h.name, e.type, e.source, e.title, e.details, e.occurred_at, e.created_at
+	      FROM hive_events e
+	      INNER JOIN hives h ON h.id = e.hive_id
+	      WHERE e.hive_id = $1 AND e.occurred_at >= $2
+	      ORDER BY e.occurred_at DESC`
+	rows, err := db.pull.Query(ctx, q, hiveId, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hive events: %w", err)
+	}
+	defer rows.Close()
+	var events []dbTypes.HiveEvent
+	for rows.Next() {
+		var e dbTypes.HiveEvent
+		if err := rows.Scan(&e.ID, &e.Hive, &e.Type, &e.Source, &e.Title, &e.Details, &e.OccurredAt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan hive event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
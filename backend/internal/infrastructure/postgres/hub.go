@@ -1,23 +1,65 @@
 package postgres
 
 import (
+	"BeeIOT/internal/domain/deviceAuth"
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func (d *Postgres) NewHub(ctx context.Context, email, nameHub, sensorName string) error {
-	q := `INSERT INTO hubs (email, name, sensor) VALUES ($1, $2, $3)`
-	_, err := d.pull.Exec(ctx, q, email, nameHub, sensorName)
+// NewHub заводит хаб. Идентификатор хаба — логин устройства в брокере, поэтому
+// если он уже занят (в том числе другим пользователем), возвращает deviceAuth.ErrHubTaken.
+func (d *Postgres) NewHub(ctx context.Context, email, nameHub, sensorName, secretHash string) error {
+	q := `INSERT INTO hubs (email, name, sensor, secret_hash)
+	      SELECT $1, $2, $3, $4
+	      WHERE NOT EXISTS (SELECT 1 FROM hubs WHERE sensor = $3)`
+	res, err := d.pull.Exec(ctx, q, email, nameHub, sensorName, secretHash)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		// хаб с тем же id завели одновременно
+		return deviceAuth.ErrHubTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert new hub: %w", err)
 	}
+	if res.RowsAffected() == 0 {
+		return deviceAuth.ErrHubTaken
+	}
+	return nil
+}
+
+// UpdateHubSecret меняет секрет хаба; сменить его может только владелец.
+func (d *Postgres) UpdateHubSecret(ctx context.Context, email, sensor, secretHash string) error {
+	q := `UPDATE hubs SET secret_hash = $3 WHERE id = ` + ownerHub
+	res, err := d.pull.Exec(ctx, q, email, sensor, secretHash)
+	if err != nil {
+		return fmt.Errorf("failed to update hub secret: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("hub not found")
+	}
 	return nil
 }
 
+// GetHubSecretHash возвращает хеш секрета хаба с данным идентификатором;
+// секрет есть не больше чем у одного хаба (см. NewHub).
+func (d *Postgres) GetHubSecretHash(ctx context.Context, sensor string) (string, error) {
+	q := `SELECT secret_hash FROM hubs WHERE sensor = $1 AND secret_hash IS NOT NULL`
+	var hash string
+	if err := d.pull.QueryRow(ctx, q, sensor).Scan(&hash); err != nil {
+		return "", fmt.Errorf("failed to get hub secret: %w", err)
+	}
+	return hash, nil
+}
+
+// GetHubs возвращает хабы пользователя и хабы ульев, где он участник.
 func (d *Postgres) GetHubs(ctx context.Context, email string) ([]dbTypes.Hub, error) {
-	q := `SELECT id, name, email, sensor FROM hubs WHERE email = $1`
+	q := `SELECT h.id, h.name, h.email, h.sensor FROM hubs h WHERE ` + viewerHubs
 	rows, err := d.pull.Query(ctx, q, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get hubs: %w", err)
@@ -36,7 +78,7 @@ func (d *Postgres) GetHubs(ctx context.Context, email string) ([]dbTypes.Hub, er
 }
 
 func (d *Postgres) GetHubBySensor(ctx context.Context, email, sensor string) (dbTypes.Hub, error) {
-	q := `SELECT id, name, email, sensor FROM hubs WHERE email = $1 AND sensor = $2`
+	q := `SELECT id, name, email, sensor FROM hubs WHERE id = ` + viewerHub
 	var hub dbTypes.Hub
 	err := d.pull.QueryRow(ctx, q, email, sensor).Scan(&hub.Id, &hub.NameHub, &hub.Email, &hub.Sensor)
 	if err != nil {
@@ -45,6 +87,7 @@ func (d *Postgres) GetHubBySensor(ctx context.Context, email, sensor string) (db
 	return hub, nil
 }
 
+// DeleteHub удаляет хаб; удалить его может только владелец.
 func (d *Postgres) DeleteHub(ctx context.Context, email, hubID string) error {
 	tx, err := d.pull.Begin(ctx)
 	if err != nil {
@@ -52,31 +95,32 @@ func (d *Postgres) DeleteHub(ctx context.Context, email, hubID string) error {
 	}
 	defer tx.Rollback(ctx)
 
+	// Хаб ищется до отвязки: участнику он доступен только через ульи
+	var id int
+	if err := tx.QueryRow(ctx, `SELECT id FROM hubs WHERE id = `+ownerHub, email, hubID).Scan(&id); err != nil {
+		return fmt.Errorf("hub not found or already deleted: %w", err)
+	}
+
 	// Отвязываем ульи от хаба
-	_, err = tx.Exec(ctx,
-		`UPDATE hives SET hub_id = NULL WHERE hub_id = (SELECT id FROM hubs WHERE email = $1 AND sensor = $2)`,
-		email, hubID)
+	_, err = tx.Exec(ctx, `UPDATE hives SET hub_id = NULL WHERE hub_id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("failed to unlink hives: %w", err)
 	}
 
 	// Удаляем хаб (телеметрия удалится по CASCADE)
-	res, err := tx.Exec(ctx, `DELETE FROM hubs WHERE email = $1 AND sensor = $2`, email, hubID)
-	if err != nil {
+	if _, err = tx.Exec(ctx, `DELETE FROM hubs WHERE id = $1`, id); err != nil {
 		return fmt.Errorf("failed to delete hub: %w", err)
 	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("hub not found or already deleted")
-	}
 
 	return tx.Commit(ctx)
 }
 
+// GetHubSensorByHive возвращает идентификатор хаба, привязанного к улью hiveName
+// владельца email.
 func (d *Postgres) GetHubSensorByHive(ctx context.Context, email, hiveName string) (string, error) {
 	q := `SELECT hu.sensor FROM hives h
-	      JOIN users u ON h.user_id = u.id
 	      JOIN hubs hu ON h.hub_id = hu.id
-	      WHERE u.email = $1 AND h.name = $2`
+	      WHERE h.id = ` + accessibleHive("$1", "$2", "$1", membership.RoleOwner)
 	var sensor string
 	err := d.pull.QueryRow(ctx, q, email, hiveName).Scan(&sensor)
 	if err != nil {
@@ -114,7 +158,7 @@ func (d *Postgres) UpdateHub(ctx context.Context, email string, data httpType.Up
 		return nil // Nothing to update
 	}
 
-	q := `UPDATE hubs SET name = $3 WHERE email = $1 AND sensor = $2`
+	q := `UPDATE hubs SET name = $3 WHERE id = ` + editorHub
 	res, err := d.pull.Exec(ctx, q, email, data.ID, *data.Name)
 	if err != nil {
 		return fmt.Errorf("failed to update hub: %w", err)
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportTelemetry записывает загруженные из файла замеры метрики хаба: COPY во временную
+// таблицу, затем перенос в таблицу сырых замеров. Замеры, время которых у хаба уже есть,
+// пропускаются. Возвращает число записанных замеров. Импортируется обычно история
+// за прошлые месяцы, до которой фоновый пересчёт агрегатов не дотягивается, поэтому
+// агрегаты хаба за период импорта пересчитываются в той же транзакции. Месячные секции
+// за период импорта создаются заранее: в секции default старые замеры не попали бы
+// под удаление по сроку хранения.
+func (db *Postgres) ImportTelemetry(ctx context.Context, metric string, batch httpType.TelemetryBatch) (int, error) {
+	table, ok := rawTables[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown metric: %s", metric)
+	}
+	if len(batch.Samples) == 0 {
+		return 0, nil
+	}
+	from, to := batch.Samples[0].Time, batch.Samples[0].Time
+	for _, s := range batch.Samples {
+		if s.Time.Before(from) {
+			from = s.Time
+		}
+		if s.Time.After(to) {
+			to = s.Time
+		}
+	}
+
+	for month := monthStart(from.UTC()); !month.After(to); month = month.AddDate(0, 1, 0) {
+		if _, err := db.EnsureTelemetryPartition(ctx, metric, month); err != nil {
+			return 0, fmt.Errorf("failed to prepare %s partitions for import: %w", metric, err)
+		}
+	}
+
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	q := `CREATE TEMP TABLE telemetry_import (
+	          level FLOAT NOT NULL,
+	          recorded_at TIMESTAMP NOT NULL,
+	          quality TEXT NOT NULL
+	      ) ON COMMIT DROP`
+	if _, err = tx.Exec(ctx, q); err != nil {
+		return 0, fmt.Errorf("failed to create import table: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"telemetry_import"}, []string{"level", "recorded_at", "quality"},
+		pgx.CopyFromSlice(len(batch.Samples), func(i int) ([]any, error) {
+			s := batch.Samples[i]
+			return []any{s.Value, s.Time, qualityOrOK(s.Quality)}, nil
+		}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy %s samples: %w", metric, err)
+	}
+
+	q = fmt.Sprintf(`INSERT INTO %s (hub_id, level, recorded_at, quality)
+	      SELECT h.id, i.level, i.recorded_at, i.quality
+	      FROM telemetry_import i
+	      INNER JOIN hubs h ON h.id = %s
+	      ON CONFLICT (hub_id, recorded_at) DO NOTHING`, table, editorHub)
+	tag, err := tx.Exec(ctx, q, batch.Email, batch.Hub)
+	if err != nil {
+		return 0, fmt.Errorf("failed to import %s samples: %w", metric, err)
+	}
+
+	q = fmt.Sprintf(`INSERT INTO telemetry_hourly (hub_id, metric, bucket, min_value, max_value, avg_value, sample_count)
+	      SELECT t.hub_id, $3, date_trunc('hour', t.recorded_at), min(t.level), max(t.level), avg(t.level), count(*)
+	      FROM %s t
+	      INNER JOIN hubs h ON h.id = t.hub_id
+	      WHERE h.id = %s AND t.quality = 'ok'
+	        AND t.recorded_at >= date_trunc('hour', $4::timestamp) AND t.recorded_at < date_trunc('hour', $5::timestamp) + interval '1 hour'
+	      GROUP BY t.hub_id, date_trunc('hour', t.recorded_at)
+	      ON CONFLICT (hub_id, metric, bucket) DO UPDATE SET
+	          min_value = EXCLUDED.min_value,
+	          max_value = EXCLUDED.max_value,
+	          avg_value = EXCLUDED.avg_value,
+	          sample_count = EXCLUDED.sample_count`, table, editorHub)
+	if _, err = tx.Exec(ctx, q, batch.Email, batch.Hub, metric, from, to); err != nil {
+		return 0, fmt.Errorf("failed to refresh hourly %s rollup: %w", metric, err)
+	}
+	q = `INSERT INTO telemetry_daily (hub_id, metric, bucket, min_value, max_value, avg_value, sample_count)
+	      SELECT r.hub_id, r.metric, date_trunc('day', r.bucket), min(r.min_value), max(r.max_value),
+	             sum(r.avg_value * r.sample_count) / sum(r.sample_count), sum(r.sample_count)
+	      FROM telemetry_hourly r
+	      INNER JOIN hubs h ON h.id = r.hub_id
+	      WHERE h.id = ` + editorHub + ` AND r.metric = $3
+	        AND r.bucket >= date_trunc('day', $4::timestamp) AND r.bucket < date_trunc('day', $5::timestamp) + interval '1 day'
+	      GROUP BY r.hub_id, r.metric, date_trunc('day', r.bucket)
+	      ON CONFLICT (hub_id, metric, bucket) DO UPDATE SET
+	          min_value = EXCLUDED.min_value,
+	          max_value = EXCLUDED.max_value,
+	          avg_value = EXCLUDED.avg_value,
+	          sample_count = EXCLUDED.sample_count`
+	if _, err = tx.Exec(ctx, q, batch.Email, batch.Hub, metric, from, to); err != nil {
+		return 0, fmt.Errorf("failed to refresh daily %s rollup: %w", metric, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
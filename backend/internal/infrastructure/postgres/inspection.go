@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const inspectionColumns = `i.id, h.name, ho.email, i.inspected_on, i.brood_frames, i.honey_frames, i.pollen_frames,
+	      i.queen_seen, i.eggs_seen, i.queen_cells, i.temperament, i.varroa_count, i.varroa_method,
+	      i.notes, COALESCE(au.email, ''), i.created_at
+	      FROM inspections i
+	      JOIN hives h ON h.id = i.hive_id
+	      JOIN users ho ON ho.id = h.user_id
+	      LEFT JOIN users au ON au.id = i.author_id`
+
+func scanInspection(row pgx.Row) (dbTypes.Inspection, error) {
+	var i dbTypes.Inspection
+	err := row.Scan(&i.Id, &i.Hive, &i.Owner, &i.Date, &i.BroodFrames, &i.HoneyFrames, &i.PollenFrames,
+		&i.QueenSeen, &i.EggsSeen, &i.QueenCells, &i.Temperament, &i.VarroaCount, &i.VarroaMethod,
+		&i.Notes, &i.Author, &i.CreatedAt)
+	return i, err
+}
+
+// NewInspection записывает осмотр улья hive владельца owner от имени пользователя email. Записывать
+// осмотры может редактор; если улья нет или прав не хватает, возвращает ошибку,
+// обёртывающую pgx.ErrNoRows.
+func (db *Postgres) NewInspection(ctx context.Context, email, hive, owner string, i dbTypes.Inspection) (int64, error) {
+	q := `INSERT INTO inspections (hive_id, inspected_on, brood_frames, honey_frames, pollen_frames,
+	          queen_seen, eggs_seen, queen_cells, temperament, varroa_count, varroa_method, notes, author_id)
+	      SELECT h.id, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, (SELECT id FROM users WHERE email = $1)
+	      FROM hives h
+	      WHERE h.id = ` + accessibleHive("$1", "$2", "$14", membership.RoleEditor) + `
+	      RETURNING id`
+	var id int64
+	err := db.pull.QueryRow(ctx, q, email, hive, i.Date, i.BroodFrames, i.HoneyFrames, i.PollenFrames,
+		i.QueenSeen, i.EggsSeen, i.QueenCells, i.Temperament, i.VarroaCount, i.VarroaMethod, i.Notes, owner).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert inspection: %w", err)
+	}
+	return id, nil
+}
+
+// GetInspections возвращает осмотры доступных пользователю ульев начиная с дня since,
+// от новых к старым; непустой hive сужает выборку до одного улья владельца owner.
+func (db *Postgres) GetInspections(ctx context.Context, email, hive, owner string, since time.Time) ([]dbTypes.Inspection, error) {
+	q := `SELECT ` + inspectionColumns + `
+	      WHERE i.inspected_on >= $2::date AND ` + hiveAccess("h", "$1", membership.RoleViewer)
+	args := []any{email, since.UTC()}
+	if hive != "" {
+		q += ` AND h.id = ` + accessibleHive("$1", "$3", "$4", membership.RoleViewer)
+		args = append(args, hive, owner)
+	}
+	q += ` ORDER BY i.inspected_on DESC, i.id DESC`
+
+	rows, err := db.pull.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inspections: %w", err)
+	}
+	defer rows.Close()
+
+	var inspections []dbTypes.Inspection
+	for rows.Next() {
+		i, err := scanInspection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inspection: %w", err)
+		}
+		inspections = append(inspections, i)
+	}
+	return inspections, rows.Err()
+}
+
+// GetInspection возвращает осмотр по id, если улей доступен пользователю.
+func (db *Postgres) GetInspection(ctx context.Context, email string, id int64) (dbTypes.Inspection, error) {
+	q := `SELECT ` + inspectionColumns + `
+	      WHERE i.id = $2 AND ` + hiveAccess("h", "$1", membership.RoleViewer)
+	i, err := scanInspection(db.pull.QueryRow(ctx, q, email, id))
+	if err != nil {
+		return i, fmt.Errorf("failed to get inspection: %w", err)
+	}
+	return i, nil
+}
+
+// UpdateInspection сохраняет показатели осмотра i.Id; менять осмотры может редактор.
+func (db *Postgres) UpdateInspection(ctx context.Context, email string, i dbTypes.Inspection) error {
+	q := `UPDATE inspections i SET inspected_on = $3, brood_frames = $4, honey_frames = $5, pollen_frames = $6,
+	          queen_seen = $7, eggs_seen = $8, queen_cells = $9, temperament = $10, varroa_count = $11,
+	          varroa_method = $12, notes = $13
+	      FROM hives h
+	      WHERE i.id = $2 AND h.id = i.hive_id AND ` + hiveAccess("h", "$1", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, email, i.Id, i.Date, i.BroodFrames, i.HoneyFrames, i.PollenFrames,
+		i.QueenSeen, i.EggsSeen, i.QueenCells, i.Temperament, i.VarroaCount, i.VarroaMethod, i.Notes)
+	if err != nil {
+		return fmt.Errorf("failed to update inspection: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update inspection: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// DeleteInspection удаляет осмотр; удалять осмотры может редактор.
+func (db *Postgres) DeleteInspection(ctx context.Context, email string, id int64) error {
+	q := `DELETE FROM inspections i USING hives h
+	      WHERE i.id = $2 AND h.id = i.hive_id AND ` + hiveAccess("h", "$1", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete inspection: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete inspection: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
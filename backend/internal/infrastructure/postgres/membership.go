@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/membership"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetHiveRole возвращает роль пользователя в улье владельца owner; если улей ему
+// недоступен — ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) GetHiveRole(ctx context.Context, email, hive, owner string) (string, error) {
+	q := `SELECT ` + hiveRole("h", "$1") + ` FROM hives h WHERE h.id = ` + accessibleHive("$1", "$2", "$3", membership.RoleViewer)
+	var role string
+	if err := db.pull.QueryRow(ctx, q, email, hive, owner).Scan(&role); err != nil {
+		return "", fmt.Errorf("failed to get hive role: %w", err)
+	}
+	return role, nil
+}
+
+// GetHubRole возвращает роль пользователя для хаба: owner для своего хаба, иначе
+// наивысшую из ролей в ульях хаба. Если хаб недоступен — ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) GetHubRole(ctx context.Context, email, sensor string) (string, error) {
+	q := `SELECT CASE WHEN hu.email = $1 THEN 'owner' ELSE (
+	          SELECT r.role FROM (SELECT ` + hiveRole("h", "$1") + ` AS role FROM hives h WHERE h.hub_id = hu.id) r
+	          WHERE r.role IS NOT NULL
+	          ORDER BY ` + roleRank("r.role") + ` DESC LIMIT 1) END
+	      FROM hubs hu WHERE hu.id = ` + viewerHub
+	var role string
+	if err := db.pull.QueryRow(ctx, q, email, sensor).Scan(&role); err != nil {
+		return "", fmt.Errorf("failed to get hub role: %w", err)
+	}
+	return role, nil
+}
+
+// GetApiaryRole возвращает роль пользователя на пасеке; если пасека недоступна —
+// ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) GetApiaryRole(ctx context.Context, email, apiary string) (string, error) {
+	q := `SELECT ` + apiaryRole("a", "$1") + ` FROM apiaries a WHERE a.id = ` + accessibleApiary("$1", "$2", membership.RoleViewer)
+	var role string
+	if err := db.pull.QueryRow(ctx, q, email, apiary).Scan(&role); err != nil {
+		return "", fmt.Errorf("failed to get apiary role: %w", err)
+	}
+	return role, nil
+}
+
+// targetIDs — id пасеки и улья, к которым обращается пользователь $1: $2 — имя
+// пасеки, $3 — имя улья; задаётся одно из двух. owner — параметр с почтой владельца улья.
+func targetIDs(owner, role string) string {
+	return `SELECT CASE WHEN $2 <> '' THEN ` + accessibleApiary("$1", "$2", role) + ` END,
+	               CASE WHEN $2 = '' THEN ` + accessibleHive("$1", "$3", owner, role) + ` END`
+}
+
+// CreateInvitation сохраняет приглашение от пользователя inviter. Если пасеки или улья нет —
+// ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) CreateInvitation(ctx context.Context, inviter string, inv httpType.InviteMember, tokenHash string, expires time.Time) error {
+	q := `INSERT INTO invitations (token_hash, email, apiary_id, hive_id, role, invited_by, expires_at)
+	      SELECT $4, $5, t.apiary_id, t.hive_id, $6, (SELECT id FROM users WHERE email = $1), $7
+	      FROM (` + targetIDs("$8", membership.RoleViewer) + `) AS t(apiary_id, hive_id)
+	      WHERE t.apiary_id IS NOT NULL OR t.hive_id IS NOT NULL`
+	res, err := db.pull.Exec(ctx, q, inviter, inv.Apiary, inv.Hive, tokenHash, inv.Email, inv.Role, expires.UTC(), inv.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to create invitation: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+const invitationSelect = `SELECT i.id, i.email, COALESCE(a.name, ''), COALESCE(h.name, ''), i.role, u.email, i.created_at, i.expires_at
+	FROM invitations i
+	JOIN users u ON u.id = i.invited_by
+	LEFT JOIN apiaries a ON a.id = i.apiary_id
+	LEFT JOIN hives h ON h.id = i.hive_id`
+
+func scanInvitation(row pgx.Row) (dbTypes.Invitation, error) {
+	var inv dbTypes.Invitation
+	err := row.Scan(&inv.Id, &inv.Email, &inv.Apiary, &inv.Hive, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt)
+	return inv, err
+}
+
+// GetInvitations возвращает действующие приглашения на адрес email.
+func (db *Postgres) GetInvitations(ctx context.Context, email string) ([]dbTypes.Invitation, error) {
+	q := invitationSelect + ` WHERE lower(i.email) = lower($1) AND i.expires_at > $2 ORDER BY i.created_at DESC`
+	rows, err := db.pull.Query(ctx, q, email, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var result []dbTypes.Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		result = append(result, inv)
+	}
+	return result, rows.Err()
+}
+
+// AcceptInvitation принимает приглашение на адрес email — по id или по хешу токена из письма —
+// и делает пользователя участником. Принятое приглашение удаляется; повторное приглашение
+// того же участника меняет его роль. Если действующего приглашения нет — ошибку,
+// обёртывающую pgx.ErrNoRows.
+func (db *Postgres) AcceptInvitation(ctx context.Context, email string, id int64, tokenHash string) (dbTypes.Invitation, error) {
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return dbTypes.Invitation{}, fmt.Errorf("failed to begin invitation transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	q := invitationSelect + ` WHERE lower(i.email) = lower($1) AND (i.id = $2 OR i.token_hash = $3) AND i.expires_at > $4
+	      FOR UPDATE OF i`
+	inv, err := scanInvitation(tx.QueryRow(ctx, q, email, id, tokenHash, time.Now().UTC()))
+	if err != nil {
+		return inv, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	conflict := `(user_id, hive_id) WHERE hive_id IS NOT NULL`
+	if inv.Apiary != "" {
+		conflict = `(user_id, apiary_id) WHERE apiary_id IS NOT NULL`
+	}
+	q = `INSERT INTO memberships (user_id, apiary_id, hive_id, role)
+	     SELECT u.id, i.apiary_id, i.hive_id, i.role
+	     FROM invitations i, users u
+	     WHERE i.id = $1 AND u.email = $2
+	     ON CONFLICT ` + conflict + ` DO UPDATE SET role = EXCLUDED.role`
+	if _, err = tx.Exec(ctx, q, inv.Id, email); err != nil {
+		return inv, fmt.Errorf("failed to insert membership: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM invitations WHERE id = $1`, inv.Id); err != nil {
+		return inv, fmt.Errorf("failed to delete accepted invitation: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return inv, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// DeclineInvitation удаляет приглашение на адрес email; если его нет — ошибку,
+// обёртывающую pgx.ErrNoRows.
+func (db *Postgres) DeclineInvitation(ctx context.Context, email string, id int64, tokenHash string) error {
+	q := `DELETE FROM invitations WHERE lower(email) = lower($1) AND (id = $2 OR token_hash = $3)`
+	res, err := db.pull.Exec(ctx, q, email, id, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to decline invitation: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// GetMembers возвращает участников пасеки apiary или улья hive владельца owner (задаётся одно из двух),
+// начиная с владельца. У улья в список входят и участники его пасеки. Если пасека или
+// улей недоступны пользователю, возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) GetMembers(ctx context.Context, email, apiary, hive, owner string) ([]dbTypes.Member, error) {
+	var target struct{ apiaryID, hiveID *int }
+	err := db.pull.QueryRow(ctx, targetIDs("$4", membership.RoleViewer), email, apiary, hive, owner).Scan(&target.apiaryID, &target.hiveID)
+	if err == nil && target.apiaryID == nil && target.hiveID == nil {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership target: %w", err)
+	}
+
+	var q string
+	var arg int
+	if target.apiaryID != nil {
+		arg = *target.apiaryID
+		q = `SELECT u.email, 'owner', a.name, '', NULL::timestamp
+		     FROM apiaries a JOIN users u ON u.id = a.user_id WHERE a.id = $1
+		     UNION ALL
+		     SELECT u.email, m.role, a.name, '', m.created_at
+		     FROM memberships m JOIN users u ON u.id = m.user_id JOIN apiaries a ON a.id = m.apiary_id
+		     WHERE m.apiary_id = $1`
+	} else {
+		arg = *target.hiveID
+		q = `SELECT u.email, 'owner', '', h.name, NULL::timestamp
+		     FROM hives h JOIN users u ON u.id = h.user_id WHERE h.id = $1
+		     UNION ALL
+		     SELECT u.email, m.role, COALESCE(a.name, ''), COALESCE(mh.name, ''), m.created_at
+		     FROM hives h
+		     JOIN memberships m ON m.hive_id = h.id OR m.apiary_id = h.apiary_id
+		     JOIN users u ON u.id = m.user_id
+		     LEFT JOIN apiaries a ON a.id = m.apiary_id
+		     LEFT JOIN hives mh ON mh.id = m.hive_id
+		     WHERE h.id = $1`
+	}
+	rows, err := db.pull.Query(ctx, q, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []dbTypes.Member
+	for rows.Next() {
+		var m dbTypes.Member
+		if err := rows.Scan(&m.Email, &m.Role, &m.Apiary, &m.Hive, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// SetMemberRole меняет роль участника пасеки apiary или улья hive. Если участника нет —
+// ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) SetMemberRole(ctx context.Context, email string, data httpType.UpdateMember) error {
+	q := `UPDATE memberships m SET role = $5
+	      FROM (` + targetIDs("$6", membership.RoleViewer) + `) AS t(apiary_id, hive_id)
+	      WHERE m.user_id = (SELECT id FROM users WHERE email = $4)
+	        AND (m.apiary_id = t.apiary_id OR m.hive_id = t.hive_id)`
+	res, err := db.pull.Exec(ctx, q, email, data.Apiary, data.Hive, data.Email, data.Role, data.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to set member role: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to set member role: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// RemoveMember исключает участника из пасеки apiary или улья hive. Если участника нет —
+// ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) RemoveMember(ctx context.Context, email string, data httpType.RemoveMember) error {
+	q := `DELETE FROM memberships m
+	      USING (` + targetIDs("$5", membership.RoleViewer) + `) AS t(apiary_id, hive_id)
+	      WHERE m.user_id = (SELECT id FROM users WHERE email = $4)
+	        AND (m.apiary_id = t.apiary_id OR m.hive_id = t.hive_id)`
+	res, err := db.pull.Exec(ctx, q, email, data.Apiary, data.Hive, data.Email, data.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to remove member: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// GetAlertRecipients возвращает, кому слать алерты по улью hive владельца ownerEmail:
+// владельцу и всем участникам улья и его пасеки. Участник, у которого есть роли и
+// в улье, и на пасеке, возвращается один раз с наивысшей ролью. У всех получателей
+// HiveID — id улья владельца; если улья нет, возвращается один владелец с нулевым id.
+func (db *Postgres) GetAlertRecipients(ctx context.Context, ownerEmail, hive string) ([]dbTypes.Member, error) {
+	owner := dbTypes.Member{Email: ownerEmail, Role: membership.RoleOwner, Hive: hive}
+	q := `SELECT h.id FROM hives h JOIN users o ON o.id = h.user_id WHERE o.email = $1 AND h.name = $2`
+	err := db.pull.QueryRow(ctx, q, ownerEmail, hive).Scan(&owner.HiveID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []dbTypes.Member{owner}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert hive: %w", err)
+	}
+
+	q = `SELECT u.email, m.role
+	      FROM hives h
+	      JOIN memberships m ON m.hive_id = h.id OR m.apiary_id = h.apiary_id
+	      JOIN users u ON u.id = m.user_id
+	      WHERE h.id = $1 AND u.email <> $2
+	      ORDER BY ` + roleRank("m.role") + ` DESC, u.email`
+	rows, err := db.pull.Query(ctx, q, owner.HiveID, ownerEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []dbTypes.Member{owner}
+	seen := map[string]bool{ownerEmail: true}
+	for rows.Next() {
+		m := dbTypes.Member{Hive: hive, HiveID: owner.HiveID}
+		if err := rows.Scan(&m.Email, &m.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan alert recipient: %w", err)
+		}
+		// строки идут по убыванию роли, поэтому первая — наивысшая
+		if seen[m.Email] {
+			continue
+		}
+		seen[m.Email] = true
+		recipients = append(recipients, m)
+	}
+	return recipients, rows.Err()
+}
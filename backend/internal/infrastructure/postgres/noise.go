@@ -8,19 +8,36 @@ import (
 )
 
 func (db *Postgres) NewNoise(ctx context.Context, noise httpType.NoiseLevel) error {
-	text := `INSERT INTO noise (hub_id, level, recorded_at)
-             SELECT id, $3, $4
+	text := `INSERT INTO noise (hub_id, level, recorded_at, quality)
+             SELECT id, $3, $4, $5
              FROM hubs
              WHERE email = $1 AND sensor = $2
              ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
-	_, err := db.pull.Exec(ctx, text, noise.Email, noise.Hub, noise.Level, noise.Time)
+	_, err := db.pull.Exec(ctx, text, noise.Email, noise.Hub, noise.Level, noise.Time, qualityOrOK(noise.Quality))
+	return err
+}
+
+// NewNoiseBatch пачкой записывает замеры в таблицу noise. Дубликаты по
+// (hub_id, recorded_at) молча пропускаются, поэтому повторная выгрузка
+// того же бэклога безопасна.
+func (db *Postgres) NewNoiseBatch(ctx context.Context, batch httpType.TelemetryBatch) error {
+	if len(batch.Samples) == 0 {
+		return nil
+	}
+	levels, times, qualities := splitSamples(batch.Samples)
+	text := `INSERT INTO noise (hub_id, level, recorded_at, quality)
+             SELECT h.id, s.level, s.recorded_at, s.quality
+             FROM hubs h, unnest($3::float8[], $4::timestamp[], $5::text[]) AS s(level, recorded_at, quality)
+             WHERE h.email = $1 AND h.sensor = $2
+             ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
+	_, err := db.pull.Exec(ctx, text, batch.Email, batch.Hub, levels, times, qualities)
 	return err
 }
 
 func (db *Postgres) GetNoiseSinceTime(ctx context.Context, email, hub string, t time.Time) ([]dbTypes.HivesNoiseData, error) {
-	text := `SELECT level, recorded_at FROM noise n
+	text := `SELECT level, recorded_at, quality FROM noise n
              INNER JOIN hubs h ON n.hub_id = h.id
-	         WHERE h.email = $1 AND h.sensor = $2 AND n.recorded_at >= $3
+	         WHERE h.id = ` + viewerHub + ` AND n.recorded_at >= $3
              ORDER BY n.recorded_at ASC;`
 	rows, err := db.pull.Query(ctx, text, email, hub, t)
 	if err != nil {
@@ -28,6 +45,27 @@ func (db *Postgres) GetNoiseSinceTime(ctx context.Context, email, hub string, t
 	}
 	defer rows.Close()
 	var noiseLevels []dbTypes.HivesNoiseData
+	for rows.Next() {
+		var n dbTypes.HivesNoiseData
+		if err := rows.Scan(&n.Level, &n.Date, &n.Quality); err != nil {
+			return nil, err
+		}
+		noiseLevels = append(noiseLevels, n)
+	}
+	return noiseLevels, nil
+}
+
+// GetNoiseSinceTimeById возвращает только достоверные замеры: его читают анализаторы.
+func (db *Postgres) GetNoiseSinceTimeById(ctx context.Context, hubId int, t time.Time) ([]dbTypes.HivesNoiseData, error) {
+	text := `SELECT level, recorded_at FROM noise
+             WHERE hub_id = $1 AND recorded_at >= $2 AND quality = 'ok'
+             ORDER BY recorded_at ASC;`
+	rows, err := db.pull.Query(ctx, text, hubId, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var noiseLevels []dbTypes.HivesNoiseData
 	for rows.Next() {
 		var n dbTypes.HivesNoiseData
 		if err := rows.Scan(&n.Level, &n.Date); err != nil {
@@ -38,11 +76,14 @@ func (db *Postgres) GetNoiseSinceTime(ctx context.Context, email, hub string, t
 	return noiseLevels, nil
 }
 
+// GetNoiseSinceDay группирует достоверные замеры по суткам в часовом поясе date
+// (поясе пасеки улья); ключ — полночь суток в этом поясе.
 func (db *Postgres) GetNoiseSinceDay(ctx context.Context, hubId int, date time.Time) (map[time.Time][]dbTypes.HivesNoiseData, error) {
 	text := `SELECT level, recorded_at FROM noise
-			 WHERE hub_id = $1 AND recorded_at >= $2
+			 WHERE hub_id = $1 AND recorded_at >= $2 AND quality = 'ok'
              ORDER BY recorded_at ASC;`
-	rows, err := db.pull.Query(ctx, text, hubId, date)
+	// recorded_at хранится в UTC, а pgx отбрасывает пояс у параметров TIMESTAMP
+	rows, err := db.pull.Query(ctx, text, hubId, date.UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -53,12 +94,14 @@ func (db *Postgres) GetNoiseSinceDay(ctx context.Context, hubId int, date time.T
 		if err := rows.Scan(&noiseData.Level, &noiseData.Date); err != nil {
 			return nil, err
 		}
-		day := db.createStartDayTime(noiseData.Date.Year(), noiseData.Date.Month(), noiseData.Date.Day())
+		day := db.createStartDayTime(noiseData.Date, date.Location())
 		noiseDataMap[day] = append(noiseDataMap[day], noiseData)
 	}
 	return noiseDataMap, nil
 }
 
-func (db *Postgres) createStartDayTime(year int, month time.Month, day int) time.Time {
-	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+// createStartDayTime — полночь суток в поясе loc, в которые попадает t.
+func (db *Postgres) createStartDayTime(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
 }
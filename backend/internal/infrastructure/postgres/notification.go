@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NewNotification сохраняет уведомление во входящие пользователя. Улей задаётся id
+// (n.HiveID): его находят один раз по владельцу, поэтому уведомление участника
+// привязывается к тому же улью. Нулевой id — уведомление без привязки к улью.
+func (db *Postgres) NewNotification(ctx context.Context, email string, n dbTypes.Notification) (int64, error) {
+	q := `INSERT INTO notifications (user_id, hive_id, type, severity, title, body)
+	      SELECT u.id, NULLIF($2::int, 0), $3, $4, $5, $6
+	      FROM users u WHERE u.email = $1
+	      RETURNING id`
+	var id int64
+	err := db.pull.QueryRow(ctx, q, email, n.HiveID, n.Type, n.Severity, n.Title, n.Body).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return id, nil
+}
+
+// GetNotifications возвращает страницу входящих уведомлений, от новых к старым.
+func (db *Postgres) GetNotifications(ctx context.Context, email string, unreadOnly bool,
+	limit, offset int) ([]dbTypes.Notification, error) {
+	q := `SELECT n.id, COALESCE(h.name, ''), n.type, n.severity, n.title, n.body, n.created_at, n.read_at
+	      FROM notifications n
+	      JOIN users u ON n.user_id = u.id
+	      LEFT JOIN hives h ON n.hive_id = h.id
+	      WHERE u.email = $1 AND (NOT $2 OR n.read_at IS NULL)
+	      ORDER BY n.created_at DESC, n.id DESC
+	      LIMIT $3 OFFSET $4`
+	rows, err := db.pull.Query(ctx, q, email, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+	var result []dbTypes.Notification
+	for rows.Next() {
+		var n dbTypes.Notification
+		if err := rows.Scan(&n.ID, &n.Hive, &n.Type, &n.Severity, &n.Title, &n.Body, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+// CountNotifications возвращает общее число уведомлений пользователя и число непрочитанных.
+func (db *Postgres) CountNotifications(ctx context.Context, email string) (total, unread int, err error) {
+	q := `SELECT COUNT(*), COUNT(*) FILTER (WHERE n.read_at IS NULL)
+	      FROM notifications n
+	      JOIN users u ON n.user_id = u.id
+	      WHERE u.email = $1`
+	if err := db.pull.QueryRow(ctx, q, email).Scan(&total, &unread); err != nil {
+		return 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return total, unread, nil
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления ids; пустой ids — все уведомления
+// пользователя. Уже прочитанные не трогает. Возвращает число отмеченных.
+func (db *Postgres) MarkNotificationsRead(ctx context.Context, email string, ids []int64) (int64, error) {
+	q := `UPDATE notifications n SET read_at = now()
+	      FROM users u
+	      WHERE n.user_id = u.id AND u.email = $1 AND n.read_at IS NULL
+	        AND (cardinality($2::bigint[]) = 0 OR n.id = ANY($2))`
+	if ids == nil {
+		ids = []int64{}
+	}
+	res, err := db.pull.Exec(ctx, q, email, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
+
+// DeleteNotification удаляет уведомление. Если у пользователя его нет,
+// возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) DeleteNotification(ctx context.Context, email string, id int64) error {
+	q := `DELETE FROM notifications n
+	      USING users u
+	      WHERE n.user_id = u.id AND u.email = $1 AND n.id = $2`
+	res, err := db.pull.Exec(ctx, q, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete notification: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
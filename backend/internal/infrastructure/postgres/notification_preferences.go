@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/notifyTypes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetNotificationPreferences возвращает настройки уведомлений. Пока пользователь
+// их не менял — значения по умолчанию из схемы (только push, без фильтров).
+func (db *Postgres) GetNotificationPreferences(ctx context.Context, email string) (dbTypes.NotificationPreferences, error) {
+	q := `SELECT COALESCE(p.channels, '{push}'), COALESCE(p.webhook_url, ''), COALESCE(p.webhook_secret, ''),
+	             COALESCE(p.alert_types, '{}'), COALESCE(p.min_severity, 'info'), COALESCE(p.timezone, 'UTC'),
+	             COALESCE(p.quiet_hours, '[]'), COALESCE(p.digest, 'off'), COALESCE(p.updated_at, 'epoch'::timestamptz)
+	      FROM users u
+	      LEFT JOIN notification_preferences p ON p.user_id = u.id
+	      WHERE u.email = $1`
+	var p dbTypes.NotificationPreferences
+	err := db.pull.QueryRow(ctx, q, email).Scan(&p.Channels, &p.WebhookURL, &p.WebhookSecret,
+		&p.AlertTypes, &p.MinSeverity, &p.Timezone, &p.QuietHours, &p.Digest, &p.UpdatedAt)
+	if err != nil {
+		return p, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return p, nil
+}
+
+// SetNotificationPreferences сохраняет настройки уведомлений целиком.
+// Если пользователя нет, возвращает ошибку, обёртывающую pgx.ErrNoRows.
+func (db *Postgres) SetNotificationPreferences(ctx context.Context, email string, p dbTypes.NotificationPreferences) error {
+	q := `INSERT INTO notification_preferences (user_id, channels, webhook_url, webhook_secret,
+	                                            alert_types, min_severity, timezone, quiet_hours, digest)
+	      SELECT u.id, $2, $3, $4, $5, $6, $7, $8, $9 FROM users u WHERE u.email = $1
+	      ON CONFLICT (user_id) DO UPDATE SET
+	          channels = EXCLUDED.channels, webhook_url = EXCLUDED.webhook_url,
+	          webhook_secret = EXCLUDED.webhook_secret, alert_types = EXCLUDED.alert_types,
+	          min_severity = EXCLUDED.min_severity, timezone = EXCLUDED.timezone,
+	          quiet_hours = EXCLUDED.quiet_hours, digest = EXCLUDED.digest, updated_at = now()`
+	channels := p.Channels
+	if channels == nil {
+		channels = []string{}
+	}
+	alertTypes := p.AlertTypes
+	if alertTypes == nil {
+		alertTypes = map[string]dbTypes.AlertTypePreference{}
+	}
+	quietHours := p.QuietHours
+	if quietHours == nil {
+		quietHours = []dbTypes.QuietHours{}
+	}
+	res, err := db.pull.Exec(ctx, q, email, channels, p.WebhookURL, p.WebhookSecret,
+		alertTypes, p.MinSeverity, p.Timezone, quietHours, p.Digest)
+	if err != nil {
+		return fmt.Errorf("failed to set notification preferences: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to set notification preferences: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// HoldNotification откладывает уведомление до конца тихих часов пользователя.
+func (db *Postgres) HoldNotification(ctx context.Context, email string, msg notifyTypes.Message) error {
+	q := `INSERT INTO held_notifications (user_id, message)
+	      SELECT u.id, $2 FROM users u WHERE u.email = $1`
+	res, err := db.pull.Exec(ctx, q, email, msg)
+	if err != nil {
+		return fmt.Errorf("failed to hold notification: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("failed to hold notification: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// GetHeldNotificationUsers возвращает пользователей, у которых есть отложенные уведомления.
+func (db *Postgres) GetHeldNotificationUsers(ctx context.Context) ([]string, error) {
+	q := `SELECT DISTINCT u.email FROM held_notifications n JOIN users u ON n.user_id = u.id`
+	rows, err := db.pull.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get held notification users: %w", err)
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, fmt.Errorf("failed to scan held notification user: %w", err)
+		}
+		result = append(result, email)
+	}
+	return result, rows.Err()
+}
+
+// ProcessHeldNotifications передаёт в fn отложенные уведомления пользователя, от старых
+// к новым, и удаляет те, чьи id вернула fn, — то есть только доставленные. Всё происходит
+// в одной транзакции, строки заблокированы до её конца (FOR UPDATE SKIP LOCKED), поэтому
+// другая реплика ту же сводку параллельно не отправит.
+func (db *Postgres) ProcessHeldNotifications(ctx context.Context, email string, fn func(held []notifyTypes.Held) []int64) error {
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin held notifications transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	q := `SELECT n.id, n.message
+	      FROM held_notifications n
+	      JOIN users u ON n.user_id = u.id
+	      WHERE u.email = $1
+	      ORDER BY n.id
+	      FOR UPDATE OF n SKIP LOCKED`
+	rows, err := tx.Query(ctx, q, email)
+	if err != nil {
+		return fmt.Errorf("failed to get held notifications: %w", err)
+	}
+	var held []notifyTypes.Held
+	for rows.Next() {
+		var h notifyTypes.Held
+		if err := rows.Scan(&h.ID, &h.Message); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan held notification: %w", err)
+		}
+		held = append(held, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get held notifications: %w", err)
+	}
+	if len(held) == 0 {
+		return nil
+	}
+
+	done := fn(held)
+	if len(done) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM held_notifications WHERE id = ANY($1)`, done); err != nil {
+		return fmt.Errorf("failed to delete held notifications: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetDigestSubscribers возвращает пользователей, включивших сводку на почту.
+func (db *Postgres) GetDigestSubscribers(ctx context.Context) ([]dbTypes.DigestSubscriber, error) {
+	q := `SELECT u.email, COALESCE(u.name, ''), p.digest, p.timezone, COALESCE(p.digest_sent_at, 'epoch'::timestamptz)
+	      FROM notification_preferences p
+	      JOIN users u ON p.user_id = u.id
+	      WHERE p.digest <> 'off'`
+	rows, err := db.pull.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest subscribers: %w", err)
+	}
+	defer rows.Close()
+	var result []dbTypes.DigestSubscriber
+	for rows.Next() {
+		var s dbTypes.DigestSubscriber
+		if err := rows.Scan(&s.Email, &s.Name, &s.Period, &s.Timezone, &s.SentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest subscriber: %w", err)
+		}
+		if s.SentAt.Unix() <= 0 {
+			s.SentAt = time.Time{}
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// SetDigestSent запоминает время отправки сводки, чтобы не отправить её повторно.
+func (db *Postgres) SetDigestSent(ctx context.Context, email string, at time.Time) error {
+	q := `UPDATE notification_preferences p SET digest_sent_at = $2
+	      FROM users u WHERE p.user_id = u.id AND u.email = $1`
+	if _, err := db.pull.Exec(ctx, q, email, at); err != nil {
+		return fmt.Errorf("failed to set digest sent: %w", err)
+	}
+	return nil
+}
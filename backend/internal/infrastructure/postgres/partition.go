@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/export"
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/spectrum"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// partitionSuffix — формат месяца в имени секции: temperature_p202605.
+const partitionSuffix = "200601"
+
+func partitionName(table string, month time.Time) string {
+	return table + "_p" + month.Format(partitionSuffix)
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// detailTables — подробности замеров (спектр шума, статусы устройств). Они секционированы
+// по месяцам, как и сырые замеры, но агрегатов по ним нет.
+var detailTables = map[string]string{
+	spectrum.Metric:     "noise_spectrum",
+	export.MetricStatus: "device_status",
+}
+
+func partitionTable(metric string) (string, error) {
+	if table, ok := rawTables[metric]; ok {
+		return table, nil
+	}
+	if table, ok := detailTables[metric]; ok {
+		return table, nil
+	}
+	return "", fmt.Errorf("unknown metric: %s", metric)
+}
+
+// EnsureTelemetryPartition создаёт секцию за месяц month, если её ещё нет, и возвращает,
+// была ли она создана. Замеры этого месяца, успевшие попасть в секцию default,
+// переносятся в новую секцию в той же транзакции — иначе ATTACH не пройдёт проверку
+// default-секции.
+func (db *Postgres) EnsureTelemetryPartition(ctx context.Context, metric string, month time.Time) (bool, error) {
+	table, err := partitionTable(metric)
+	if err != nil {
+		return false, err
+	}
+	from := monthStart(month)
+	to := from.AddDate(0, 1, 0)
+	name := partitionName(table, from)
+
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin partition transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var exists bool
+	if err = tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name, table),
+		fmt.Sprintf(`WITH moved AS (
+		     DELETE FROM %s_default WHERE recorded_at >= '%s' AND recorded_at < '%s' RETURNING *)
+		 INSERT INTO %s SELECT * FROM moved`, table, from.Format(time.DateOnly), to.Format(time.DateOnly), name),
+		fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+			table, name, from.Format(time.DateOnly), to.Format(time.DateOnly)),
+	}
+	for _, q := range stmts {
+		if _, err = tx.Exec(ctx, q); err != nil {
+			return false, fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit partition %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// GetTelemetryPartitions возвращает месячные секции таблицы метрики, отсортированные по месяцу.
+// Секция default и секции, созданные не по нашей схеме имён, не возвращаются.
+func (db *Postgres) GetTelemetryPartitions(ctx context.Context, metric string) ([]dbTypes.TelemetryPartition, error) {
+	table, err := partitionTable(metric)
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT c.relname FROM pg_inherits i
+	      INNER JOIN pg_class c ON c.oid = i.inhrelid
+	      INNER JOIN pg_class p ON p.oid = i.inhparent
+	      WHERE p.relname = $1
+	      ORDER BY c.relname`
+	rows, err := db.pull.Query(ctx, q, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", table, err)
+	}
+	defer rows.Close()
+	prefix := table + "_p"
+	var partitions []dbTypes.TelemetryPartition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		from, err := time.Parse(partitionSuffix, strings.TrimPrefix(name, prefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, dbTypes.TelemetryPartition{Name: name, From: from, To: from.AddDate(0, 1, 0)})
+	}
+	return partitions, rows.Err()
+}
+
+// GetUnpartitionedMonths возвращает месяцы, замеры за которые лежат в секции default:
+// данные, перенесённые миграцией, и замеры, пришедшие до создания секции.
+func (db *Postgres) GetUnpartitionedMonths(ctx context.Context, metric string) ([]time.Time, error) {
+	table, err := partitionTable(metric)
+	if err != nil {
+		return nil, err
+	}
+	q := fmt.Sprintf(`SELECT DISTINCT date_trunc('month', recorded_at) FROM %s_default ORDER BY 1`, table)
+	rows, err := db.pull.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unpartitioned months of %s: %w", table, err)
+	}
+	defer rows.Close()
+	var months []time.Time
+	for rows.Next() {
+		var month time.Time
+		if err := rows.Scan(&month); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
+
+// IsTelemetryPartitionRolledUp проверяет, что каждый час с замерами в секции уже
+// посчитан в telemetry_hourly и агрегат учитывает все достоверные замеры этого часа.
+// Только такую секцию можно удалять без потери истории на графиках.
+func (db *Postgres) IsTelemetryPartitionRolledUp(ctx context.Context, metric string, partition dbTypes.TelemetryPartition) (bool, error) {
+	if _, ok := rawTables[metric]; !ok {
+		return false, fmt.Errorf("metric %s has no rollups", metric)
+	}
+	q := fmt.Sprintf(`SELECT NOT EXISTS (
+	          SELECT 1 FROM (
+	              SELECT hub_id, date_trunc('hour', recorded_at) AS bucket, count(*) AS samples
+	              FROM %s WHERE quality = 'ok' GROUP BY hub_id, date_trunc('hour', recorded_at)
+	          ) r
+	          LEFT JOIN telemetry_hourly h
+	              ON h.hub_id = r.hub_id AND h.metric = $1 AND h.bucket = r.bucket
+	          WHERE h.sample_count IS DISTINCT FROM r.samples)`, partition.Name)
+	var covered bool
+	if err := db.pull.QueryRow(ctx, q, metric).Scan(&covered); err != nil {
+		return false, fmt.Errorf("failed to check rollup coverage of %s: %w", partition.Name, err)
+	}
+	return covered, nil
+}
+
+// DropTelemetryPartition удаляет месячную секцию вместе с сырыми замерами.
+func (db *Postgres) DropTelemetryPartition(ctx context.Context, metric string, partition dbTypes.TelemetryPartition) error {
+	table, err := partitionTable(metric)
+	if err != nil {
+		return err
+	}
+	// Имя сверяем со схемой, чтобы случайно не удалить default или чужую таблицу
+	if partition.Name != partitionName(table, partition.From) {
+		return fmt.Errorf("unexpected partition name %s", partition.Name)
+	}
+	if _, err := db.pull.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, partition.Name)); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", partition.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetRecentTelemetry возвращает историю метрики хаба до before по возрастанию времени —
+// для проверки достоверности нового замера: до limit последних замеров и первый замер
+// текущей серии одинаковых значений, если серия началась раньше них, но не раньше since.
+// По началу серии видно залипание датчика, сколько бы замеров в ней ни было.
+// Отбракованные замеры тоже возвращаются: залипший датчик продолжает серию.
+func (db *Postgres) GetRecentTelemetry(ctx context.Context, email, hub, metric string, since, before time.Time, limit int) ([]dbTypes.TelemetrySample, error) {
+	table, ok := rawTables[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric: %s", metric)
+	}
+	q := fmt.Sprintf(`WITH hub AS (SELECT id FROM hubs WHERE email = $1 AND sensor = $2),
+	      last AS (SELECT t.level FROM %[1]s t, hub
+	               WHERE t.hub_id = hub.id AND t.recorded_at < $3
+	               ORDER BY t.recorded_at DESC LIMIT 1),
+	      changed AS (SELECT max(t.recorded_at) AS at FROM %[1]s t, hub, last
+	                  WHERE t.hub_id = hub.id AND t.recorded_at >= $5 AND t.recorded_at < $3
+	                    AND t.level <> last.level)
+	      SELECT level, recorded_at, quality FROM (
+	          (SELECT t.level, t.recorded_at, t.quality FROM %[1]s t, hub
+	           WHERE t.hub_id = hub.id AND t.recorded_at < $3
+	           ORDER BY t.recorded_at DESC
+	           LIMIT $4)
+	          UNION
+	          (SELECT t.level, t.recorded_at, t.quality FROM %[1]s t, hub, changed
+	           WHERE t.hub_id = hub.id AND t.recorded_at >= $5 AND t.recorded_at < $3
+	             AND (changed.at IS NULL OR t.recorded_at > changed.at)
+	           ORDER BY t.recorded_at ASC
+	           LIMIT 1)) recent
+	      ORDER BY recorded_at ASC`, table)
+	rows, err := db.pull.Query(ctx, q, email, hub, before, limit, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent %s samples: %w", metric, err)
+	}
+	defer rows.Close()
+	var samples []dbTypes.TelemetrySample
+	for rows.Next() {
+		var s dbTypes.TelemetrySample
+		if err := rows.Scan(&s.Value, &s.Date, &s.Quality); err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
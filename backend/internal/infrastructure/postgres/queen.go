@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"context"
@@ -16,8 +17,9 @@ func (d *Postgres) NewQueen(ctx context.Context, email, name, startDate string)
 	return nil
 }
 
+// GetQueens возвращает маток пользователя и маток ульев, где он участник.
 func (d *Postgres) GetQueens(ctx context.Context, email string) ([]dbTypes.Queen, error) {
-	q := `SELECT id, email, name, start_date FROM queens WHERE email = $1`
+	q := `SELECT q.id, q.email, q.name, q.start_date FROM queens q WHERE ` + queenAccess("q", "$1", membership.RoleViewer)
 	rows, err := d.pull.Query(ctx, q, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get queens: %w", err)
@@ -36,7 +38,7 @@ func (d *Postgres) GetQueens(ctx context.Context, email string) ([]dbTypes.Queen
 }
 
 func (d *Postgres) GetQueenByName(ctx context.Context, email, name string) (dbTypes.Queen, error) {
-	q := `SELECT id, email, name, start_date FROM queens WHERE email = $1 AND name = $2`
+	q := `SELECT id, email, name, start_date FROM queens WHERE id = ` + accessibleQueen("$1", "$2", membership.RoleViewer)
 	var qn dbTypes.Queen
 	err := d.pull.QueryRow(ctx, q, email, name).Scan(&qn.Id, &qn.Email, &qn.Name, &qn.StartDate)
 	if err != nil {
@@ -45,8 +47,9 @@ func (d *Postgres) GetQueenByName(ctx context.Context, email, name string) (dbTy
 	return qn, nil
 }
 
+// DeleteQueen удаляет матку; удалить её может только владелец.
 func (d *Postgres) DeleteQueen(ctx context.Context, email, name string) error {
-	q := `DELETE FROM queens WHERE email = $1 AND name = $2`
+	q := `DELETE FROM queens WHERE id = ` + accessibleQueen("$1", "$2", membership.RoleOwner)
 	_, err := d.pull.Exec(ctx, q, email, name)
 	if err != nil {
 		return fmt.Errorf("failed to delete queen: %w", err)
@@ -77,7 +80,7 @@ func (d *Postgres) UpdateQueen(ctx context.Context, email string, data httpType.
 		return nil // Nothing to update
 	}
 
-	queryStr += " WHERE email = $1 AND name = $2"
+	queryStr += " WHERE id = " + accessibleQueen("$1", "$2", membership.RoleEditor)
 
 	res, err := d.pull.Exec(ctx, queryStr, args...)
 	if err != nil {
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/rollup"
+	"context"
+	"fmt"
+	"time"
+)
+
+// rawTables сопоставляет метрику с таблицей сырых замеров. Имя таблицы подставляется
+// в запрос строкой, поэтому берём его только отсюда, а не из запроса клиента.
+var rawTables = map[string]string{
+	rollup.MetricTemperature: "temperature",
+	rollup.MetricNoise:       "noise",
+	rollup.MetricWeight:      "weight",
+}
+
+var rollupTables = map[string]string{
+	rollup.ResolutionHour: "telemetry_hourly",
+	rollup.ResolutionDay:  "telemetry_daily",
+}
+
+// RefreshRollups пересчитывает часовые агрегаты по сырым замерам начиная с часа,
+// в который попадает since, и суточные — по часовым начиная с суток since.
+// Интервалы пересчитываются целиком, поэтому повторный запуск и досланный
+// задним числом бэклог дают те же значения, что и однократный расчёт.
+// Отбракованные замеры (см. domain/quality) в агрегаты не попадают.
+func (db *Postgres) RefreshRollups(ctx context.Context, since time.Time) error {
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin rollup transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, metric := range rollup.Metrics {
+		q := fmt.Sprintf(`INSERT INTO telemetry_hourly (hub_id, metric, bucket, min_value, max_value, avg_value, sample_count)
+		      SELECT hub_id, $2, date_trunc('hour', recorded_at), min(level), max(level), avg(level), count(*)
+		      FROM %s
+		      WHERE recorded_at >= date_trunc('hour', $1::timestamp) AND quality = 'ok'
+		      GROUP BY hub_id, date_trunc('hour', recorded_at)
+		      ON CONFLICT (hub_id, metric, bucket) DO UPDATE SET
+		          min_value = EXCLUDED.min_value,
+		          max_value = EXCLUDED.max_value,
+		          avg_value = EXCLUDED.avg_value,
+		          sample_count = EXCLUDED.sample_count`, rawTables[metric])
+		if _, err = tx.Exec(ctx, q, since, metric); err != nil {
+			return fmt.Errorf("failed to refresh hourly %s rollup: %w", metric, err)
+		}
+	}
+
+	// Среднее за сутки взвешиваем по числу замеров в часе: частота опроса
+	// могла меняться в течение дня.
+	q := `INSERT INTO telemetry_daily (hub_id, metric, bucket, min_value, max_value, avg_value, sample_count)
+	      SELECT hub_id, metric, date_trunc('day', bucket), min(min_value), max(max_value),
+	             sum(avg_value * sample_count) / sum(sample_count), sum(sample_count)
+	      FROM telemetry_hourly
+	      WHERE bucket >= date_trunc('day', $1::timestamp)
+	      GROUP BY hub_id, metric, date_trunc('day', bucket)
+	      ON CONFLICT (hub_id, metric, bucket) DO UPDATE SET
+	          min_value = EXCLUDED.min_value,
+	          max_value = EXCLUDED.max_value,
+	          avg_value = EXCLUDED.avg_value,
+	          sample_count = EXCLUDED.sample_count`
+	if _, err = tx.Exec(ctx, q, since); err != nil {
+		return fmt.Errorf("failed to refresh daily rollup: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetLatestRollupTime возвращает начало последнего посчитанного часа
+// или нулевое время, если агрегатов ещё нет.
+func (db *Postgres) GetLatestRollupTime(ctx context.Context) (time.Time, error) {
+	q := `SELECT COALESCE(max(bucket), '0001-01-01'::timestamp) FROM telemetry_hourly`
+	var latest time.Time
+	if err := db.pull.QueryRow(ctx, q).Scan(&latest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get latest rollup: %w", err)
+	}
+	return latest, nil
+}
+
+// GetTelemetryRollup возвращает часовые или суточные агрегаты метрики хаба начиная с since.
+// Интервал, в который попадает since, включается целиком.
+func (db *Postgres) GetTelemetryRollup(ctx context.Context, email, hub, metric, resolution string, since time.Time) ([]dbTypes.TelemetryRollup, error) {
+	table, ok := rollupTables[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unknown rollup resolution: %s", resolution)
+	}
+	trunc := "hour"
+	if resolution == rollup.ResolutionDay {
+		trunc = "day"
+	}
+	q := fmt.Sprintf(`SELECT r.bucket, r.min_value, r.max_value, r.avg_value, r.sample_count
+	      FROM %s r
+	      INNER JOIN hubs h ON h.id = r.hub_id
+	      WHERE h.id = %s AND r.metric = $3
+	        AND r.bucket >= date_trunc('%s', $4::timestamp)
+	      ORDER BY r.bucket ASC`, table, viewerHub, trunc)
+	rows, err := db.pull.Query(ctx, q, email, hub, metric, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s rollup: %w", resolution, err)
+	}
+	defer rows.Close()
+	var result []dbTypes.TelemetryRollup
+	for rows.Next() {
+		var r dbTypes.TelemetryRollup
+		if err := rows.Scan(&r.Date, &r.Min, &r.Max, &r.Avg, &r.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// CountTelemetrySinceTime возвращает количество сырых замеров метрики хаба начиная с since.
+func (db *Postgres) CountTelemetrySinceTime(ctx context.Context, email, hub, metric string, since time.Time) (int, error) {
+	table, ok := rawTables[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown metric: %s", metric)
+	}
+	q := fmt.Sprintf(`SELECT count(*) FROM %s t
+	      INNER JOIN hubs h ON h.id = t.hub_id
+	      WHERE h.id = %s AND t.recorded_at >= $3`, table, viewerHub)
+	var n int
+	if err := db.pull.QueryRow(ctx, q, email, hub, since).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count %s samples: %w", metric, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetDesiredConfig сохраняет желаемую конфигурацию хаба. Параметры со значением -1
+// не меняются — так можно обновить, например, только частоту статуса.
+func (d *Postgres) SetDesiredConfig(ctx context.Context, email, sensor string, config httpType.ShadowConfig) error {
+	q := `INSERT INTO device_shadow (hub_id, desired_sampling_noise, desired_sampling_temp, desired_frequency, desired_at)
+	      SELECT id, $3, $4, $5, now() FROM hubs WHERE id = ` + editorHub + `
+	      ON CONFLICT (hub_id) DO UPDATE SET
+	          desired_sampling_noise = CASE WHEN EXCLUDED.desired_sampling_noise = -1
+	              THEN device_shadow.desired_sampling_noise ELSE EXCLUDED.desired_sampling_noise END,
+	          desired_sampling_temp = CASE WHEN EXCLUDED.desired_sampling_temp = -1
+	              THEN device_shadow.desired_sampling_temp ELSE EXCLUDED.desired_sampling_temp END,
+	          desired_frequency = CASE WHEN EXCLUDED.desired_frequency = -1
+	              THEN device_shadow.desired_frequency ELSE EXCLUDED.desired_frequency END,
+	          desired_at = EXCLUDED.desired_at`
+	res, err := d.pull.Exec(ctx, q, email, sensor, config.SamplingNoise, config.SamplingTemp, config.Frequency)
+	if err != nil {
+		return fmt.Errorf("failed to set desired config: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("hub not found")
+	}
+	return nil
+}
+
+// SetReportedConfig сохраняет конфигурацию, о которой отчиталось устройство.
+func (d *Postgres) SetReportedConfig(ctx context.Context, sensor string, config httpType.ShadowConfig, reportedAt time.Time) error {
+	q := `INSERT INTO device_shadow (hub_id, reported_sampling_noise, reported_sampling_temp, reported_frequency, reported_at)
+	      SELECT id, $2, $3, $4, $5 FROM hubs WHERE sensor = $1
+	      ON CONFLICT (hub_id) DO UPDATE SET
+	          reported_sampling_noise = CASE WHEN EXCLUDED.reported_sampling_noise = -1
+	              THEN device_shadow.reported_sampling_noise ELSE EXCLUDED.reported_sampling_noise END,
+	          reported_sampling_temp = CASE WHEN EXCLUDED.reported_sampling_temp = -1
+	              THEN device_shadow.reported_sampling_temp ELSE EXCLUDED.reported_sampling_temp END,
+	          reported_frequency = CASE WHEN EXCLUDED.reported_frequency = -1
+	              THEN device_shadow.reported_frequency ELSE EXCLUDED.reported_frequency END,
+	          reported_at = EXCLUDED.reported_at`
+	_, err := d.pull.Exec(ctx, q, sensor, config.SamplingNoise, config.SamplingTemp, config.Frequency, reportedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set reported config: %w", err)
+	}
+	return nil
+}
+
+const shadowSelect = `SELECT h.id, h.sensor,
+	       COALESCE(s.desired_sampling_noise, -1), COALESCE(s.desired_sampling_temp, -1),
+	       COALESCE(s.desired_frequency, -1), s.desired_at,
+	       COALESCE(s.reported_sampling_noise, -1), COALESCE(s.reported_sampling_temp, -1),
+	       COALESCE(s.reported_frequency, -1), s.reported_at
+	FROM hubs h
+	LEFT JOIN device_shadow s ON s.hub_id = h.id`
+
+func (d *Postgres) GetShadow(ctx context.Context, email, sensor string) (dbTypes.DeviceShadow, error) {
+	q := shadowSelect + ` WHERE h.id = ` + viewerHub
+	var s dbTypes.DeviceShadow
+	err := d.pull.QueryRow(ctx, q, email, sensor).Scan(&s.HubId, &s.Sensor,
+		&s.DesiredNoise, &s.DesiredTemp, &s.DesiredFrequency, &s.DesiredAt,
+		&s.ReportedNoise, &s.ReportedTemp, &s.ReportedFrequency, &s.ReportedAt)
+	if err != nil {
+		return s, fmt.Errorf("failed to get device shadow: %w", err)
+	}
+	return s, nil
+}
+
+// GetShadowBySensor возвращает shadow хаба по идентификатору датчика.
+func (d *Postgres) GetShadowBySensor(ctx context.Context, sensor string) (dbTypes.DeviceShadow, error) {
+	q := shadowSelect + ` WHERE h.sensor = $1`
+	var s dbTypes.DeviceShadow
+	err := d.pull.QueryRow(ctx, q, sensor).Scan(&s.HubId, &s.Sensor,
+		&s.DesiredNoise, &s.DesiredTemp, &s.DesiredFrequency, &s.DesiredAt,
+		&s.ReportedNoise, &s.ReportedTemp, &s.ReportedFrequency, &s.ReportedAt)
+	if err != nil {
+		return s, fmt.Errorf("failed to get device shadow by sensor: %w", err)
+	}
+	return s, nil
+}
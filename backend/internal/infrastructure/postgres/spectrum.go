@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"BeeIOT/internal/domain/models/httpType"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (db *Postgres) NewNoiseSpectrum(ctx context.Context, s httpType.NoiseSpectrum) error {
+	text := `INSERT INTO noise_spectrum (hub_id, edges, levels, recorded_at)
+             SELECT id, $3, $4, $5
+             FROM hubs
+             WHERE email = $1 AND sensor = $2
+             ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
+	_, err := db.pull.Exec(ctx, text, s.Email, s.Hub, s.Edges, s.Levels, s.Time)
+	return err
+}
+
+// NewNoiseSpectrumBatch записывает спектры из оффлайн-бэклога одной транзакцией.
+// Через unnest их не развернуть: у спектров может быть разное число полос.
+func (db *Postgres) NewNoiseSpectrumBatch(ctx context.Context, spectra []httpType.NoiseSpectrum) error {
+	if len(spectra) == 0 {
+		return nil
+	}
+	tx, err := db.pull.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin spectrum transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	text := `INSERT INTO noise_spectrum (hub_id, edges, levels, recorded_at)
+             SELECT id, $3, $4, $5
+             FROM hubs
+             WHERE email = $1 AND sensor = $2
+             ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
+	for _, s := range spectra {
+		if _, err := tx.Exec(ctx, text, s.Email, s.Hub, s.Edges, s.Levels, s.Time); err != nil {
+			return fmt.Errorf("failed to insert spectrum: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *Postgres) GetNoiseSpectrumSinceTime(ctx context.Context, email, hub string, t time.Time) ([]dbTypes.HivesNoiseSpectrum, error) {
+	text := `SELECT s.edges, s.levels, s.recorded_at FROM noise_spectrum s
+             INNER JOIN hubs h ON s.hub_id = h.id
+             WHERE h.id = ` + viewerHub + ` AND s.recorded_at >= $3
+             ORDER BY s.recorded_at ASC;`
+	rows, err := db.pull.Query(ctx, text, email, hub, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var spectra []dbTypes.HivesNoiseSpectrum
+	for rows.Next() {
+		var s dbTypes.HivesNoiseSpectrum
+		if err := rows.Scan(&s.Edges, &s.Levels, &s.Date); err != nil {
+			return nil, err
+		}
+		spectra = append(spectra, s)
+	}
+	return spectra, rows.Err()
+}
+
+func (db *Postgres) GetNoiseSpectrumSinceTimeById(ctx context.Context, hubId int, t time.Time) ([]dbTypes.HivesNoiseSpectrum, error) {
+	text := `SELECT edges, levels, recorded_at FROM noise_spectrum
+             WHERE hub_id = $1 AND recorded_at >= $2
+             ORDER BY recorded_at ASC;`
+	rows, err := db.pull.Query(ctx, text, hubId, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var spectra []dbTypes.HivesNoiseSpectrum
+	for rows.Next() {
+		var s dbTypes.HivesNoiseSpectrum
+		if err := rows.Scan(&s.Edges, &s.Levels, &s.Date); err != nil {
+			return nil, err
+		}
+		spectra = append(spectra, s)
+	}
+	return spectra, rows.Err()
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"BeeIOT/internal/domain/models/dbTypes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetTelemetryStats считает статистику метрик хаба в окне [since, until) по интервалам
+// bucket (hour, day или week — единица date_trunc). Все метрики считаются одним
+// запросом; интервалы без замеров не возвращаются. Отбракованные замеры не учитываются.
+// Стандартное отклонение по одному замеру — 0.
+func (db *Postgres) GetTelemetryStats(ctx context.Context, email, hub string, metrics []string, bucket string, since, until time.Time, percentiles []float64) ([]dbTypes.TelemetryStats, error) {
+	parts := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		table, ok := rawTables[metric]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric: %s", metric)
+		}
+		// метрика приходит из rawTables, поэтому её можно подставить строкой
+		parts = append(parts, fmt.Sprintf(`SELECT '%s' AS metric, date_trunc($3, t.recorded_at) AS bucket,
+		          count(*), min(t.level), max(t.level), avg(t.level), COALESCE(stddev_samp(t.level), 0),
+		          percentile_cont($6::float8[]) WITHIN GROUP (ORDER BY t.level),
+		          (array_agg(t.level ORDER BY t.recorded_at ASC))[1], min(t.recorded_at),
+		          (array_agg(t.level ORDER BY t.recorded_at DESC))[1], max(t.recorded_at)
+		      FROM %s t
+		      INNER JOIN hubs h ON h.id = t.hub_id
+		      WHERE h.id = %s AND t.recorded_at >= $4 AND t.recorded_at < $5
+		        AND t.quality = 'ok'
+		      GROUP BY date_trunc($3, t.recorded_at)`, metric, table, viewerHub))
+	}
+	q := strings.Join(parts, "\n UNION ALL\n") + "\n ORDER BY metric, bucket"
+	rows, err := db.pull.Query(ctx, q, email, hub, bucket, since, until, percentiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get telemetry stats: %w", err)
+	}
+	defer rows.Close()
+	var result []dbTypes.TelemetryStats
+	for rows.Next() {
+		var s dbTypes.TelemetryStats
+		if err := rows.Scan(&s.Metric, &s.Bucket, &s.Count, &s.Min, &s.Max, &s.Avg, &s.StdDev,
+			&s.Percentiles, &s.First, &s.FirstAt, &s.Last, &s.LastAt); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"context"
@@ -8,29 +9,38 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// CreateTask добавляет работу по улью. Работа записывается на владельца улья, чтобы
+// её видели все участники. Если улья нет, возвращает ошибку, обёртывающую pgx.ErrNoRows.
 func (db *Postgres) CreateTask(ctx context.Context, email string, req httpType.CreateTaskRequest) (string, error) {
 	taskID := uuid.New().String()
 	q := `INSERT INTO tasks (id, email, hive_name, title, description, created_at)
-	      VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := db.pull.Exec(ctx, q, taskID, email, req.HiveName, req.Title, req.Description, time.Now())
+	      SELECT $3, u.email, h.name, $4, $5, $6
+	      FROM hives h JOIN users u ON h.user_id = u.id
+	      WHERE h.id = ` + accessibleHive("$1", "$2", "$7", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, email, req.HiveName, taskID, req.Title, req.Description, time.Now(), req.Owner)
 	if err != nil {
 		return "", fmt.Errorf("failed to create task: %w", err)
 	}
+	if res.RowsAffected() == 0 {
+		return "", fmt.Errorf("failed to create task: %w", pgx.ErrNoRows)
+	}
 	return taskID, nil
 }
 
+// GetTasks возвращает работы пользователя и работы по ульям, где он участник.
 func (db *Postgres) GetTasks(ctx context.Context, email, hiveName string) ([]dbTypes.Task, error) {
-	q := `SELECT id, email, hive_name, title, description, created_at FROM tasks
-	      WHERE email = $1`
+	q := `SELECT t.id, t.email, t.hive_name, t.title, t.description, t.created_at FROM tasks t
+	      WHERE ` + taskAccess("t", "$1", membership.RoleViewer)
 	args := []interface{}{email}
 
 	if hiveName != "" {
-		q += ` AND hive_name = $2`
+		q += ` AND t.hive_name = $2`
 		args = append(args, hiveName)
 	}
-	q += ` ORDER BY created_at DESC`
+	q += ` ORDER BY t.created_at DESC`
 
 	rows, err := db.pull.Query(ctx, q, args...)
 	if err != nil {
@@ -66,10 +76,6 @@ func (db *Postgres) UpdateTask(ctx context.Context, email string, req httpType.U
 		return err
 	}
 
-	if task.Email != email {
-		return fmt.Errorf("unauthorized to update this task")
-	}
-
 	if req.Title != nil {
 		task.Title = *req.Title
 	}
@@ -77,34 +83,29 @@ func (db *Postgres) UpdateTask(ctx context.Context, email string, req httpType.U
 		task.Description = *req.Description
 	}
 
-	q := `UPDATE tasks SET title = $1, description = $2 WHERE id = $3`
-	res, err := db.pull.Exec(ctx, q, task.Title, task.Description, req.ID)
+	q := `UPDATE tasks t SET title = $1, description = $2 WHERE t.id = $3 AND ` + taskAccess("t", "$4", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, task.Title, task.Description, req.ID, email)
 	if err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 	if res.RowsAffected() == 0 {
-		return fmt.Errorf("task not found")
+		return fmt.Errorf("unauthorized to update this task")
 	}
 	return nil
 }
 
 func (db *Postgres) DeleteTask(ctx context.Context, email, taskID string) error {
-	task, err := db.GetTaskByID(ctx, taskID)
-	if err != nil {
+	if _, err := db.GetTaskByID(ctx, taskID); err != nil {
 		return err
 	}
 
-	if task.Email != email {
-		return fmt.Errorf("unauthorized to delete this task")
-	}
-
-	q := `DELETE FROM tasks WHERE id = $1`
-	res, err := db.pull.Exec(ctx, q, taskID)
+	q := `DELETE FROM tasks t WHERE t.id = $1 AND ` + taskAccess("t", "$2", membership.RoleEditor)
+	res, err := db.pull.Exec(ctx, q, taskID, email)
 	if err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 	if res.RowsAffected() == 0 {
-		return fmt.Errorf("task not found")
+		return fmt.Errorf("unauthorized to delete this task")
 	}
 	return nil
 }
@@ -8,19 +8,36 @@ import (
 )
 
 func (db *Postgres) NewTemperature(ctx context.Context, temp httpType.Temperature) error {
-	text := `INSERT INTO temperature (hub_id, level, recorded_at)
-             SELECT id, $3, $4
+	text := `INSERT INTO temperature (hub_id, level, recorded_at, quality)
+             SELECT id, $3, $4, $5
              FROM hubs
              WHERE email = $1 AND sensor = $2
              ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
-	_, err := db.pull.Exec(ctx, text, temp.Email, temp.Hub, temp.Temperature, temp.Time)
+	_, err := db.pull.Exec(ctx, text, temp.Email, temp.Hub, temp.Temperature, temp.Time, qualityOrOK(temp.Quality))
+	return err
+}
+
+// NewTemperatureBatch пачкой записывает замеры в таблицу temperature. Дубликаты по
+// (hub_id, recorded_at) молча пропускаются, поэтому повторная выгрузка
+// того же бэклога безопасна.
+func (db *Postgres) NewTemperatureBatch(ctx context.Context, batch httpType.TelemetryBatch) error {
+	if len(batch.Samples) == 0 {
+		return nil
+	}
+	levels, times, qualities := splitSamples(batch.Samples)
+	text := `INSERT INTO temperature (hub_id, level, recorded_at, quality)
+             SELECT h.id, s.level, s.recorded_at, s.quality
+             FROM hubs h, unnest($3::float8[], $4::timestamp[], $5::text[]) AS s(level, recorded_at, quality)
+             WHERE h.email = $1 AND h.sensor = $2
+             ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
+	_, err := db.pull.Exec(ctx, text, batch.Email, batch.Hub, levels, times, qualities)
 	return err
 }
 
 func (db *Postgres) GetTemperaturesSinceTime(ctx context.Context, email, hub string, t time.Time) ([]dbTypes.HivesTemperatureData, error) {
-	text := `SELECT level, recorded_at FROM temperature t
+	text := `SELECT level, recorded_at, quality FROM temperature t
              INNER JOIN hubs h ON t.hub_id = h.id
-             WHERE h.email = $1 AND h.sensor = $2 AND t.recorded_at >= $3
+             WHERE h.id = ` + viewerHub + ` AND t.recorded_at >= $3
              ORDER BY t.recorded_at ASC;`
 	rows, err := db.pull.Query(ctx, text, email, hub, t)
 	if err != nil {
@@ -30,7 +47,7 @@ func (db *Postgres) GetTemperaturesSinceTime(ctx context.Context, email, hub str
 	var temperatures []dbTypes.HivesTemperatureData
 	for rows.Next() {
 		var temp dbTypes.HivesTemperatureData
-		if err := rows.Scan(&temp.Temperature, &temp.Date); err != nil {
+		if err := rows.Scan(&temp.Temperature, &temp.Date, &temp.Quality); err != nil {
 			return nil, err
 		}
 		temperatures = append(temperatures, temp)
@@ -38,10 +55,11 @@ func (db *Postgres) GetTemperaturesSinceTime(ctx context.Context, email, hub str
 	return temperatures, nil
 }
 
+// GetTemperaturesSinceTimeById возвращает только достоверные замеры: его читают анализаторы.
 func (db *Postgres) GetTemperaturesSinceTimeById(ctx context.Context, hubId int, t time.Time) ([]dbTypes.HivesTemperatureData, error) {
 	text := `SELECT level, recorded_at FROM temperature
              WHERE hub_id = $1
-			 AND recorded_at >= $2
+			 AND recorded_at >= $2 AND quality = 'ok'
              ORDER BY recorded_at ASC;`
 	rows, err := db.pull.Query(ctx, text, hubId, t)
 	if err != nil {
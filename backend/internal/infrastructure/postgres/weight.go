@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"BeeIOT/internal/domain/membership"
 	"BeeIOT/internal/domain/models/dbTypes"
 	"BeeIOT/internal/domain/models/httpType"
 	"context"
@@ -8,12 +9,29 @@ import (
 )
 
 func (db *Postgres) NewHiveWeight(ctx context.Context, weight httpType.HubWeight) error {
-	text := `INSERT INTO weight (hub_id, level, recorded_at)
-             SELECT id, $1, $2
+	text := `INSERT INTO weight (hub_id, level, recorded_at, quality)
+             SELECT id, $1, $2, $5
              FROM hubs
-			 WHERE email = $3 AND sensor = $4
+			 WHERE id = ` + accessibleHub("$3", "$4", membership.RoleEditor) + `
 			 ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
-	_, err := db.pull.Exec(ctx, text, weight.Weight, weight.Time, weight.Email, weight.Hub)
+	_, err := db.pull.Exec(ctx, text, weight.Weight, weight.Time, weight.Email, weight.Hub, qualityOrOK(weight.Quality))
+	return err
+}
+
+// NewHiveWeightBatch пачкой записывает замеры в таблицу weight. Дубликаты по
+// (hub_id, recorded_at) молча пропускаются, поэтому повторная выгрузка
+// того же бэклога безопасна.
+func (db *Postgres) NewHiveWeightBatch(ctx context.Context, batch httpType.TelemetryBatch) error {
+	if len(batch.Samples) == 0 {
+		return nil
+	}
+	levels, times, qualities := splitSamples(batch.Samples)
+	text := `INSERT INTO weight (hub_id, level, recorded_at, quality)
+             SELECT h.id, s.level, s.recorded_at, s.quality
+             FROM hubs h, unnest($3::float8[], $4::timestamp[], $5::text[]) AS s(level, recorded_at, quality)
+             WHERE h.email = $1 AND h.sensor = $2
+             ON CONFLICT (hub_id, recorded_at) DO NOTHING;`
+	_, err := db.pull.Exec(ctx, text, batch.Email, batch.Hub, levels, times, qualities)
 	return err
 }
 
@@ -21,18 +39,17 @@ func (db *Postgres) DeleteHiveWeight(ctx context.Context, weight httpType.HubWei
 	text := `DELETE FROM weight w
              USING hubs h
              WHERE w.hub_id = h.id
-              AND h.email = $1
-              AND h.sensor = $2
+              AND h.id = ` + editorHub + `
               AND w.recorded_at = $3;`
 	_, err := db.pull.Exec(ctx, text, weight.Email, weight.Hub, weight.Time)
 	return err
 }
 
 func (db *Postgres) GetWeightSinceTime(ctx context.Context, email, hub string, t time.Time) ([]dbTypes.HivesWeightData, error) {
-	text := `SELECT level, recorded_at
+	text := `SELECT level, recorded_at, quality
              FROM weight w
              INNER JOIN hubs h ON h.id = w.hub_id
-             WHERE h.email = $1 AND h.sensor = $2 AND w.recorded_at >= $3
+             WHERE h.id = ` + viewerHub + ` AND w.recorded_at >= $3
              ORDER BY w.recorded_at ASC;`
 	rows, err := db.pull.Query(ctx, text, email, hub, t)
 	if err != nil {
@@ -40,6 +57,27 @@ func (db *Postgres) GetWeightSinceTime(ctx context.Context, email, hub string, t
 	}
 	defer rows.Close()
 	var weights []dbTypes.HivesWeightData
+	for rows.Next() {
+		var weight dbTypes.HivesWeightData
+		if err := rows.Scan(&weight.Weight, &weight.Date, &weight.Quality); err != nil {
+			return nil, err
+		}
+		weights = append(weights, weight)
+	}
+	return weights, nil
+}
+
+// GetWeightSinceTimeById возвращает только достоверные замеры: его читают анализаторы.
+func (db *Postgres) GetWeightSinceTimeById(ctx context.Context, hubId int, t time.Time) ([]dbTypes.HivesWeightData, error) {
+	text := `SELECT level, recorded_at FROM weight
+             WHERE hub_id = $1 AND recorded_at >= $2 AND quality = 'ok'
+             ORDER BY recorded_at ASC;`
+	rows, err := db.pull.Query(ctx, text, hubId, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var weights []dbTypes.HivesWeightData
 	for rows.Next() {
 		var weight dbTypes.HivesWeightData
 		if err := rows.Scan(&weight.Weight, &weight.Date); err != nil {
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -139,10 +141,63 @@ func (r *Redis) GetAllSensors(ctx context.Context) (map[string]int64, error) {
 }
 
 func (r *Redis) DeleteSensor(ctx context.Context, sensorID string) error {
-	err := r.rds.HDel(ctx, "sensors", sensorID).Err()
+	pipe := r.rds.TxPipeline()
+	pipe.HDel(ctx, "sensors", sensorID)
+	pipe.HDel(ctx, "sensor_intervals", sensorID)
+	pipe.SRem(ctx, "sensors_offline", sensorID)
+	_, err := pipe.Exec(ctx)
 	return err
 }
 
+// SetSensorInterval сохраняет ожидаемый интервал выхода датчика на связь (в секундах).
+// Пишется при отправке конфига, читается watchdog-ом.
+func (r *Redis) SetSensorInterval(ctx context.Context, sensorID string, seconds int64) error {
+	return r.rds.HSet(ctx, "sensor_intervals", sensorID, seconds).Err()
+}
+
+func (r *Redis) GetSensorIntervals(ctx context.Context) (map[string]int64, error) {
+	result, err := r.rds.HGetAll(ctx, "sensor_intervals").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string]int64, len(result))
+	for key, value := range result {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		intervals[key] = seconds
+	}
+	return intervals, nil
+}
+
+// MarkSensorOffline помечает датчик как недоступный.
+// Возвращает true, только если датчик до этого был онлайн — т.е. началось новое отключение.
+func (r *Redis) MarkSensorOffline(ctx context.Context, sensorID string) (bool, error) {
+	added, err := r.rds.SAdd(ctx, "sensors_offline", sensorID).Result()
+	return added == 1, err
+}
+
+// MarkSensorOnline снимает отметку недоступности.
+// Возвращает true, если датчик до этого считался офлайн.
+func (r *Redis) MarkSensorOnline(ctx context.Context, sensorID string) (bool, error) {
+	removed, err := r.rds.SRem(ctx, "sensors_offline", sensorID).Result()
+	return removed == 1, err
+}
+
+func (r *Redis) GetOfflineSensors(ctx context.Context) (map[string]bool, error) {
+	members, err := r.rds.SMembers(ctx, "sensors_offline").Result()
+	if err != nil {
+		return nil, err
+	}
+	offline := make(map[string]bool, len(members))
+	for _, m := range members {
+		offline[m] = true
+	}
+	return offline, nil
+}
+
 func (r *Redis) SetLastSensorData(ctx context.Context, sensorID string, data string) error {
 	return r.rds.Set(ctx, "sensor_data:"+sensorID, data, 0).Err()
 }
@@ -158,3 +213,108 @@ func (r *Redis) SetLastDeviceStatus(ctx context.Context, sensorID string, data s
 func (r *Redis) GetLastDeviceStatus(ctx context.Context, sensorID string) (string, error) {
 	return r.rds.Get(ctx, "device_status:"+sensorID).Result()
 }
+
+func alertStateKey(key string) string {
+	return "alert_state:" + key
+}
+
+// GetAlertState возвращает сохранённое состояние алерта; пустая строка — состояния нет.
+func (r *Redis) GetAlertState(ctx context.Context, key string) (string, error) {
+	state, err := r.rds.Get(ctx, alertStateKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return state, err
+}
+
+// SetAlertState сохраняет состояние алерта; по истечении ttl состояние пропадает,
+// и следующее срабатывание откроет новый инцидент.
+func (r *Redis) SetAlertState(ctx context.Context, key, state string, ttl time.Duration) error {
+	return r.rds.Set(ctx, alertStateKey(key), state, ttl).Err()
+}
+
+// GetAlertStates возвращает состояния алертов, ключ которых начинается с prefix
+// (например, "email:" — все инциденты пользователя). Ключи — без служебного префикса.
+func (r *Redis) GetAlertStates(ctx context.Context, prefix string) (map[string]string, error) {
+	pattern := alertStateKey(globEscaper.Replace(prefix)) + "*"
+	states := make(map[string]string)
+	iter := r.rds.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		state, err := r.rds.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			// истёк между SCAN и GET
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states[strings.TrimPrefix(key, alertStateKey(""))] = state
+	}
+	return states, iter.Err()
+}
+
+// globEscaper экранирует спецсимволы шаблона SCAN MATCH.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func streamTicketKey(ticket string) string {
+	return "stream_ticket:" + ticket
+}
+
+// SetStreamTicket сохраняет билет на подключение к потоку телеметрии на ttl.
+func (r *Redis) SetStreamTicket(ctx context.Context, ticket, email string, ttl time.Duration) error {
+	return r.rds.Set(ctx, streamTicketKey(ticket), email, ttl).Err()
+}
+
+// TakeStreamTicket возвращает email владельца билета и сразу удаляет билет,
+// поэтому предъявить его можно только один раз. Пустая строка — билета нет или он истёк.
+func (r *Redis) TakeStreamTicket(ctx context.Context, ticket string) (string, error) {
+	email, err := r.rds.GetDel(ctx, streamTicketKey(ticket)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return email, err
+}
+
+// telemetryChannel — канал pub/sub, через который реплики сервера раздают
+// события телеметрии подключённым к ним клиентам.
+const telemetryChannel = "telemetry_events"
+
+func (r *Redis) PublishTelemetry(ctx context.Context, event string) error {
+	return r.rds.Publish(ctx, telemetryChannel, event).Err()
+}
+
+// SubscribeTelemetry подписывается на события телеметрии. Канал закрывается,
+// когда отменяется ctx; переподключение к Redis go-redis выполняет сам.
+func (r *Redis) SubscribeTelemetry(ctx context.Context) (<-chan string, error) {
+	ps := r.rds.Subscribe(ctx, telemetryChannel)
+	// Дожидаемся подтверждения подписки, чтобы не потерять первые события
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer func() {
+			_ = ps.Close()
+		}()
+		ch := ps.Channel()
+		for {
+			select {
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
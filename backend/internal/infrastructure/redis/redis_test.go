@@ -181,3 +181,122 @@ func TestGetAllSensors_ParseError(t *testing.T) {
 		t.Fatalf("expected parse error when sensor timestamp is not integer")
 	}
 }
+
+func TestSensors_OfflineAndIntervals(t *testing.T) {
+	rds, m := newTestRedis(t)
+	defer m.Close()
+	ctx := context.Background()
+
+	sid := "s123"
+	if err := rds.SetSensorInterval(ctx, sid, 300); err != nil {
+		t.Fatalf("SetSensorInterval failed: %v", err)
+	}
+	intervals, err := rds.GetSensorIntervals(ctx)
+	if err != nil {
+		t.Fatalf("GetSensorIntervals failed: %v", err)
+	}
+	if intervals[sid] != 300 {
+		t.Fatalf("expected interval 300, got %d", intervals[sid])
+	}
+
+	// первое отключение — новое, повторное — нет
+	isNew, err := rds.MarkSensorOffline(ctx, sid)
+	if err != nil || !isNew {
+		t.Fatalf("expected new outage, got %v, %v", isNew, err)
+	}
+	isNew, err = rds.MarkSensorOffline(ctx, sid)
+	if err != nil || isNew {
+		t.Fatalf("expected repeated outage to be ignored, got %v, %v", isNew, err)
+	}
+
+	offline, err := rds.GetOfflineSensors(ctx)
+	if err != nil {
+		t.Fatalf("GetOfflineSensors failed: %v", err)
+	}
+	if !offline[sid] {
+		t.Fatalf("expected sensor %s to be offline", sid)
+	}
+
+	wasOffline, err := rds.MarkSensorOnline(ctx, sid)
+	if err != nil || !wasOffline {
+		t.Fatalf("expected sensor to come back online, got %v, %v", wasOffline, err)
+	}
+	wasOffline, _ = rds.MarkSensorOnline(ctx, sid)
+	if wasOffline {
+		t.Fatalf("expected second MarkSensorOnline to report false")
+	}
+
+	// DeleteSensor чистит и интервал, и отметку недоступности
+	_, _ = rds.MarkSensorOffline(ctx, sid)
+	if err := rds.DeleteSensor(ctx, sid); err != nil {
+		t.Fatalf("DeleteSensor failed: %v", err)
+	}
+	intervals, _ = rds.GetSensorIntervals(ctx)
+	offline, _ = rds.GetOfflineSensors(ctx)
+	if _, ok := intervals[sid]; ok || offline[sid] {
+		t.Fatalf("expected sensor state to be removed after delete")
+	}
+}
+
+func TestTelemetry_PublishSubscribe(t *testing.T) {
+	rds, m := newTestRedis(t)
+	defer m.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := rds.SubscribeTelemetry(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeTelemetry failed: %v", err)
+	}
+	if err := rds.PublishTelemetry(context.Background(), `{"hub":"hub-1"}`); err != nil {
+		t.Fatalf("PublishTelemetry failed: %v", err)
+	}
+
+	select {
+	case got := <-events:
+		if got != `{"hub":"hub-1"}` {
+			t.Errorf("unexpected event: %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected channel to be closed after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for channel close")
+	}
+}
+
+func TestAlertState_SetGetExpire(t *testing.T) {
+	rds, m := newTestRedis(t)
+	defer m.Close()
+	ctx := context.Background()
+
+	state, err := rds.GetAlertState(ctx, "a@b.c:Hive1:battery_low")
+	if err != nil || state != "" {
+		t.Fatalf("expected empty state, got %q, %v", state, err)
+	}
+	if err := rds.SetAlertState(ctx, "a@b.c:Hive1:battery_low", `{"status":"open"}`, time.Hour); err != nil {
+		t.Fatalf("SetAlertState failed: %v", err)
+	}
+	state, err = rds.GetAlertState(ctx, "a@b.c:Hive1:battery_low")
+	if err != nil || state != `{"status":"open"}` {
+		t.Fatalf("unexpected state %q, %v", state, err)
+	}
+
+	_ = rds.SetAlertState(ctx, "a@b.c:Hive2:signal_low", `{"status":"resolved"}`, 3*time.Hour)
+	_ = rds.SetAlertState(ctx, "x@b.c:Hive1:battery_low", `{"status":"open"}`, time.Hour)
+	states, err := rds.GetAlertStates(ctx, "a@b.c:")
+	if err != nil || len(states) != 2 || states["a@b.c:Hive2:signal_low"] != `{"status":"resolved"}` {
+		t.Fatalf("unexpected states %v, %v", states, err)
+	}
+
+	m.FastForward(2 * time.Hour)
+	if state, _ = rds.GetAlertState(ctx, "a@b.c:Hive1:battery_low"); state != "" {
+		t.Fatalf("expected state to expire, got %q", state)
+	}
+}
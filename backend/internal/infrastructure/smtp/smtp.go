@@ -42,3 +42,17 @@ func (smtp *SMTP) SendConfirmationCode(toEmail, code string) error {
 	err := e.Send(smtp.smtpAddress, auth)
 	return err
 }
+
+// SendEmail отправляет письмо с текстовой и, если задана, HTML-версией.
+func (smtp *SMTP) SendEmail(toEmail, subject, text, html string) error {
+	e := email.NewEmail()
+	e.From = "Hive Monitoring <" + smtp.smtpUser + ">"
+	e.To = []string{toEmail}
+	e.Subject = subject
+	e.Text = []byte(text)
+	if html != "" {
+		e.HTML = []byte(html)
+	}
+	auth := smtpLib.PlainAuth("", smtp.smtpUser, smtp.smtpPass, smtp.smtpHost)
+	return e.Send(smtp.smtpAddress, auth)
+}